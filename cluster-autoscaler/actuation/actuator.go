@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// Actuator executes the decisions taken by scale-up and scale-down. All calls that
+// modify the cloud provider or the nodes and pods of the cluster go through it.
+type Actuator interface {
+	// IncreaseSize increases the size of the node group by delta. The cloud provider call is
+	// abandoned once ctx is done.
+	IncreaseSize(ctx context.Context, nodeGroup cloudprovider.NodeGroup, delta int) error
+	// DecreaseTargetSize decreases the target size of the node group by delta (delta is negative).
+	// The cloud provider call is abandoned once ctx is done.
+	DecreaseTargetSize(ctx context.Context, nodeGroup cloudprovider.NodeGroup, delta int) error
+	// DeleteNodes removes the given nodes from the node group. The cloud provider call is abandoned
+	// once ctx is done.
+	DeleteNodes(ctx context.Context, nodeGroup cloudprovider.NodeGroup, nodes []*apiv1.Node) error
+	// MarkToBeDeleted makes the node unschedulable ahead of its deletion.
+	MarkToBeDeleted(node *apiv1.Node) error
+	// CleanToBeDeleted reverts MarkToBeDeleted. Returns true if the node was changed.
+	CleanToBeDeleted(node *apiv1.Node) (bool, error)
+	// MarkDeletionCandidate makes the node preferably unschedulable while it's unneeded.
+	MarkDeletionCandidate(node *apiv1.Node) error
+	// CleanDeletionCandidate reverts MarkDeletionCandidate. Returns true if the node was changed.
+	CleanDeletionCandidate(node *apiv1.Node) (bool, error)
+	// DrainNode evicts the given pods from the node and waits until they are gone. ctx carries the
+	// trace of the drain; drains are stopped with AbortDrains.
+	DrainNode(ctx context.Context, node *apiv1.Node, pods []*apiv1.Pod) errors.AutoscalerError
+	// AbortDrains makes drains in progress and all later drains fail. Used when the autoscaler stops.
+	AbortDrains()
+}
+
+// ActionType describes the kind of an action taken by an Actuator.
+type ActionType string
+
+const (
+	// IncreaseSizeAction - node group size increase.
+	IncreaseSizeAction ActionType = "IncreaseSize"
+	// DecreaseTargetSizeAction - node group target size decrease.
+	DecreaseTargetSizeAction ActionType = "DecreaseTargetSize"
+	// DeleteNodesAction - removal of nodes from a node group.
+	DeleteNodesAction ActionType = "DeleteNodes"
+	// MarkToBeDeletedAction - tainting a node ahead of its deletion.
+	MarkToBeDeletedAction ActionType = "MarkToBeDeleted"
+	// CleanToBeDeletedAction - removing the deletion taint from a node.
+	CleanToBeDeletedAction ActionType = "CleanToBeDeleted"
+	// MarkDeletionCandidateAction - soft-tainting an unneeded node.
+	MarkDeletionCandidateAction ActionType = "MarkDeletionCandidate"
+	// CleanDeletionCandidateAction - removing the soft taint from a node.
+	CleanDeletionCandidateAction ActionType = "CleanDeletionCandidate"
+	// DrainNodeAction - eviction of pods from a node.
+	DrainNodeAction ActionType = "DrainNode"
+)
+
+// Action is a single action taken (or, in dry-run mode, intended) by an Actuator.
+type Action struct {
+	Type      ActionType `json:"type"`
+	NodeGroup string     `json:"nodeGroup,omitempty"`
+	Delta     int        `json:"delta,omitempty"`
+	Nodes     []string   `json:"nodes,omitempty"`
+	Pods      []string   `json:"pods,omitempty"`
+	Time      time.Time  `json:"time"`
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/deletetaint"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_record "k8s.io/client-go/tools/record"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// DefaultMaxRecordedActions is the number of most recent actions kept by DryRunActuator.
+	DefaultMaxRecordedActions = 500
+)
+
+// DryRunActuator is an Actuator that doesn't touch the cloud provider or the cluster. It only
+// records the actions it was asked to take and exposes them as events, metrics and over HTTP.
+type DryRunActuator struct {
+	sync.Mutex
+	maxActions  int
+	actions     []Action
+	recorder    kube_record.EventRecorder
+	logRecorder *utils.LogEventRecorder
+}
+
+// NewDryRunActuator builds new DryRunActuator.
+func NewDryRunActuator(recorder kube_record.EventRecorder, logRecorder *utils.LogEventRecorder) *DryRunActuator {
+	return &DryRunActuator{
+		maxActions:  DefaultMaxRecordedActions,
+		actions:     make([]Action, 0),
+		recorder:    recorder,
+		logRecorder: logRecorder,
+	}
+}
+
+// IncreaseSize records the intended node group size increase.
+func (a *DryRunActuator) IncreaseSize(_ context.Context, nodeGroup cloudprovider.NodeGroup, delta int) error {
+	a.record(Action{Type: IncreaseSizeAction, NodeGroup: nodeGroup.Id(), Delta: delta})
+	a.logRecorder.Eventf(apiv1.EventTypeNormal, "DryRunScaleUp", "Dry-run: would increase size of group %s by %d", nodeGroup.Id(), delta)
+	return nil
+}
+
+// DecreaseTargetSize records the intended node group target size decrease.
+func (a *DryRunActuator) DecreaseTargetSize(_ context.Context, nodeGroup cloudprovider.NodeGroup, delta int) error {
+	a.record(Action{Type: DecreaseTargetSizeAction, NodeGroup: nodeGroup.Id(), Delta: delta})
+	a.logRecorder.Eventf(apiv1.EventTypeNormal, "DryRunFixNodeGroupSize", "Dry-run: would decrease target size of group %s by %d", nodeGroup.Id(), -delta)
+	return nil
+}
+
+// DeleteNodes records the intended node removal.
+func (a *DryRunActuator) DeleteNodes(_ context.Context, nodeGroup cloudprovider.NodeGroup, nodes []*apiv1.Node) error {
+	a.record(Action{Type: DeleteNodesAction, NodeGroup: nodeGroup.Id(), Nodes: nodeNames(nodes)})
+	for _, node := range nodes {
+		a.recorder.Eventf(node, apiv1.EventTypeNormal, "DryRunScaleDown", "dry-run: node would be removed by cluster autoscaler")
+		a.logRecorder.Eventf(apiv1.EventTypeNormal, "DryRunScaleDown", "Dry-run: would remove node %s from group %s", node.Name, nodeGroup.Id())
+	}
+	return nil
+}
+
+// MarkToBeDeleted records the intended tainting of the node.
+func (a *DryRunActuator) MarkToBeDeleted(node *apiv1.Node) error {
+	a.record(Action{Type: MarkToBeDeletedAction, Nodes: []string{node.Name}})
+	a.recorder.Eventf(node, apiv1.EventTypeNormal, "DryRunScaleDown", "dry-run: node would be marked as toBeDeleted/unschedulable")
+	return nil
+}
+
+// CleanToBeDeleted records the intended removal of the deletion taint. Nothing is recorded
+// for nodes that don't have the taint.
+func (a *DryRunActuator) CleanToBeDeleted(node *apiv1.Node) (bool, error) {
+	if !deletetaint.HasToBeDeletedTaint(node) {
+		return false, nil
+	}
+	a.record(Action{Type: CleanToBeDeletedAction, Nodes: []string{node.Name}})
+	return false, nil
+}
+
+// MarkDeletionCandidate records the intended soft-tainting of the node.
+func (a *DryRunActuator) MarkDeletionCandidate(node *apiv1.Node) error {
+	a.record(Action{Type: MarkDeletionCandidateAction, Nodes: []string{node.Name}})
+	return nil
+}
+
+// CleanDeletionCandidate records the intended removal of the soft taint. Nothing is recorded
+// for nodes that don't have the taint.
+func (a *DryRunActuator) CleanDeletionCandidate(node *apiv1.Node) (bool, error) {
+	if !deletetaint.HasDeletionCandidateTaint(node) {
+		return false, nil
+	}
+	a.record(Action{Type: CleanDeletionCandidateAction, Nodes: []string{node.Name}})
+	return false, nil
+}
+
+// DrainNode records the intended eviction of the pods.
+func (a *DryRunActuator) DrainNode(_ context.Context, node *apiv1.Node, pods []*apiv1.Pod) errors.AutoscalerError {
+	podNames := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		podNames = append(podNames, pod.Namespace+"/"+pod.Name)
+	}
+	a.record(Action{Type: DrainNodeAction, Nodes: []string{node.Name}, Pods: podNames})
+	a.recorder.Eventf(node, apiv1.EventTypeNormal, "DryRunScaleDown", "dry-run: %d pods would be evicted from the node", len(pods))
+	return nil
+}
+
+// AbortDrains does nothing, drains are only recorded.
+func (a *DryRunActuator) AbortDrains() {}
+
+// GetActions returns a copy of the recorded actions, oldest first.
+func (a *DryRunActuator) GetActions() []Action {
+	a.Lock()
+	defer a.Unlock()
+	result := make([]Action, len(a.actions))
+	copy(result, a.actions)
+	return result
+}
+
+// ServeHTTP implements http.Handler interface to expose the recorded actions as JSON.
+func (a *DryRunActuator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(a.GetActions())
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(body)
+}
+
+func (a *DryRunActuator) record(action Action) {
+	action.Time = time.Now()
+	glog.V(0).Infof("Dry-run: skipping %s %+v", action.Type, action)
+	metrics.RegisterDryRunAction(string(action.Type))
+
+	a.Lock()
+	defer a.Unlock()
+	a.actions = append(a.actions, action)
+	if len(a.actions) > a.maxActions {
+		updated := make([]Action, a.maxActions)
+		copy(updated, a.actions[len(a.actions)-a.maxActions:])
+		a.actions = updated
+	}
+}
+
+func nodeNames(nodes []*apiv1.Node) []string {
+	result := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node.Name)
+	}
+	return result
+}
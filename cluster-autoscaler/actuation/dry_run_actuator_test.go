@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	testprovider "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/deletetaint"
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDryRunActuator() *DryRunActuator {
+	fakeClient := &fake.Clientset{}
+	fakeRecorder := kube_record.NewFakeRecorder(100)
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", fakeRecorder, false)
+	return NewDryRunActuator(fakeRecorder, fakeLogRecorder)
+}
+
+func TestDryRunActuatorDoesNotExecute(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(func(id string, delta int) error {
+		return fmt.Errorf("unexpected scale up of %s by %d", id, delta)
+	}, func(id string, name string) error {
+		return fmt.Errorf("unexpected scale down of %s in %s", name, id)
+	})
+	n1 := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 100)
+	p1.Namespace = "default"
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+	ng1 := provider.GetNodeGroup("ng1")
+
+	actuator := newTestDryRunActuator()
+	assert.NoError(t, actuator.IncreaseSize(context.Background(), ng1, 2))
+	assert.NoError(t, actuator.DecreaseTargetSize(context.Background(), ng1, -1))
+	assert.NoError(t, actuator.MarkToBeDeleted(n1))
+	assert.Nil(t, actuator.DrainNode(context.Background(), n1, []*apiv1.Pod{p1}))
+	assert.NoError(t, actuator.DeleteNodes(context.Background(), ng1, []*apiv1.Node{n1}))
+
+	size, err := ng1.TargetSize()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, size)
+	assert.False(t, deletetaint.HasToBeDeletedTaint(n1))
+
+	actions := actuator.GetActions()
+	assert.Equal(t, 5, len(actions))
+	assert.Equal(t, Action{Type: IncreaseSizeAction, NodeGroup: "ng1", Delta: 2, Time: actions[0].Time}, actions[0])
+	assert.Equal(t, Action{Type: DecreaseTargetSizeAction, NodeGroup: "ng1", Delta: -1, Time: actions[1].Time}, actions[1])
+	assert.Equal(t, Action{Type: MarkToBeDeletedAction, Nodes: []string{"n1"}, Time: actions[2].Time}, actions[2])
+	assert.Equal(t, Action{Type: DrainNodeAction, Nodes: []string{"n1"}, Pods: []string{"default/p1"}, Time: actions[3].Time}, actions[3])
+	assert.Equal(t, Action{Type: DeleteNodesAction, NodeGroup: "ng1", Nodes: []string{"n1"}, Time: actions[4].Time}, actions[4])
+}
+
+func TestDryRunActuatorCleanToBeDeleted(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n2.Spec.Taints = []apiv1.Taint{{Key: deletetaint.ToBeDeletedTaint, Value: "1", Effect: apiv1.TaintEffectNoSchedule}}
+
+	actuator := newTestDryRunActuator()
+	cleaned, err := actuator.CleanToBeDeleted(n1)
+	assert.NoError(t, err)
+	assert.False(t, cleaned)
+	cleaned, err = actuator.CleanToBeDeleted(n2)
+	assert.NoError(t, err)
+	assert.False(t, cleaned)
+	assert.True(t, deletetaint.HasToBeDeletedTaint(n2))
+
+	actions := actuator.GetActions()
+	assert.Equal(t, 1, len(actions))
+	assert.Equal(t, CleanToBeDeletedAction, actions[0].Type)
+	assert.Equal(t, []string{"n2"}, actions[0].Nodes)
+}
+
+func TestDryRunActuatorMaxActions(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	ng1 := provider.GetNodeGroup("ng1")
+
+	actuator := newTestDryRunActuator()
+	actuator.maxActions = 3
+	for i := 1; i <= 5; i++ {
+		actuator.IncreaseSize(context.Background(), ng1, i)
+	}
+	actions := actuator.GetActions()
+	assert.Equal(t, 3, len(actions))
+	assert.Equal(t, 3, actions[0].Delta)
+	assert.Equal(t, 5, actions[2].Delta)
+}
+
+func TestDryRunActuatorServeHTTP(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+
+	actuator := newTestDryRunActuator()
+	actuator.IncreaseSize(context.Background(), provider.GetNodeGroup("ng1"), 3)
+
+	req := httptest.NewRequest("GET", "/dry-run-actions", nil)
+	w := httptest.NewRecorder()
+	actuator.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var actions []Action
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actions))
+	assert.Equal(t, 1, len(actions))
+	assert.Equal(t, IncreaseSizeAction, actions[0].Type)
+	assert.Equal(t, "ng1", actions[0].NodeGroup)
+	assert.Equal(t, 3, actions[0].Delta)
+}
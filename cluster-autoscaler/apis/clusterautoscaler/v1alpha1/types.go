@@ -0,0 +1,160 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterAutoscalerStatus holds the structured status of a Cluster Autoscaler
+// instance. It is the typed counterpart of the cluster-autoscaler-status
+// ConfigMap and is written by Cluster Autoscaler in every loop iteration.
+type ClusterAutoscalerStatus struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Standard object metadata.
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status of the autoscaler as observed in the most recent loop iteration.
+	// +optional
+	Status AutoscalerStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterAutoscalerStatusList is a collection of ClusterAutoscalerStatus objects.
+type ClusterAutoscalerStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Standard list metadata
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// items is the list of ClusterAutoscalerStatus
+	Items []ClusterAutoscalerStatus `json:"items"`
+}
+
+// AutoscalerStatus is the structured status of Cluster Autoscaler.
+type AutoscalerStatus struct {
+	// LastUpdateTime is the time at which the status was written.
+	// +optional
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// ClusterwideConditions contains conditions that apply to the whole autoscaler.
+	// +optional
+	ClusterwideConditions []Condition `json:"clusterwideConditions,omitempty"`
+	// NodeGroups contains status information of individual node groups on which CA works.
+	// +optional
+	NodeGroups []NodeGroupStatus `json:"nodeGroups,omitempty"`
+	// ScaleUpHistory lists the most recent scale-ups, oldest first.
+	// +optional
+	ScaleUpHistory []ScaleUpEvent `json:"scaleUpHistory,omitempty"`
+	// ScaleDownHistory lists the most recent node deletions, oldest first.
+	// +optional
+	ScaleDownHistory []ScaleDownEvent `json:"scaleDownHistory,omitempty"`
+	// UnremovableNodes lists nodes that were considered for scale down but
+	// could not be removed, together with the reason.
+	// +optional
+	UnremovableNodes []UnremovableNode `json:"unremovableNodes,omitempty"`
+}
+
+// ConditionType is the type of Condition.
+type ConditionType string
+
+const (
+	// HealthCondition explains what is the current health of Cluster Autoscaler or its node groups.
+	HealthCondition ConditionType = "Health"
+	// ScaleDownCondition explains what is the current status of a node group with regard to
+	// scale down activities.
+	ScaleDownCondition ConditionType = "ScaleDown"
+	// ScaleUpCondition explains what is the current status of a node group with regard to
+	// scale up activities.
+	ScaleUpCondition ConditionType = "ScaleUp"
+)
+
+// ConditionStatus is a status of Condition. The set of possible values depends
+// on the condition type, e.g. Healthy/Unhealthy for Health or Needed/NotNeeded/
+// InProgress/NoActivity/Backoff for ScaleUp.
+type ConditionStatus string
+
+// Condition describes some aspect of Cluster Autoscaler work.
+type Condition struct {
+	// Type defines the aspect that the condition describes.
+	Type ConditionType `json:"type"`
+	// Status of the condition.
+	Status ConditionStatus `json:"status"`
+	// Message is a free text extra information about the condition.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Reason is a unique, one-word, CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// LastProbeTime is the last time we probed the condition.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+	// LastTransitionTime is the time since when the condition was in the given state.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// NodeGroupStatus contains status of a group of nodes controlled by Cluster Autoscaler.
+type NodeGroupStatus struct {
+	// Name is the cloud-provider-specific name of the node group.
+	Name string `json:"name"`
+	// Conditions is a list of conditions that describe the state of the node group.
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// ScaleUpEvent describes a scale-up of a single node group.
+type ScaleUpEvent struct {
+	// NodeGroup is the node group that was scaled up.
+	NodeGroup string `json:"nodeGroup"`
+	// Increase is the number of nodes requested.
+	Increase int32 `json:"increase"`
+	// Time is the time when the scale-up was requested.
+	Time metav1.Time `json:"time"`
+	// ExpectedAddTime is the time by which the new nodes were expected to register.
+	ExpectedAddTime metav1.Time `json:"expectedAddTime"`
+}
+
+// ScaleDownEvent describes the deletion of a single node.
+type ScaleDownEvent struct {
+	// NodeName is the name of the deleted node.
+	NodeName string `json:"nodeName"`
+	// NodeGroup is the node group of the deleted node.
+	NodeGroup string `json:"nodeGroup"`
+	// Time is the time when the deletion was requested.
+	Time metav1.Time `json:"time"`
+	// ExpectedDeleteTime is the time by which the node was expected to be gone.
+	ExpectedDeleteTime metav1.Time `json:"expectedDeleteTime"`
+}
+
+// UnremovableNode describes a node that Cluster Autoscaler could not remove.
+type UnremovableNode struct {
+	// NodeName is the name of the node.
+	NodeName string `json:"nodeName"`
+	// Reason explains why the node cannot be removed.
+	Reason string `json:"reason"`
+	// BlockingPod is the namespace/name of the pod that prevents the removal.
+	// +optional
+	BlockingPod string `json:"blockingPod,omitempty"`
+	// BlockingPodReason explains why BlockingPod can't be moved.
+	// +optional
+	BlockingPodReason string `json:"blockingPodReason,omitempty"`
+}
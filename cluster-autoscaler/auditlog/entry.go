@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// DecisionType is the kind of a decision recorded in the audit log.
+type DecisionType string
+
+const (
+	// ScaleUpDecision - node groups were scaled up.
+	ScaleUpDecision DecisionType = "ScaleUp"
+	// ScaleDownDecision - a node was chosen for removal.
+	ScaleDownDecision DecisionType = "ScaleDown"
+	// SkippedDecision - a scale-up or scale-down wasn't attempted or didn't find anything to do.
+	SkippedDecision DecisionType = "Skipped"
+	// NoScaleUpDecision - pods didn't trigger a scale-up.
+	NoScaleUpDecision DecisionType = "NoScaleUp"
+)
+
+// Action is the scaling action a skipped decision refers to.
+type Action string
+
+const (
+	// ScaleUpAction - scale-up.
+	ScaleUpAction Action = "ScaleUp"
+	// ScaleDownAction - scale-down.
+	ScaleDownAction Action = "ScaleDown"
+)
+
+// Entry is a single line of the audit log. Exactly one of the decision fields is set, as given by Decision.
+type Entry struct {
+	Time      time.Time      `json:"time"`
+	Decision  DecisionType   `json:"decision"`
+	DryRun    bool           `json:"dryRun,omitempty"`
+	ScaleUp   *ScaleUp       `json:"scaleUp,omitempty"`
+	ScaleDown *ScaleDown     `json:"scaleDown,omitempty"`
+	Skipped   *Skipped       `json:"skipped,omitempty"`
+	NoScaleUp []PodNoScaleUp `json:"noScaleUp,omitempty"`
+}
+
+// ScaleUpOption is a node group evaluated in scale-up.
+type ScaleUpOption struct {
+	NodeGroup string `json:"nodeGroup"`
+	NodeCount int    `json:"nodeCount"`
+	// Pods are the pods that would fit on the new nodes.
+	Pods []string `json:"pods"`
+	// Debug is the debug information provided by the estimator or the expander, if any.
+	Debug string `json:"debug,omitempty"`
+}
+
+// NodeGroupIncrease is a node group size increase made in scale-up.
+type NodeGroupIncrease struct {
+	NodeGroup   string `json:"nodeGroup"`
+	CurrentSize int    `json:"currentSize"`
+	NewSize     int    `json:"newSize"`
+}
+
+// ScaleUp records a scale-up.
+type ScaleUp struct {
+	// Chosen is the option picked by the expander.
+	Chosen ScaleUpOption `json:"chosen"`
+	// Options are all options the expander picked from.
+	Options []ScaleUpOption `json:"options"`
+	// Increases are the size increases made, more than one if the scale-up was balanced between similar node groups.
+	Increases []NodeGroupIncrease `json:"increases"`
+	// SkippedNodeGroups are the node groups that weren't evaluated, with the reasons.
+	SkippedNodeGroups map[string][]string `json:"skippedNodeGroups,omitempty"`
+}
+
+// PodToReschedule is a pod evicted in scale-down.
+type PodToReschedule struct {
+	Pod string `json:"pod"`
+	// TargetNode is the node the pod is expected to be rescheduled on, if known.
+	TargetNode string `json:"targetNode,omitempty"`
+}
+
+// ScaleDown records the choice of a node for removal.
+type ScaleDown struct {
+	Node      string `json:"node"`
+	NodeGroup string `json:"nodeGroup"`
+	// Reason is the scale-down reason, as in the scaled_down_nodes_total metric.
+	Reason           string            `json:"reason"`
+	Utilization      float64           `json:"utilization"`
+	PodsToReschedule []PodToReschedule `json:"podsToReschedule,omitempty"`
+}
+
+// Skipped records why a scale-up or scale-down wasn't done.
+type Skipped struct {
+	Action Action `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// PodNoScaleUp records why a pod didn't trigger scale-up.
+type PodNoScaleUp struct {
+	Pod     string `json:"pod"`
+	Reasons string `json:"reasons"`
+}
+
+// PodName returns the name of the pod as recorded in the audit log.
+func PodName(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// PodNames returns the names of the pods as recorded in the audit log.
+func PodNames(pods []*apiv1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, PodName(pod))
+	}
+	return names
+}
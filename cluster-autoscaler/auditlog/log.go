@@ -0,0 +1,215 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Log is an append-only log of scaling decisions, written as JSON Lines. All methods do nothing
+// on a nil Log, so callers don't need to check whether the audit log is enabled.
+type Log struct {
+	sync.Mutex
+	writer io.Writer
+	dryRun bool
+	// lastSkipReasons are the reasons of the last Skipped entries, by action, cleared once the
+	// action is taken.
+	lastSkipReasons map[Action]string
+
+	// Set only for logs written to a file.
+	file       *os.File
+	path       string
+	size       int64
+	maxBytes   int64
+	maxBackups int
+	// backupsShifted is set if the file was moved to a backup, but the new file couldn't be opened.
+	backupsShifted bool
+}
+
+// NewWriterLog builds new Log writing to the given writer, e.g. stdout. The writer isn't rotated.
+func NewWriterLog(writer io.Writer, dryRun bool) *Log {
+	return &Log{writer: writer, dryRun: dryRun}
+}
+
+// NewFileLog builds new Log appending to the file at path. Once the file would grow over maxBytes,
+// it is rotated: renamed to path.1, path.1 to path.2 and so on, keeping at most maxBackups old files.
+func NewFileLog(path string, maxBytes int64, maxBackups int, dryRun bool) (*Log, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("audit log max size must be positive, got %d", maxBytes)
+	}
+	if maxBackups < 0 {
+		return nil, fmt.Errorf("audit log max backups must not be negative, got %d", maxBackups)
+	}
+	l := &Log{path: path, maxBytes: maxBytes, maxBackups: maxBackups, dryRun: dryRun}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ScaleUp records a scale-up.
+func (l *Log) ScaleUp(scaleUp *ScaleUp) {
+	l.clearSkipReason(ScaleUpAction)
+	l.write(&Entry{Decision: ScaleUpDecision, ScaleUp: scaleUp})
+}
+
+// ScaleDown records the choice of a node for removal.
+func (l *Log) ScaleDown(scaleDown *ScaleDown) {
+	l.clearSkipReason(ScaleDownAction)
+	l.write(&Entry{Decision: ScaleDownDecision, ScaleDown: scaleDown})
+}
+
+// Skip records why a scale-up or scale-down wasn't done. The entry is written only if the reason
+// differs from the one of the last skip of the action, so that a condition lasting for many loops
+// is recorded once.
+func (l *Log) Skip(action Action, reason string) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	if l.lastSkipReasons == nil {
+		l.lastSkipReasons = make(map[Action]string)
+	}
+	lastReason, found := l.lastSkipReasons[action]
+	l.lastSkipReasons[action] = reason
+	l.Unlock()
+	if found && lastReason == reason {
+		return
+	}
+	l.write(&Entry{Decision: SkippedDecision, Skipped: &Skipped{Action: action, Reason: reason}})
+}
+
+// NoScaleUp records why pods didn't trigger scale-up.
+func (l *Log) NoScaleUp(pods []PodNoScaleUp) {
+	if len(pods) == 0 {
+		return
+	}
+	l.clearSkipReason(ScaleUpAction)
+	l.write(&Entry{Decision: NoScaleUpDecision, NoScaleUp: pods})
+}
+
+func (l *Log) clearSkipReason(action Action) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	delete(l.lastSkipReasons, action)
+}
+
+// Close closes the file the log is written to, if any.
+func (l *Log) Close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.Lock()
+	defer l.Unlock()
+	return l.file.Close()
+}
+
+func (l *Log) write(entry *Entry) {
+	if l == nil {
+		return
+	}
+	entry.Time = time.Now()
+	entry.DryRun = l.dryRun
+	line, err := json.Marshal(entry)
+	if err != nil {
+		glog.Errorf("Failed to marshal audit log entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.Lock()
+	defer l.Unlock()
+	if l.file != nil && l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			glog.Errorf("Failed to rotate audit log: %v", err)
+		}
+	}
+	n, err := l.writer.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		glog.Errorf("Failed to write audit log entry: %v", err)
+	}
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %v", l.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit log %s: %v", l.path, err)
+	}
+	l.file = file
+	l.writer = file
+	l.size = info.Size()
+	return nil
+}
+
+// To be executed under the lock. The file is reopened even if old files couldn't be shifted. The old
+// file is kept open until the new one is open, so if that fails, entries are still written to the old
+// file and opening is retried with the next entry.
+func (l *Log) rotate() error {
+	oldFile := l.file
+	var shiftErr error
+	if !l.backupsShifted {
+		shiftErr = l.shiftBackups()
+		l.backupsShifted = true
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	l.backupsShifted = false
+	if err := oldFile.Close(); err != nil {
+		glog.Warningf("Failed to close audit log %s: %v", l.path, err)
+	}
+	return shiftErr
+}
+
+func (l *Log) shiftBackups() error {
+	if l.maxBackups == 0 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	os.Remove(backupPath(l.path, l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(l.path, i), backupPath(l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(l.path, backupPath(l.path, 1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
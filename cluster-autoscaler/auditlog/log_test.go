@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readEntries(t *testing.T, data []byte) []Entry {
+	entries := []Entry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry Entry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestWriterLog(t *testing.T) {
+	var buffer bytes.Buffer
+	log := NewWriterLog(&buffer, true)
+	log.ScaleUp(&ScaleUp{
+		Chosen:    ScaleUpOption{NodeGroup: "ng1", NodeCount: 2, Pods: []string{"default/p1"}},
+		Options:   []ScaleUpOption{{NodeGroup: "ng1", NodeCount: 2, Pods: []string{"default/p1"}}, {NodeGroup: "ng2", NodeCount: 3, Debug: "cheaper"}},
+		Increases: []NodeGroupIncrease{{NodeGroup: "ng1", CurrentSize: 1, NewSize: 3}},
+	})
+	log.ScaleDown(&ScaleDown{Node: "n1", NodeGroup: "ng1", Reason: "underutilized", Utilization: 0.3,
+		PodsToReschedule: []PodToReschedule{{Pod: "default/p2", TargetNode: "n2"}}})
+	log.Skip(ScaleDownAction, "scale-down delay after add")
+	log.NoScaleUp(nil)
+	log.NoScaleUp([]PodNoScaleUp{{Pod: "default/p3", Reasons: "1 max limit reached"}})
+
+	entries := readEntries(t, buffer.Bytes())
+	assert.Len(t, entries, 4)
+	assert.Equal(t, ScaleUpDecision, entries[0].Decision)
+	assert.True(t, entries[0].DryRun)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, "ng1", entries[0].ScaleUp.Chosen.NodeGroup)
+	assert.Equal(t, "cheaper", entries[0].ScaleUp.Options[1].Debug)
+	assert.Equal(t, 3, entries[0].ScaleUp.Increases[0].NewSize)
+	assert.Equal(t, ScaleDownDecision, entries[1].Decision)
+	assert.Equal(t, "n2", entries[1].ScaleDown.PodsToReschedule[0].TargetNode)
+	assert.Equal(t, &Skipped{Action: ScaleDownAction, Reason: "scale-down delay after add"}, entries[2].Skipped)
+	assert.Nil(t, entries[2].ScaleUp)
+	assert.Equal(t, NoScaleUpDecision, entries[3].Decision)
+	assert.Equal(t, "default/p3", entries[3].NoScaleUp[0].Pod)
+}
+
+func TestFileLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.jsonl")
+
+	log, err := NewFileLog(path, 150, 2, false)
+	assert.NoError(t, err)
+	for i := 0; i < 6; i++ {
+		log.Skip(ScaleUpAction, fmt.Sprintf("no expansion options %d", i))
+	}
+	assert.NoError(t, log.Close())
+
+	// Every entry is about 100 bytes, so each file holds one entry and only the newest three are kept.
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		data, err := ioutil.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Len(t, readEntries(t, data), 1)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	// Reopening appends to the existing file.
+	log, err = NewFileLog(path, 1000, 2, false)
+	assert.NoError(t, err)
+	log.Skip(ScaleUpAction, "no expansion options")
+	assert.NoError(t, log.Close())
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, readEntries(t, data), 2)
+}
+
+func TestFileLogRotationOpenFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.jsonl")
+
+	log, err := NewFileLog(path, 150, 2, false)
+	assert.NoError(t, err)
+	log.Skip(ScaleUpAction, "reason 1")
+	// The new file can't be created, so the entry goes to the old one.
+	log.path = filepath.Join(dir, "missing", "audit.jsonl")
+	log.Skip(ScaleUpAction, "reason 2")
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, readEntries(t, data), 2)
+
+	// Opening is retried with the next entry.
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "missing"), 0755))
+	log.Skip(ScaleUpAction, "reason 3")
+	assert.NoError(t, log.Close())
+	data, err = ioutil.ReadFile(log.path)
+	assert.NoError(t, err)
+	assert.Len(t, readEntries(t, data), 1)
+}
+
+func TestSkipWrittenOnReasonChange(t *testing.T) {
+	var buffer bytes.Buffer
+	log := NewWriterLog(&buffer, false)
+	log.Skip(ScaleDownAction, "scale-down delay after add")
+	log.Skip(ScaleDownAction, "scale-down delay after add")
+	log.Skip(ScaleUpAction, "no expansion options")
+	log.Skip(ScaleDownAction, "cluster unhealthy")
+	log.Skip(ScaleDownAction, "cluster unhealthy")
+	log.ScaleDown(&ScaleDown{Node: "n1"})
+	log.Skip(ScaleDownAction, "cluster unhealthy")
+
+	entries := readEntries(t, buffer.Bytes())
+	reasons := []string{}
+	for _, entry := range entries {
+		if entry.Skipped != nil {
+			reasons = append(reasons, entry.Skipped.Reason)
+		}
+	}
+	assert.Len(t, entries, 5)
+	assert.Equal(t, []string{"scale-down delay after add", "no expansion options", "cluster unhealthy", "cluster unhealthy"}, reasons)
+}
+
+func TestNilLog(t *testing.T) {
+	var log *Log
+	log.ScaleUp(&ScaleUp{})
+	log.ScaleDown(&ScaleDown{})
+	log.Skip(ScaleUpAction, "reason")
+	log.NoScaleUp([]PodNoScaleUp{{Pod: "default/p1"}})
+	assert.NoError(t, log.Close())
+}
+
+func TestNewFileLogErrors(t *testing.T) {
+	_, err := NewFileLog("/nonexistent/audit.jsonl", 100, 1, false)
+	assert.Error(t, err)
+	_, err = NewFileLog("audit.jsonl", 0, 1, false)
+	assert.Error(t, err)
+	_, err = NewFileLog("audit.jsonl", 100, -1, false)
+	assert.Error(t, err)
+}
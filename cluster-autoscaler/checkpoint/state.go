@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/backoff"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// State is the part of the autoscaler state that has to survive restarts and leader changes
+// for scale-down to make progress.
+type State struct {
+	// Time is the time at which the state was taken.
+	Time time.Time `json:"time"`
+	// UnneededNodes maps node names to the time since which they are unneeded.
+	UnneededNodes map[string]time.Time `json:"unneededNodes,omitempty"`
+	// UnremovableNodes maps node names to the time at which they should be checked again.
+	UnremovableNodes map[string]time.Time `json:"unremovableNodes,omitempty"`
+	// UnremovableReasons maps names of unremovable nodes to the reason.
+	UnremovableReasons map[string]simulator.UnremovableReason `json:"unremovableReasons,omitempty"`
+	// PodLocationHints maps pods (namespace/name) to the node they are expected to be moved to.
+	PodLocationHints map[string]string `json:"podLocationHints,omitempty"`
+	// UsageTracker holds usage records indexed by node name.
+	UsageTracker map[string]simulator.UsageInfo `json:"usageTracker,omitempty"`
+	// NodeGroupBackoff holds scale-up backoff data indexed by node group id.
+	NodeGroupBackoff map[string]backoff.Entry `json:"nodeGroupBackoff,omitempty"`
+	// LastScaleUpTime is the time of the last scale-up, zero if unknown.
+	LastScaleUpTime time.Time `json:"lastScaleUpTime"`
+	// LastScaleDownDeleteTime is the time of the last successful node deletion, zero if unknown.
+	LastScaleDownDeleteTime time.Time `json:"lastScaleDownDeleteTime"`
+	// LastScaleDownFailTime is the time of the last failed scale-down, zero if unknown.
+	LastScaleDownFailTime time.Time `json:"lastScaleDownFailTime"`
+}
+
+// IsStale returns true if the state is older than maxAge at the given time.
+func (s *State) IsStale(now time.Time, maxAge time.Duration) bool {
+	return s.Time.Add(maxAge).Before(now)
+}
+
+// Prune removes information about nodes and node groups that no longer exist,
+// as well as entries that make no sense at the given time.
+func (s *State) Prune(nodeNames, nodeGroupIds sets.String, now time.Time) {
+	for name, since := range s.UnneededNodes {
+		if !nodeNames.Has(name) || since.After(now) {
+			delete(s.UnneededNodes, name)
+		}
+	}
+	for name, recheckTime := range s.UnremovableNodes {
+		if !nodeNames.Has(name) || !recheckTime.After(now) {
+			delete(s.UnremovableNodes, name)
+		}
+	}
+	for name := range s.UnremovableReasons {
+		if _, found := s.UnremovableNodes[name]; !found {
+			delete(s.UnremovableReasons, name)
+		}
+	}
+	for pod, node := range s.PodLocationHints {
+		if !nodeNames.Has(node) {
+			delete(s.PodLocationHints, pod)
+		}
+	}
+	for name, info := range s.UsageTracker {
+		if !nodeNames.Has(name) {
+			delete(s.UsageTracker, name)
+			continue
+		}
+		for other := range info.Using {
+			if !nodeNames.Has(other) {
+				delete(info.Using, other)
+			}
+		}
+		for other := range info.UsedBy {
+			if !nodeNames.Has(other) {
+				delete(info.UsedBy, other)
+			}
+		}
+	}
+	for id := range s.NodeGroupBackoff {
+		if !nodeGroupIds.Has(id) {
+			delete(s.NodeGroupBackoff, id)
+		}
+	}
+	for _, lastTime := range []*time.Time{&s.LastScaleUpTime, &s.LastScaleDownDeleteTime, &s.LastScaleDownFailTime} {
+		if lastTime.After(now) {
+			*lastTime = time.Time{}
+		}
+	}
+}
+
+// Marshal serializes the state to gzipped JSON.
+func (s *State) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(s); err != nil {
+		return nil, fmt.Errorf("failed to encode state: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress state: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalState deserializes the state produced by Marshal.
+func UnmarshalState(data []byte) (*State, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress state: %v", err)
+	}
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress state: %v", err)
+	}
+	state := &State{}
+	if err := json.Unmarshal(decompressed, state); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %v", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/backoff"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsStale(t *testing.T) {
+	now := time.Now()
+	state := &State{Time: now.Add(-10 * time.Minute)}
+	assert.False(t, state.IsStale(now, 15*time.Minute))
+	assert.True(t, state.IsStale(now, 5*time.Minute))
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	state := &State{
+		Time:               now,
+		UnneededNodes:      map[string]time.Time{"n1": now.Add(-time.Minute), "n2": now.Add(-time.Minute), "n3": now.Add(time.Minute)},
+		UnremovableNodes:   map[string]time.Time{"n1": now.Add(time.Minute), "n2": now.Add(time.Minute), "n4": now.Add(-time.Minute)},
+		UnremovableReasons: map[string]simulator.UnremovableReason{"n1": simulator.BlockedByPod, "n2": simulator.NoPlaceToMovePods, "n4": simulator.BlockedByPod},
+		PodLocationHints:   map[string]string{"ns/p1": "n1", "ns/p2": "n2"},
+		UsageTracker: map[string]simulator.UsageInfo{
+			"n1": {Using: map[string]time.Time{"n2": now, "n4": now}},
+			"n2": {UsedBy: map[string]time.Time{"n1": now}},
+		},
+		NodeGroupBackoff:        map[string]backoff.Entry{"ng1": {Duration: time.Minute}, "ng2": {Duration: time.Minute}},
+		LastScaleUpTime:         now.Add(-time.Minute),
+		LastScaleDownDeleteTime: now.Add(time.Minute),
+	}
+	state.Prune(sets.NewString("n1", "n3", "n4"), sets.NewString("ng1"), now)
+
+	assert.Equal(t, map[string]time.Time{"n1": now.Add(-time.Minute)}, state.UnneededNodes)
+	assert.Equal(t, map[string]time.Time{"n1": now.Add(time.Minute)}, state.UnremovableNodes)
+	assert.Equal(t, map[string]simulator.UnremovableReason{"n1": simulator.BlockedByPod}, state.UnremovableReasons)
+	assert.Equal(t, map[string]string{"ns/p1": "n1"}, state.PodLocationHints)
+	assert.Equal(t, map[string]simulator.UsageInfo{"n1": {Using: map[string]time.Time{"n4": now}}}, state.UsageTracker)
+	assert.Equal(t, map[string]backoff.Entry{"ng1": {Duration: time.Minute}}, state.NodeGroupBackoff)
+	assert.Equal(t, now.Add(-time.Minute), state.LastScaleUpTime)
+	assert.True(t, state.LastScaleDownDeleteTime.IsZero())
+}
+
+func TestMarshal(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := &State{
+		Time:             now,
+		UnneededNodes:    map[string]time.Time{"n1": now},
+		NodeGroupBackoff: map[string]backoff.Entry{"ng1": {Duration: time.Minute, BackoffUntil: now}},
+		LastScaleUpTime:  now.Add(-time.Minute),
+	}
+	data, err := state.Marshal()
+	assert.NoError(t, err)
+	restored, err := UnmarshalState(data)
+	assert.NoError(t, err)
+	assert.Equal(t, state, restored)
+
+	_, err = UnmarshalState([]byte("{}"))
+	assert.Error(t, err)
+}
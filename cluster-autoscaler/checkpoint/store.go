@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ConfigMapStateKey is the key of the ConfigMap holding the serialized state.
+	ConfigMapStateKey = "state.json.gz"
+)
+
+// Store persists the autoscaler state.
+type Store interface {
+	// Save persists the state, replacing the previously saved one.
+	Save(state *State) error
+	// Load returns the most recently saved state or nil if there is none.
+	Load() (*State, error)
+}
+
+// ConfigMapStore keeps the state in a ConfigMap.
+type ConfigMapStore struct {
+	client    kube_client.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapStore builds new ConfigMapStore.
+func NewConfigMapStore(client kube_client.Interface, namespace, name string) *ConfigMapStore {
+	return &ConfigMapStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Save writes the state to the ConfigMap, creating it if needed.
+func (s *ConfigMapStore) Save(state *State) error {
+	data, err := state.Marshal()
+	if err != nil {
+		return err
+	}
+	maps := s.client.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := maps.Get(s.name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		configMap = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.namespace,
+				Name:      s.name,
+			},
+			BinaryData: map[string][]byte{ConfigMapStateKey: data},
+		}
+		_, err = maps.Create(configMap)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get state configmap: %v", err)
+	}
+	if configMap.BinaryData == nil {
+		configMap.BinaryData = make(map[string][]byte)
+	}
+	configMap.BinaryData[ConfigMapStateKey] = data
+	_, err = maps.Update(configMap)
+	return err
+}
+
+// Load reads the state from the ConfigMap. It returns nil if the ConfigMap doesn't exist.
+func (s *ConfigMapStore) Load() (*State, error) {
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state configmap: %v", err)
+	}
+	data, found := configMap.BinaryData[ConfigMapStateKey]
+	if !found {
+		return nil, nil
+	}
+	return UnmarshalState(data)
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigMapStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapStore(client, "kube-system", "cluster-autoscaler-state")
+
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, store.Save(&State{Time: now, UnneededNodes: map[string]time.Time{"n1": now}}))
+	assert.NoError(t, store.Save(&State{Time: now.Add(time.Minute), UnneededNodes: map[string]time.Time{"n2": now}}))
+
+	state, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), state.Time)
+	assert.Equal(t, map[string]time.Time{"n2": now}, state.UnneededNodes)
+}
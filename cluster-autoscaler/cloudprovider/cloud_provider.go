@@ -163,33 +163,41 @@ const (
 	ResourceNameMemory = "memory"
 )
 
-// IsGpuResource checks if given resource name point denotes a gpu type
-func IsGpuResource(resourceName string) bool {
-	// hack: we assume anything which is not cpu/memory to be a gpu.
-	// we are not getting anything more that a map string->limits from the user
-	return resourceName != ResourceNameCores && resourceName != ResourceNameMemory
-}
+// ResourceType tells how the cluster-wide amount of a resource limited by ResourceLimiter is counted.
+type ResourceType string
 
-// ContainsGpuResources returns true iff given list contains any resource name denoting a gpu type
-func ContainsGpuResources(resources []string) bool {
-	for _, resource := range resources {
-		if IsGpuResource(resource) {
-			return true
-		}
-	}
-	return false
-}
+const (
+	// ResourceTypeCores is counted as the number of cores in node capacity.
+	ResourceTypeCores ResourceType = "cores"
+	// ResourceTypeMemory is counted as bytes of memory in node capacity.
+	ResourceTypeMemory ResourceType = "memory"
+	// ResourceTypeGpu is a gpu type (e.g. nvidia-tesla-k80), counted as the number of gpus of that type on nodes.
+	ResourceTypeGpu ResourceType = "gpu"
+	// ResourceTypeNode is any other node resource (ephemeral-storage, hugepages-2Mi, example.com/fpga etc.),
+	// counted as node capacity of the resource with the same name as the limit.
+	ResourceTypeNode ResourceType = "node"
+)
 
 // ResourceLimiter contains limits (max, min) for resources (cores, memory etc.).
 type ResourceLimiter struct {
 	minLimits map[string]int64
 	maxLimits map[string]int64
+	types     map[string]ResourceType
 }
 
 // NewResourceLimiter creates new ResourceLimiter for map. Maps are deep copied.
+// Resources other than cores and memory are assumed to be gpu types, as that is all the limits
+// of some cloud providers can express. Use NewResourceLimiterWithTypes for other node resources.
 func NewResourceLimiter(minLimits map[string]int64, maxLimits map[string]int64) *ResourceLimiter {
+	return NewResourceLimiterWithTypes(minLimits, maxLimits, nil)
+}
+
+// NewResourceLimiterWithTypes creates new ResourceLimiter with explicitly typed resources. Maps are deep copied.
+// Resources missing from types are typed as in NewResourceLimiter.
+func NewResourceLimiterWithTypes(minLimits map[string]int64, maxLimits map[string]int64, types map[string]ResourceType) *ResourceLimiter {
 	minLimitsCopy := make(map[string]int64)
 	maxLimitsCopy := make(map[string]int64)
+	typesCopy := make(map[string]ResourceType)
 	for key, value := range minLimits {
 		if value > 0 {
 			minLimitsCopy[key] = value
@@ -198,7 +206,36 @@ func NewResourceLimiter(minLimits map[string]int64, maxLimits map[string]int64)
 	for key, value := range maxLimits {
 		maxLimitsCopy[key] = value
 	}
-	return &ResourceLimiter{minLimitsCopy, maxLimitsCopy}
+	for key, value := range types {
+		typesCopy[key] = value
+	}
+	return &ResourceLimiter{minLimitsCopy, maxLimitsCopy, typesCopy}
+}
+
+// GetResourceType returns the type of a given resource.
+func (r *ResourceLimiter) GetResourceType(resourceName string) ResourceType {
+	if resourceType, found := r.types[resourceName]; found {
+		return resourceType
+	}
+	switch resourceName {
+	case ResourceNameCores:
+		return ResourceTypeCores
+	case ResourceNameMemory:
+		return ResourceTypeMemory
+	default:
+		return ResourceTypeGpu
+	}
+}
+
+// GetResourcesOfType returns list of resource names of a given type for which min or max limits are defined.
+func (r *ResourceLimiter) GetResourcesOfType(resourceType ResourceType) []string {
+	result := make([]string, 0)
+	for _, name := range r.GetResources() {
+		if r.GetResourceType(name) == resourceType {
+			result = append(result, name)
+		}
+	}
+	return result
 }
 
 // GetMin returns minimal number of resources for a given resource type.
@@ -46,3 +46,21 @@ func TestResourceLimiterHasMaxLimitSet(t *testing.T) {
 	assert.True(t, limiter.HasMaxLimitSet("c"), "expected HasMaxLimitSet to return true for c")
 	assert.False(t, limiter.HasMaxLimitSet("d"), "expected HasMaxLimitSet to return false for d")
 }
+
+func TestResourceLimiterGetResourceType(t *testing.T) {
+	limiter := NewResourceLimiter(map[string]int64{}, map[string]int64{"cpu": 10, "memory": 100, "nvidia-tesla-k80": 2})
+	assert.Equal(t, ResourceTypeCores, limiter.GetResourceType("cpu"))
+	assert.Equal(t, ResourceTypeMemory, limiter.GetResourceType("memory"))
+	assert.Equal(t, ResourceTypeGpu, limiter.GetResourceType("nvidia-tesla-k80"))
+
+	limiter = NewResourceLimiterWithTypes(
+		map[string]int64{"example.com/fpga": 1},
+		map[string]int64{"cpu": 10, "nvidia-tesla-k80": 2, "example.com/fpga": 4, "ephemeral-storage": 1000},
+		map[string]ResourceType{"example.com/fpga": ResourceTypeNode, "ephemeral-storage": ResourceTypeNode})
+	assert.Equal(t, ResourceTypeCores, limiter.GetResourceType("cpu"))
+	assert.Equal(t, ResourceTypeGpu, limiter.GetResourceType("nvidia-tesla-k80"))
+	assert.Equal(t, ResourceTypeNode, limiter.GetResourceType("example.com/fpga"))
+	assert.Equal(t, []string{"ephemeral-storage", "example.com/fpga"}, limiter.GetResourcesOfType(ResourceTypeNode))
+	assert.Equal(t, []string{"nvidia-tesla-k80"}, limiter.GetResourcesOfType(ResourceTypeGpu))
+	assert.Equal(t, []string{}, limiter.GetResourcesOfType(ResourceTypeMemory))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ContextNodeGroup contains the context-aware variants of the NodeGroup methods that call the
+// cloud provider. Implementations should give up and return ctx.Err() once ctx is done. Methods
+// that change the node group must only return once the change is either done or cancelled.
+type ContextNodeGroup interface {
+	// TargetSizeWithContext is the context-aware variant of NodeGroup.TargetSize.
+	TargetSizeWithContext(ctx context.Context) (int, error)
+
+	// IncreaseSizeWithContext is the context-aware variant of NodeGroup.IncreaseSize.
+	IncreaseSizeWithContext(ctx context.Context, delta int) error
+
+	// DeleteNodesWithContext is the context-aware variant of NodeGroup.DeleteNodes.
+	DeleteNodesWithContext(ctx context.Context, nodes []*apiv1.Node) error
+
+	// DecreaseTargetSizeWithContext is the context-aware variant of NodeGroup.DecreaseTargetSize.
+	DecreaseTargetSizeWithContext(ctx context.Context, delta int) error
+
+	// NodesWithContext is the context-aware variant of NodeGroup.Nodes.
+	NodesWithContext(ctx context.Context) ([]string, error)
+}
+
+// ContextCloudProvider contains the context-aware variants of the CloudProvider methods that
+// call the cloud provider.
+type ContextCloudProvider interface {
+	// RefreshWithContext is the context-aware variant of CloudProvider.Refresh.
+	RefreshWithContext(ctx context.Context) error
+}
+
+// NodeGroupWithContext returns the context-aware variant of the node group. Node groups that
+// don't implement ContextNodeGroup are adapted: reads are made with ReadWithContext, so the
+// caller doesn't wait for them once the context is done, but the call itself is not interrupted.
+// Changes are not started once the context is done, but otherwise waited for, since an
+// abandoned change would still take effect later without the caller knowing about it.
+func NodeGroupWithContext(nodeGroup NodeGroup) ContextNodeGroup {
+	if contextNodeGroup, ok := nodeGroup.(ContextNodeGroup); ok {
+		return contextNodeGroup
+	}
+	return &contextNodeGroupAdapter{nodeGroup: nodeGroup, target: readTarget(nodeGroup, nodeGroup.Id)}
+}
+
+// CloudProviderWithContext returns the context-aware variant of the cloud provider, adapting
+// cloud providers that don't implement ContextCloudProvider like NodeGroupWithContext does.
+func CloudProviderWithContext(cloudProvider CloudProvider) ContextCloudProvider {
+	if contextCloudProvider, ok := cloudProvider.(ContextCloudProvider); ok {
+		return contextCloudProvider
+	}
+	return &contextCloudProviderAdapter{cloudProvider: cloudProvider, target: readTarget(cloudProvider, cloudProvider.Name)}
+}
+
+// ReadCallKey identifies a read made with ReadWithContext. Target must be comparable.
+type ReadCallKey struct {
+	Target interface{}
+	Method string
+}
+
+type pendingRead struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+var pendingReads = struct {
+	sync.Mutex
+	reads map[ReadCallKey]*pendingRead
+}{reads: make(map[ReadCallKey]*pendingRead)}
+
+// ReadWithContext runs read in the background and waits until it returns or ctx is done. It must
+// only be used for calls that don't change anything in the cloud provider. Only one read per key
+// is in flight: while a read, possibly abandoned by an earlier caller, is still running, no second
+// one is started and its result is returned instead. This keeps calls that are not safe to run
+// concurrently, like Refresh, from piling up when the cloud provider hangs.
+func ReadWithContext(ctx context.Context, key ReadCallKey, read func() (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pendingReads.Lock()
+	pending, found := pendingReads.reads[key]
+	if !found {
+		pending = &pendingRead{done: make(chan struct{})}
+		pendingReads.reads[key] = pending
+		go func() {
+			pending.result, pending.err = read()
+			pendingReads.Lock()
+			delete(pendingReads.reads, key)
+			pendingReads.Unlock()
+			close(pending.done)
+		}()
+	}
+	pendingReads.Unlock()
+	select {
+	case <-pending.done:
+		return pending.result, pending.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// readTarget returns the value identifying target in ReadCallKey. target itself is used if it can
+// be, so that e.g. node groups of different cloud providers don't share reads, otherwise its name.
+func readTarget(target interface{}, name func() string) interface{} {
+	if reflect.TypeOf(target).Comparable() {
+		return target
+	}
+	return name()
+}
+
+type contextNodeGroupAdapter struct {
+	nodeGroup NodeGroup
+	target    interface{}
+}
+
+func (a *contextNodeGroupAdapter) TargetSizeWithContext(ctx context.Context) (int, error) {
+	size, err := ReadWithContext(ctx, ReadCallKey{Target: a.target, Method: "TargetSize"}, func() (interface{}, error) {
+		return a.nodeGroup.TargetSize()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size.(int), nil
+}
+
+func (a *contextNodeGroupAdapter) IncreaseSizeWithContext(ctx context.Context, delta int) error {
+	return changeWithContext(ctx, func() error { return a.nodeGroup.IncreaseSize(delta) })
+}
+
+func (a *contextNodeGroupAdapter) DeleteNodesWithContext(ctx context.Context, nodes []*apiv1.Node) error {
+	return changeWithContext(ctx, func() error { return a.nodeGroup.DeleteNodes(nodes) })
+}
+
+func (a *contextNodeGroupAdapter) DecreaseTargetSizeWithContext(ctx context.Context, delta int) error {
+	return changeWithContext(ctx, func() error { return a.nodeGroup.DecreaseTargetSize(delta) })
+}
+
+func (a *contextNodeGroupAdapter) NodesWithContext(ctx context.Context) ([]string, error) {
+	nodes, err := ReadWithContext(ctx, ReadCallKey{Target: a.target, Method: "Nodes"}, func() (interface{}, error) {
+		return a.nodeGroup.Nodes()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes.([]string), nil
+}
+
+type contextCloudProviderAdapter struct {
+	cloudProvider CloudProvider
+	target        interface{}
+}
+
+func (a *contextCloudProviderAdapter) RefreshWithContext(ctx context.Context) error {
+	_, err := ReadWithContext(ctx, ReadCallKey{Target: a.target, Method: "Refresh"}, func() (interface{}, error) {
+		return nil, a.cloudProvider.Refresh()
+	})
+	return err
+}
+
+// changeWithContext runs call unless ctx is already done, and waits until it returns.
+func changeWithContext(ctx context.Context, call func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return call()
+}
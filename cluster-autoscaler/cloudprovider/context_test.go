@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingNodeGroup struct {
+	NodeGroup
+	unblock chan struct{}
+	size    int
+}
+
+func (ng *blockingNodeGroup) TargetSize() (int, error) {
+	<-ng.unblock
+	return ng.size, nil
+}
+
+func (ng *blockingNodeGroup) IncreaseSize(delta int) error {
+	<-ng.unblock
+	if delta <= 0 {
+		return fmt.Errorf("size increase must be positive")
+	}
+	ng.size += delta
+	return nil
+}
+
+type contextAwareNodeGroup struct {
+	blockingNodeGroup
+	ContextNodeGroup
+}
+
+func TestNodeGroupWithContext(t *testing.T) {
+	unblock := make(chan struct{})
+	close(unblock)
+	ng := &blockingNodeGroup{unblock: unblock, size: 1}
+	contextNodeGroup := NodeGroupWithContext(ng)
+
+	assert.NoError(t, contextNodeGroup.IncreaseSizeWithContext(context.Background(), 2))
+	size, err := contextNodeGroup.TargetSizeWithContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, size)
+	assert.EqualError(t, contextNodeGroup.IncreaseSizeWithContext(context.Background(), 0), "size increase must be positive")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, contextNodeGroup.IncreaseSizeWithContext(cancelled, 1))
+	assert.Equal(t, 3, ng.size)
+
+	// Context-aware node groups are used as they are.
+	native := &contextAwareNodeGroup{}
+	assert.Equal(t, native, NodeGroupWithContext(native))
+}
+
+func TestNodeGroupWithContextTimeout(t *testing.T) {
+	ng := &blockingNodeGroup{unblock: make(chan struct{}), size: 1}
+	callCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NodeGroupWithContext(ng).TargetSizeWithContext(callCtx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Now().Sub(start) < 5*time.Second)
+	close(ng.unblock)
+}
+
+func TestNodeGroupWithContextWaitsForChanges(t *testing.T) {
+	ng := &blockingNodeGroup{unblock: make(chan struct{}), size: 1}
+	callCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	go func() {
+		<-callCtx.Done()
+		close(ng.unblock)
+	}()
+	// The size increase isn't abandoned when the deadline passes.
+	assert.NoError(t, NodeGroupWithContext(ng).IncreaseSizeWithContext(callCtx, 2))
+	assert.Equal(t, 3, ng.size)
+
+	// But it isn't started after the deadline.
+	assert.Equal(t, context.DeadlineExceeded, NodeGroupWithContext(ng).IncreaseSizeWithContext(callCtx, 2))
+	assert.Equal(t, 3, ng.size)
+}
+
+type blockingCloudProvider struct {
+	CloudProvider
+	unblock chan struct{}
+
+	sync.Mutex
+	refreshing int
+	refreshes  int
+	// cache is written by Refresh without locking, like the caches of cloud providers.
+	cache int
+}
+
+func (p *blockingCloudProvider) Name() string {
+	return "blocking"
+}
+
+func (p *blockingCloudProvider) Refresh() error {
+	p.Lock()
+	p.refreshing++
+	p.refreshes++
+	p.Unlock()
+	<-p.unblock
+	p.cache++
+	p.Lock()
+	p.refreshing--
+	p.Unlock()
+	return nil
+}
+
+func TestCloudProviderWithContextTimeoutsInARow(t *testing.T) {
+	provider := &blockingCloudProvider{unblock: make(chan struct{})}
+	ng := &blockingNodeGroup{unblock: provider.unblock, size: 1}
+
+	// Two loops time out while the cloud provider hangs.
+	for i := 0; i < 2; i++ {
+		loopCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		assert.Equal(t, context.DeadlineExceeded, CloudProviderWithContext(provider).RefreshWithContext(loopCtx))
+		_, err := NodeGroupWithContext(ng).TargetSizeWithContext(loopCtx)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		cancel()
+	}
+	provider.Lock()
+	assert.Equal(t, 1, provider.refreshes)
+	provider.Unlock()
+
+	// The next loop gets the result of the abandoned calls once the cloud provider recovers.
+	close(provider.unblock)
+	assert.NoError(t, CloudProviderWithContext(provider).RefreshWithContext(context.Background()))
+	size, err := NodeGroupWithContext(ng).TargetSizeWithContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, size)
+
+	// After that, calls are made again.
+	assert.NoError(t, CloudProviderWithContext(provider).RefreshWithContext(context.Background()))
+	provider.Lock()
+	assert.True(t, provider.refreshes > 1)
+	assert.Equal(t, 0, provider.refreshing)
+	provider.Unlock()
+}
@@ -22,6 +22,7 @@ Modifications Copyright (c) 2017 SAP SE or an SAP affiliate company. All rights
 package mcm
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -205,6 +206,18 @@ func (machinedeployment *MachineDeployment) TargetSize() (int, error) {
 	return int(size), err
 }
 
+// TargetSizeWithContext is TargetSize giving up once ctx is done. The read is left to finish in
+// the background and not repeated until it does.
+func (machinedeployment *MachineDeployment) TargetSizeWithContext(ctx context.Context) (int, error) {
+	size, err := cloudprovider.ReadWithContext(ctx, cloudprovider.ReadCallKey{Target: machinedeployment, Method: "TargetSize"}, func() (interface{}, error) {
+		return machinedeployment.TargetSize()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size.(int), nil
+}
+
 // Exist checks if the node group really exists on the cloud provider side. Allows to tell the
 // theoretical node group from the real one.
 // TODO: Implement this to check if machine-deployment really exists.
@@ -230,9 +243,17 @@ func (machinedeployment *MachineDeployment) Delete() error {
 
 // IncreaseSize of the Machinedeployment.
 func (machinedeployment *MachineDeployment) IncreaseSize(delta int) error {
+	return machinedeployment.IncreaseSizeWithContext(context.Background(), delta)
+}
+
+// IncreaseSizeWithContext is IncreaseSize giving up retries once ctx is done.
+func (machinedeployment *MachineDeployment) IncreaseSizeWithContext(ctx context.Context, delta int) error {
 	if delta <= 0 {
 		return fmt.Errorf("size increase must be positive")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	size, err := machinedeployment.mcmManager.GetMachineDeploymentSize(machinedeployment)
 	if err != nil {
 		return err
@@ -240,7 +261,7 @@ func (machinedeployment *MachineDeployment) IncreaseSize(delta int) error {
 	if int(size)+delta > machinedeployment.MaxSize() {
 		return fmt.Errorf("size increase too large - desired:%d max:%d", int(size)+delta, machinedeployment.MaxSize())
 	}
-	return machinedeployment.mcmManager.SetMachineDeploymentSize(machinedeployment, size+int64(delta))
+	return machinedeployment.mcmManager.SetMachineDeploymentSize(ctx, machinedeployment, size+int64(delta))
 }
 
 // DecreaseTargetSize decreases the target size of the node group. This function
@@ -249,9 +270,17 @@ func (machinedeployment *MachineDeployment) IncreaseSize(delta int) error {
 // It is assumed that cloud provider will not delete the existing nodes if the size
 // when there is an option to just decrease the target.
 func (machinedeployment *MachineDeployment) DecreaseTargetSize(delta int) error {
+	return machinedeployment.DecreaseTargetSizeWithContext(context.Background(), delta)
+}
+
+// DecreaseTargetSizeWithContext is DecreaseTargetSize giving up retries once ctx is done.
+func (machinedeployment *MachineDeployment) DecreaseTargetSizeWithContext(ctx context.Context, delta int) error {
 	if delta >= 0 {
 		return fmt.Errorf("size decrease size must be negative")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	size, err := machinedeployment.mcmManager.GetMachineDeploymentSize(machinedeployment)
 	if err != nil {
 		return err
@@ -265,7 +294,7 @@ func (machinedeployment *MachineDeployment) DecreaseTargetSize(delta int) error
 		return fmt.Errorf("attempt to delete existing nodes targetSize:%d delta:%d existingNodes: %s", size, delta, nodes)
 	}
 
-	return machinedeployment.mcmManager.SetMachineDeploymentSize(machinedeployment, size+int64(delta))
+	return machinedeployment.mcmManager.SetMachineDeploymentSize(ctx, machinedeployment, size+int64(delta))
 }
 
 // Belongs returns true if the given node belongs to the NodeGroup.
@@ -290,6 +319,14 @@ func (machinedeployment *MachineDeployment) Belongs(node *apiv1.Node) (bool, err
 
 // DeleteNodes deletes the nodes from the group.
 func (machinedeployment *MachineDeployment) DeleteNodes(nodes []*apiv1.Node) error {
+	return machinedeployment.DeleteNodesWithContext(context.Background(), nodes)
+}
+
+// DeleteNodesWithContext is DeleteNodes giving up retries once ctx is done.
+func (machinedeployment *MachineDeployment) DeleteNodesWithContext(ctx context.Context, nodes []*apiv1.Node) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	size, err := machinedeployment.mcmManager.GetMachineDeploymentSize(machinedeployment)
 	if err != nil {
 		return err
@@ -309,7 +346,7 @@ func (machinedeployment *MachineDeployment) DeleteNodes(nodes []*apiv1.Node) err
 		}
 		machines = append(machines, ref)
 	}
-	return machinedeployment.mcmManager.DeleteMachines(machines)
+	return machinedeployment.mcmManager.DeleteMachines(ctx, machines)
 }
 
 // Id returns machinedeployment id.
@@ -327,6 +364,18 @@ func (machinedeployment *MachineDeployment) Nodes() ([]string, error) {
 	return machinedeployment.mcmManager.GetMachineDeploymentNodes(machinedeployment)
 }
 
+// NodesWithContext is Nodes giving up once ctx is done. The read is left to finish in the
+// background and not repeated until it does.
+func (machinedeployment *MachineDeployment) NodesWithContext(ctx context.Context) ([]string, error) {
+	nodes, err := cloudprovider.ReadWithContext(ctx, cloudprovider.ReadCallKey{Target: machinedeployment, Method: "Nodes"}, func() (interface{}, error) {
+		return machinedeployment.Nodes()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes.([]string), nil
+}
+
 // TemplateNodeInfo returns a node template for this node group.
 func (machinedeployment *MachineDeployment) TemplateNodeInfo() (*schedulercache.NodeInfo, error) {
 	return nil, cloudprovider.ErrNotImplemented
@@ -22,6 +22,7 @@ Modifications Copyright (c) 2017 SAP SE or an SAP affiliate company. All rights
 package mcm
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -191,15 +192,20 @@ func (m *McmManager) GetMachineDeploymentSize(machinedeployment *MachineDeployme
 	return int64(md.Spec.Replicas), nil
 }
 
-//SetMachineDeploymentSize sets the desired size for the Machinedeployment.
-func (m *McmManager) SetMachineDeploymentSize(machinedeployment *MachineDeployment, size int64) error {
+//SetMachineDeploymentSize sets the desired size for the Machinedeployment. Retries are given up once ctx is done.
+func (m *McmManager) SetMachineDeploymentSize(ctx context.Context, machinedeployment *MachineDeployment, size int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	retryDeadline := time.Now().Add(maxRetryDeadline)
 	for {
 		md, err := m.machineclient.MachineDeployments(machinedeployment.Namespace).Get(machinedeployment.Name, metav1.GetOptions{})
 		if err != nil && time.Now().Before(retryDeadline) {
 			glog.Warningf("Unable to fetch MachineDeployment object %s, Error: %+v", machinedeployment.Name, err)
-			time.Sleep(conflictRetryInterval)
+			if waitErr := waitForRetry(ctx); waitErr != nil {
+				return waitErr
+			}
 			continue
 		} else if err != nil {
 			// Timeout occurred
@@ -213,7 +219,9 @@ func (m *McmManager) SetMachineDeploymentSize(machinedeployment *MachineDeployme
 		_, err = m.machineclient.MachineDeployments(machinedeployment.Namespace).Update(clone)
 		if err != nil && time.Now().Before(retryDeadline) {
 			glog.Warningf("Unable to update MachineDeployment object %s, Error: %+v", machinedeployment.Name, err)
-			time.Sleep(conflictRetryInterval)
+			if waitErr := waitForRetry(ctx); waitErr != nil {
+				return waitErr
+			}
 			continue
 		} else if err != nil {
 			// Timeout occurred
@@ -228,7 +236,8 @@ func (m *McmManager) SetMachineDeploymentSize(machinedeployment *MachineDeployme
 }
 
 //DeleteMachines deletes the Machines and also reduces the desired replicas of the Machinedeplyoment in parallel.
-func (m *McmManager) DeleteMachines(machines []*Ref) error {
+//Retries are given up once ctx is done.
+func (m *McmManager) DeleteMachines(ctx context.Context, machines []*Ref) error {
 
 	var (
 		mdclone *v1alpha1.MachineDeployment
@@ -253,13 +262,18 @@ func (m *McmManager) DeleteMachines(machines []*Ref) error {
 	}
 
 	for _, machine := range machines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		retryDeadline := time.Now().Add(maxRetryDeadline)
 		for {
 			mach, err := m.machineclient.Machines(machine.Namespace).Get(machine.Name, metav1.GetOptions{})
 			if err != nil && time.Now().Before(retryDeadline) {
 				glog.Warningf("Unable to fetch Machine object %s, Error: %s", machine.Name, err)
-				time.Sleep(conflictRetryInterval)
+				if waitErr := waitForRetry(ctx); waitErr != nil {
+					return waitErr
+				}
 				continue
 			} else if err != nil {
 				// Timeout occurred
@@ -279,7 +293,9 @@ func (m *McmManager) DeleteMachines(machines []*Ref) error {
 			_, err = m.machineclient.Machines(machine.Namespace).Update(mclone)
 			if err != nil && time.Now().Before(retryDeadline) {
 				glog.Warningf("Unable to update Machine object %s, Error: %s", machine.Name, err)
-				time.Sleep(conflictRetryInterval)
+				if waitErr := waitForRetry(ctx); waitErr != nil {
+					return waitErr
+				}
 				continue
 			} else if err != nil {
 				// Timeout occurred
@@ -292,12 +308,17 @@ func (m *McmManager) DeleteMachines(machines []*Ref) error {
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	retryDeadline := time.Now().Add(maxRetryDeadline)
 	for {
 		md, err := m.machineclient.MachineDeployments(commonMachineDeployment.Namespace).Get(commonMachineDeployment.Name, metav1.GetOptions{})
 		if err != nil && time.Now().Before(retryDeadline) {
 			glog.Warningf("Unable to fetch MachineDeployment object %s, Error: %s", commonMachineDeployment.Name, err)
-			time.Sleep(conflictRetryInterval)
+			if waitErr := waitForRetry(ctx); waitErr != nil {
+				return waitErr
+			}
 			continue
 		} else if err != nil {
 			// Timeout occurred
@@ -314,7 +335,9 @@ func (m *McmManager) DeleteMachines(machines []*Ref) error {
 		_, err = m.machineclient.MachineDeployments(mdclone.Namespace).Update(mdclone)
 		if err != nil && time.Now().Before(retryDeadline) {
 			glog.Warningf("Unable to update MachineDeployment object %s, Error: %s", commonMachineDeployment.Name, err)
-			time.Sleep(conflictRetryInterval)
+			if waitErr := waitForRetry(ctx); waitErr != nil {
+				return waitErr
+			}
 			continue
 		} else if err != nil {
 			// Timeout occurred
@@ -331,6 +354,17 @@ func (m *McmManager) DeleteMachines(machines []*Ref) error {
 	return nil
 }
 
+// waitForRetry waits conflictRetryInterval before the next attempt of a call. It returns ctx.Err()
+// if ctx is done first.
+func waitForRetry(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(conflictRetryInterval):
+		return nil
+	}
+}
+
 //GetMachineDeploymentNodes returns the set of Nodes which belongs to the MachineDeployment.
 func (m *McmManager) GetMachineDeploymentNodes(machinedeployment *MachineDeployment) ([]string, error) {
 	md, err := m.machineclient.MachineDeployments(m.namespace).Get(machinedeployment.Name, metav1.GetOptions{})
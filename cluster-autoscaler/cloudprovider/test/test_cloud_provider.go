@@ -195,6 +195,14 @@ func (tcp *TestCloudProvider) AddNode(nodeGroupId string, node *apiv1.Node) {
 	tcp.nodes[node.Name] = nodeGroupId
 }
 
+// DeleteNode removes the given node from the cloud provider.
+func (tcp *TestCloudProvider) DeleteNode(nodeName string) {
+	tcp.Lock()
+	defer tcp.Unlock()
+
+	delete(tcp.nodes, nodeName)
+}
+
 // GetResourceLimiter returns struct containing limits (max, min) for resources (cores, memory etc.).
 func (tcp *TestCloudProvider) GetResourceLimiter() (*cloudprovider.ResourceLimiter, error) {
 	return tcp.resourceLimiter, nil
@@ -92,6 +92,8 @@ type ClusterAutoscalerStatus struct {
 	NodeGroupStatuses []NodeGroupStatus `json:"nodeGroupStatuses,omitempty"`
 	// ClusterwideConditions contains conditions that apply to the whole autoscaler.
 	ClusterwideConditions []ClusterAutoscalerCondition `json:"clusterwideConditions,omitempty"`
+	// UnremovableNodesCount contains the number of nodes that can't be scaled down, by reason.
+	UnremovableNodesCount map[string]int `json:"unremovableNodesCount,omitempty"`
 }
 
 // NodeGroupStatus contains status of a group of nodes controlled by ClusterAutoscaler.
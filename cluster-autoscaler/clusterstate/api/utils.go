@@ -19,6 +19,7 @@ package api
 import (
 	"bytes"
 	"fmt"
+	"sort"
 )
 
 // GetConditionByType gets condition by type.
@@ -79,6 +80,17 @@ func (status ClusterAutoscalerStatus) GetReadableString() string {
 	var buffer bytes.Buffer
 	buffer.WriteString("Cluster-wide:\n")
 	buffer.WriteString(getConditionsString(status.ClusterwideConditions, "  "))
+	if len(status.UnremovableNodesCount) > 0 {
+		reasons := make([]string, 0, len(status.UnremovableNodesCount))
+		for reason := range status.UnremovableNodesCount {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		buffer.WriteString("\nUnremovableNodes:\n")
+		for _, reason := range reasons {
+			buffer.WriteString(fmt.Sprintf("  %v: %v\n", reason, status.UnremovableNodesCount[reason]))
+		}
+	}
 	if len(status.NodeGroupStatuses) == 0 {
 		return buffer.String()
 	}
@@ -89,3 +89,12 @@ func TestGetStringNodeGroups(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile("(?ms)NodeGroups:.*Name:\\s*ng1"), result)
 	assert.Regexp(t, regexp.MustCompile("(?ms)NodeGroups:.*Name:\\s*ng2"), result)
 }
+
+func TestGetStringUnremovableNodes(t *testing.T) {
+	var status ClusterAutoscalerStatus
+	healthCondition, _ := prepareConditions()
+	status.ClusterwideConditions = append(status.ClusterwideConditions, healthCondition)
+	status.UnremovableNodesCount = map[string]int{"NotUnderutilized": 3, "BlockedByPod": 2}
+	result := status.GetReadableString()
+	assert.Regexp(t, regexp.MustCompile("(?ms)UnremovableNodes:\\s*BlockedByPod: 2\\s*NotUnderutilized: 3"), result)
+}
@@ -17,11 +17,13 @@ limitations under the License.
 package clusterstate
 
 import (
+	ctx "context"
 	"fmt"
 	"reflect"
 	"sync"
 	"time"
 
+	"github.com/gardener/autoscaler/cluster-autoscaler/apis/clusterautoscaler/v1alpha1"
 	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/api"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
@@ -53,30 +55,33 @@ const (
 
 	// NodeGroupBackoffResetTimeout is the time after last failed scale-up when the backoff duration is reset.
 	NodeGroupBackoffResetTimeout = 3 * time.Hour
+
+	// MaxScaleHistoryLength is the number of most recent scale-up and scale-down requests kept for status reporting.
+	MaxScaleHistoryLength = 20
 )
 
 // ScaleUpRequest contains information about the requested node group scale up.
 type ScaleUpRequest struct {
 	// NodeGroupName is the node group to be scaled up.
-	NodeGroupName string
+	NodeGroupName string `json:"nodeGroupName"`
 	// Time is the time when the request was submitted.
-	Time time.Time
+	Time time.Time `json:"time"`
 	// ExpectedAddTime is the time at which the request should be fulfilled.
-	ExpectedAddTime time.Time
+	ExpectedAddTime time.Time `json:"expectedAddTime"`
 	// How much the node group is increased.
-	Increase int
+	Increase int `json:"increase"`
 }
 
 // ScaleDownRequest contains information about the requested node deletion.
 type ScaleDownRequest struct {
 	// NodeName is the name of the node to be deleted.
-	NodeName string
+	NodeName string `json:"nodeName"`
 	// NodeGroupName is the node group of the deleted node.
-	NodeGroupName string
+	NodeGroupName string `json:"nodeGroupName"`
 	// Time is the time when the node deletion was requested.
-	Time time.Time
+	Time time.Time `json:"time"`
 	// ExpectedDeleteTime is the time when the node is expected to be deleted.
-	ExpectedDeleteTime time.Time
+	ExpectedDeleteTime time.Time `json:"expectedDeleteTime"`
 }
 
 // ClusterStateRegistryConfig contains configuration information for ClusterStateRegistry.
@@ -95,11 +100,11 @@ type ClusterStateRegistryConfig struct {
 // or startup issues.
 type IncorrectNodeGroupSize struct {
 	// ExpectedSize is the size of the node group measured on the cloud provider side.
-	ExpectedSize int
+	ExpectedSize int `json:"expectedSize"`
 	// CurrentSize is the size of the node group measured on the kubernetes side.
-	CurrentSize int
+	CurrentSize int `json:"currentSize"`
 	// FirstObserved is the time when the given difference occurred.
-	FirstObserved time.Time
+	FirstObserved time.Time `json:"firstObserved"`
 }
 
 // UnregisteredNode contains information about nodes that are present on the cluster provider side
@@ -117,6 +122,8 @@ type ClusterStateRegistry struct {
 	config                  ClusterStateRegistryConfig
 	scaleUpRequests         []*ScaleUpRequest
 	scaleDownRequests       []*ScaleDownRequest
+	scaleUpHistory          []ScaleUpRequest
+	scaleDownHistory        []ScaleDownRequest
 	nodes                   []*apiv1.Node
 	cloudProvider           cloudprovider.CloudProvider
 	perNodeGroupReadiness   map[string]Readiness
@@ -159,6 +166,10 @@ func (csr *ClusterStateRegistry) RegisterScaleUp(request *ScaleUpRequest) {
 	csr.Lock()
 	defer csr.Unlock()
 	csr.scaleUpRequests = append(csr.scaleUpRequests, request)
+	csr.scaleUpHistory = append(csr.scaleUpHistory, *request)
+	if len(csr.scaleUpHistory) > MaxScaleHistoryLength {
+		csr.scaleUpHistory = csr.scaleUpHistory[len(csr.scaleUpHistory)-MaxScaleHistoryLength:]
+	}
 }
 
 // RegisterScaleDown registers node scale down.
@@ -166,6 +177,24 @@ func (csr *ClusterStateRegistry) RegisterScaleDown(request *ScaleDownRequest) {
 	csr.Lock()
 	defer csr.Unlock()
 	csr.scaleDownRequests = append(csr.scaleDownRequests, request)
+	csr.scaleDownHistory = append(csr.scaleDownHistory, *request)
+	if len(csr.scaleDownHistory) > MaxScaleHistoryLength {
+		csr.scaleDownHistory = csr.scaleDownHistory[len(csr.scaleDownHistory)-MaxScaleHistoryLength:]
+	}
+}
+
+// GetScaleUpHistory returns up to MaxScaleHistoryLength most recently registered scale-ups, oldest first.
+func (csr *ClusterStateRegistry) GetScaleUpHistory() []ScaleUpRequest {
+	csr.Lock()
+	defer csr.Unlock()
+	return append([]ScaleUpRequest{}, csr.scaleUpHistory...)
+}
+
+// GetScaleDownHistory returns up to MaxScaleHistoryLength most recently registered node deletions, oldest first.
+func (csr *ClusterStateRegistry) GetScaleDownHistory() []ScaleDownRequest {
+	csr.Lock()
+	defer csr.Unlock()
+	return append([]ScaleDownRequest{}, csr.scaleDownHistory...)
 }
 
 // To be executed under a lock.
@@ -182,6 +211,7 @@ func (csr *ClusterStateRegistry) updateScaleRequests(currentTime time.Time) {
 			csr.nodeGroupBackoffInfo.RemoveBackoff(sur.NodeGroupName)
 			glog.V(4).Infof("Scale up in group %v finished successfully in %v",
 				sur.NodeGroupName, currentTime.Sub(sur.Time))
+			metrics.RegisterScaleUpNodeReady(sur.NodeGroupName, currentTime.Sub(sur.Time))
 			continue
 		}
 		if sur.ExpectedAddTime.After(currentTime) {
@@ -203,7 +233,7 @@ func (csr *ClusterStateRegistry) updateScaleRequests(currentTime time.Time) {
 			csr.logRecorder.Eventf(apiv1.EventTypeWarning, "ScaleUpTimedOut",
 				"Nodes added to group %s failed to register within %v",
 				sur.NodeGroupName, currentTime.Sub(sur.Time))
-			metrics.RegisterFailedScaleUp(metrics.Timeout)
+			metrics.RegisterFailedScaleUp(sur.NodeGroupName, metrics.Timeout)
 			csr.backoffNodeGroup(sur.NodeGroupName, currentTime)
 		}
 	}
@@ -217,6 +247,20 @@ func (csr *ClusterStateRegistry) updateScaleRequests(currentTime time.Time) {
 	csr.scaleDownRequests = newSdr
 }
 
+// GetNodeGroupBackoff returns a copy of scale-up backoff data of all node groups.
+func (csr *ClusterStateRegistry) GetNodeGroupBackoff() map[string]backoff.Entry {
+	csr.Lock()
+	defer csr.Unlock()
+	return csr.nodeGroupBackoffInfo.GetEntries()
+}
+
+// RestoreNodeGroupBackoff restores scale-up backoff data saved with GetNodeGroupBackoff.
+func (csr *ClusterStateRegistry) RestoreNodeGroupBackoff(entries map[string]backoff.Entry) {
+	csr.Lock()
+	defer csr.Unlock()
+	csr.nodeGroupBackoffInfo.RestoreEntries(entries)
+}
+
 // To be executed under a lock.
 func (csr *ClusterStateRegistry) backoffNodeGroup(nodeGroupName string, currentTime time.Time) {
 	backoffUntil := csr.nodeGroupBackoffInfo.Backoff(nodeGroupName, currentTime)
@@ -226,22 +270,28 @@ func (csr *ClusterStateRegistry) backoffNodeGroup(nodeGroupName string, currentT
 // RegisterFailedScaleUp should be called after getting error from cloudprovider
 // when trying to scale-up node group. It will mark this group as not safe to autoscale
 // for some time.
-func (csr *ClusterStateRegistry) RegisterFailedScaleUp(nodeGroupName string, reason metrics.FailedScaleUpReason) {
+func (csr *ClusterStateRegistry) RegisterFailedScaleUp(nodeGroupName string, reason metrics.FailedScaleUpReason, currentTime time.Time) {
 	csr.Lock()
 	defer csr.Unlock()
 
-	metrics.RegisterFailedScaleUp(reason)
-	csr.backoffNodeGroup(nodeGroupName, time.Now())
+	metrics.RegisterFailedScaleUp(nodeGroupName, reason)
+	csr.backoffNodeGroup(nodeGroupName, currentTime)
 }
 
 // UpdateNodes updates the state of the nodes in the ClusterStateRegistry and recalculates the stats
 func (csr *ClusterStateRegistry) UpdateNodes(nodes []*apiv1.Node, currentTime time.Time) error {
+	return csr.UpdateNodesWithContext(ctx.Background(), nodes, currentTime)
+}
+
+// UpdateNodesWithContext is UpdateNodes giving up on reading node group sizes and instances from the
+// cloud provider once callCtx is done.
+func (csr *ClusterStateRegistry) UpdateNodesWithContext(callCtx ctx.Context, nodes []*apiv1.Node, currentTime time.Time) error {
 	csr.updateNodeGroupMetrics()
-	targetSizes, err := getTargetSizes(csr.cloudProvider)
+	targetSizes, err := getTargetSizes(callCtx, csr.cloudProvider)
 	if err != nil {
 		return err
 	}
-	notRegistered, err := getNotRegisteredNodes(nodes, csr.cloudProvider, currentTime)
+	notRegistered, err := getNotRegisteredNodes(callCtx, nodes, csr.cloudProvider, currentTime)
 	if err != nil {
 		return err
 	}
@@ -266,7 +316,7 @@ func (csr *ClusterStateRegistry) UpdateNodes(nodes []*apiv1.Node, currentTime ti
 
 // Recalculate cluster state after scale-ups or scale-downs were registered.
 func (csr *ClusterStateRegistry) Recalculate() {
-	targetSizes, err := getTargetSizes(csr.cloudProvider)
+	targetSizes, err := getTargetSizes(ctx.Background(), csr.cloudProvider)
 	if err != nil {
 		glog.Warningf("Failed to get target sizes, when trying to recalculate cluster state: %v", err)
 	}
@@ -277,10 +327,10 @@ func (csr *ClusterStateRegistry) Recalculate() {
 }
 
 // getTargetSizes gets target sizes of node groups.
-func getTargetSizes(cp cloudprovider.CloudProvider) (map[string]int, error) {
+func getTargetSizes(callCtx ctx.Context, cp cloudprovider.CloudProvider) (map[string]int, error) {
 	result := make(map[string]int)
 	for _, ng := range cp.NodeGroups() {
-		size, err := ng.TargetSize()
+		size, err := cloudprovider.NodeGroupWithContext(ng).TargetSizeWithContext(callCtx)
 		if err != nil {
 			return map[string]int{}, err
 		}
@@ -400,11 +450,11 @@ func (csr *ClusterStateRegistry) IsNodeGroupScalingUp(nodeGroupName string) bool
 // AcceptableRange contains information about acceptable size of a node group.
 type AcceptableRange struct {
 	// MinNodes is the minimum number of nodes in the group.
-	MinNodes int
+	MinNodes int `json:"minNodes"`
 	// MaxNodes is the maximum number of nodes in the group.
-	MaxNodes int
+	MaxNodes int `json:"maxNodes"`
 	// CurrentTarget is the current target size of the group.
-	CurrentTarget int
+	CurrentTarget int `json:"currentTarget"`
 }
 
 // updateAcceptableRanges updates cluster state registry with how many nodes can be in a cluster.
@@ -439,24 +489,24 @@ func (csr *ClusterStateRegistry) updateAcceptableRanges(targetSize map[string]in
 // Readiness contains readiness information about a group of nodes.
 type Readiness struct {
 	// Number of ready nodes.
-	Ready int
+	Ready int `json:"ready"`
 	// Number of unready nodes that broke down after they started.
-	Unready int
+	Unready int `json:"unready"`
 	// Number of nodes that are being currently deleted. They exist in K8S but
 	// are not included in NodeGroup.TargetSize().
-	Deleted int
+	Deleted int `json:"deleted"`
 	// Number of nodes that failed to start within a reasonable limit.
-	LongNotStarted int
+	LongNotStarted int `json:"longNotStarted"`
 	// Number of nodes that are not yet fully started.
-	NotStarted int
+	NotStarted int `json:"notStarted"`
 	// Number of all registered nodes in the group (ready/unready/deleted/etc).
-	Registered int
+	Registered int `json:"registered"`
 	// Number of nodes that failed to register within a reasonable limit.
-	LongUnregistered int
+	LongUnregistered int `json:"longUnregistered"`
 	// Number of nodes that haven't yet registered.
-	Unregistered int
+	Unregistered int `json:"unregistered"`
 	// Time when the readiness was measured.
-	Time time.Time
+	Time time.Time `json:"time"`
 }
 
 func (csr *ClusterStateRegistry) updateReadinessStats(currentTime time.Time) {
@@ -572,7 +622,7 @@ func (csr *ClusterStateRegistry) updateUnregisteredNodes(unregisteredNodes []Unr
 	csr.unregisteredNodes = result
 }
 
-//GetUnregisteredNodes returns a list of all unregistered nodes.
+// GetUnregisteredNodes returns a list of all unregistered nodes.
 func (csr *ClusterStateRegistry) GetUnregisteredNodes() []UnregisteredNode {
 	csr.Lock()
 	defer csr.Unlock()
@@ -645,6 +695,29 @@ func (csr *ClusterStateRegistry) GetStatus(now time.Time) *api.ClusterAutoscaler
 	return result
 }
 
+// GetAutoscalerStatus converts status returned by GetStatus to the status of ClusterAutoscalerStatus
+// resource and fills in the recent scale-up and scale-down history.
+func (csr *ClusterStateRegistry) GetAutoscalerStatus(status *api.ClusterAutoscalerStatus) v1alpha1.AutoscalerStatus {
+	result := utils.ConvertStatus(status)
+	for _, request := range csr.GetScaleUpHistory() {
+		result.ScaleUpHistory = append(result.ScaleUpHistory, v1alpha1.ScaleUpEvent{
+			NodeGroup:       request.NodeGroupName,
+			Increase:        int32(request.Increase),
+			Time:            metav1.NewTime(request.Time),
+			ExpectedAddTime: metav1.NewTime(request.ExpectedAddTime),
+		})
+	}
+	for _, request := range csr.GetScaleDownHistory() {
+		result.ScaleDownHistory = append(result.ScaleDownHistory, v1alpha1.ScaleDownEvent{
+			NodeName:           request.NodeName,
+			NodeGroup:          request.NodeGroupName,
+			Time:               metav1.NewTime(request.Time),
+			ExpectedDeleteTime: metav1.NewTime(request.ExpectedDeleteTime),
+		})
+	}
+	return result
+}
+
 // GetClusterReadiness returns current readiness stats of cluster
 func (csr *ClusterStateRegistry) GetClusterReadiness() Readiness {
 	return csr.totalReadiness
@@ -846,7 +919,11 @@ func (csr *ClusterStateRegistry) GetIncorrectNodeGroupSize(nodeGroupName string)
 func (csr *ClusterStateRegistry) GetUpcomingNodes() map[string]int {
 	csr.Lock()
 	defer csr.Unlock()
+	return csr.getUpcomingNodes()
+}
 
+// To be executed under a lock.
+func (csr *ClusterStateRegistry) getUpcomingNodes() map[string]int {
 	result := make(map[string]int)
 	for _, nodeGroup := range csr.cloudProvider.NodeGroups() {
 		id := nodeGroup.Id()
@@ -863,15 +940,50 @@ func (csr *ClusterStateRegistry) GetUpcomingNodes() map[string]int {
 	return result
 }
 
+// GetNodeGroupStates returns the state of all node groups, by node group id, as exposed in
+// per-node-group metrics.
+func (csr *ClusterStateRegistry) GetNodeGroupStates(currentTime time.Time) map[string]metrics.NodeGroupState {
+	csr.Lock()
+	defer csr.Unlock()
+
+	upcoming := csr.getUpcomingNodes()
+	result := make(map[string]metrics.NodeGroupState)
+	for _, nodeGroup := range csr.cloudProvider.NodeGroups() {
+		if !nodeGroup.Exist() {
+			continue
+		}
+		id := nodeGroup.Id()
+		readiness := csr.perNodeGroupReadiness[id]
+		state := metrics.NodeGroupState{
+			MinSize:          nodeGroup.MinSize(),
+			MaxSize:          nodeGroup.MaxSize(),
+			TargetSize:       csr.acceptableRanges[id].CurrentTarget,
+			CurrentSize:      readiness.Registered,
+			Ready:            readiness.Ready,
+			Unready:          readiness.Unready + readiness.LongNotStarted,
+			NotStarted:       readiness.NotStarted,
+			LongUnregistered: readiness.LongUnregistered,
+			Unregistered:     readiness.Unregistered,
+			Upcoming:         upcoming[id],
+			Unneeded:         len(csr.candidatesForScaleDown[id]),
+		}
+		if backoffUntil, backedOff := csr.nodeGroupBackoffInfo.GetBackoffUntil(id, currentTime); backedOff {
+			state.BackoffRemaining = backoffUntil.Sub(currentTime)
+		}
+		result[id] = state
+	}
+	return result
+}
+
 // Calculates which of the existing cloud provider nodes are not registered in Kubernetes.
-func getNotRegisteredNodes(allNodes []*apiv1.Node, cloudProvider cloudprovider.CloudProvider, time time.Time) ([]UnregisteredNode, error) {
+func getNotRegisteredNodes(callCtx ctx.Context, allNodes []*apiv1.Node, cloudProvider cloudprovider.CloudProvider, time time.Time) ([]UnregisteredNode, error) {
 	registered := sets.NewString()
 	for _, node := range allNodes {
 		registered.Insert(node.Spec.ProviderID)
 	}
 	notRegistered := make([]UnregisteredNode, 0)
 	for _, nodeGroup := range cloudProvider.NodeGroups() {
-		nodes, err := nodeGroup.Nodes()
+		nodes, err := cloudprovider.NodeGroupWithContext(nodeGroup).NodesWithContext(callCtx)
 		if err != nil {
 			return []UnregisteredNode{}, err
 		}
@@ -906,3 +1018,73 @@ func (csr *ClusterStateRegistry) GetClusterSize() (currentSize, targetSize int)
 	currentSize = csr.totalReadiness.Registered - csr.totalReadiness.NotStarted - csr.totalReadiness.LongNotStarted
 	return currentSize, targetSize
 }
+
+// NodeGroupDebugState describes the state of a single node group tracked by ClusterStateRegistry.
+type NodeGroupDebugState struct {
+	Readiness       Readiness               `json:"readiness"`
+	AcceptableRange AcceptableRange         `json:"acceptableRange"`
+	Healthy         bool                    `json:"healthy"`
+	UpcomingNodes   int                     `json:"upcomingNodes"`
+	BackoffUntil    *time.Time              `json:"backoffUntil,omitempty"`
+	IncorrectSize   *IncorrectNodeGroupSize `json:"incorrectSize,omitempty"`
+}
+
+// DebugState is a copy of the internal state of ClusterStateRegistry, intended for debugging.
+type DebugState struct {
+	Healthy             bool                           `json:"healthy"`
+	TotalReadiness      Readiness                      `json:"totalReadiness"`
+	NodeGroups          map[string]NodeGroupDebugState `json:"nodeGroups"`
+	UnregisteredNodes   map[string]time.Time           `json:"unregisteredNodes,omitempty"`
+	ScaleUpRequests     []ScaleUpRequest               `json:"scaleUpRequests,omitempty"`
+	ScaleDownRequests   []ScaleDownRequest             `json:"scaleDownRequests,omitempty"`
+	ScaleDownCandidates map[string][]string            `json:"scaleDownCandidates,omitempty"`
+}
+
+// GetDebugState returns a copy of the internal state of the registry.
+func (csr *ClusterStateRegistry) GetDebugState(now time.Time) *DebugState {
+	healthy := csr.IsClusterHealthy()
+	upcomingNodes := csr.GetUpcomingNodes()
+
+	csr.Lock()
+	defer csr.Unlock()
+
+	result := &DebugState{
+		Healthy:        healthy,
+		TotalReadiness: csr.totalReadiness,
+		NodeGroups:     make(map[string]NodeGroupDebugState, len(csr.acceptableRanges)),
+	}
+	for name, acceptableRange := range csr.acceptableRanges {
+		nodeGroupState := NodeGroupDebugState{
+			Readiness:       csr.perNodeGroupReadiness[name],
+			AcceptableRange: acceptableRange,
+			Healthy:         csr.IsNodeGroupHealthy(name),
+			UpcomingNodes:   upcomingNodes[name],
+		}
+		if backoffUntil, backedOff := csr.nodeGroupBackoffInfo.GetBackoffUntil(name, now); backedOff {
+			nodeGroupState.BackoffUntil = &backoffUntil
+		}
+		if incorrectSize, found := csr.incorrectNodeGroupSizes[name]; found {
+			nodeGroupState.IncorrectSize = &incorrectSize
+		}
+		result.NodeGroups[name] = nodeGroupState
+	}
+	if len(csr.unregisteredNodes) > 0 {
+		result.UnregisteredNodes = make(map[string]time.Time, len(csr.unregisteredNodes))
+		for name, unregistered := range csr.unregisteredNodes {
+			result.UnregisteredNodes[name] = unregistered.UnregisteredSince
+		}
+	}
+	for _, request := range csr.scaleUpRequests {
+		result.ScaleUpRequests = append(result.ScaleUpRequests, *request)
+	}
+	for _, request := range csr.scaleDownRequests {
+		result.ScaleDownRequests = append(result.ScaleDownRequests, *request)
+	}
+	if len(csr.candidatesForScaleDown) > 0 {
+		result.ScaleDownCandidates = make(map[string][]string, len(csr.candidatesForScaleDown))
+		for name, candidates := range csr.candidatesForScaleDown {
+			result.ScaleDownCandidates[name] = append([]string{}, candidates...)
+		}
+	}
+	return result
+}
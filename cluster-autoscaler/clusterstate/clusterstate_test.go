@@ -25,6 +25,7 @@ import (
 	testprovider "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/api"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
 	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/client-go/kubernetes/fake"
 	kube_record "k8s.io/client-go/tools/record"
@@ -371,6 +372,50 @@ func TestRegisterScaleDown(t *testing.T) {
 	assert.Equal(t, 0, len(clusterstate.scaleDownRequests))
 }
 
+func TestScaleHistory(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 100, 1)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder)
+
+	now := time.Now()
+	for i := 1; i <= MaxScaleHistoryLength+5; i++ {
+		clusterstate.RegisterScaleUp(&ScaleUpRequest{
+			NodeGroupName:   "ng1",
+			Increase:        i,
+			Time:            now,
+			ExpectedAddTime: now.Add(time.Minute),
+		})
+	}
+	clusterstate.RegisterScaleDown(&ScaleDownRequest{
+		NodeGroupName:      "ng1",
+		NodeName:           "ng1-1",
+		Time:               now,
+		ExpectedDeleteTime: now.Add(time.Minute),
+	})
+	clusterstate.updateScaleRequests(now.Add(5 * time.Minute))
+	assert.Equal(t, 0, len(clusterstate.scaleDownRequests))
+
+	scaleUpHistory := clusterstate.GetScaleUpHistory()
+	assert.Equal(t, MaxScaleHistoryLength, len(scaleUpHistory))
+	assert.Equal(t, 6, scaleUpHistory[0].Increase)
+	assert.Equal(t, MaxScaleHistoryLength+5, scaleUpHistory[MaxScaleHistoryLength-1].Increase)
+
+	status := clusterstate.GetAutoscalerStatus(clusterstate.GetStatus(now))
+	assert.Equal(t, MaxScaleHistoryLength, len(status.ScaleUpHistory))
+	assert.Equal(t, int32(6), status.ScaleUpHistory[0].Increase)
+	assert.Equal(t, 1, len(status.ScaleDownHistory))
+	assert.Equal(t, "ng1-1", status.ScaleDownHistory[0].NodeName)
+	assert.Equal(t, "ng1", status.ScaleDownHistory[0].NodeGroup)
+	assert.Equal(t, 1, len(status.NodeGroups))
+	assert.Equal(t, "ng1", status.NodeGroups[0].Name)
+}
+
 func TestUpcomingNodes(t *testing.T) {
 	provider := testprovider.NewTestCloudProvider(nil, nil)
 	now := time.Now()
@@ -754,3 +799,96 @@ func TestIsNodeStillStarting(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDebugState(t *testing.T) {
+	now := time.Now()
+
+	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
+	SetNodeReadyState(ng1_1, true, now.Add(-time.Minute))
+	ng2_1 := BuildTestNode("ng2-1", 1000, 1000)
+	SetNodeReadyState(ng2_1, true, now.Add(-time.Minute))
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNodeGroup("ng2", 1, 10, 2)
+	provider.AddNode("ng1", ng1_1)
+	provider.AddNode("ng2", ng2_1)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder)
+	clusterstate.RegisterScaleUp(&ScaleUpRequest{
+		NodeGroupName:   "ng2",
+		Increase:        1,
+		Time:            now.Add(-3 * time.Minute),
+		ExpectedAddTime: now.Add(-1 * time.Minute),
+	})
+	err := clusterstate.UpdateNodes([]*apiv1.Node{ng1_1, ng2_1}, now)
+	assert.NoError(t, err)
+
+	state := clusterstate.GetDebugState(now)
+	assert.True(t, state.Healthy)
+	assert.Equal(t, 2, state.TotalReadiness.Ready)
+	assert.Equal(t, 2, len(state.NodeGroups))
+
+	ng1 := state.NodeGroups["ng1"]
+	assert.Equal(t, 1, ng1.Readiness.Ready)
+	assert.Equal(t, 2, ng1.AcceptableRange.CurrentTarget)
+	assert.Equal(t, 1, ng1.UpcomingNodes)
+	assert.Nil(t, ng1.BackoffUntil)
+
+	ng2 := state.NodeGroups["ng2"]
+	assert.NotNil(t, ng2.BackoffUntil)
+	assert.True(t, ng2.BackoffUntil.After(now))
+}
+
+func TestGetNodeGroupStates(t *testing.T) {
+	now := time.Now()
+
+	ng1_1 := BuildTestNode("ng1-1", 1000, 1000)
+	SetNodeReadyState(ng1_1, true, now.Add(-time.Minute))
+	ng1_2 := BuildTestNode("ng1-2", 1000, 1000)
+	SetNodeReadyState(ng1_2, false, now.Add(-time.Minute))
+	ng2_1 := BuildTestNode("ng2-1", 1000, 1000)
+	SetNodeReadyState(ng2_1, true, now.Add(-time.Minute))
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 4)
+	provider.AddNode("ng1", ng1_1)
+	provider.AddNode("ng1", ng1_2)
+	provider.AddNodeGroup("ng2", 0, 5, 1)
+	provider.AddNode("ng2", ng2_1)
+
+	fakeClient := &fake.Clientset{}
+	fakeLogRecorder, _ := utils.NewStatusMapRecorder(fakeClient, "kube-system", kube_record.NewFakeRecorder(5), false)
+	clusterstate := NewClusterStateRegistry(provider, ClusterStateRegistryConfig{
+		MaxTotalUnreadyPercentage: 10,
+		OkTotalUnreadyCount:       1,
+	}, fakeLogRecorder)
+	err := clusterstate.UpdateNodes([]*apiv1.Node{ng1_1, ng1_2, ng2_1}, now)
+	assert.NoError(t, err)
+	clusterstate.UpdateScaleDownCandidates([]*apiv1.Node{ng2_1}, now)
+	clusterstate.RegisterFailedScaleUp("ng1", metrics.APIError, now)
+
+	states := clusterstate.GetNodeGroupStates(now)
+	assert.Equal(t, 2, len(states))
+	ng1 := states["ng1"]
+	assert.Equal(t, 1, ng1.MinSize)
+	assert.Equal(t, 10, ng1.MaxSize)
+	assert.Equal(t, 4, ng1.TargetSize)
+	assert.Equal(t, 2, ng1.CurrentSize)
+	assert.Equal(t, 1, ng1.Ready)
+	assert.Equal(t, 1, ng1.Unready)
+	assert.Equal(t, 2, ng1.Upcoming)
+	assert.Equal(t, 0, ng1.Unneeded)
+	assert.True(t, ng1.BackoffRemaining > 0)
+	ng2 := states["ng2"]
+	assert.Equal(t, 1, ng2.TargetSize)
+	assert.Equal(t, 1, ng2.Ready)
+	assert.Equal(t, 0, ng2.Upcoming)
+	assert.Equal(t, 1, ng2.Unneeded)
+	assert.Equal(t, time.Duration(0), ng2.BackoffRemaining)
+}
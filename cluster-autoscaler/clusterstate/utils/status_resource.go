@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/apis/clusterautoscaler/v1alpha1"
+	"github.com/gardener/autoscaler/cluster-autoscaler/client/clientset/versioned"
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/api"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// StatusResourceName is the name of the ClusterAutoscalerStatus object with status.
+	StatusResourceName = "cluster-autoscaler-status"
+)
+
+// ConvertConditions converts ClusterAutoscalerConditions to their typed API representation.
+func ConvertConditions(conditions []api.ClusterAutoscalerCondition) []v1alpha1.Condition {
+	if len(conditions) == 0 {
+		return nil
+	}
+	result := make([]v1alpha1.Condition, 0, len(conditions))
+	for _, condition := range conditions {
+		result = append(result, v1alpha1.Condition{
+			Type:               v1alpha1.ConditionType(condition.Type),
+			Status:             v1alpha1.ConditionStatus(condition.Status),
+			Message:            condition.Message,
+			Reason:             condition.Reason,
+			LastProbeTime:      condition.LastProbeTime,
+			LastTransitionTime: condition.LastTransitionTime,
+		})
+	}
+	return result
+}
+
+// ConvertStatus converts ClusterAutoscalerStatus to the status of ClusterAutoscalerStatus resource.
+// Only conditions are filled in, history and unremovable nodes are left to the caller.
+func ConvertStatus(status *api.ClusterAutoscalerStatus) v1alpha1.AutoscalerStatus {
+	result := v1alpha1.AutoscalerStatus{
+		ClusterwideConditions: ConvertConditions(status.ClusterwideConditions),
+	}
+	for _, nodeGroupStatus := range status.NodeGroupStatuses {
+		result.NodeGroups = append(result.NodeGroups, v1alpha1.NodeGroupStatus{
+			Name:       nodeGroupStatus.ProviderID,
+			Conditions: ConvertConditions(nodeGroupStatus.Conditions),
+		})
+	}
+	return result
+}
+
+// WriteStatusResource updates the ClusterAutoscalerStatus object with the given status or creates
+// a new one if it doesn't exist.
+func WriteStatusResource(client versioned.Interface, namespace string, status v1alpha1.AutoscalerStatus) (*v1alpha1.ClusterAutoscalerStatus, error) {
+	status.LastUpdateTime = metav1.NewTime(time.Now())
+	resources := client.ClusterautoscalerV1alpha1().ClusterAutoscalerStatuses(namespace)
+	resource, err := resources.Get(StatusResourceName, metav1.GetOptions{})
+	if err == nil {
+		resource.Status = status
+		resource, err = resources.Update(resource)
+		if err != nil {
+			err = fmt.Errorf("Failed to write status resource: %v", err)
+		}
+	} else if kube_errors.IsNotFound(err) {
+		resource = &v1alpha1.ClusterAutoscalerStatus{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      StatusResourceName,
+			},
+			Status: status,
+		}
+		resource, err = resources.Create(resource)
+		if err != nil {
+			err = fmt.Errorf("Failed to write status resource: %v", err)
+		}
+	} else {
+		err = fmt.Errorf("Failed to retrieve status resource for update: %v", err)
+	}
+	if err != nil {
+		glog.Error(err)
+		return nil, err
+	}
+	glog.V(8).Infof("Successfully wrote status resource %s/%s", namespace, StatusResourceName)
+	return resource, nil
+}
+
+// DeleteStatusResource deletes the ClusterAutoscalerStatus object.
+func DeleteStatusResource(client versioned.Interface, namespace string) error {
+	err := client.ClusterautoscalerV1alpha1().ClusterAutoscalerStatuses(namespace).Delete(StatusResourceName, &metav1.DeleteOptions{})
+	if err != nil {
+		glog.Error("Failed to delete status resource")
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/apis/clusterautoscaler/v1alpha1"
+	"github.com/gardener/autoscaler/cluster-autoscaler/client/clientset/versioned/fake"
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertStatus(t *testing.T) {
+	now := metav1.NewTime(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	status := &api.ClusterAutoscalerStatus{
+		ClusterwideConditions: []api.ClusterAutoscalerCondition{
+			{Type: api.ClusterAutoscalerHealth, Status: api.ClusterAutoscalerHealthy, Message: "ready=1", LastProbeTime: now},
+		},
+		NodeGroupStatuses: []api.NodeGroupStatus{
+			{ProviderID: "ng1", Conditions: []api.ClusterAutoscalerCondition{
+				{Type: api.ClusterAutoscalerScaleUp, Status: api.ClusterAutoscalerBackoff, LastTransitionTime: now},
+			}},
+		},
+	}
+	result := ConvertStatus(status)
+	assert.Equal(t, []v1alpha1.Condition{
+		{Type: v1alpha1.HealthCondition, Status: "Healthy", Message: "ready=1", LastProbeTime: now},
+	}, result.ClusterwideConditions)
+	assert.Equal(t, []v1alpha1.NodeGroupStatus{
+		{Name: "ng1", Conditions: []v1alpha1.Condition{
+			{Type: v1alpha1.ScaleUpCondition, Status: "Backoff", LastTransitionTime: now},
+		}},
+	}, result.NodeGroups)
+}
+
+func TestWriteStatusResource(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	status := v1alpha1.AutoscalerStatus{
+		UnremovableNodes: []v1alpha1.UnremovableNode{{NodeName: "n1", Reason: "pod p1 is not replicated"}},
+	}
+	created, err := WriteStatusResource(client, "kube-system", status)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusResourceName, created.Name)
+	assert.False(t, created.Status.LastUpdateTime.IsZero())
+
+	status.UnremovableNodes = nil
+	status.ScaleUpHistory = []v1alpha1.ScaleUpEvent{{NodeGroup: "ng1", Increase: 2}}
+	_, err = WriteStatusResource(client, "kube-system", status)
+	assert.NoError(t, err)
+
+	stored, err := client.ClusterautoscalerV1alpha1().ClusterAutoscalerStatuses("kube-system").Get(StatusResourceName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, stored.Status.UnremovableNodes)
+	assert.Equal(t, []v1alpha1.ScaleUpEvent{{NodeGroup: "ng1", Increase: 2}}, stored.Status.ScaleUpHistory)
+
+	assert.NoError(t, DeleteStatusResource(client, "kube-system"))
+	_, err = client.ClusterautoscalerV1alpha1().ClusterAutoscalerStatuses("kube-system").Get(StatusResourceName, metav1.GetOptions{})
+	assert.Error(t, err)
+}
@@ -30,16 +30,38 @@ type GpuLimits struct {
 	Max int64
 }
 
+// ResourceLimits define lower and upper bound on a node resource other than cores, memory and GPUs in cluster
+type ResourceLimits struct {
+	// Name of the resource as reported in node capacity (e.g. ephemeral-storage, hugepages-2Mi, example.com/fpga)
+	Name string
+	// Lower bound on the amount of the resource in cluster
+	Min int64
+	// Upper bound on the amount of the resource in cluster
+	Max int64
+}
+
 // AutoscalingOptions contain various options to customize how autoscaling works
 type AutoscalingOptions struct {
 	// MaxEmptyBulkDelete is a number of empty nodes that can be removed at the same time.
 	MaxEmptyBulkDelete int
+	// MaxBulkSoftTaintCount sets the maximum number of nodes that can be (un)tainted PreferNoSchedule during a single scale down run.
+	// Zero disables soft tainting of unneeded nodes.
+	MaxBulkSoftTaintCount int
+	// MaxBulkSoftTaintTime sets the maximum duration of a single run of PreferNoSchedule tainting.
+	MaxBulkSoftTaintTime time.Duration
 	// ScaleDownUtilizationThreshold sets threshold for nodes to be considered for scale down.
 	// Well-utilized nodes are not touched.
 	ScaleDownUtilizationThreshold float64
+	// UtilizationResourceWeights are weights of per resource utilization in node utilization, by resource name.
+	// Resources without a weight count with weight 1, a weight of 0 excludes a resource.
+	UtilizationResourceWeights map[string]float64
 	// ScaleDownUnneededTime sets the duration CA expects a node to be unneeded/eligible for removal
 	// before scaling down the node.
 	ScaleDownUnneededTime time.Duration
+	// ScaleDownStateCheckpointInterval is how often scale-down bookkeeping is saved, if a state store is configured.
+	ScaleDownStateCheckpointInterval time.Duration
+	// ScaleDownStateMaxAge is the maximum age of saved scale-down bookkeeping that is still restored on start.
+	ScaleDownStateMaxAge time.Duration
 	// ScaleDownUnreadyTime represents how long an unready node should be unneeded before it is eligible for scale down
 	ScaleDownUnreadyTime time.Duration
 	// MaxNodesTotal sets the maximum number of nodes in the whole cluster
@@ -54,12 +76,16 @@ type AutoscalingOptions struct {
 	MinMemoryTotal int64
 	// GpuTotal is a list of strings with configuration of min/max limits for different GPUs.
 	GpuTotal []GpuLimits
+	// ResourceTotal is a list of min/max limits for other node resources, like ephemeral-storage or extended resources.
+	ResourceTotal []ResourceLimits
 	// NodeGroupAutoDiscovery represents one or more definition(s) of node group auto-discovery
 	NodeGroupAutoDiscovery []string
 	// EstimatorName is the estimator used to estimate the number of needed nodes in scale up.
 	EstimatorName string
 	// ExpanderName sets the type of node group expander to be used in scale up
 	ExpanderName string
+	// ScaleDownOrderName sets the strategy used to order nodes considered for removal in scale down
+	ScaleDownOrderName string
 	// MaxGracefulTerminationSec is maximum number of seconds scale down waits for pods to terminate before
 	// removing the node from cloud provider.
 	MaxGracefulTerminationSec int
@@ -98,6 +124,8 @@ type AutoscalingOptions struct {
 	ScaleDownCandidatesPoolMinCount int
 	// WriteStatusConfigMap tells if the status information should be written to a ConfigMap
 	WriteStatusConfigMap bool
+	// WriteStatusResource tells if the status information should be written to a ClusterAutoscalerStatus resource
+	WriteStatusResource bool
 	// BalanceSimilarNodeGroups enables logic that identifies node groups with similar machines and tries to balance node count between them.
 	BalanceSimilarNodeGroups bool
 	// ConfigNamespace is the namespace cluster-autoscaler is running in and all related configmaps live in
@@ -110,9 +138,36 @@ type AutoscalingOptions struct {
 	MaxAutoprovisionedNodeGroupCount int
 	// UnremovableNodeRecheckTimeout is the timeout before we check again a node that couldn't be removed before
 	UnremovableNodeRecheckTimeout time.Duration
+	// UnremovableNodeEventInterval is the minimal interval between two events explaining why a node can't be removed.
+	// Zero disables the events.
+	UnremovableNodeEventInterval time.Duration
 	// Pods with priority below cutoff are expendable. They can be killed without any consideration during scale down and they don't cause scale-up.
 	// Pods with null priority (PodPriority disabled) are non-expendable.
 	ExpendablePodsPriorityCutoff int
 	// Regional tells whether the cluster is regional.
 	Regional bool
+	// DryRun makes CA compute scaling decisions without executing them on the cloud provider or the cluster.
+	DryRun bool
+	// SafeToEvictLocalVolumes are names of emptyDir volumes whose data may be lost when a pod is evicted in scale down.
+	SafeToEvictLocalVolumes []string
+	// ReplicatedControllerKinds are controller kinds, in the "Kind.group" format, whose pods are treated as replicated.
+	ReplicatedControllerKinds []string
+	// DrainPolicyConfigMap is the name of the ConfigMap in ConfigNamespace holding namespace-level drain policies.
+	DrainPolicyConfigMap string
+	// DrainPolicyNamespaceAnnotations tells if namespace annotations may set namespace-level drain policies.
+	DrainPolicyNamespaceAnnotations bool
+	// LoopDeadline is how long the cloud provider calls made in one loop may take in total. Zero means no deadline.
+	LoopDeadline time.Duration
+	// SimulationParallelism is the number of goroutines evaluating scale-down candidates and unschedulable pods.
+	SimulationParallelism int
+	// SimulationSeed seeds the random choices made in simulations, making them reproducible. Zero means a random seed.
+	SimulationSeed int64
+	// SchedulerPolicyFile is the path of the kube-scheduler Policy file the predicates are taken from.
+	SchedulerPolicyFile string
+	// SchedulerPolicyConfigMap is the name of the ConfigMap holding the kube-scheduler Policy the predicates are taken from.
+	SchedulerPolicyConfigMap string
+	// SchedulerPolicyNamespace is the namespace of SchedulerPolicyConfigMap.
+	SchedulerPolicyNamespace string
+	// SchedulerAlgorithmProvider is the scheduler algorithm provider whose predicates are used unless the policy lists them.
+	SchedulerAlgorithmProvider string
 }
@@ -18,10 +18,15 @@ package context
 
 import (
 	"github.com/golang/glog"
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
+	"github.com/gardener/autoscaler/cluster-autoscaler/client/clientset/versioned"
 	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
+	"github.com/gardener/autoscaler/cluster-autoscaler/deletionhooks"
 	"github.com/gardener/autoscaler/cluster-autoscaler/expander"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
 	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
 	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
 	kube_client "k8s.io/client-go/kubernetes"
@@ -42,6 +47,14 @@ type AutoscalingContext struct {
 	PredicateChecker *simulator.PredicateChecker
 	// ExpanderStrategy is the strategy used to choose which node group to expand when scaling up
 	ExpanderStrategy expander.Strategy
+	// ScaleDownOrderStrategy is the strategy used to choose the order in which nodes are removed when scaling down
+	ScaleDownOrderStrategy scaledownorder.Strategy
+	// Actuator executes scale-up and scale-down decisions (or only records them in dry-run mode).
+	Actuator actuation.Actuator
+	// DeletionHooks are called around removal of nodes in scale down. May be nil.
+	DeletionHooks *deletionhooks.Runner
+	// AuditLog records scaling decisions. May be nil.
+	AuditLog *auditlog.Log
 }
 
 // AutoscalingKubeClients contains all Kubernetes API clients,
@@ -55,6 +68,15 @@ type AutoscalingKubeClients struct {
 	Recorder kube_record.EventRecorder
 	// LogRecorder can be used to collect log messages to expose via Events on some central object.
 	LogRecorder *utils.LogEventRecorder
+	// StatusClient is used to write the ClusterAutoscalerStatus resource. May be nil.
+	StatusClient versioned.Interface
+	// NamespaceLister lists namespaces for namespace-level drain policies. May be nil.
+	NamespaceLister kube_util.NamespaceLister
+	// DrainPolicyConfigMapLister gets the ConfigMap with namespace-level drain policies. May be nil.
+	DrainPolicyConfigMapLister kube_util.ConfigMapLister
+	// VolumeLister gets the persistent volumes bound to pods, so that scale-up can respect their topology. It
+	// shares the informers of the predicate checker. May be nil.
+	VolumeLister kube_util.VolumeLister
 }
 
 // NewResourceLimiterFromAutoscalingOptions creates new instance of cloudprovider.ResourceLimiter
@@ -63,6 +85,7 @@ func NewResourceLimiterFromAutoscalingOptions(options config.AutoscalingOptions)
 	// build min/max maps for resources limits
 	minResources := make(map[string]int64)
 	maxResources := make(map[string]int64)
+	resourceTypes := make(map[string]cloudprovider.ResourceType)
 
 	minResources[cloudprovider.ResourceNameCores] = options.MinCoresTotal
 	minResources[cloudprovider.ResourceNameMemory] = options.MinMemoryTotal
@@ -72,19 +95,31 @@ func NewResourceLimiterFromAutoscalingOptions(options config.AutoscalingOptions)
 	for _, gpuLimits := range options.GpuTotal {
 		minResources[gpuLimits.GpuType] = gpuLimits.Min
 		maxResources[gpuLimits.GpuType] = gpuLimits.Max
+		resourceTypes[gpuLimits.GpuType] = cloudprovider.ResourceTypeGpu
 	}
-	return cloudprovider.NewResourceLimiter(minResources, maxResources)
+	for _, resourceLimits := range options.ResourceTotal {
+		minResources[resourceLimits.Name] = resourceLimits.Min
+		maxResources[resourceLimits.Name] = resourceLimits.Max
+		resourceTypes[resourceLimits.Name] = cloudprovider.ResourceTypeNode
+	}
+	return cloudprovider.NewResourceLimiterWithTypes(minResources, maxResources, resourceTypes)
 }
 
 // NewAutoscalingContext returns an autoscaling context from all the necessary parameters passed via arguments
 func NewAutoscalingContext(options config.AutoscalingOptions, predicateChecker *simulator.PredicateChecker,
-	autoscalingKubeClients *AutoscalingKubeClients, cloudProvider cloudprovider.CloudProvider, expanderStrategy expander.Strategy) *AutoscalingContext {
+	autoscalingKubeClients *AutoscalingKubeClients, cloudProvider cloudprovider.CloudProvider, expanderStrategy expander.Strategy,
+	scaleDownOrderStrategy scaledownorder.Strategy, actuator actuation.Actuator, deletionHooks *deletionhooks.Runner,
+	auditLog *auditlog.Log) *AutoscalingContext {
 	return &AutoscalingContext{
 		AutoscalingOptions:     options,
 		CloudProvider:          cloudProvider,
 		AutoscalingKubeClients: *autoscalingKubeClients,
 		PredicateChecker:       predicateChecker,
 		ExpanderStrategy:       expanderStrategy,
+		ScaleDownOrderStrategy: scaleDownOrderStrategy,
+		Actuator:               actuator,
+		DeletionHooks:          deletionHooks,
+		AuditLog:               auditLog,
 	}
 }
 
@@ -101,10 +136,21 @@ func NewAutoscalingKubeClients(opts config.AutoscalingOptions, kubeClient kube_c
 		logRecorder, _ = utils.NewStatusMapRecorder(kubeClient, opts.ConfigNamespace, kubeEventRecorder, false)
 	}
 
+	var namespaceLister kube_util.NamespaceLister
+	if opts.DrainPolicyNamespaceAnnotations {
+		namespaceLister = kube_util.NewNamespaceLister(kubeClient, listerRegistryStopChannel)
+	}
+	var drainPolicyConfigMapLister kube_util.ConfigMapLister
+	if opts.DrainPolicyConfigMap != "" {
+		drainPolicyConfigMapLister = kube_util.NewConfigMapLister(kubeClient, opts.ConfigNamespace, opts.DrainPolicyConfigMap, listerRegistryStopChannel)
+	}
+
 	return &AutoscalingKubeClients{
-		ListerRegistry: listerRegistry,
-		ClientSet:      kubeClient,
-		Recorder:       kubeEventRecorder,
-		LogRecorder:    logRecorder,
+		ListerRegistry:             listerRegistry,
+		ClientSet:                  kubeClient,
+		Recorder:                   kubeEventRecorder,
+		LogRecorder:                logRecorder,
+		NamespaceLister:            namespaceLister,
+		DrainPolicyConfigMapLister: drainPolicyConfigMapLister,
 	}
 }
@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	ctx "context"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/deletetaint"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+// DefaultActuator is an actuation.Actuator that executes actions on the cloud provider
+// and the Kubernetes API.
+type DefaultActuator struct {
+	client            kube_client.Interface
+	recorder          kube_record.EventRecorder
+	drainer           *Drainer
+	cloudProviderName string
+}
+
+// NewDefaultActuator builds new DefaultActuator. The cloud provider name is used in metrics.
+func NewDefaultActuator(client kube_client.Interface, recorder kube_record.EventRecorder, drainer *Drainer,
+	cloudProviderName string) actuation.Actuator {
+	return &DefaultActuator{
+		client:            client,
+		recorder:          recorder,
+		drainer:           drainer,
+		cloudProviderName: cloudProviderName,
+	}
+}
+
+// IncreaseSize increases the size of the node group by delta.
+func (a *DefaultActuator) IncreaseSize(callCtx ctx.Context, nodeGroup cloudprovider.NodeGroup, delta int) error {
+	return callCloudProvider(callCtx, a.cloudProviderName, string(actuation.IncreaseSizeAction), func(callCtx ctx.Context) error {
+		return cloudprovider.NodeGroupWithContext(nodeGroup).IncreaseSizeWithContext(callCtx, delta)
+	})
+}
+
+// DecreaseTargetSize decreases the target size of the node group by delta.
+func (a *DefaultActuator) DecreaseTargetSize(callCtx ctx.Context, nodeGroup cloudprovider.NodeGroup, delta int) error {
+	return callCloudProvider(callCtx, a.cloudProviderName, string(actuation.DecreaseTargetSizeAction), func(callCtx ctx.Context) error {
+		return cloudprovider.NodeGroupWithContext(nodeGroup).DecreaseTargetSizeWithContext(callCtx, delta)
+	})
+}
+
+// DeleteNodes removes the given nodes from the node group.
+func (a *DefaultActuator) DeleteNodes(callCtx ctx.Context, nodeGroup cloudprovider.NodeGroup, nodes []*apiv1.Node) error {
+	return callCloudProvider(callCtx, a.cloudProviderName, string(actuation.DeleteNodesAction), func(callCtx ctx.Context) error {
+		return cloudprovider.NodeGroupWithContext(nodeGroup).DeleteNodesWithContext(callCtx, nodes)
+	})
+}
+
+// MarkToBeDeleted sets the ToBeDeleted taint on the node.
+func (a *DefaultActuator) MarkToBeDeleted(node *apiv1.Node) error {
+	return deletetaint.MarkToBeDeleted(node, a.client)
+}
+
+// CleanToBeDeleted removes the ToBeDeleted taint from the node.
+func (a *DefaultActuator) CleanToBeDeleted(node *apiv1.Node) (bool, error) {
+	return deletetaint.CleanToBeDeleted(node, a.client)
+}
+
+// MarkDeletionCandidate sets the DeletionCandidate soft taint on the node.
+func (a *DefaultActuator) MarkDeletionCandidate(node *apiv1.Node) error {
+	return deletetaint.MarkDeletionCandidate(node, a.client)
+}
+
+// CleanDeletionCandidate removes the DeletionCandidate soft taint from the node.
+func (a *DefaultActuator) CleanDeletionCandidate(node *apiv1.Node) (bool, error) {
+	return deletetaint.CleanDeletionCandidate(node, a.client)
+}
+
+// DrainNode evicts the given pods from the node and waits until they are gone.
+func (a *DefaultActuator) DrainNode(traceCtx ctx.Context, node *apiv1.Node, pods []*apiv1.Pod) errors.AutoscalerError {
+	return a.drainer.DrainNode(traceCtx, node, pods)
+}
+
+// AbortDrains aborts drains in progress and all later drains.
+func (a *DefaultActuator) AbortDrains() {
+	if a.drainer != nil {
+		a.drainer.Abort()
+	}
+}
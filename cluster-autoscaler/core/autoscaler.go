@@ -17,38 +17,66 @@ limitations under the License.
 package core
 
 import (
+	ctx "context"
+	"fmt"
 	"time"
 
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
+	"github.com/gardener/autoscaler/cluster-autoscaler/checkpoint"
+	"github.com/gardener/autoscaler/cluster-autoscaler/client/clientset/versioned"
 	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	cloudBuilder "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/builder"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
 	"github.com/gardener/autoscaler/cluster-autoscaler/context"
+	"github.com/gardener/autoscaler/cluster-autoscaler/debugging"
+	"github.com/gardener/autoscaler/cluster-autoscaler/deletionhooks"
 	"github.com/gardener/autoscaler/cluster-autoscaler/expander"
 	"github.com/gardener/autoscaler/cluster-autoscaler/expander/factory"
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
 	ca_processors "github.com/gardener/autoscaler/cluster-autoscaler/processors"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
+	scaledownorder_factory "github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder/factory"
 	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
 	kube_client "k8s.io/client-go/kubernetes"
+	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
+	schedulerfactory "k8s.io/kubernetes/pkg/scheduler/factory"
+
+	"github.com/golang/glog"
 )
 
 // AutoscalerOptions is the whole set of options for configuring an autoscaler
 type AutoscalerOptions struct {
 	config.AutoscalingOptions
 	KubeClient             kube_client.Interface
+	StatusClient           versioned.Interface
 	AutoscalingKubeClients *context.AutoscalingKubeClients
 	CloudProvider          cloudprovider.CloudProvider
 	PredicateChecker       *simulator.PredicateChecker
 	ExpanderStrategy       expander.Strategy
+	ScaleDownOrderStrategy scaledownorder.Strategy
 	Processors             *ca_processors.AutoscalingProcessors
+	Actuator               actuation.Actuator
+	DeletionHooks          *deletionhooks.Runner
+	LoopRecorder           looprecorder.Recorder
+	DebuggingServer        *debugging.Server
+	StateStore             checkpoint.Store
+	AuditLog               *auditlog.Log
 }
 
 // Autoscaler is the main component of CA which scales up/down node groups according to its configuration
 // The configuration can be injected at the creation of an autoscaler
 type Autoscaler interface {
-	// RunOnce represents an iteration in the control-loop of CA
-	RunOnce(currentTime time.Time) errors.AutoscalerError
-	// ExitCleanUp is a clean-up performed just before process termination.
-	ExitCleanUp()
+	// RunOnce represents an iteration in the control-loop of CA. No new scaling actions are started
+	// once runCtx is done.
+	RunOnce(runCtx ctx.Context, currentTime time.Time) errors.AutoscalerError
+	// StopInFlightActions waits until waitCtx is done for node deletions in progress to finish and
+	// aborts the remaining ones. Returns false if any deletion had to be aborted.
+	StopInFlightActions(waitCtx ctx.Context) bool
+	// ExitCleanUp is a clean-up performed just before process termination. If leading is false,
+	// the leadership was lost and the state shared with the new leader is left alone.
+	ExitCleanUp(leading bool)
 }
 
 // NewAutoscaler creates an autoscaler of an appropriate type according to the parameters
@@ -57,7 +85,24 @@ func NewAutoscaler(opts AutoscalerOptions) (Autoscaler, errors.AutoscalerError)
 	if err != nil {
 		return nil, errors.ToAutoscalerError(errors.InternalError, err)
 	}
-	return NewStaticAutoscaler(opts.AutoscalingOptions, opts.PredicateChecker, opts.AutoscalingKubeClients, opts.Processors, opts.CloudProvider, opts.ExpanderStrategy), nil
+	return NewStaticAutoscaler(opts.AutoscalingOptions, opts.PredicateChecker, opts.AutoscalingKubeClients, opts.Processors, opts.CloudProvider, opts.ExpanderStrategy, opts.ScaleDownOrderStrategy, opts.Actuator, opts.DeletionHooks, opts.AuditLog, opts.LoopRecorder, opts.DebuggingServer, opts.StateStore), nil
+}
+
+// loadSchedulerPolicy reads the scheduler policy from the configured file or ConfigMap. It returns nil
+// if none is configured.
+func loadSchedulerPolicy(opts *AutoscalerOptions) (*schedulerapi.Policy, error) {
+	if opts.SchedulerPolicyFile != "" && opts.SchedulerPolicyConfigMap != "" {
+		return nil, fmt.Errorf("only one of scheduler policy file and scheduler policy ConfigMap can be set")
+	}
+	if opts.SchedulerPolicyFile != "" {
+		glog.V(1).Infof("Loading scheduler policy from file %s", opts.SchedulerPolicyFile)
+		return simulator.LoadSchedulerPolicyFile(opts.SchedulerPolicyFile)
+	}
+	if opts.SchedulerPolicyConfigMap != "" {
+		glog.V(1).Infof("Loading scheduler policy from ConfigMap %s/%s", opts.SchedulerPolicyNamespace, opts.SchedulerPolicyConfigMap)
+		return simulator.LoadSchedulerPolicyConfigMap(opts.KubeClient, opts.SchedulerPolicyNamespace, opts.SchedulerPolicyConfigMap)
+	}
+	return nil, nil
 }
 
 // Initialize default options if not provided.
@@ -68,14 +113,28 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 	if opts.AutoscalingKubeClients == nil {
 		opts.AutoscalingKubeClients = context.NewAutoscalingKubeClients(opts.AutoscalingOptions, opts.KubeClient)
 	}
+	if opts.AutoscalingKubeClients.StatusClient == nil {
+		opts.AutoscalingKubeClients.StatusClient = opts.StatusClient
+	}
 	if opts.PredicateChecker == nil {
+		policy, err := loadSchedulerPolicy(opts)
+		if err != nil {
+			return err
+		}
+		provider := opts.SchedulerAlgorithmProvider
+		if provider == "" {
+			provider = schedulerfactory.DefaultProvider
+		}
 		predicateCheckerStopChannel := make(chan struct{})
-		predicateChecker, err := simulator.NewPredicateChecker(opts.KubeClient, predicateCheckerStopChannel)
+		predicateChecker, err := simulator.NewPredicateCheckerWithPolicy(opts.KubeClient, provider, policy, predicateCheckerStopChannel)
 		if err != nil {
 			return err
 		}
 		opts.PredicateChecker = predicateChecker
 	}
+	if opts.AutoscalingKubeClients.VolumeLister == nil {
+		opts.AutoscalingKubeClients.VolumeLister = opts.PredicateChecker.VolumeLister()
+	}
 	if opts.CloudProvider == nil {
 		opts.CloudProvider = cloudBuilder.NewCloudProvider(opts.AutoscalingOptions)
 	}
@@ -87,6 +146,25 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 		}
 		opts.ExpanderStrategy = expanderStrategy
 	}
+	if opts.ScaleDownOrderStrategy == nil {
+		scaleDownOrderStrategy, err := scaledownorder_factory.ScaleDownOrderStrategyFromString(opts.ScaleDownOrderName,
+			opts.CloudProvider)
+		if err != nil {
+			return err
+		}
+		opts.ScaleDownOrderStrategy = scaleDownOrderStrategy
+	}
+	if opts.Actuator == nil {
+		if opts.DryRun {
+			opts.Actuator = actuation.NewDryRunActuator(opts.AutoscalingKubeClients.Recorder, opts.AutoscalingKubeClients.LogRecorder)
+		} else {
+			drainer := NewDrainer(opts.AutoscalingKubeClients.ClientSet, opts.AutoscalingKubeClients.Recorder,
+				opts.AutoscalingKubeClients.ScheduledPodLister(), opts.AutoscalingKubeClients.PodDisruptionBudgetLister(),
+				opts.MaxGracefulTerminationSec)
+			opts.Actuator = NewDefaultActuator(opts.AutoscalingKubeClients.ClientSet, opts.AutoscalingKubeClients.Recorder, drainer,
+				opts.CloudProvider.Name())
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	ctx "context"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+	"github.com/gardener/autoscaler/cluster-autoscaler/tracing"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+
+	"go.opencensus.io/trace"
+)
+
+// Outcomes of cloud provider calls reported in metrics.
+const (
+	cloudProviderCallSucceeded = "success"
+	cloudProviderCallFailed    = "error"
+	cloudProviderCallTimedOut  = "timeout"
+	cloudProviderCallCancelled = "cancelled"
+)
+
+// withLoopDeadline returns a context for the cloud provider calls of one loop, which is done
+// deadline from now. A zero deadline means the calls are only cancelled together with parent.
+func withLoopDeadline(parent ctx.Context, deadline time.Duration) (ctx.Context, ctx.CancelFunc) {
+	if deadline <= 0 {
+		return ctx.WithCancel(parent)
+	}
+	return ctx.WithTimeout(parent, deadline)
+}
+
+// callCloudProvider makes a context-aware cloud provider call and records its outcome and duration
+// in metrics and in a span of the trace in callCtx. If the call fails once callCtx is done, a CloudProviderError saying that it timed out or was
+// cancelled is returned.
+func callCloudProvider(callCtx ctx.Context, provider, method string, call func(ctx.Context) error) error {
+	callCtx, span := tracing.StartSpan(callCtx, "CloudProvider."+method, trace.StringAttribute("provider", provider))
+	start := time.Now()
+	err := call(callCtx)
+	duration := time.Now().Sub(start)
+	outcome := cloudProviderCallSucceeded
+	if err != nil {
+		switch callCtx.Err() {
+		case nil:
+			outcome = cloudProviderCallFailed
+		case ctx.DeadlineExceeded:
+			outcome = cloudProviderCallTimedOut
+			err = errors.NewAutoscalerError(errors.CloudProviderError, "%s timed out after %v: %v", method, duration, err)
+		default:
+			outcome = cloudProviderCallCancelled
+			err = errors.NewAutoscalerError(errors.CloudProviderError, "%s cancelled after %v: %v", method, duration, err)
+		}
+	}
+	metrics.RegisterCloudProviderCall(provider, method, outcome, duration)
+	span.AddAttributes(trace.StringAttribute("outcome", outcome))
+	tracing.EndSpan(span, err)
+	return err
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	ctx "context"
+	"fmt"
+	"testing"
+	"time"
+
+	testprovider "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallCloudProvider(t *testing.T) {
+	err := callCloudProvider(ctx.Background(), "test", "Refresh", func(ctx.Context) error { return nil })
+	assert.NoError(t, err)
+
+	err = callCloudProvider(ctx.Background(), "test", "Refresh", func(ctx.Context) error { return fmt.Errorf("quota exceeded") })
+	assert.EqualError(t, err, "quota exceeded")
+
+	timedOut, cancel := ctx.WithTimeout(ctx.Background(), time.Millisecond)
+	defer cancel()
+	err = callCloudProvider(timedOut, "test", "Refresh", func(callCtx ctx.Context) error {
+		<-callCtx.Done()
+		return callCtx.Err()
+	})
+	assert.Error(t, err)
+	assert.Equal(t, errors.CloudProviderError, err.(errors.AutoscalerError).Type())
+	assert.Contains(t, err.Error(), "Refresh timed out")
+
+	cancelled, cancel := ctx.WithCancel(ctx.Background())
+	cancel()
+	err = callCloudProvider(cancelled, "test", "Refresh", func(callCtx ctx.Context) error { return callCtx.Err() })
+	assert.Equal(t, errors.CloudProviderError, err.(errors.AutoscalerError).Type())
+	assert.Contains(t, err.Error(), "Refresh cancelled")
+}
+
+func TestWithLoopDeadline(t *testing.T) {
+	loopCtx, cancel := withLoopDeadline(ctx.Background(), 0)
+	_, hasDeadline := loopCtx.Deadline()
+	assert.False(t, hasDeadline)
+	cancel()
+	assert.Equal(t, ctx.Canceled, loopCtx.Err())
+
+	loopCtx, cancel = withLoopDeadline(ctx.Background(), time.Minute)
+	defer cancel()
+	deadline, hasDeadline := loopCtx.Deadline()
+	assert.True(t, hasDeadline)
+	assert.True(t, deadline.After(time.Now().Add(50*time.Second)))
+}
+
+func TestDefaultActuatorIncreaseSizeTimeout(t *testing.T) {
+	callCtx, cancel := ctx.WithTimeout(ctx.Background(), 50*time.Millisecond)
+	defer cancel()
+	calls := 0
+	provider := testprovider.NewTestCloudProvider(func(string, int) error {
+		calls++
+		<-callCtx.Done()
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	actuator := NewDefaultActuator(fake.NewSimpleClientset(), kube_record.NewFakeRecorder(5), nil, provider.Name())
+
+	// A size increase in progress is waited for, even if it misses the deadline.
+	err := actuator.IncreaseSize(callCtx, provider.GetNodeGroup("ng1"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	// No size increase is started after the deadline.
+	err = actuator.IncreaseSize(callCtx, provider.GetNodeGroup("ng1"), 1)
+	assert.Error(t, err)
+	assert.Equal(t, errors.CloudProviderError, err.(errors.AutoscalerError).Type())
+	assert.Contains(t, err.Error(), "IncreaseSize timed out")
+	assert.Equal(t, 1, calls)
+}
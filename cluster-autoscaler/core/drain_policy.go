@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"github.com/gardener/autoscaler/cluster-autoscaler/context"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/golang/glog"
+)
+
+// drainPolicyBuilder builds the drain policy used in scale down from the autoscaling options, the drain
+// policy ConfigMap and the namespace annotations. The controller resolver is built once and the ConfigMap
+// is parsed again only when its resourceVersion changes. Failures to read the namespace-level policies are
+// logged and these policies are skipped, so that scale down falls back to the stricter default rules.
+type drainPolicyBuilder struct {
+	context            *context.AutoscalingContext
+	controllerResolver drain.ControllerResolver
+	// configMapParsed is set if configMapPolicies were parsed from the ConfigMap with configMapVersion.
+	configMapParsed   bool
+	configMapVersion  string
+	configMapPolicies map[string]drain.NamespacePolicy
+}
+
+func newDrainPolicyBuilder(context *context.AutoscalingContext) *drainPolicyBuilder {
+	resolver, err := drain.NewReplicatedKindsResolver(context.ReplicatedControllerKinds)
+	if err != nil {
+		glog.Errorf("Ignoring replicated controller kinds: %v", err)
+		resolver = drain.NewChainControllerResolver()
+	}
+	return &drainPolicyBuilder{
+		context:            context,
+		controllerResolver: drain.NewChainControllerResolver(drain.NewBuiltinControllerResolver(), resolver),
+	}
+}
+
+// build returns the current drain policy.
+func (b *drainPolicyBuilder) build() *drain.Policy {
+	policy := &drain.Policy{
+		ControllerResolver:      b.controllerResolver,
+		SafeToEvictLocalVolumes: b.context.SafeToEvictLocalVolumes,
+		Namespaces:              make(map[string]drain.NamespacePolicy),
+	}
+
+	for namespace, namespacePolicy := range b.getConfigMapPolicies() {
+		policy.Namespaces[namespace] = namespacePolicy
+	}
+
+	if b.context.NamespaceLister != nil {
+		namespaces, err := b.context.NamespaceLister.List()
+		if err != nil {
+			glog.Errorf("Failed to list namespaces for drain policy: %v", err)
+		}
+		for _, namespace := range namespaces {
+			if namespacePolicy, found := drain.NamespacePolicyFromAnnotations(namespace); found {
+				policy.Namespaces[namespace.Name] = policy.Namespaces[namespace.Name].Merge(namespacePolicy)
+			}
+		}
+	}
+	return policy
+}
+
+// getConfigMapPolicies returns the namespace policies from the drain policy ConfigMap. The returned map
+// must not be modified.
+func (b *drainPolicyBuilder) getConfigMapPolicies() map[string]drain.NamespacePolicy {
+	lister := b.context.DrainPolicyConfigMapLister
+	if b.context.DrainPolicyConfigMap == "" || lister == nil {
+		return nil
+	}
+	configMap, err := lister.Get(b.context.DrainPolicyConfigMap)
+	if err != nil {
+		if !kube_errors.IsNotFound(err) {
+			glog.Errorf("Failed to get drain policy ConfigMap %s/%s: %v", b.context.ConfigNamespace, b.context.DrainPolicyConfigMap, err)
+		}
+		b.configMapParsed = false
+		b.configMapPolicies = nil
+		return nil
+	}
+	if b.configMapParsed && configMap.ResourceVersion == b.configMapVersion {
+		return b.configMapPolicies
+	}
+
+	namespaces, err := drain.ParseNamespacePolicies([]byte(configMap.Data[drain.DrainPolicyConfigMapKey]))
+	if err != nil {
+		glog.Errorf("Ignoring drain policy ConfigMap %s/%s: %v", b.context.ConfigNamespace, b.context.DrainPolicyConfigMap, err)
+	}
+	b.configMapParsed = true
+	b.configMapVersion = configMap.ResourceVersion
+	b.configMapPolicies = namespaces
+	return namespaces
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/config"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDrainPolicy(t *testing.T) {
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain-policy", Namespace: "kube-system"},
+		Data: map[string]string{
+			drain.DrainPolicyConfigMapKey: `
+namespaces:
+  ci:
+    safeToEvictLocalVolumes: [workspace]
+  monitoring:
+    safeToEvictLocalVolumes: [cache]
+`,
+		},
+	}
+	options := config.AutoscalingOptions{
+		ConfigNamespace:           "kube-system",
+		DrainPolicyConfigMap:      "drain-policy",
+		SafeToEvictLocalVolumes:   []string{"tmp"},
+		ReplicatedControllerKinds: []string{"Workflow.argoproj.io"},
+	}
+	context := NewScaleTestAutoscalingContext(options, fake.NewSimpleClientset(), nil)
+	context.DrainPolicyConfigMapLister = kube_util.NewTestConfigMapLister([]*apiv1.ConfigMap{configMap})
+	context.NamespaceLister = kube_util.NewTestNamespaceLister([]*apiv1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ci", Annotations: map[string]string{drain.PodSafeToEvictKey: "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	})
+
+	policy := newDrainPolicyBuilder(&context).build()
+	assert.Equal(t, []string{"tmp"}, policy.SafeToEvictLocalVolumes)
+	assert.Equal(t, map[string]drain.NamespacePolicy{
+		"ci":         {SafeToEvict: true, SafeToEvictLocalVolumes: []string{"workspace"}},
+		"monitoring": {SafeToEvictLocalVolumes: []string{"cache"}},
+	}, policy.Namespaces)
+
+	workflowPod := BuildTestPod("workflow", 100, 0)
+	workflowPod.OwnerReferences = GenerateOwnerReferences("wf", "Workflow", "argoproj.io/v1alpha1", "")
+	pods, _, err := drain.GetPodsForDeletionOnNodeDrain([]*apiv1.Pod{workflowPod}, nil, false, true, true, false, nil, 0,
+		time.Now(), policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []*apiv1.Pod{workflowPod}, pods)
+}
+
+func TestBuildDrainPolicyWithoutConfigMap(t *testing.T) {
+	options := config.AutoscalingOptions{
+		ConfigNamespace:      "kube-system",
+		DrainPolicyConfigMap: "missing",
+	}
+	context := NewScaleTestAutoscalingContext(options, fake.NewSimpleClientset(), nil)
+	context.DrainPolicyConfigMapLister = kube_util.NewTestConfigMapLister(nil)
+
+	policy := newDrainPolicyBuilder(&context).build()
+	assert.Empty(t, policy.Namespaces)
+	assert.Empty(t, policy.SafeToEvictLocalVolumes)
+}
+
+func TestDrainPolicyBuilderParsesConfigMapOnChange(t *testing.T) {
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain-policy", Namespace: "kube-system", ResourceVersion: "1"},
+		Data:       map[string]string{drain.DrainPolicyConfigMapKey: "namespaces: {ci: {safeToEvict: true}}"},
+	}
+	options := config.AutoscalingOptions{
+		ConfigNamespace:      "kube-system",
+		DrainPolicyConfigMap: "drain-policy",
+	}
+	context := NewScaleTestAutoscalingContext(options, fake.NewSimpleClientset(), nil)
+	lister := kube_util.NewTestConfigMapLister([]*apiv1.ConfigMap{configMap})
+	context.DrainPolicyConfigMapLister = lister
+	builder := newDrainPolicyBuilder(&context)
+
+	assert.Equal(t, map[string]drain.NamespacePolicy{"ci": {SafeToEvict: true}}, builder.build().Namespaces)
+
+	// Same resourceVersion, the cached policies are used.
+	configMap.Data[drain.DrainPolicyConfigMapKey] = "namespaces: {monitoring: {safeToEvict: true}}"
+	assert.Equal(t, map[string]drain.NamespacePolicy{"ci": {SafeToEvict: true}}, builder.build().Namespaces)
+
+	updated := configMap.DeepCopy()
+	updated.ResourceVersion = "2"
+	lister.SetConfigMaps([]*apiv1.ConfigMap{updated})
+	assert.Equal(t, map[string]drain.NamespacePolicy{"monitoring": {SafeToEvict: true}}, builder.build().Namespaces)
+
+	lister.SetConfigMaps(nil)
+	assert.Empty(t, builder.build().Namespaces)
+}
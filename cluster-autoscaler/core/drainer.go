@@ -0,0 +1,421 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	ctx "context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+	"github.com/gardener/autoscaler/cluster-autoscaler/tracing"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kube_client "k8s.io/client-go/kubernetes"
+	kube_record "k8s.io/client-go/tools/record"
+
+	"github.com/golang/glog"
+	"go.opencensus.io/trace"
+)
+
+// Drainer evicts pods from nodes removed by scale down.
+//
+// Evictions rejected by a pod disruption budget (HTTP 429) are retried with an exponential backoff
+// shared by all pods covered by that budget, also across drains of different nodes. A drain is aborted
+// without waiting for the eviction timeout if a budget covering one of the pods can never allow
+// an eviction. Evicted pods are watched through the scheduled pod lister, not with API calls.
+type Drainer struct {
+	client                    kube_client.Interface
+	recorder                  kube_record.EventRecorder
+	podLister                 kube_util.PodLister
+	pdbLister                 kube_util.PodDisruptionBudgetLister
+	maxGracefulTerminationSec int
+	maxPodEvictionTime        time.Duration
+	evictionRetryTime         time.Duration
+	maxEvictionBackoff        time.Duration
+	podCheckInterval          time.Duration
+
+	backoffMutex sync.Mutex
+	pdbBackoff   map[string]*evictionBackoff
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type evictionBackoff struct {
+	delay time.Duration
+	until time.Time
+}
+
+// unsatisfiablePdbError is returned when a pod is covered by a pdb that can never allow its eviction.
+type unsatisfiablePdbError struct {
+	pod *apiv1.Pod
+	pdb *policyv1.PodDisruptionBudget
+}
+
+func (e *unsatisfiablePdbError) Error() string {
+	return fmt.Sprintf("pod disruption budget %s/%s covering pod %s/%s can never allow an eviction",
+		e.pdb.Namespace, e.pdb.Name, e.pod.Namespace, e.pod.Name)
+}
+
+// NewDrainer builds new Drainer. podLister has to list scheduled pods.
+func NewDrainer(client kube_client.Interface, recorder kube_record.EventRecorder, podLister kube_util.PodLister,
+	pdbLister kube_util.PodDisruptionBudgetLister, maxGracefulTerminationSec int) *Drainer {
+	return &Drainer{
+		client:                    client,
+		recorder:                  recorder,
+		podLister:                 podLister,
+		pdbLister:                 pdbLister,
+		maxGracefulTerminationSec: maxGracefulTerminationSec,
+		maxPodEvictionTime:        MaxPodEvictionTime,
+		evictionRetryTime:         EvictionRetryTime,
+		maxEvictionBackoff:        MaxEvictionBackoff,
+		podCheckInterval:          PodCheckInterval,
+		pdbBackoff:                make(map[string]*evictionBackoff),
+		stop:                      make(chan struct{}),
+	}
+}
+
+// Abort makes drains in progress and all later drains fail without evicting more pods.
+func (d *Drainer) Abort() {
+	d.stopOnce.Do(func() { close(d.stop) })
+}
+
+// DrainNode evicts all pods from the node and waits until they are gone, giving them up to their
+// grace period plus PodEvictionHeadroom to finish. Each eviction is recorded in a span of the trace in traceCtx.
+func (d *Drainer) DrainNode(traceCtx ctx.Context, node *apiv1.Node, pods []*apiv1.Pod) errors.AutoscalerError {
+	select {
+	case <-d.stop:
+		return d.drainAborted(node)
+	default:
+	}
+	pdbs, err := d.pdbLister.List()
+	if err != nil {
+		return errors.NewAutoscalerError(errors.ApiCallError, "Failed to drain node %s: failed to list pod disruption budgets: %v", node.Name, err)
+	}
+	podPdbs := make(map[*apiv1.Pod][]*policyv1.PodDisruptionBudget, len(pods))
+	for _, pod := range pods {
+		podPdbs[pod] = matchingPdbs(pod, pdbs)
+		if pdb := findUnsatisfiablePdb(podPdbs[pod]); pdb != nil {
+			return d.abortDrain(node, &unsatisfiablePdbError{pod: pod, pdb: pdb})
+		}
+	}
+
+	retryUntil := time.Now().Add(d.maxPodEvictionTime)
+	abort := make(chan struct{})
+	confirmations := make(chan error, len(pods))
+	maxGracePeriod := int64(d.maxGracefulTerminationSec)
+	for _, pod := range pods {
+		gracePeriod := d.gracePeriod(pod)
+		if gracePeriod > maxGracePeriod {
+			maxGracePeriod = gracePeriod
+		}
+		go func(podToEvict *apiv1.Pod, gracePeriod int64) {
+			_, span := tracing.StartSpan(traceCtx, "EvictPod", trace.StringAttribute("pod", podToEvict.Namespace+"/"+podToEvict.Name))
+			err := d.evictPod(podToEvict, gracePeriod, podPdbs[podToEvict], retryUntil, abort)
+			tracing.EndSpan(span, err)
+			confirmations <- err
+		}(pod, gracePeriod)
+	}
+
+	evictionErrs := make([]error, 0)
+	for range pods {
+		select {
+		case err := <-confirmations:
+			if unsatisfiable, ok := err.(*unsatisfiablePdbError); ok {
+				close(abort)
+				return d.abortDrain(node, unsatisfiable)
+			}
+			if err != nil {
+				evictionErrs = append(evictionErrs, err)
+			} else {
+				metrics.RegisterEvictions(1)
+			}
+		case <-d.stop:
+			close(abort)
+			return d.drainAborted(node)
+		case <-time.After(retryUntil.Sub(time.Now()) + 5*time.Second):
+			close(abort)
+			return errors.NewAutoscalerError(
+				errors.ApiCallError, "Failed to drain node %s/%s: timeout when waiting for creating evictions", node.Namespace, node.Name)
+		}
+	}
+	if len(evictionErrs) != 0 {
+		return errors.NewAutoscalerError(
+			errors.ApiCallError, "Failed to drain node %s/%s, due to following errors: %v", node.Namespace, node.Name, evictionErrs)
+	}
+
+	// Evictions created successfully, wait the longest grace period + PodEvictionHeadroom to see if pods really disappeared.
+	for start := time.Now(); time.Now().Sub(start) < time.Duration(maxGracePeriod)*time.Second+PodEvictionHeadroom; {
+		select {
+		case <-d.stop:
+			return d.drainAborted(node)
+		case <-time.After(d.podCheckInterval):
+		}
+		remaining, err := d.remainingPod(node, pods)
+		if err != nil {
+			glog.Errorf("Failed to list pods remaining on %s: %v", node.Name, err)
+			continue
+		}
+		if remaining == nil {
+			glog.V(1).Infof("All pods removed from %s", node.Name)
+			return nil
+		}
+		glog.V(4).Infof("Not deleted yet %s/%s", remaining.Namespace, remaining.Name)
+	}
+	return errors.NewAutoscalerError(
+		errors.TransientError, "Failed to drain node %s/%s: pods remaining after timeout", node.Namespace, node.Name)
+}
+
+func (d *Drainer) drainAborted(node *apiv1.Node) errors.AutoscalerError {
+	glog.Warningf("Drain of %s aborted, the autoscaler is stopping", node.Name)
+	return errors.NewAutoscalerError(errors.TransientError, "Failed to drain node %s: the autoscaler is stopping", node.Name)
+}
+
+func (d *Drainer) abortDrain(node *apiv1.Node, err *unsatisfiablePdbError) errors.AutoscalerError {
+	glog.Errorf("Aborting drain of %s: %v", node.Name, err)
+	d.recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "drain aborted: %v", err)
+	return errors.NewAutoscalerError(errors.TransientError, "Failed to drain node %s: %v", node.Name, err)
+}
+
+// evictPod creates an eviction for the pod, retrying until retryUntil or until abort is closed.
+func (d *Drainer) evictPod(podToEvict *apiv1.Pod, gracePeriod int64, pdbs []*policyv1.PodDisruptionBudget,
+	retryUntil time.Time, abort <-chan struct{}) error {
+	d.recorder.Eventf(podToEvict, apiv1.EventTypeNormal, "ScaleDown", "deleting pod for node scale down")
+
+	var lastError error
+	podBackoff := d.evictionRetryTime
+	for first := true; ; first = false {
+		if !first {
+			wait := d.evictionRetryTime
+			if kube_errors.IsTooManyRequests(lastError) {
+				if len(pdbs) > 0 {
+					wait = d.pdbWait(pdbs)
+				} else {
+					wait = podBackoff
+					podBackoff = nextBackoff(podBackoff, d.maxEvictionBackoff)
+				}
+			}
+			if !time.Now().Add(wait).Before(retryUntil) {
+				break
+			}
+			select {
+			case <-abort:
+				return fmt.Errorf("eviction of pod %s/%s aborted", podToEvict.Namespace, podToEvict.Name)
+			case <-time.After(wait):
+			}
+		}
+
+		eviction := &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: podToEvict.Namespace,
+				Name:      podToEvict.Name,
+			},
+			DeleteOptions: &metav1.DeleteOptions{
+				GracePeriodSeconds: &gracePeriod,
+			},
+		}
+		lastError = d.client.CoreV1().Pods(podToEvict.Namespace).Evict(eviction)
+		if lastError == nil || kube_errors.IsNotFound(lastError) {
+			d.resetPdbBackoff(pdbs)
+			return nil
+		}
+		if kube_errors.IsTooManyRequests(lastError) {
+			glog.V(2).Infof("Eviction of %s/%s rejected: %v", podToEvict.Namespace, podToEvict.Name, lastError)
+			d.backOffPdbs(pdbs)
+			if pdb := d.findCurrentUnsatisfiablePdb(pdbs); pdb != nil {
+				return &unsatisfiablePdbError{pod: podToEvict, pdb: pdb}
+			}
+		}
+	}
+	glog.Errorf("Failed to evict pod %s, error: %v", podToEvict.Name, lastError)
+	d.recorder.Eventf(podToEvict, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to delete pod for ScaleDown")
+	return fmt.Errorf("Failed to evict pod %s/%s within allowed timeout (last error: %v)", podToEvict.Namespace, podToEvict.Name, lastError)
+}
+
+// gracePeriod returns the grace period given to the evicted pod. It is the pod's termination grace period
+// capped by maxGracefulTerminationSec, unless overridden with the PodEvictionGracePeriodKey annotation.
+func (d *Drainer) gracePeriod(pod *apiv1.Pod) int64 {
+	if value, found := pod.Annotations[drain.PodEvictionGracePeriodKey]; found {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err == nil && seconds >= 0 {
+			return seconds
+		}
+		glog.Warningf("Ignoring invalid %s annotation %q on pod %s/%s", drain.PodEvictionGracePeriodKey, value, pod.Namespace, pod.Name)
+	}
+	gracePeriod := int64(apiv1.DefaultTerminationGracePeriodSeconds)
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		if *pod.Spec.TerminationGracePeriodSeconds < int64(d.maxGracefulTerminationSec) {
+			gracePeriod = *pod.Spec.TerminationGracePeriodSeconds
+		} else {
+			gracePeriod = int64(d.maxGracefulTerminationSec)
+		}
+	}
+	return gracePeriod
+}
+
+// remainingPod returns one of the pods that is still running on the node, or nil if all are gone.
+func (d *Drainer) remainingPod(node *apiv1.Node, pods []*apiv1.Pod) (*apiv1.Pod, error) {
+	scheduledPods, err := d.podLister.List()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*apiv1.Pod, len(scheduledPods))
+	for _, pod := range scheduledPods {
+		if pod.Spec.NodeName == node.Name {
+			current[pod.Namespace+"/"+pod.Name] = pod
+		}
+	}
+	for _, pod := range pods {
+		if running, found := current[pod.Namespace+"/"+pod.Name]; found && (pod.UID == "" || running.UID == pod.UID) {
+			return running, nil
+		}
+	}
+	return nil, nil
+}
+
+// pdbWait returns how long to wait until all the pdbs are out of backoff.
+func (d *Drainer) pdbWait(pdbs []*policyv1.PodDisruptionBudget) time.Duration {
+	d.backoffMutex.Lock()
+	defer d.backoffMutex.Unlock()
+	now := time.Now()
+	wait := time.Duration(0)
+	for _, pdb := range pdbs {
+		if backoff, found := d.pdbBackoff[pdbKey(pdb)]; found && backoff.until.Sub(now) > wait {
+			wait = backoff.until.Sub(now)
+		}
+	}
+	return wait
+}
+
+// backOffPdbs starts or extends the backoff of the pdbs. The delay is doubled only if a retry
+// after the previous backoff was rejected again, so concurrent rejections count once.
+func (d *Drainer) backOffPdbs(pdbs []*policyv1.PodDisruptionBudget) {
+	d.backoffMutex.Lock()
+	defer d.backoffMutex.Unlock()
+	now := time.Now()
+	for _, pdb := range pdbs {
+		key := pdbKey(pdb)
+		backoff, found := d.pdbBackoff[key]
+		if !found {
+			backoff = &evictionBackoff{delay: d.evictionRetryTime}
+			d.pdbBackoff[key] = backoff
+		} else if !now.Before(backoff.until) {
+			backoff.delay = nextBackoff(backoff.delay, d.maxEvictionBackoff)
+		} else {
+			continue
+		}
+		backoff.until = now.Add(backoff.delay)
+	}
+}
+
+func (d *Drainer) resetPdbBackoff(pdbs []*policyv1.PodDisruptionBudget) {
+	d.backoffMutex.Lock()
+	defer d.backoffMutex.Unlock()
+	for _, pdb := range pdbs {
+		delete(d.pdbBackoff, pdbKey(pdb))
+	}
+}
+
+// findCurrentUnsatisfiablePdb checks the latest version of the pdbs.
+func (d *Drainer) findCurrentUnsatisfiablePdb(pdbs []*policyv1.PodDisruptionBudget) *policyv1.PodDisruptionBudget {
+	current, err := d.pdbLister.List()
+	if err != nil {
+		glog.Warningf("Failed to list pod disruption budgets: %v", err)
+		return nil
+	}
+	keys := make(map[string]bool, len(pdbs))
+	for _, pdb := range pdbs {
+		keys[pdbKey(pdb)] = true
+	}
+	latest := make([]*policyv1.PodDisruptionBudget, 0, len(pdbs))
+	for _, pdb := range current {
+		if keys[pdbKey(pdb)] {
+			latest = append(latest, pdb)
+		}
+	}
+	return findUnsatisfiablePdb(latest)
+}
+
+func nextBackoff(delay, maxDelay time.Duration) time.Duration {
+	delay = 2 * delay
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+func pdbKey(pdb *policyv1.PodDisruptionBudget) string {
+	return pdb.Namespace + "/" + pdb.Name
+}
+
+func matchingPdbs(pod *apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget) []*policyv1.PodDisruptionBudget {
+	result := make([]*policyv1.PodDisruptionBudget, 0)
+	for _, pdb := range pdbs {
+		if pdb.Namespace != pod.Namespace {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			glog.Warningf("Invalid selector in pod disruption budget %s: %v", pdbKey(pdb), err)
+			continue
+		}
+		if !selector.Empty() && selector.Matches(labels.Set(pod.Labels)) {
+			result = append(result, pdb)
+		}
+	}
+	return result
+}
+
+// findUnsatisfiablePdb returns a pdb that can never allow an eviction: one with maxUnavailable of zero,
+// or with minAvailable equal to or above the number of pods it expects. A pdb whose status is not
+// populated yet is only reported if its spec alone rules out evictions, otherwise the usual eviction
+// backoff applies.
+func findUnsatisfiablePdb(pdbs []*policyv1.PodDisruptionBudget) *policyv1.PodDisruptionBudget {
+	for _, pdb := range pdbs {
+		expected := int(pdb.Status.ExpectedPods)
+		if pdb.Spec.MaxUnavailable != nil {
+			total := expected
+			if expected == 0 && pdb.Spec.MaxUnavailable.Type == intstr.String {
+				// Without the number of expected pods only a percentage of zero is known to block evictions.
+				total = 100
+			}
+			maxUnavailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MaxUnavailable, total, true)
+			if err == nil && maxUnavailable <= 0 {
+				return pdb
+			}
+			continue
+		}
+		if pdb.Spec.MinAvailable != nil && expected > 0 {
+			minAvailable, err := intstr.GetValueFromIntOrPercent(pdb.Spec.MinAvailable, expected, true)
+			if err == nil && minAvailable >= expected {
+				return pdb
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	ctx "context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDrainer(client *fake.Clientset, podLister kube_util.PodLister, pdbLister kube_util.PodDisruptionBudgetLister) *Drainer {
+	if pdbLister == nil {
+		pdbLister = kube_util.NewTestPodDisruptionBudgetLister(nil)
+	}
+	drainer := NewDrainer(client, kube_util.CreateEventRecorder(client), podLister, pdbLister, 20)
+	drainer.maxPodEvictionTime = 5 * time.Second
+	drainer.evictionRetryTime = 0
+	drainer.podCheckInterval = 10 * time.Millisecond
+	return drainer
+}
+
+func buildTestPdb(name string, minAvailable, maxUnavailable *intstr.IntOrString, expectedPods int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+			MinAvailable:   minAvailable,
+			MaxUnavailable: maxUnavailable,
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: expectedPods},
+	}
+}
+
+func intOrStr(value int) *intstr.IntOrString {
+	result := intstr.FromInt(value)
+	return &result
+}
+
+func TestDrainNode(t *testing.T) {
+	deletedPods := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p2 := BuildTestPod("p2", 300, 0)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		createAction := action.(core.CreateAction)
+		if createAction == nil {
+			return false, nil, nil
+		}
+		eviction := createAction.GetObject().(*policyv1.Eviction)
+		if eviction == nil {
+			return false, nil, nil
+		}
+		deletedPods <- eviction.Name
+		return true, nil, nil
+	})
+	err := newTestDrainer(fakeClient, kube_util.NewTestPodLister(nil), nil).DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1, p2})
+	assert.NoError(t, err)
+	deleted := make([]string, 0)
+	deleted = append(deleted, getStringFromChan(deletedPods))
+	deleted = append(deleted, getStringFromChan(deletedPods))
+	sort.Strings(deleted)
+	assert.Equal(t, p1.Name, deleted[0])
+	assert.Equal(t, p2.Name, deleted[1])
+}
+
+func TestDrainNodeWithRescheduled(t *testing.T) {
+	deletedPods := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p2 := BuildTestPod("p2", 300, 0)
+	p2Rescheduled := BuildTestPod("p2", 300, 0)
+	p2Rescheduled.Spec.NodeName = "n2"
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		createAction := action.(core.CreateAction)
+		if createAction == nil {
+			return false, nil, nil
+		}
+		eviction := createAction.GetObject().(*policyv1.Eviction)
+		if eviction == nil {
+			return false, nil, nil
+		}
+		deletedPods <- eviction.Name
+		return true, nil, nil
+	})
+	err := newTestDrainer(fakeClient, kube_util.NewTestPodLister([]*apiv1.Pod{p2Rescheduled}), nil).DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1, p2})
+	assert.NoError(t, err)
+	deleted := make([]string, 0)
+	deleted = append(deleted, getStringFromChan(deletedPods))
+	deleted = append(deleted, getStringFromChan(deletedPods))
+	sort.Strings(deleted)
+	assert.Equal(t, p1.Name, deleted[0])
+	assert.Equal(t, p2.Name, deleted[1])
+}
+
+func TestDrainNodeWithRetries(t *testing.T) {
+	deletedPods := make(chan string, 10)
+	// Simulate pdb of size 1 by making the 'eviction' goroutine:
+	// - read from (at first empty) channel
+	// - if it's empty, fail and write to it, then retry
+	// - succeed on successful read.
+	ticket := make(chan bool, 1)
+	fakeClient := &fake.Clientset{}
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p2 := BuildTestPod("p2", 300, 0)
+	p3 := BuildTestPod("p3", 300, 0)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		createAction := action.(core.CreateAction)
+		if createAction == nil {
+			return false, nil, nil
+		}
+		eviction := createAction.GetObject().(*policyv1.Eviction)
+		if eviction == nil {
+			return false, nil, nil
+		}
+		select {
+		case <-ticket:
+			deletedPods <- eviction.Name
+			return true, nil, nil
+		default:
+			select {
+			case ticket <- true:
+			default:
+			}
+			return true, nil, fmt.Errorf("Too many concurrent evictions")
+		}
+	})
+	err := newTestDrainer(fakeClient, kube_util.NewTestPodLister(nil), nil).DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1, p2, p3})
+	assert.NoError(t, err)
+	deleted := make([]string, 0)
+	deleted = append(deleted, getStringFromChan(deletedPods))
+	deleted = append(deleted, getStringFromChan(deletedPods))
+	deleted = append(deleted, getStringFromChan(deletedPods))
+	sort.Strings(deleted)
+	assert.Equal(t, p1.Name, deleted[0])
+	assert.Equal(t, p2.Name, deleted[1])
+	assert.Equal(t, p3.Name, deleted[2])
+}
+
+func TestDrainNodeWaitsForPods(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Spec.NodeName = "n1"
+	podLister := kube_util.NewTestPodLister([]*apiv1.Pod{p1})
+
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			podLister.SetPods(nil)
+		}()
+		return true, nil, nil
+	})
+	start := time.Now()
+	err := newTestDrainer(fakeClient, podLister, nil).DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1})
+	assert.NoError(t, err)
+	assert.True(t, time.Now().Sub(start) >= 100*time.Millisecond)
+}
+
+func TestDrainNodeBacksOffPerPdb(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Labels = map[string]string{"app": "a"}
+	pdb := buildTestPdb("pdb", intOrStr(1), nil, 3)
+
+	var mutex sync.Mutex
+	evictions := 0
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		evictions++
+		if evictions <= 3 {
+			return true, nil, kube_errors.NewTooManyRequests("disruption budget exceeded", 0)
+		}
+		return true, nil, nil
+	})
+	drainer := newTestDrainer(fakeClient, kube_util.NewTestPodLister(nil), kube_util.NewTestPodDisruptionBudgetLister([]*policyv1.PodDisruptionBudget{pdb}))
+	drainer.evictionRetryTime = 20 * time.Millisecond
+	start := time.Now()
+	err := drainer.DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, evictions)
+	// The backoff doubles with consecutive rejections: 20ms, 40ms, 80ms.
+	assert.True(t, time.Now().Sub(start) >= 140*time.Millisecond)
+	// A successful eviction resets the backoff.
+	assert.Empty(t, drainer.pdbBackoff)
+}
+
+func TestPdbBackoff(t *testing.T) {
+	drainer := newTestDrainer(&fake.Clientset{}, kube_util.NewTestPodLister(nil), nil)
+	drainer.evictionRetryTime = time.Second
+	drainer.maxEvictionBackoff = 3 * time.Second
+	pdb := buildTestPdb("pdb", intOrStr(1), nil, 3)
+	pdbs := []*policyv1.PodDisruptionBudget{pdb}
+
+	drainer.backOffPdbs(pdbs)
+	assert.Equal(t, time.Second, drainer.pdbBackoff["default/pdb"].delay)
+	// Rejections during the backoff don't extend it.
+	drainer.backOffPdbs(pdbs)
+	assert.Equal(t, time.Second, drainer.pdbBackoff["default/pdb"].delay)
+	wait := drainer.pdbWait(pdbs)
+	assert.True(t, wait > 0 && wait <= time.Second)
+
+	drainer.pdbBackoff["default/pdb"].until = time.Now().Add(-time.Millisecond)
+	drainer.backOffPdbs(pdbs)
+	assert.Equal(t, 2*time.Second, drainer.pdbBackoff["default/pdb"].delay)
+	drainer.pdbBackoff["default/pdb"].until = time.Now().Add(-time.Millisecond)
+	drainer.backOffPdbs(pdbs)
+	assert.Equal(t, 3*time.Second, drainer.pdbBackoff["default/pdb"].delay)
+
+	drainer.resetPdbBackoff(pdbs)
+	assert.Equal(t, time.Duration(0), drainer.pdbWait(pdbs))
+}
+
+func TestDrainNodeUnsatisfiablePdb(t *testing.T) {
+	testCases := []struct {
+		name string
+		pdb  *policyv1.PodDisruptionBudget
+	}{
+		{"min available equal to expected pods", buildTestPdb("pdb", intOrStr(2), nil, 2)},
+		{"zero max unavailable", buildTestPdb("pdb", nil, intOrStr(0), 2)},
+		{"zero percent max unavailable", buildTestPdb("pdb", nil, &intstr.IntOrString{Type: intstr.String, StrVal: "0%"}, 2)},
+		{"hundred percent min available", buildTestPdb("pdb", &intstr.IntOrString{Type: intstr.String, StrVal: "100%"}, nil, 2)},
+	}
+	for _, tc := range testCases {
+		fakeClient := &fake.Clientset{}
+		n1 := BuildTestNode("n1", 1000, 1000)
+		p1 := BuildTestPod("p1", 100, 0)
+		p1.Labels = map[string]string{"app": "a"}
+		p2 := BuildTestPod("p2", 100, 0)
+		fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+			t.Errorf("%s: unexpected eviction", tc.name)
+			return true, nil, nil
+		})
+		drainer := newTestDrainer(fakeClient, kube_util.NewTestPodLister(nil), kube_util.NewTestPodDisruptionBudgetLister([]*policyv1.PodDisruptionBudget{tc.pdb}))
+		err := drainer.DrainNode(ctx.Background(), n1, []*apiv1.Pod{p2, p1})
+		assert.Error(t, err, tc.name)
+	}
+}
+
+func TestFindUnsatisfiablePdbWithoutStatus(t *testing.T) {
+	percent := func(value string) *intstr.IntOrString {
+		return &intstr.IntOrString{Type: intstr.String, StrVal: value}
+	}
+	assert.Nil(t, findUnsatisfiablePdb([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", nil, percent("50%"), 0)}))
+	assert.Nil(t, findUnsatisfiablePdb([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", nil, percent("1%"), 0)}))
+	assert.Nil(t, findUnsatisfiablePdb([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", nil, intOrStr(1), 0)}))
+	assert.Nil(t, findUnsatisfiablePdb([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", percent("100%"), nil, 0)}))
+	assert.NotNil(t, findUnsatisfiablePdb([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", nil, percent("0%"), 0)}))
+	assert.NotNil(t, findUnsatisfiablePdb([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", nil, intOrStr(0), 0)}))
+}
+
+func TestDrainNodeAbortsWhenPdbBecomesUnsatisfiable(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Labels = map[string]string{"app": "a"}
+	pdbLister := kube_util.NewTestPodDisruptionBudgetLister([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", intOrStr(1), nil, 2)})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		// The other pod covered by the budget went away in the meantime.
+		pdbLister.SetPdbs([]*policyv1.PodDisruptionBudget{buildTestPdb("pdb", intOrStr(1), nil, 1)})
+		return true, nil, kube_errors.NewTooManyRequests("disruption budget exceeded", 0)
+	})
+	drainer := newTestDrainer(fakeClient, kube_util.NewTestPodLister(nil), pdbLister)
+	drainer.evictionRetryTime = time.Second
+
+	start := time.Now()
+	err := drainer.DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1})
+	assert.Error(t, err)
+	assert.True(t, time.Now().Sub(start) < time.Second)
+}
+
+func TestEvictionGracePeriod(t *testing.T) {
+	drainer := newTestDrainer(&fake.Clientset{}, kube_util.NewTestPodLister(nil), nil)
+	drainer.maxGracefulTerminationSec = 60
+
+	p1 := BuildTestPod("p1", 100, 0)
+	assert.Equal(t, int64(apiv1.DefaultTerminationGracePeriodSeconds), drainer.gracePeriod(p1))
+
+	long := int64(600)
+	p1.Spec.TerminationGracePeriodSeconds = &long
+	assert.Equal(t, int64(60), drainer.gracePeriod(p1))
+
+	p1.Annotations = map[string]string{drain.PodEvictionGracePeriodKey: "300"}
+	assert.Equal(t, int64(300), drainer.gracePeriod(p1))
+
+	p1.Annotations = map[string]string{drain.PodEvictionGracePeriodKey: "soon"}
+	assert.Equal(t, int64(60), drainer.gracePeriod(p1))
+}
+
+func TestDrainNodeUsesGracePeriodAnnotation(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Annotations = map[string]string{drain.PodEvictionGracePeriodKey: "5"}
+	gracePeriods := make(chan int64, 1)
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1.Eviction)
+		gracePeriods <- *eviction.DeleteOptions.GracePeriodSeconds
+		return true, nil, nil
+	})
+	err := newTestDrainer(fakeClient, kube_util.NewTestPodLister(nil), nil).DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), <-gracePeriods)
+}
+
+func TestDrainNodeAborted(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	n1 := BuildTestNode("n1", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Spec.NodeName = "n1"
+	podLister := kube_util.NewTestPodLister([]*apiv1.Pod{p1})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	drainer := newTestDrainer(fakeClient, podLister, nil)
+
+	// The pod never goes away, so only the abort ends the drain.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		drainer.Abort()
+	}()
+	start := time.Now()
+	err := drainer.DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stopping")
+	assert.True(t, time.Now().Sub(start) < 5*time.Second)
+
+	// Later drains fail right away.
+	err = drainer.DrainNode(ctx.Background(), n1, []*apiv1.Pod{p1})
+	assert.Error(t, err)
+	drainer.Abort()
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+	"github.com/gardener/autoscaler/cluster-autoscaler/processors/status"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// pendingPodsTracker follows unschedulable pods from the loop in which CA first sees them until
+// they are scheduled or disappear, and records how long they were pending.
+type pendingPodsTracker struct {
+	pods map[string]*pendingPod
+}
+
+type pendingPod struct {
+	uid         types.UID
+	firstSeen   time.Time
+	scaleUpTime time.Time
+}
+
+func newPendingPodsTracker() *pendingPodsTracker {
+	return &pendingPodsTracker{pods: make(map[string]*pendingPod)}
+}
+
+// Update starts tracking new unschedulable pods and finishes tracking of pods that were scheduled
+// or are no longer pending, e.g. because they were deleted or gave up. Does nothing on a nil tracker.
+func (t *pendingPodsTracker) Update(unschedulable, scheduled []*apiv1.Pod, now time.Time) {
+	if t == nil {
+		return
+	}
+	for _, pod := range scheduled {
+		key := podKey(pod)
+		if tracked, found := t.pods[key]; found && tracked.uid == pod.UID {
+			t.finish(key, metrics.PodScheduled, now)
+		}
+	}
+	pending := make(map[string]bool, len(unschedulable))
+	for _, pod := range unschedulable {
+		key := podKey(pod)
+		pending[key] = true
+		if tracked, found := t.pods[key]; found && tracked.uid != pod.UID {
+			// The pod was recreated under the same name.
+			t.finish(key, metrics.PodAbandoned, now)
+		}
+		if _, found := t.pods[key]; !found {
+			t.pods[key] = &pendingPod{uid: pod.UID, firstSeen: now}
+		}
+	}
+	for key := range t.pods {
+		if !pending[key] {
+			t.finish(key, metrics.PodAbandoned, now)
+		}
+	}
+}
+
+// RegisterScaleUp records the time from first seeing a pod to triggering a scale-up for it, for
+// all pods that triggered their first scale-up. Does nothing on a nil tracker.
+func (t *pendingPodsTracker) RegisterScaleUp(scaleUpStatus *status.ScaleUpStatus, now time.Time) {
+	if t == nil || scaleUpStatus == nil || !scaleUpStatus.ScaledUp {
+		return
+	}
+	for _, pod := range scaleUpStatus.PodsTriggeredScaleUp {
+		tracked, found := t.pods[podKey(pod)]
+		if !found || !tracked.scaleUpTime.IsZero() {
+			continue
+		}
+		tracked.scaleUpTime = now
+		metrics.RegisterPodScaleUpDecision(now.Sub(tracked.firstSeen))
+	}
+}
+
+func (t *pendingPodsTracker) finish(key string, outcome metrics.PendingPodOutcome, now time.Time) {
+	tracked := t.pods[key]
+	delete(t.pods, key)
+	metrics.RegisterPendingPod(outcome, !tracked.scaleUpTime.IsZero(), now.Sub(tracked.firstSeen))
+}
+
+func podKey(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
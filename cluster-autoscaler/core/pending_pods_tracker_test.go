@@ -0,0 +1,79 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/processors/status"
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTrackedPod(name string, uid types.UID) *apiv1.Pod {
+	pod := BuildTestPod(name, 100, 0)
+	pod.UID = uid
+	return pod
+}
+
+func TestPendingPodsTracker(t *testing.T) {
+	now := time.Now()
+	p1 := buildTrackedPod("p1", "uid1")
+	p2 := buildTrackedPod("p2", "uid2")
+	p3 := buildTrackedPod("p3", "uid3")
+
+	tracker := newPendingPodsTracker()
+	tracker.Update([]*apiv1.Pod{p1, p2, p3}, []*apiv1.Pod{}, now)
+	assert.Len(t, tracker.pods, 3)
+
+	tracker.RegisterScaleUp(&status.ScaleUpStatus{ScaledUp: true, PodsTriggeredScaleUp: []*apiv1.Pod{p1, p2}}, now.Add(time.Minute))
+	assert.Equal(t, now.Add(time.Minute), tracker.pods[podKey(p1)].scaleUpTime)
+	assert.Equal(t, now.Add(time.Minute), tracker.pods[podKey(p2)].scaleUpTime)
+	assert.True(t, tracker.pods[podKey(p3)].scaleUpTime.IsZero())
+
+	// The second scale-up for the same pod doesn't change the time of the first one.
+	tracker.RegisterScaleUp(&status.ScaleUpStatus{ScaledUp: true, PodsTriggeredScaleUp: []*apiv1.Pod{p1}}, now.Add(2*time.Minute))
+	assert.Equal(t, now.Add(time.Minute), tracker.pods[podKey(p1)].scaleUpTime)
+
+	// p1 was scheduled, p2 is still pending, p3 was deleted.
+	tracker.Update([]*apiv1.Pod{p2}, []*apiv1.Pod{p1}, now.Add(5*time.Minute))
+	assert.Len(t, tracker.pods, 1)
+	assert.Equal(t, now, tracker.pods[podKey(p2)].firstSeen)
+
+	// p2 was recreated under the same name.
+	p2Recreated := buildTrackedPod("p2", "uid4")
+	tracker.Update([]*apiv1.Pod{p2Recreated}, []*apiv1.Pod{}, now.Add(6*time.Minute))
+	assert.Len(t, tracker.pods, 1)
+	assert.Equal(t, types.UID("uid4"), tracker.pods[podKey(p2)].uid)
+	assert.Equal(t, now.Add(6*time.Minute), tracker.pods[podKey(p2)].firstSeen)
+	assert.True(t, tracker.pods[podKey(p2)].scaleUpTime.IsZero())
+
+	// The scheduled pod with an old uid doesn't finish tracking of the recreated one.
+	tracker.Update([]*apiv1.Pod{p2Recreated}, []*apiv1.Pod{p2}, now.Add(7*time.Minute))
+	assert.Len(t, tracker.pods, 1)
+}
+
+func TestPendingPodsTrackerNil(t *testing.T) {
+	var tracker *pendingPodsTracker
+	tracker.Update([]*apiv1.Pod{buildTrackedPod("p1", "uid1")}, []*apiv1.Pod{}, time.Now())
+	tracker.RegisterScaleUp(&status.ScaleUpStatus{ScaledUp: true}, time.Now())
+}
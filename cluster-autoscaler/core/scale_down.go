@@ -17,18 +17,27 @@ limitations under the License.
 package core
 
 import (
+	ctx "context"
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/apis/clusterautoscaler/v1alpha1"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
+	"github.com/gardener/autoscaler/cluster-autoscaler/checkpoint"
 	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
 	"github.com/gardener/autoscaler/cluster-autoscaler/context"
+	"github.com/gardener/autoscaler/cluster-autoscaler/debugging"
+	"github.com/gardener/autoscaler/cluster-autoscaler/deletionhooks"
 	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
 	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/tracing"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/deletetaint"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
 	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
@@ -36,12 +45,11 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
-	kube_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	kube_client "k8s.io/client-go/kubernetes"
 	kube_record "k8s.io/client-go/tools/record"
 
 	"github.com/golang/glog"
+	"go.opencensus.io/trace"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/gpu"
 )
@@ -76,6 +84,10 @@ const (
 	MaxPodEvictionTime = 2 * time.Minute
 	// EvictionRetryTime is the time after CA retries failed pod eviction.
 	EvictionRetryTime = 10 * time.Second
+	// MaxEvictionBackoff is the maximum time CA waits before retrying evictions rejected by a pod disruption budget.
+	MaxEvictionBackoff = time.Minute
+	// PodCheckInterval is how often CA checks whether evicted pods are gone.
+	PodCheckInterval = time.Second
 	// PodEvictionHeadroom is the extra time we wait to catch situations when the pod is ignoring SIGTERM and
 	// is killed with SIGKILL after MaxGracefulTerminationTime
 	PodEvictionHeadroom = 30 * time.Second
@@ -112,9 +124,10 @@ func computeScaleDownResourcesLeftLimits(nodes []*apiv1.Node, resourceLimiter *c
 
 	var totalGpus map[string]int64
 	var totalGpusErr error
-	if cloudprovider.ContainsGpuResources(resourceLimiter.GetResources()) {
+	if len(resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeGpu)) > 0 {
 		totalGpus, totalGpusErr = calculateScaleDownGpusTotal(nodes, cp, timestamp)
 	}
+	totalNodeResources := calculateScaleDownNodeResourcesTotal(nodes, resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeNode), timestamp)
 
 	resultScaleDownLimits := make(scaleDownResourcesLimits)
 	for _, resource := range resourceLimiter.GetResources() {
@@ -122,17 +135,19 @@ func computeScaleDownResourcesLeftLimits(nodes []*apiv1.Node, resourceLimiter *c
 
 		// we put only actual limits into final map. No entry means no limit.
 		if min > 0 {
-			switch {
-			case resource == cloudprovider.ResourceNameCores:
+			switch resourceLimiter.GetResourceType(resource) {
+			case cloudprovider.ResourceTypeCores:
 				resultScaleDownLimits[resource] = computeAboveMin(totalCores, min)
-			case resource == cloudprovider.ResourceNameMemory:
+			case cloudprovider.ResourceTypeMemory:
 				resultScaleDownLimits[resource] = computeAboveMin(totalMem, min)
-			case cloudprovider.IsGpuResource(resource):
+			case cloudprovider.ResourceTypeGpu:
 				if totalGpusErr != nil {
 					resultScaleDownLimits[resource] = scaleDownLimitUnknown
 				} else {
 					resultScaleDownLimits[resource] = computeAboveMin(totalGpus[resource], min)
 				}
+			case cloudprovider.ResourceTypeNode:
+				resultScaleDownLimits[resource] = computeAboveMin(totalNodeResources[resource], min)
 			default:
 				glog.Errorf("Scale down limits defined for unsupported resource '%s'", resource)
 			}
@@ -165,6 +180,23 @@ func calculateScaleDownCoresMemoryTotal(nodes []*apiv1.Node, timestamp time.Time
 	return coresTotal, memoryTotal
 }
 
+func calculateScaleDownNodeResourcesTotal(nodes []*apiv1.Node, resources []string, timestamp time.Time) map[string]int64 {
+	result := make(map[string]int64)
+	if len(resources) == 0 {
+		return result
+	}
+	for _, node := range nodes {
+		if isNodeBeingDeleted(node, timestamp) {
+			// Nodes being deleted do not count towards total cluster resources
+			continue
+		}
+		for _, resource := range resources {
+			result[resource] += getNodeResource(node, apiv1.ResourceName(resource))
+		}
+	}
+	return result
+}
+
 func calculateScaleDownGpusTotal(nodes []*apiv1.Node, cp cloudprovider.CloudProvider, timestamp time.Time) (map[string]int64, error) {
 	type gpuInfo struct {
 		name  string
@@ -236,20 +268,24 @@ func copyScaleDownResourcesLimits(source scaleDownResourcesLimits) scaleDownReso
 	return copy
 }
 
-func computeScaleDownResourcesDelta(node *apiv1.Node, nodeGroup cloudprovider.NodeGroup, resourcesWithLimits []string) (scaleDownResourcesDelta, errors.AutoscalerError) {
+func computeScaleDownResourcesDelta(node *apiv1.Node, nodeGroup cloudprovider.NodeGroup, resourceLimiter *cloudprovider.ResourceLimiter) (scaleDownResourcesDelta, errors.AutoscalerError) {
 	resultScaleDownDelta := make(scaleDownResourcesDelta)
 
 	nodeCPU, nodeMemory := getNodeCoresAndMemory(node)
 	resultScaleDownDelta[cloudprovider.ResourceNameCores] = nodeCPU
 	resultScaleDownDelta[cloudprovider.ResourceNameMemory] = nodeMemory
 
-	if cloudprovider.ContainsGpuResources(resourcesWithLimits) {
+	if len(resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeGpu)) > 0 {
 		gpuType, gpuCount, err := gpu.GetNodeTargetGpus(node, nodeGroup)
 		if err != nil {
 			return scaleDownResourcesDelta{}, errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("Failed to get node %v gpu: %v", node.Name)
 		}
 		resultScaleDownDelta[gpuType] = gpuCount
 	}
+
+	for _, resource := range resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeNode) {
+		resultScaleDownDelta[resource] = getNodeResource(node, apiv1.ResourceName(resource))
+	}
 	return resultScaleDownDelta, nil
 }
 
@@ -300,10 +336,13 @@ type ScaleDown struct {
 	unneededNodes        map[string]time.Time
 	unneededNodesList    []*apiv1.Node
 	unremovableNodes     map[string]time.Time
+	unremovableReasons   map[string]*simulator.UnremovableNode
+	unremovableEvents    map[string]time.Time
 	podLocationHints     map[string]string
 	nodeUtilizationMap   map[string]float64
 	usageTracker         *simulator.UsageTracker
 	nodeDeleteStatus     *NodeDeleteStatus
+	drainPolicyBuilder   *drainPolicyBuilder
 }
 
 // NewScaleDown builds new ScaleDown object.
@@ -313,11 +352,14 @@ func NewScaleDown(context *context.AutoscalingContext, clusterStateRegistry *clu
 		clusterStateRegistry: clusterStateRegistry,
 		unneededNodes:        make(map[string]time.Time),
 		unremovableNodes:     make(map[string]time.Time),
+		unremovableReasons:   make(map[string]*simulator.UnremovableNode),
+		unremovableEvents:    make(map[string]time.Time),
 		podLocationHints:     make(map[string]string),
 		nodeUtilizationMap:   make(map[string]float64),
 		usageTracker:         simulator.NewUsageTracker(),
 		unneededNodesList:    make([]*apiv1.Node, 0),
 		nodeDeleteStatus:     &NodeDeleteStatus{},
+		drainPolicyBuilder:   newDrainPolicyBuilder(context),
 	}
 }
 
@@ -331,6 +373,101 @@ func (sd *ScaleDown) GetCandidatesForScaleDown() []*apiv1.Node {
 	return sd.unneededNodesList
 }
 
+// GetDebugState returns a copy of the internal state of scale-down.
+func (sd *ScaleDown) GetDebugState() *debugging.ScaleDownState {
+	result := &debugging.ScaleDownState{
+		UnneededNodes:      make(map[string]time.Time, len(sd.unneededNodes)),
+		UnremovableNodes:   make(map[string]debugging.UnremovableNode, len(sd.unremovableReasons)),
+		Utilization:        make(map[string]float64, len(sd.nodeUtilizationMap)),
+		DeletionInProgress: sd.nodeDeleteStatus.IsDeleteInProgress(),
+	}
+	for name, since := range sd.unneededNodes {
+		result.UnneededNodes[name] = since
+	}
+	for name, unremovable := range sd.unremovableReasons {
+		node := debugging.UnremovableNode{RecheckTime: sd.unremovableNodes[name], Reason: string(unremovable.Reason)}
+		if unremovable.BlockingPod != nil {
+			node.BlockingPod = unremovable.BlockingPod.Pod.Namespace + "/" + unremovable.BlockingPod.Pod.Name
+			node.BlockingPodReason = string(unremovable.BlockingPod.Reason)
+		}
+		result.UnremovableNodes[name] = node
+	}
+	for name, utilization := range sd.nodeUtilizationMap {
+		result.Utilization[name] = utilization
+	}
+	return result
+}
+
+// GetUnremovableNodes returns nodes that were recently found unremovable together with the reason, sorted by name.
+func (sd *ScaleDown) GetUnremovableNodes() []v1alpha1.UnremovableNode {
+	result := make([]v1alpha1.UnremovableNode, 0, len(sd.unremovableReasons))
+	for name, unremovable := range sd.unremovableReasons {
+		node := v1alpha1.UnremovableNode{NodeName: name, Reason: string(unremovable.Reason)}
+		if unremovable.BlockingPod != nil {
+			node.BlockingPod = unremovable.BlockingPod.Pod.Namespace + "/" + unremovable.BlockingPod.Pod.Name
+			node.BlockingPodReason = string(unremovable.BlockingPod.Reason)
+		}
+		result = append(result, node)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NodeName < result[j].NodeName })
+	return result
+}
+
+// GetUnremovableNodesCount returns the number of unremovable nodes by reason.
+func (sd *ScaleDown) GetUnremovableNodesCount() map[string]int {
+	result := make(map[string]int)
+	for _, unremovable := range sd.unremovableReasons {
+		result[string(unremovable.Reason)]++
+	}
+	return result
+}
+
+// GetCheckpointState fills the given state with a copy of the scale-down bookkeeping that has to
+// survive autoscaler restarts.
+func (sd *ScaleDown) GetCheckpointState(state *checkpoint.State) {
+	state.UnneededNodes = make(map[string]time.Time, len(sd.unneededNodes))
+	for name, since := range sd.unneededNodes {
+		state.UnneededNodes[name] = since
+	}
+	state.UnremovableNodes = make(map[string]time.Time, len(sd.unremovableNodes))
+	state.UnremovableReasons = make(map[string]simulator.UnremovableReason, len(sd.unremovableNodes))
+	for name, recheckTime := range sd.unremovableNodes {
+		state.UnremovableNodes[name] = recheckTime
+		if unremovable, found := sd.unremovableReasons[name]; found {
+			state.UnremovableReasons[name] = unremovable.Reason
+		}
+	}
+	state.PodLocationHints = make(map[string]string, len(sd.podLocationHints))
+	for pod, node := range sd.podLocationHints {
+		state.PodLocationHints[pod] = node
+	}
+	state.UsageTracker = sd.usageTracker.GetUsageInfos()
+}
+
+// RestoreCheckpointState restores the state saved with GetCheckpointState. The state should
+// be pruned against the current nodes first. unneededNodesList is rebuilt by the next
+// UpdateUnneededNodes call, which also drops nodes that are no longer unneeded.
+func (sd *ScaleDown) RestoreCheckpointState(state *checkpoint.State) {
+	sd.unneededNodes = make(map[string]time.Time, len(state.UnneededNodes))
+	for name, since := range state.UnneededNodes {
+		sd.unneededNodes[name] = since
+	}
+	sd.unremovableNodes = make(map[string]time.Time, len(state.UnremovableNodes))
+	sd.unremovableReasons = make(map[string]*simulator.UnremovableNode, len(state.UnremovableReasons))
+	for name, recheckTime := range state.UnremovableNodes {
+		sd.unremovableNodes[name] = recheckTime
+		if reason, found := state.UnremovableReasons[name]; found {
+			// The node object and the blocking pod are filled in again once the node is re-checked.
+			sd.unremovableReasons[name] = &simulator.UnremovableNode{Reason: reason}
+		}
+	}
+	sd.podLocationHints = make(map[string]string, len(state.PodLocationHints))
+	for pod, node := range state.PodLocationHints {
+		sd.podLocationHints[pod] = node
+	}
+	sd.usageTracker.RestoreUsageInfos(state.UsageTracker)
+}
+
 // CleanUpUnneededNodes clears the list of unneeded nodes.
 func (sd *ScaleDown) CleanUpUnneededNodes() {
 	sd.unneededNodesList = make([]*apiv1.Node, 0)
@@ -355,14 +492,20 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	utilizationMap := make(map[string]float64)
 
 	sd.updateUnremovableNodes(nodes)
+	// Reasons are recomputed for all checked nodes, only the ones that are not re-checked yet are kept.
+	unremovableReasons := make(map[string]*simulator.UnremovableNode)
 	// Filter out nodes that were recently checked
 	filteredNodesToCheck := make([]*apiv1.Node, 0)
 	for _, node := range nodesToCheck {
 		if unremovableTimestamp, found := sd.unremovableNodes[node.Name]; found {
 			if unremovableTimestamp.After(timestamp) {
+				if unremovable, found := sd.unremovableReasons[node.Name]; found {
+					unremovableReasons[node.Name] = unremovable
+				}
 				continue
 			}
 			delete(sd.unremovableNodes, node.Name)
+			delete(sd.unremovableReasons, node.Name)
 		}
 		filteredNodesToCheck = append(filteredNodesToCheck, node)
 	}
@@ -380,21 +523,24 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		// and they have not been deleted.
 		if isNodeBeingDeleted(node, timestamp) {
 			glog.V(1).Infof("Skipping %s from delete considerations - the node is currently being deleted", node.Name)
+			unremovableReasons[node.Name] = &simulator.UnremovableNode{Node: node, Reason: simulator.CurrentlyBeingDeleted}
 			continue
 		}
 
 		// Skip nodes marked with no scale down annotation
 		if hasNoScaleDownAnnotation(node) {
 			glog.V(1).Infof("Skipping %s from delete consideration - the node is marked as no scale down", node.Name)
+			unremovableReasons[node.Name] = &simulator.UnremovableNode{Node: node, Reason: simulator.ScaleDownDisabledAnnotation}
 			continue
 		}
 
 		nodeInfo, found := nodeNameToNodeInfo[node.Name]
 		if !found {
 			glog.Errorf("Node info for %s not found", node.Name)
+			unremovableReasons[node.Name] = &simulator.UnremovableNode{Node: node, Reason: simulator.UnexpectedError}
 			continue
 		}
-		utilization, err := simulator.CalculateUtilization(node, nodeInfo)
+		utilization, err := simulator.CalculateUtilization(node, nodeInfo, sd.context.UtilizationResourceWeights)
 
 		if err != nil {
 			glog.Warningf("Failed to calculate utilization for %s: %v", node.Name, err)
@@ -404,10 +550,12 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 
 		if utilization >= sd.context.ScaleDownUtilizationThreshold {
 			glog.V(4).Infof("Node %s is not suitable for removal - utilization too big (%f)", node.Name, utilization)
+			unremovableReasons[node.Name] = &simulator.UnremovableNode{Node: node, Reason: simulator.NotUnderutilized}
 			continue
 		}
 		currentlyUnneededNodes = append(currentlyUnneededNodes, node)
 	}
+	currentlyUnneededNodes = sd.orderForScaleDown(currentlyUnneededNodes, nodes, utilizationMap)
 
 	emptyNodes := make(map[string]bool)
 
@@ -426,10 +574,13 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	// Phase2 - check which nodes can be probably removed using fast drain.
 	currentCandidates, currentNonCandidates := sd.chooseCandidates(currentlyUnneededNonEmptyNodes)
 
+	drainPolicy := sd.drainPolicyBuilder.build()
+
 	// Look for nodes to remove in the current candidates
 	nodesToRemove, unremovable, newHints, simulatorErr := simulator.FindNodesToRemove(
 		currentCandidates, nodes, nonExpendablePods, nil, sd.context.PredicateChecker,
-		len(currentCandidates), true, sd.podLocationHints, sd.usageTracker, timestamp, pdbs)
+		len(currentCandidates), true, sd.podLocationHints, sd.usageTracker, timestamp, pdbs, drainPolicy,
+		sd.context.SimulationParallelism, sd.context.SimulationSeed)
 	if simulatorErr != nil {
 		return sd.markSimulationError(simulatorErr, timestamp)
 	}
@@ -452,7 +603,8 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		additionalNodesToRemove, additionalUnremovable, additionalNewHints, simulatorErr :=
 			simulator.FindNodesToRemove(currentNonCandidates[:additionalCandidatesPoolSize], nodes, nonExpendablePods, nil,
 				sd.context.PredicateChecker, additionalCandidatesCount, true,
-				sd.podLocationHints, sd.usageTracker, timestamp, pdbs)
+				sd.podLocationHints, sd.usageTracker, timestamp, pdbs, drainPolicy,
+				sd.context.SimulationParallelism, sd.context.SimulationSeed)
 		if simulatorErr != nil {
 			return sd.markSimulationError(simulatorErr, timestamp)
 		}
@@ -477,13 +629,20 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 		} else {
 			result[name] = val
 		}
+		// Nodes that stay unneeded keep the reasons found by TryToScaleDown.
+		if _, wasUnneeded := sd.unneededNodes[name]; wasUnneeded {
+			if unremovable, found := sd.unremovableReasons[name]; found {
+				unremovableReasons[name] = unremovable
+			}
+		}
 	}
 
 	// Add nodes to unremovable map
 	if len(unremovable) > 0 {
 		unremovableTimeout := timestamp.Add(sd.context.AutoscalingOptions.UnremovableNodeRecheckTimeout)
 		for _, node := range unremovable {
-			sd.unremovableNodes[node.Name] = unremovableTimeout
+			sd.unremovableNodes[node.Node.Name] = unremovableTimeout
+			unremovableReasons[node.Node.Name] = node
 		}
 		glog.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v", len(unremovable), unremovableTimeout)
 	}
@@ -493,11 +652,88 @@ func (sd *ScaleDown) UpdateUnneededNodes(
 	sd.unneededNodes = result
 	sd.podLocationHints = newHints
 	sd.nodeUtilizationMap = utilizationMap
+	sd.unremovableReasons = unremovableReasons
 	sd.clusterStateRegistry.UpdateScaleDownCandidates(sd.unneededNodesList, timestamp)
 	metrics.UpdateUnneededNodesCount(len(sd.unneededNodesList))
+	sd.reportUnremovableNodes(timestamp)
+	sd.softTaintUnneededNodes(nodes)
 	return nil
 }
 
+// softTaintUnneededNodes adds the DeletionCandidate PreferNoSchedule taint to unneeded nodes and
+// removes it from nodes that are needed again, so that the scheduler avoids placing new pods on
+// nodes that are about to be removed. At most MaxBulkSoftTaintCount nodes are updated within
+// MaxBulkSoftTaintTime, the rest is handled in the following loops.
+func (sd *ScaleDown) softTaintUnneededNodes(allNodes []*apiv1.Node) {
+	apiCallBudget := sd.context.MaxBulkSoftTaintCount
+	if apiCallBudget <= 0 {
+		return
+	}
+	deadline := time.Now().Add(sd.context.MaxBulkSoftTaintTime)
+	skipped := 0
+	for _, node := range allNodes {
+		if deletetaint.HasToBeDeletedTaint(node) {
+			// The node is being deleted already.
+			continue
+		}
+		_, unneeded := sd.unneededNodes[node.Name]
+		tainted := deletetaint.HasDeletionCandidateTaint(node)
+		if unneeded == tainted {
+			continue
+		}
+		if apiCallBudget <= 0 || time.Now().After(deadline) {
+			skipped++
+			continue
+		}
+		apiCallBudget--
+		if unneeded {
+			if err := sd.context.Actuator.MarkDeletionCandidate(node); err != nil {
+				glog.Warningf("Failed to soft taint unneeded node %s: %v", node.Name, err)
+			}
+		} else {
+			if _, err := sd.context.Actuator.CleanDeletionCandidate(node); err != nil {
+				glog.Warningf("Failed to remove soft taint from node %s: %v", node.Name, err)
+			}
+		}
+	}
+	if skipped > 0 {
+		glog.V(4).Infof("Skipped soft (un)tainting of %d nodes, the budget for this loop was exceeded", skipped)
+	}
+}
+
+// reportUnremovableNodes updates the unremovable nodes metrics and records an event on each
+// node that is blocked from scale down. Events for a node are emitted at most once per
+// UnremovableNodeEventInterval. Nodes that are simply utilized too much get no events.
+func (sd *ScaleDown) reportUnremovableNodes(timestamp time.Time) {
+	metrics.UpdateUnremovableNodesCount(sd.GetUnremovableNodesCount())
+
+	for name := range sd.unremovableEvents {
+		if _, found := sd.unremovableReasons[name]; !found {
+			delete(sd.unremovableEvents, name)
+		}
+	}
+	interval := sd.context.UnremovableNodeEventInterval
+	if interval <= 0 {
+		return
+	}
+	for name, unremovable := range sd.unremovableReasons {
+		if unremovable.Node == nil || unremovable.Reason == simulator.NotUnderutilized {
+			continue
+		}
+		if lastEvent, found := sd.unremovableEvents[name]; found && lastEvent.Add(interval).After(timestamp) {
+			continue
+		}
+		sd.context.Recorder.Eventf(unremovable.Node, apiv1.EventTypeNormal, "ScaleDownBlocked",
+			"node can't be removed by cluster autoscaler: %s", unremovable.Message())
+		sd.unremovableEvents[name] = timestamp
+	}
+}
+
+// addUnremovableNode records the reason why the given node can't be removed.
+func (sd *ScaleDown) addUnremovableNode(node *apiv1.Node, reason simulator.UnremovableReason) {
+	sd.unremovableReasons[node.Name] = &simulator.UnremovableNode{Node: node, Reason: reason}
+}
+
 // updateUnremovableNodes updates unremovableNodes map according to current
 // state of the cluster. Removes from the map nodes that are no longer in the
 // nodes list.
@@ -518,6 +754,7 @@ func (sd *ScaleDown) updateUnremovableNodes(nodes []*apiv1.Node) {
 	}
 	for nodeName := range nodesToDelete {
 		delete(sd.unremovableNodes, nodeName)
+		delete(sd.unremovableReasons, nodeName)
 	}
 }
 
@@ -533,6 +770,16 @@ func (sd *ScaleDown) markSimulationError(simulatorErr errors.AutoscalerError,
 	return simulatorErr.AddPrefix("error while simulating node drains: ")
 }
 
+// orderForScaleDown sorts candidates with the configured scale-down order strategy, most preferred
+// for removal first. Candidates are returned unchanged if no strategy is configured.
+func (sd *ScaleDown) orderForScaleDown(candidates []*apiv1.Node, allNodes []*apiv1.Node,
+	utilization map[string]float64) []*apiv1.Node {
+	if sd.context.ScaleDownOrderStrategy == nil {
+		return candidates
+	}
+	return sd.context.ScaleDownOrderStrategy.Order(candidates, allNodes, utilization)
+}
+
 // chooseCandidates splits nodes into current candidates for scale-down and the
 // rest. Current candidates are unneeded nodes from the previous run that are
 // still in the nodes list.
@@ -555,8 +802,8 @@ func (sd *ScaleDown) chooseCandidates(nodes []*apiv1.Node) ([]*apiv1.Node, []*ap
 }
 
 // TryToScaleDown tries to scale down the cluster. It returns ScaleDownResult indicating if any node was
-// removed and error if such occurred.
-func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget, currentTime time.Time) (ScaleDownResult, errors.AutoscalerError) {
+// removed and error if such occurred. Deletions of empty nodes are abandoned once loopCtx is done.
+func (sd *ScaleDown) TryToScaleDown(loopCtx ctx.Context, allNodes []*apiv1.Node, pods []*apiv1.Pod, pdbs []*policyv1.PodDisruptionBudget, currentTime time.Time) (ScaleDownResult, errors.AutoscalerError) {
 	nodeDeletionDuration := time.Duration(0)
 	findNodesToRemoveDuration := time.Duration(0)
 	defer updateScaleDownMetrics(time.Now(), &findNodesToRemoveDuration, &nodeDeletionDuration)
@@ -575,7 +822,6 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 	scaleDownResourcesLeft := computeScaleDownResourcesLeftLimits(nodesWithoutMaster, resourceLimiter, sd.context.CloudProvider, currentTime)
 
 	nodeGroupSize := getNodeGroupSizeMap(sd.context.CloudProvider)
-	resourcesWithLimits := resourceLimiter.GetResources()
 	for _, node := range nodesWithoutMaster {
 		if val, found := sd.unneededNodes[node.Name]; found {
 
@@ -584,6 +830,7 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 			// Check if node is marked with no scale down annotation.
 			if hasNoScaleDownAnnotation(node) {
 				glog.V(4).Infof("Skipping %s - scale down disabled annotation found", node.Name)
+				sd.addUnremovableNode(node, simulator.ScaleDownDisabledAnnotation)
 				continue
 			}
 
@@ -607,6 +854,7 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 			}
 			if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
 				glog.V(4).Infof("Skipping %s - no node group config", node.Name)
+				sd.addUnremovableNode(node, simulator.NotAutoscaled)
 				continue
 			}
 
@@ -618,10 +866,11 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 
 			if size <= nodeGroup.MinSize() {
 				glog.V(1).Infof("Skipping %s - node group min size reached", node.Name)
+				sd.addUnremovableNode(node, simulator.NodeGroupMinSizeReached)
 				continue
 			}
 
-			scaleDownResourcesDelta, err := computeScaleDownResourcesDelta(node, nodeGroup, resourcesWithLimits)
+			scaleDownResourcesDelta, err := computeScaleDownResourcesDelta(node, nodeGroup, resourceLimiter)
 			if err != nil {
 				glog.Errorf("Error getting node resources: %v", err)
 				continue
@@ -630,26 +879,47 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 			checkResult := scaleDownResourcesLeft.checkScaleDownDeltaWithinLimits(scaleDownResourcesDelta)
 			if checkResult.exceeded {
 				glog.V(4).Infof("Skipping %s - minimal limit exceeded for %v", node.Name, checkResult.exceededResources)
+				sd.addUnremovableNode(node, simulator.MinimalResourceLimitExceeded)
 				continue
 			}
 
+			delete(sd.unremovableReasons, node.Name)
 			candidates = append(candidates, node)
 			candidateNodeGroups[node.Name] = nodeGroup
 		}
 	}
+	sd.reportUnremovableNodes(currentTime)
 	if len(candidates) == 0 {
 		glog.V(1).Infof("No candidates for scale down")
 		return ScaleDownNoUnneeded, nil
 	}
+	candidates = sd.orderForScaleDown(candidates, nodesWithoutMaster, sd.nodeUtilizationMap)
 
 	// Trying to delete empty nodes in bulk. If there are no empty nodes then CA will
 	// try to delete not-so-empty nodes, possibly killing some pods and allowing them
 	// to recreate on other nodes.
-	emptyNodes := getEmptyNodes(candidates, pods, sd.context.MaxEmptyBulkDelete, scaleDownResourcesLeft, sd.context.CloudProvider)
+	emptyNodes := getEmptyNodes(candidates, pods, sd.context.MaxEmptyBulkDelete, scaleDownResourcesLeft, resourceLimiter, sd.context.CloudProvider)
+	if len(emptyNodes) > 0 {
+		allowedEmptyNodes := sd.runPreDeletionHooks(emptyNodes, nil, currentTime)
+		if len(allowedEmptyNodes) == 0 {
+			// Non-empty candidates may still be removed. The denied empty nodes are left out,
+			// as they would be picked again and denied by the same hooks.
+			glog.V(1).Infof("Removal of all empty nodes was denied or postponed by pre-deletion hooks, trying non-empty nodes")
+			candidates = filterOutNodes(candidates, emptyNodes)
+		}
+		emptyNodes = allowedEmptyNodes
+	}
 	if len(emptyNodes) > 0 {
+		for _, node := range emptyNodes {
+			reason := metrics.Empty
+			if !readinessMap[node.Name] {
+				reason = metrics.Unready
+			}
+			sd.auditScaleDown(node, candidateNodeGroups[node.Name], reason, nil, nil)
+		}
 		nodeDeletionStart := time.Now()
 		confirmation := make(chan errors.AutoscalerError, len(emptyNodes))
-		sd.scheduleDeleteEmptyNodes(emptyNodes, sd.context.ClientSet, sd.context.Recorder, readinessMap, candidateNodeGroups, confirmation)
+		sd.scheduleDeleteEmptyNodes(loopCtx, emptyNodes, sd.context.Actuator, sd.context.Recorder, readinessMap, candidateNodeGroups, confirmation)
 		err := sd.waitForEmptyNodesDeleted(emptyNodes, confirmation)
 		nodeDeletionDuration = time.Now().Sub(nodeDeletionStart)
 		if err == nil {
@@ -662,19 +932,37 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 	// Only scheduled non expendable pods are taken into account and have to be moved.
 	nonExpendablePods := FilterOutExpendablePods(pods, sd.context.ExpendablePodsPriorityCutoff)
 	// We look for only 1 node so new hints may be incomplete.
-	nodesToRemove, _, _, err := simulator.FindNodesToRemove(candidates, nodesWithoutMaster, nonExpendablePods, sd.context.ClientSet,
+	nodesToRemove, unremovable, hints, err := simulator.FindNodesToRemove(candidates, nodesWithoutMaster, nonExpendablePods, sd.context.ClientSet,
 		sd.context.PredicateChecker, 1, false,
-		sd.podLocationHints, sd.usageTracker, time.Now(), pdbs)
+		sd.podLocationHints, sd.usageTracker, time.Now(), pdbs, sd.drainPolicyBuilder.build(),
+		sd.context.SimulationParallelism, sd.context.SimulationSeed)
 	findNodesToRemoveDuration = time.Now().Sub(findNodesToRemoveStart)
 
 	if err != nil {
 		return ScaleDownError, err.AddPrefix("Find node to remove failed: ")
 	}
+	for _, node := range unremovable {
+		sd.unremovableReasons[node.Node.Name] = node
+	}
+	if len(unremovable) > 0 {
+		sd.reportUnremovableNodes(currentTime)
+	}
 	if len(nodesToRemove) == 0 {
 		glog.V(1).Infof("No node to remove")
+		sd.context.AuditLog.Skip(auditlog.ScaleDownAction, "pods of none of the unneeded nodes can be moved elsewhere")
 		return ScaleDownNoNodeDeleted, nil
 	}
 	toRemove := nodesToRemove[0]
+	podsToReschedule := map[string][]*apiv1.Pod{toRemove.Node.Name: toRemove.PodsToReschedule}
+	if len(sd.runPreDeletionHooks([]*apiv1.Node{toRemove.Node}, podsToReschedule, currentTime)) == 0 {
+		sd.context.AuditLog.Skip(auditlog.ScaleDownAction, fmt.Sprintf("removal of %s was denied or postponed by pre-deletion hooks", toRemove.Node.Name))
+		return ScaleDownNoNodeDeleted, nil
+	}
+	reason := metrics.Underutilized
+	if !readinessMap[toRemove.Node.Name] {
+		reason = metrics.Unready
+	}
+	sd.auditScaleDown(toRemove.Node, candidateNodeGroups[toRemove.Node.Name], reason, toRemove.PodsToReschedule, hints)
 	utilization := sd.nodeUtilizationMap[toRemove.Node.Name]
 	podNames := make([]string, 0, len(toRemove.PodsToReschedule))
 	for _, pod := range toRemove.PodsToReschedule {
@@ -693,25 +981,47 @@ func (sd *ScaleDown) TryToScaleDown(allNodes []*apiv1.Node, pods []*apiv1.Pod, p
 	nodeDeletionDuration = time.Now().Sub(nodeDeletionStart)
 	sd.nodeDeleteStatus.SetDeleteInProgress(true)
 
+	// The deletion outlives the loop, but belongs to its trace.
+	deleteCtx := tracing.Detach(loopCtx)
 	go func() {
 		// Finishing the delete process once this goroutine is over.
 		defer sd.nodeDeleteStatus.SetDeleteInProgress(false)
-		err := sd.deleteNode(toRemove.Node, toRemove.PodsToReschedule)
+		err := sd.deleteNode(deleteCtx, toRemove.Node, toRemove.PodsToReschedule)
 		if err != nil {
 			glog.Errorf("Failed to delete %s: %v", toRemove.Node.Name, err)
 			return
 		}
+		if sd.context.DryRun {
+			return
+		}
 		nodeGroup := candidateNodeGroups[toRemove.Node.Name]
 		if readinessMap[toRemove.Node.Name] {
-			metrics.RegisterScaleDown(1, gpu.GetGpuTypeForMetrics(toRemove.Node, nodeGroup), metrics.Underutilized)
+			metrics.RegisterScaleDown(nodeGroup.Id(), 1, gpu.GetGpuTypeForMetrics(toRemove.Node, nodeGroup), metrics.Underutilized)
 		} else {
-			metrics.RegisterScaleDown(1, gpu.GetGpuTypeForMetrics(toRemove.Node, nodeGroup), metrics.Unready)
+			metrics.RegisterScaleDown(nodeGroup.Id(), 1, gpu.GetGpuTypeForMetrics(toRemove.Node, nodeGroup), metrics.Unready)
 		}
 	}()
 
 	return ScaleDownNodeDeleteStarted, nil
 }
 
+// auditScaleDown records the choice of the node for removal in the audit log. hints map pods, by
+// namespace/name, to the nodes they are expected to be rescheduled on.
+func (sd *ScaleDown) auditScaleDown(node *apiv1.Node, nodeGroup cloudprovider.NodeGroup, reason metrics.NodeScaleDownReason,
+	pods []*apiv1.Pod, hints map[string]string) {
+	entry := &auditlog.ScaleDown{
+		Node:        node.Name,
+		NodeGroup:   nodeGroup.Id(),
+		Reason:      string(reason),
+		Utilization: sd.nodeUtilizationMap[node.Name],
+	}
+	for _, pod := range pods {
+		name := auditlog.PodName(pod)
+		entry.PodsToReschedule = append(entry.PodsToReschedule, auditlog.PodToReschedule{Pod: name, TargetNode: hints[name]})
+	}
+	sd.context.AuditLog.ScaleDown(entry)
+}
+
 // updateScaleDownMetrics registers duration of different parts of scale down.
 // Separates time spent on finding nodes to remove, deleting nodes and other operations.
 func updateScaleDownMetrics(scaleDownStart time.Time, findNodesToRemoveDuration *time.Duration, nodeDeletionDuration *time.Duration) {
@@ -724,19 +1034,18 @@ func updateScaleDownMetrics(scaleDownStart time.Time, findNodesToRemoveDuration
 
 func getEmptyNodesNoResourceLimits(candidates []*apiv1.Node, pods []*apiv1.Pod, maxEmptyBulkDelete int,
 	cloudProvider cloudprovider.CloudProvider) []*apiv1.Node {
-	return getEmptyNodes(candidates, pods, maxEmptyBulkDelete, noScaleDownLimitsOnResources(), cloudProvider)
+	return getEmptyNodes(candidates, pods, maxEmptyBulkDelete, noScaleDownLimitsOnResources(), cloudprovider.NewResourceLimiter(nil, nil), cloudProvider)
 }
 
 // This functions finds empty nodes among passed candidates and returns a list of empty nodes
 // that can be deleted at the same time.
 func getEmptyNodes(candidates []*apiv1.Node, pods []*apiv1.Pod, maxEmptyBulkDelete int,
-	resourcesLimits scaleDownResourcesLimits, cloudProvider cloudprovider.CloudProvider) []*apiv1.Node {
+	resourcesLimits scaleDownResourcesLimits, resourceLimiter *cloudprovider.ResourceLimiter, cloudProvider cloudprovider.CloudProvider) []*apiv1.Node {
 
 	emptyNodes := simulator.FindEmptyNodesToRemove(candidates, pods)
 	availabilityMap := make(map[string]int)
 	result := make([]*apiv1.Node, 0)
 	resourcesLimitsCopy := copyScaleDownResourcesLimits(resourcesLimits) // we do not want to modify input parameter
-	resourcesNames := sets.StringKeySet(resourcesLimits).List()
 
 	for _, node := range emptyNodes {
 		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
@@ -763,7 +1072,7 @@ func getEmptyNodes(candidates []*apiv1.Node, pods []*apiv1.Pod, maxEmptyBulkDele
 			availabilityMap[nodeGroup.Id()] = available
 		}
 		if available > 0 {
-			resourcesDelta, err := computeScaleDownResourcesDelta(node, nodeGroup, resourcesNames)
+			resourcesDelta, err := computeScaleDownResourcesDelta(node, nodeGroup, resourceLimiter)
 			if err != nil {
 				glog.Errorf("Error: %v", err)
 				continue
@@ -784,7 +1093,7 @@ func getEmptyNodes(candidates []*apiv1.Node, pods []*apiv1.Pod, maxEmptyBulkDele
 	return result[:limit]
 }
 
-func (sd *ScaleDown) scheduleDeleteEmptyNodes(emptyNodes []*apiv1.Node, client kube_client.Interface,
+func (sd *ScaleDown) scheduleDeleteEmptyNodes(loopCtx ctx.Context, emptyNodes []*apiv1.Node, actuator actuation.Actuator,
 	recorder kube_record.EventRecorder, readinessMap map[string]bool,
 	candidateNodeGroups map[string]cloudprovider.NodeGroup, confirmation chan errors.AutoscalerError) {
 	for _, node := range emptyNodes {
@@ -792,7 +1101,7 @@ func (sd *ScaleDown) scheduleDeleteEmptyNodes(emptyNodes []*apiv1.Node, client k
 		sd.context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaleDownEmpty", "Scale-down: removing empty node %s", node.Name)
 		simulator.RemoveNodeFromTracker(sd.usageTracker, node.Name, sd.unneededNodes)
 		go func(nodeToDelete *apiv1.Node) {
-			taintErr := deletetaint.MarkToBeDeleted(nodeToDelete, client)
+			taintErr := actuator.MarkToBeDeleted(nodeToDelete)
 			if taintErr != nil {
 				recorder.Eventf(nodeToDelete, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to mark the node as toBeDeleted/unschedulable: %v", taintErr)
 				confirmation <- errors.ToAutoscalerError(errors.ApiCallError, taintErr)
@@ -803,21 +1112,21 @@ func (sd *ScaleDown) scheduleDeleteEmptyNodes(emptyNodes []*apiv1.Node, client k
 			// If we fail to delete the node we want to remove delete taint
 			defer func() {
 				if deleteErr != nil {
-					deletetaint.CleanToBeDeleted(nodeToDelete, client)
+					actuator.CleanToBeDeleted(nodeToDelete)
 					recorder.Eventf(nodeToDelete, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to delete empty node: %v", deleteErr)
-				} else {
+				} else if !sd.context.DryRun {
 					sd.context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaleDownEmpty", "Scale-down: empty node %s removed", nodeToDelete.Name)
 				}
 			}()
 
-			deleteErr = deleteNodeFromCloudProvider(nodeToDelete, sd.context.CloudProvider,
-				sd.context.Recorder, sd.clusterStateRegistry)
-			if deleteErr == nil {
+			deleteErr = deleteNodeFromCloudProvider(loopCtx, nodeToDelete, sd.context, sd.clusterStateRegistry)
+			if deleteErr == nil && !sd.context.DryRun {
+				sd.runPostDeletionHooks(nodeToDelete, nil)
 				nodeGroup := candidateNodeGroups[nodeToDelete.Name]
 				if readinessMap[nodeToDelete.Name] {
-					metrics.RegisterScaleDown(1, gpu.GetGpuTypeForMetrics(nodeToDelete, nodeGroup), metrics.Empty)
+					metrics.RegisterScaleDown(nodeGroup.Id(), 1, gpu.GetGpuTypeForMetrics(nodeToDelete, nodeGroup), metrics.Empty)
 				} else {
-					metrics.RegisterScaleDown(1, gpu.GetGpuTypeForMetrics(nodeToDelete, nodeGroup), metrics.Unready)
+					metrics.RegisterScaleDown(nodeGroup.Id(), 1, gpu.GetGpuTypeForMetrics(nodeToDelete, nodeGroup), metrics.Unready)
 				}
 			}
 			confirmation <- deleteErr
@@ -848,11 +1157,15 @@ func (sd *ScaleDown) waitForEmptyNodesDeleted(emptyNodes []*apiv1.Node, confirma
 	return finalError
 }
 
-func (sd *ScaleDown) deleteNode(node *apiv1.Node, pods []*apiv1.Pod) errors.AutoscalerError {
+// deleteNode drains the node and removes it from the cloud provider. The cloud provider call gets a
+// deadline of its own; traceCtx only carries the trace the deletion is part of.
+func (sd *ScaleDown) deleteNode(traceCtx ctx.Context, node *apiv1.Node, pods []*apiv1.Pod) (typedErr errors.AutoscalerError) {
+	traceCtx, span := tracing.StartSpan(traceCtx, "deleteNode", trace.StringAttribute("node", node.Name))
+	defer func() { tracing.EndSpan(span, typedErr) }()
 	deleteSuccessful := false
 	drainSuccessful := false
 
-	if err := deletetaint.MarkToBeDeleted(node, sd.context.ClientSet); err != nil {
+	if err := sd.context.Actuator.MarkToBeDeleted(node); err != nil {
 		sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to mark the node as toBeDeleted/unschedulable: %v", err)
 		return errors.ToAutoscalerError(errors.ApiCallError, err)
 	}
@@ -860,7 +1173,7 @@ func (sd *ScaleDown) deleteNode(node *apiv1.Node, pods []*apiv1.Pod) errors.Auto
 	// If we fail to evict all the pods from the node we want to remove delete taint
 	defer func() {
 		if !deleteSuccessful {
-			deletetaint.CleanToBeDeleted(node, sd.context.ClientSet)
+			sd.context.Actuator.CleanToBeDeleted(node)
 			if !drainSuccessful {
 				sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to drain the node, aborting ScaleDown")
 			} else {
@@ -869,124 +1182,97 @@ func (sd *ScaleDown) deleteNode(node *apiv1.Node, pods []*apiv1.Pod) errors.Auto
 		}
 	}()
 
-	sd.context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDown", "marked the node as toBeDeleted/unschedulable")
+	if !sd.context.DryRun {
+		sd.context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDown", "marked the node as toBeDeleted/unschedulable")
+	}
 
 	// attempt drain
-	if err := drainNode(node, pods, sd.context.ClientSet, sd.context.Recorder, sd.context.MaxGracefulTerminationSec, MaxPodEvictionTime, EvictionRetryTime); err != nil {
+	drainCtx, drainSpan := tracing.StartSpan(traceCtx, "DrainNode", trace.Int64Attribute("pods", int64(len(pods))))
+	err := sd.context.Actuator.DrainNode(drainCtx, node, pods)
+	tracing.EndSpan(drainSpan, err)
+	if err != nil {
 		return err
 	}
 	drainSuccessful = true
 
-	// attempt delete from cloud provider
-	err := deleteNodeFromCloudProvider(node, sd.context.CloudProvider, sd.context.Recorder, sd.clusterStateRegistry)
+	// attempt delete from cloud provider, the deletion may outlive the loop that started it, so it
+	// gets a deadline of its own
+	callCtx, cancel := withLoopDeadline(tracing.Detach(traceCtx), sd.context.LoopDeadline)
+	defer cancel()
+	err = deleteNodeFromCloudProvider(callCtx, node, sd.context, sd.clusterStateRegistry)
 	if err != nil {
 		return err
 	}
 
 	deleteSuccessful = true // Let the deferred function know there is no need to cleanup
+	if !sd.context.DryRun {
+		sd.runPostDeletionHooks(node, pods)
+	}
 	return nil
 }
 
-func evictPod(podToEvict *apiv1.Pod, client kube_client.Interface, recorder kube_record.EventRecorder,
-	maxGracefulTerminationSec int, retryUntil time.Time, waitBetweenRetries time.Duration) error {
-	recorder.Eventf(podToEvict, apiv1.EventTypeNormal, "ScaleDown", "deleting pod for node scale down")
+// runPreDeletionHooks calls pre-deletion hooks for the given nodes in parallel and returns the nodes
+// whose removal was allowed. Nodes whose removal was denied or postponed are not checked again until
+// the time requested by the hook, or UnremovableNodeRecheckTimeout if none, passes.
+func (sd *ScaleDown) runPreDeletionHooks(nodes []*apiv1.Node, podsByNode map[string][]*apiv1.Pod,
+	currentTime time.Time) []*apiv1.Node {
+	if sd.context.DeletionHooks == nil || sd.context.DryRun {
+		return nodes
+	}
 
-	maxTermination := int64(apiv1.DefaultTerminationGracePeriodSeconds)
-	if podToEvict.Spec.TerminationGracePeriodSeconds != nil {
-		if *podToEvict.Spec.TerminationGracePeriodSeconds < int64(maxGracefulTerminationSec) {
-			maxTermination = *podToEvict.Spec.TerminationGracePeriodSeconds
-		} else {
-			maxTermination = int64(maxGracefulTerminationSec)
-		}
+	results := make([]*deletionhooks.Result, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *apiv1.Node) {
+			defer wg.Done()
+			results[i] = sd.context.DeletionHooks.RunPreDeletion(node, podsByNode[node.Name])
+		}(i, node)
 	}
+	wg.Wait()
 
-	var lastError error
-	for first := true; first || time.Now().Before(retryUntil); time.Sleep(waitBetweenRetries) {
-		first = false
-		eviction := &policyv1.Eviction{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: podToEvict.Namespace,
-				Name:      podToEvict.Name,
-			},
-			DeleteOptions: &metav1.DeleteOptions{
-				GracePeriodSeconds: &maxTermination,
-			},
+	allowed := make([]*apiv1.Node, 0, len(nodes))
+	for i, node := range nodes {
+		result := results[i]
+		if result.Decision == deletionhooks.Allow {
+			allowed = append(allowed, node)
+			continue
+		}
+		retryAfter := result.RetryAfter
+		if retryAfter == 0 {
+			retryAfter = sd.context.UnremovableNodeRecheckTimeout
 		}
-		lastError = client.CoreV1().Pods(podToEvict.Namespace).Evict(eviction)
-		if lastError == nil || kube_errors.IsNotFound(lastError) {
-			return nil
+		sd.unremovableNodes[node.Name] = currentTime.Add(retryAfter)
+		if result.Decision == deletionhooks.RetryLater {
+			glog.V(1).Infof("Scale-down: removal of %s postponed by hook %s for %v: %s", node.Name, result.Hook, retryAfter, result.Message)
+			sd.context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDownPostponed",
+				"removal postponed by hook %s: %s", result.Hook, result.Message)
+			sd.addUnremovableNode(node, simulator.DeletionHookRetryLater)
+		} else {
+			glog.V(1).Infof("Scale-down: removal of %s denied by hook %s: %s", node.Name, result.Hook, result.Message)
+			sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownVetoed",
+				"removal denied by hook %s: %s", result.Hook, result.Message)
+			sd.addUnremovableNode(node, simulator.DeletionHookDenied)
 		}
 	}
-	glog.Errorf("Failed to evict pod %s, error: %v", podToEvict.Name, lastError)
-	recorder.Eventf(podToEvict, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to delete pod for ScaleDown")
-	return fmt.Errorf("Failed to evict pod %s/%s within allowed timeout (last error: %v)", podToEvict.Namespace, podToEvict.Name, lastError)
+	return allowed
 }
 
-// Performs drain logic on the node. Marks the node as unschedulable and later removes all pods, giving
-// them up to MaxGracefulTerminationTime to finish.
-func drainNode(node *apiv1.Node, pods []*apiv1.Pod, client kube_client.Interface, recorder kube_record.EventRecorder,
-	maxGracefulTerminationSec int, maxPodEvictionTime time.Duration, waitBetweenRetries time.Duration) errors.AutoscalerError {
-
-	toEvict := len(pods)
-	retryUntil := time.Now().Add(maxPodEvictionTime)
-	confirmations := make(chan error, toEvict)
-	for _, pod := range pods {
-		go func(podToEvict *apiv1.Pod) {
-			confirmations <- evictPod(podToEvict, client, recorder, maxGracefulTerminationSec, retryUntil, waitBetweenRetries)
-		}(pod)
-	}
-
-	evictionErrs := make([]error, 0)
-
-	for range pods {
-		select {
-		case err := <-confirmations:
-			if err != nil {
-				evictionErrs = append(evictionErrs, err)
-			} else {
-				metrics.RegisterEvictions(1)
-			}
-		case <-time.After(retryUntil.Sub(time.Now()) + 5*time.Second):
-			return errors.NewAutoscalerError(
-				errors.ApiCallError, "Failed to drain node %s/%s: timeout when waiting for creating evictions", node.Namespace, node.Name)
-		}
+// runPostDeletionHooks calls post-deletion hooks for a removed node.
+func (sd *ScaleDown) runPostDeletionHooks(node *apiv1.Node, pods []*apiv1.Pod) {
+	if sd.context.DeletionHooks == nil {
+		return
 	}
-	if len(evictionErrs) != 0 {
-		return errors.NewAutoscalerError(
-			errors.ApiCallError, "Failed to drain node %s/%s, due to following errors: %v", node.Namespace, node.Name, evictionErrs)
-	}
-
-	// Evictions created successfully, wait maxGracefulTerminationSec + PodEvictionHeadroom to see if pods really disappeared.
-	allGone := true
-	for start := time.Now(); time.Now().Sub(start) < time.Duration(maxGracefulTerminationSec)*time.Second+PodEvictionHeadroom; time.Sleep(5 * time.Second) {
-		allGone = true
-		for _, pod := range pods {
-			podreturned, err := client.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
-			if err == nil && (podreturned == nil || podreturned.Spec.NodeName == node.Name) {
-				glog.Errorf("Not deleted yet %v", podreturned)
-				allGone = false
-				break
-			}
-			if err != nil && !kube_errors.IsNotFound(err) {
-				glog.Errorf("Failed to check pod %s/%s: %v", pod.Namespace, pod.Name, err)
-				allGone = false
-				break
-			}
-		}
-		if allGone {
-			glog.V(1).Infof("All pods removed from %s", node.Name)
-			// Let the deferred function know there is no need for cleanup
-			return nil
-		}
+	if err := sd.context.DeletionHooks.RunPostDeletion(node, pods); err != nil {
+		glog.Warningf("Post-deletion hooks failed for %s: %v", node.Name, err)
+		sd.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownHookFailed", "post-deletion hooks failed: %v", err)
 	}
-	return errors.NewAutoscalerError(
-		errors.TransientError, "Failed to drain node %s/%s: pods remaining after timeout", node.Namespace, node.Name)
 }
 
 // cleanToBeDeleted cleans ToBeDeleted taints.
-func cleanToBeDeleted(nodes []*apiv1.Node, client kube_client.Interface, recorder kube_record.EventRecorder) {
+func cleanToBeDeleted(nodes []*apiv1.Node, actuator actuation.Actuator, recorder kube_record.EventRecorder) {
 	for _, node := range nodes {
-		cleaned, err := deletetaint.CleanToBeDeleted(node, client)
+		cleaned, err := actuator.CleanToBeDeleted(node)
 		if err != nil {
 			glog.Warningf("Error while releasing taints on node %v: %v", node.Name, err)
 			recorder.Eventf(node, apiv1.EventTypeWarning, "ClusterAutoscalerCleanup",
@@ -998,11 +1284,28 @@ func cleanToBeDeleted(nodes []*apiv1.Node, client kube_client.Interface, recorde
 	}
 }
 
-// Removes the given node from cloud provider. No extra pre-deletion actions are executed on
-// the Kubernetes side.
-func deleteNodeFromCloudProvider(node *apiv1.Node, cloudProvider cloudprovider.CloudProvider,
-	recorder kube_record.EventRecorder, registry *clusterstate.ClusterStateRegistry) errors.AutoscalerError {
-	nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+// cleanDeletionCandidates cleans DeletionCandidate soft taints.
+func cleanDeletionCandidates(nodes []*apiv1.Node, actuator actuation.Actuator, recorder kube_record.EventRecorder) {
+	for _, node := range nodes {
+		if !deletetaint.HasDeletionCandidateTaint(node) {
+			continue
+		}
+		cleaned, err := actuator.CleanDeletionCandidate(node)
+		if err != nil {
+			glog.Warningf("Error while releasing soft taints on node %v: %v", node.Name, err)
+			recorder.Eventf(node, apiv1.EventTypeWarning, "ClusterAutoscalerCleanup",
+				"failed to clean deletionCandidateTaint: %v", err)
+		} else if cleaned {
+			glog.V(1).Infof("Successfully released deletionCandidateTaint on node %v", node.Name)
+		}
+	}
+}
+
+// Removes the given node from cloud provider, giving up once callCtx is done. No extra pre-deletion
+// actions are executed on the Kubernetes side. In dry-run mode the deletion is only recorded by the actuator.
+func deleteNodeFromCloudProvider(callCtx ctx.Context, node *apiv1.Node, context *context.AutoscalingContext,
+	registry *clusterstate.ClusterStateRegistry) errors.AutoscalerError {
+	nodeGroup, err := context.CloudProvider.NodeGroupForNode(node)
 	if err != nil {
 		return errors.NewAutoscalerError(
 			errors.CloudProviderError, "failed to find node group for %s: %v", node.Name, err)
@@ -1010,10 +1313,13 @@ func deleteNodeFromCloudProvider(node *apiv1.Node, cloudProvider cloudprovider.C
 	if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
 		return errors.NewAutoscalerError(errors.InternalError, "picked node that doesn't belong to a node group: %s", node.Name)
 	}
-	if err = nodeGroup.DeleteNodes([]*apiv1.Node{node}); err != nil {
+	if err = context.Actuator.DeleteNodes(callCtx, nodeGroup, []*apiv1.Node{node}); err != nil {
 		return errors.NewAutoscalerError(errors.CloudProviderError, "failed to delete %s: %v", node.Name, err)
 	}
-	recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDown", "node removed by cluster autoscaler")
+	if context.DryRun {
+		return nil
+	}
+	context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDown", "node removed by cluster autoscaler")
 	registry.RegisterScaleDown(&clusterstate.ScaleDownRequest{
 		NodeGroupName:      nodeGroup.Id(),
 		NodeName:           node.Name,
@@ -1032,6 +1338,21 @@ const (
 	apiServerLabelValue = "kube-apiserver"
 )
 
+// filterOutNodes returns nodes without the ones in toRemove.
+func filterOutNodes(nodes []*apiv1.Node, toRemove []*apiv1.Node) []*apiv1.Node {
+	removed := make(map[string]bool, len(toRemove))
+	for _, node := range toRemove {
+		removed[node.Name] = true
+	}
+	result := make([]*apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if !removed[node.Name] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
 func filterOutMasters(nodes []*apiv1.Node, pods []*apiv1.Pod) []*apiv1.Node {
 	masters := make(map[string]bool)
 	for _, pod := range pods {
@@ -17,6 +17,8 @@ limitations under the License.
 package core
 
 import (
+	"bytes"
+	ctx "context"
 	"fmt"
 	"sort"
 	"testing"
@@ -26,18 +28,25 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
 	testprovider "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
 	"github.com/gardener/autoscaler/cluster-autoscaler/context"
+	"github.com/gardener/autoscaler/cluster-autoscaler/deletionhooks"
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
 	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
 	scheduler_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/scheduler"
 	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/units"
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
+	kube_record "k8s.io/client-go/tools/record"
 
 	"strconv"
 
@@ -141,6 +150,30 @@ func TestFindUnneededNodes(t *testing.T) {
 	assert.Contains(t, sd.podLocationHints, p2.Namespace+"/"+p2.Name)
 	assert.Equal(t, 6, len(sd.nodeUtilizationMap))
 
+	debugState := sd.GetDebugState()
+	assert.Equal(t, 3, len(debugState.UnneededNodes))
+	assert.Equal(t, sd.unneededNodes["n2"], debugState.UnneededNodes["n2"])
+	assert.Equal(t, 6, len(debugState.Utilization))
+	assert.Equal(t, len(sd.unremovableReasons), len(debugState.UnremovableNodes))
+	assert.Equal(t, string(simulator.BlockedByPod), debugState.UnremovableNodes["n1"].Reason)
+	assert.Equal(t, "default/p1", debugState.UnremovableNodes["n1"].BlockingPod)
+	assert.Equal(t, string(drain.NotReplicated), debugState.UnremovableNodes["n1"].BlockingPodReason)
+	assert.False(t, debugState.DeletionInProgress)
+
+	unremovableNodes := sd.GetUnremovableNodes()
+	assert.Equal(t, len(sd.unremovableReasons), len(unremovableNodes))
+	assert.Equal(t, "n1", unremovableNodes[0].NodeName)
+	assert.Equal(t, debugState.UnremovableNodes["n1"].Reason, unremovableNodes[0].Reason)
+	assert.Equal(t, "default/p1", unremovableNodes[0].BlockingPod)
+	assert.Equal(t, map[string]int{
+		string(simulator.BlockedByPod):                1,
+		string(simulator.NotUnderutilized):            1,
+		string(simulator.NoPlaceToMovePods):           1,
+		string(simulator.ScaleDownDisabledAnnotation): 1,
+		string(simulator.UnexpectedError):             1,
+		string(simulator.CurrentlyBeingDeleted):       1,
+	}, sd.GetUnremovableNodesCount())
+
 	sd.unremovableNodes = make(map[string]time.Time)
 	sd.unneededNodes["n1"] = time.Now()
 	sd.UpdateUnneededNodes([]*apiv1.Node{n1, n2, n3, n4}, []*apiv1.Node{n1, n2, n3, n4}, []*apiv1.Pod{p1, p2, p3, p4}, time.Now(), nil)
@@ -169,6 +202,45 @@ func TestFindUnneededNodes(t *testing.T) {
 	assert.Equal(t, 0, len(sd.unremovableNodes))
 }
 
+func TestReportUnremovableNodes(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 10)
+	n2 := BuildTestNode("n2", 1000, 10)
+	p1 := BuildTestPod("p1", 100, 0)
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	options := config.AutoscalingOptions{
+		UnremovableNodeEventInterval: 30 * time.Minute,
+	}
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, provider)
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	sd := NewScaleDown(&context, clusterStateRegistry)
+	sd.unremovableReasons = map[string]*simulator.UnremovableNode{
+		"n1": {Node: n1, Reason: simulator.BlockedByPod, BlockingPod: &drain.BlockingPod{Pod: p1, Reason: drain.NotReplicated}},
+		"n2": {Node: n2, Reason: simulator.NotUnderutilized},
+	}
+	events := context.Recorder.(*kube_record.FakeRecorder).Events
+	now := time.Now()
+
+	sd.reportUnremovableNodes(now)
+	assert.Equal(t, 1, len(events))
+	event := <-events
+	assert.Contains(t, event, "ScaleDownBlocked")
+	assert.Contains(t, event, "BlockedByPod: pod default/p1 (NotReplicated)")
+
+	// Events are rate limited.
+	sd.reportUnremovableNodes(now.Add(time.Minute))
+	assert.Equal(t, 0, len(events))
+	sd.reportUnremovableNodes(now.Add(31 * time.Minute))
+	assert.Equal(t, 1, len(events))
+	<-events
+
+	// Nodes that are no longer unremovable are forgotten.
+	delete(sd.unremovableReasons, "n1")
+	sd.reportUnremovableNodes(now.Add(32 * time.Minute))
+	assert.Empty(t, sd.unremovableEvents)
+	assert.Equal(t, 0, len(events))
+}
+
 func TestPodsWithPrioritiesFindUnneededNodes(t *testing.T) {
 	// shared owner reference
 	ownerRef := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
@@ -535,7 +607,7 @@ func TestDeleteNode(t *testing.T) {
 			sd := NewScaleDown(&context, clusterStateRegistry)
 
 			// attempt delete
-			err := sd.deleteNode(n1, pods)
+			err := sd.deleteNode(ctx.Background(), n1, pods)
 
 			// verify
 			if scenario.expectedDeletion {
@@ -557,134 +629,6 @@ func TestDeleteNode(t *testing.T) {
 	}
 }
 
-func TestDrainNode(t *testing.T) {
-	deletedPods := make(chan string, 10)
-	fakeClient := &fake.Clientset{}
-
-	p1 := BuildTestPod("p1", 100, 0)
-	p2 := BuildTestPod("p2", 300, 0)
-	n1 := BuildTestNode("n1", 1000, 1000)
-	SetNodeReadyState(n1, true, time.Time{})
-
-	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
-		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
-	})
-	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
-		createAction := action.(core.CreateAction)
-		if createAction == nil {
-			return false, nil, nil
-		}
-		eviction := createAction.GetObject().(*policyv1.Eviction)
-		if eviction == nil {
-			return false, nil, nil
-		}
-		deletedPods <- eviction.Name
-		return true, nil, nil
-	})
-	err := drainNode(n1, []*apiv1.Pod{p1, p2}, fakeClient, kube_util.CreateEventRecorder(fakeClient), 20, 5*time.Second, 0*time.Second)
-	assert.NoError(t, err)
-	deleted := make([]string, 0)
-	deleted = append(deleted, getStringFromChan(deletedPods))
-	deleted = append(deleted, getStringFromChan(deletedPods))
-	sort.Strings(deleted)
-	assert.Equal(t, p1.Name, deleted[0])
-	assert.Equal(t, p2.Name, deleted[1])
-}
-
-func TestDrainNodeWithRescheduled(t *testing.T) {
-	deletedPods := make(chan string, 10)
-	fakeClient := &fake.Clientset{}
-
-	p1 := BuildTestPod("p1", 100, 0)
-	p2 := BuildTestPod("p2", 300, 0)
-	p2Rescheduled := BuildTestPod("p2", 300, 0)
-	p2Rescheduled.Spec.NodeName = "n2"
-	n1 := BuildTestNode("n1", 1000, 1000)
-	SetNodeReadyState(n1, true, time.Time{})
-
-	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
-		getAction := action.(core.GetAction)
-		if getAction == nil {
-			return false, nil, nil
-		}
-		if getAction.GetName() == "p2" {
-			return true, p2Rescheduled, nil
-		}
-		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
-	})
-	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
-		createAction := action.(core.CreateAction)
-		if createAction == nil {
-			return false, nil, nil
-		}
-		eviction := createAction.GetObject().(*policyv1.Eviction)
-		if eviction == nil {
-			return false, nil, nil
-		}
-		deletedPods <- eviction.Name
-		return true, nil, nil
-	})
-	err := drainNode(n1, []*apiv1.Pod{p1, p2}, fakeClient, kube_util.CreateEventRecorder(fakeClient), 20, 5*time.Second, 0*time.Second)
-	assert.NoError(t, err)
-	deleted := make([]string, 0)
-	deleted = append(deleted, getStringFromChan(deletedPods))
-	deleted = append(deleted, getStringFromChan(deletedPods))
-	sort.Strings(deleted)
-	assert.Equal(t, p1.Name, deleted[0])
-	assert.Equal(t, p2.Name, deleted[1])
-}
-
-func TestDrainNodeWithRetries(t *testing.T) {
-	deletedPods := make(chan string, 10)
-	// Simulate pdb of size 1 by making the 'eviction' goroutine:
-	// - read from (at first empty) channel
-	// - if it's empty, fail and write to it, then retry
-	// - succeed on successful read.
-	ticket := make(chan bool, 1)
-	fakeClient := &fake.Clientset{}
-
-	p1 := BuildTestPod("p1", 100, 0)
-	p2 := BuildTestPod("p2", 300, 0)
-	p3 := BuildTestPod("p3", 300, 0)
-	n1 := BuildTestNode("n1", 1000, 1000)
-	SetNodeReadyState(n1, true, time.Time{})
-
-	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
-		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
-	})
-	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
-		createAction := action.(core.CreateAction)
-		if createAction == nil {
-			return false, nil, nil
-		}
-		eviction := createAction.GetObject().(*policyv1.Eviction)
-		if eviction == nil {
-			return false, nil, nil
-		}
-		select {
-		case <-ticket:
-			deletedPods <- eviction.Name
-			return true, nil, nil
-		default:
-			select {
-			case ticket <- true:
-			default:
-			}
-			return true, nil, fmt.Errorf("Too many concurrent evictions")
-		}
-	})
-	err := drainNode(n1, []*apiv1.Pod{p1, p2, p3}, fakeClient, kube_util.CreateEventRecorder(fakeClient), 20, 5*time.Second, 0*time.Second)
-	assert.NoError(t, err)
-	deleted := make([]string, 0)
-	deleted = append(deleted, getStringFromChan(deletedPods))
-	deleted = append(deleted, getStringFromChan(deletedPods))
-	deleted = append(deleted, getStringFromChan(deletedPods))
-	sort.Strings(deleted)
-	assert.Equal(t, p1.Name, deleted[0])
-	assert.Equal(t, p2.Name, deleted[1])
-	assert.Equal(t, p3.Name, deleted[2])
-}
-
 func TestScaleDown(t *testing.T) {
 	deletedPods := make(chan string, 10)
 	updatedNodes := make(chan string, 10)
@@ -759,17 +703,28 @@ func TestScaleDown(t *testing.T) {
 		ExpendablePodsPriorityCutoff:  10,
 	}
 	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+	var auditBuffer bytes.Buffer
+	context.AuditLog = auditlog.NewWriterLog(&auditBuffer, false)
 
 	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
 	scaleDown := NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2},
 		[]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2, p3}, time.Now().Add(-5*time.Minute), nil)
-	result, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2, p3}, nil, time.Now())
+	result, err := scaleDown.TryToScaleDown(ctx.Background(), []*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2, p3}, nil, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 	assert.NoError(t, err)
 	assert.Equal(t, ScaleDownNodeDeleteStarted, result)
 	assert.Equal(t, n1.Name, getStringFromChan(deletedNodes))
 	assert.Equal(t, n1.Name, getStringFromChan(updatedNodes))
+
+	entries := readAuditLog(t, &auditBuffer)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, auditlog.ScaleDownDecision, entries[0].Decision)
+	assert.Equal(t, "n1", entries[0].ScaleDown.Node)
+	assert.Equal(t, "ng1", entries[0].ScaleDown.NodeGroup)
+	assert.Equal(t, string(metrics.Underutilized), entries[0].ScaleDown.Reason)
+	assert.InDelta(t, 0.1, entries[0].ScaleDown.Utilization, 0.01)
+	assert.Equal(t, []auditlog.PodToReschedule{{Pod: "default/p1", TargetNode: "n2"}}, entries[0].ScaleDown.PodsToReschedule)
 }
 
 func waitForDeleteToFinish(t *testing.T, sd *ScaleDown) {
@@ -910,6 +865,174 @@ func TestScaleDownEmptyMinGroupSizeLimitHit(t *testing.T) {
 	}
 	simpleScaleDownEmpty(t, config)
 }
+
+type reverseOrder struct{}
+
+func (r *reverseOrder) Order(candidates []*apiv1.Node, allNodes []*apiv1.Node, utilization map[string]float64) []*apiv1.Node {
+	result := make([]*apiv1.Node, 0, len(candidates))
+	for i := len(candidates) - 1; i >= 0; i-- {
+		result = append(result, candidates[i])
+	}
+	return result
+}
+
+func TestScaleDownEmptyOrdered(t *testing.T) {
+	options := defaultScaleDownOptions
+	options.MaxEmptyBulkDelete = 1
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n1", 1000, 1000, 0, true, "ng1"},
+			{"n2", 1000, 1000, 0, true, "ng1"},
+			{"n3", 1000, 1000, 0, true, "ng1"},
+		},
+		options:                options,
+		scaleDownOrderStrategy: &reverseOrder{},
+		expectedScaleDowns:     []string{"n3"},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
+// nodeDecisionHook answers pre-deletion calls with a decision configured per node, allowing other nodes.
+type nodeDecisionHook struct {
+	decisions map[string]*deletionhooks.Response
+}
+
+func (h *nodeDecisionHook) Name() string {
+	return "test"
+}
+
+func (h *nodeDecisionHook) Call(request *deletionhooks.Request) (*deletionhooks.Response, error) {
+	if response, found := h.decisions[request.Node.Name]; found {
+		return response, nil
+	}
+	return &deletionhooks.Response{Decision: deletionhooks.Allow}, nil
+}
+
+func TestScaleDownEmptyDeletionHookDenied(t *testing.T) {
+	hooks := deletionhooks.NewRunner()
+	hooks.Register(&nodeDecisionHook{decisions: map[string]*deletionhooks.Response{
+		"n1": {Decision: deletionhooks.Deny, Message: "attached to storage"},
+	}}, []deletionhooks.Phase{deletionhooks.PreDeletion}, deletionhooks.Fail)
+	config := &scaleTestConfig{
+		nodes: []nodeConfig{
+			{"n1", 1000, 1000, 0, true, "ng1"},
+			{"n2", 1000, 1000, 0, true, "ng1"},
+			{"n3", 1000, 1000, 0, true, "ng1"},
+		},
+		options:            defaultScaleDownOptions,
+		deletionHooks:      hooks,
+		expectedScaleDowns: []string{"n2"},
+	}
+	simpleScaleDownEmpty(t, config)
+}
+
+func TestScaleDownDeletionHookDeniedAllEmptyNodes(t *testing.T) {
+	deletedNodes := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+	n2 := BuildTestNode("n2", 1000, 1000)
+	SetNodeReadyState(n2, true, time.Time{})
+	n3 := BuildTestNode("n3", 1000, 1000)
+	SetNodeReadyState(n3, true, time.Time{})
+	nodesMap := map[string]*apiv1.Node{"n1": n1, "n2": n2, "n3": n3}
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	p1.Spec.NodeName = "n1"
+	p2 := BuildTestPod("p2", 800, 0)
+	p2.Spec.NodeName = "n2"
+
+	fakeClient.Fake.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &apiv1.PodList{Items: []apiv1.Pod{*p1, *p2}}, nil
+	})
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("get", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		getAction := action.(core.GetAction)
+		if node, found := nodesMap[getAction.GetName()]; found {
+			return true, node, nil
+		}
+		return true, nil, fmt.Errorf("Wrong node: %v", getAction.GetName())
+	})
+	fakeClient.Fake.AddReactor("delete", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	fakeClient.Fake.AddReactor("update", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		update := action.(core.UpdateAction)
+		return true, update.GetObject(), nil
+	})
+
+	provider := testprovider.NewTestCloudProvider(nil, func(nodeGroup string, node string) error {
+		deletedNodes <- node
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 1, 10, 3)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNode("ng1", n3)
+
+	hooks := deletionhooks.NewRunner()
+	hooks.Register(&nodeDecisionHook{decisions: map[string]*deletionhooks.Response{
+		"n3": {Decision: deletionhooks.Deny, Message: "attached to storage"},
+	}}, []deletionhooks.Phase{deletionhooks.PreDeletion}, deletionhooks.Fail)
+
+	options := config.AutoscalingOptions{
+		ScaleDownUtilizationThreshold: 0.5,
+		ScaleDownUnneededTime:         time.Minute,
+		MaxGracefulTerminationSec:     60,
+		MaxEmptyBulkDelete:            10,
+	}
+	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+	context.DeletionHooks = hooks
+
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	scaleDown := NewScaleDown(&context, clusterStateRegistry)
+	nodes := []*apiv1.Node{n1, n2, n3}
+	scaleDown.UpdateUnneededNodes(nodes, nodes, []*apiv1.Pod{p1, p2}, time.Now().Add(-5*time.Minute), nil)
+	result, err := scaleDown.TryToScaleDown(ctx.Background(), nodes, []*apiv1.Pod{p1, p2}, nil, time.Now())
+	waitForDeleteToFinish(t, scaleDown)
+	assert.NoError(t, err)
+	assert.Equal(t, ScaleDownNodeDeleteStarted, result)
+	assert.Equal(t, n1.Name, getStringFromChan(deletedNodes))
+	assert.Equal(t, simulator.DeletionHookDenied, scaleDown.unremovableReasons["n3"].Reason)
+}
+
+func TestRunPreDeletionHooks(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	hooks := deletionhooks.NewRunner()
+	hooks.Register(&nodeDecisionHook{decisions: map[string]*deletionhooks.Response{
+		"n1": {Decision: deletionhooks.Deny, Message: "attached to storage"},
+		"n3": {Decision: deletionhooks.RetryLater, RetryAfterSeconds: 60},
+	}}, []deletionhooks.Phase{deletionhooks.PreDeletion}, deletionhooks.Fail)
+
+	options := defaultScaleDownOptions
+	options.UnremovableNodeRecheckTimeout = 5 * time.Minute
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, provider)
+	context.DeletionHooks = hooks
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	sd := NewScaleDown(&context, clusterStateRegistry)
+
+	now := time.Now()
+	allowed := sd.runPreDeletionHooks([]*apiv1.Node{n1, n2, n3}, nil, now)
+
+	assert.Equal(t, []*apiv1.Node{n2}, allowed)
+	assert.Equal(t, now.Add(5*time.Minute), sd.unremovableNodes["n1"])
+	assert.Equal(t, now.Add(time.Minute), sd.unremovableNodes["n3"])
+	assert.Equal(t, simulator.DeletionHookDenied, sd.unremovableReasons["n1"].Reason)
+	assert.Equal(t, simulator.DeletionHookRetryLater, sd.unremovableReasons["n3"].Reason)
+	assert.NotContains(t, sd.unremovableReasons, "n2")
+
+	// Hooks are not called in dry-run mode.
+	context.DryRun = true
+	assert.Equal(t, []*apiv1.Node{n1, n2, n3}, sd.runPreDeletionHooks([]*apiv1.Node{n1, n2, n3}, nil, now))
+}
+
 func simpleScaleDownEmpty(t *testing.T, config *scaleTestConfig) {
 	updatedNodes := make(chan string, 10)
 	deletedNodes := make(chan string, 10)
@@ -968,12 +1091,14 @@ func simpleScaleDownEmpty(t *testing.T, config *scaleTestConfig) {
 	assert.NotNil(t, provider)
 
 	context := NewScaleTestAutoscalingContext(config.options, fakeClient, provider)
+	context.ScaleDownOrderStrategy = config.scaleDownOrderStrategy
+	context.DeletionHooks = config.deletionHooks
 
 	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
 	scaleDown := NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes(nodes,
 		nodes, []*apiv1.Pod{}, time.Now().Add(-5*time.Minute), nil)
-	result, err := scaleDown.TryToScaleDown(nodes, []*apiv1.Pod{}, nil, time.Now())
+	result, err := scaleDown.TryToScaleDown(ctx.Background(), nodes, []*apiv1.Pod{}, nil, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 	// This helps to verify that TryToScaleDown doesn't attempt to remove anything
 	// after delete in progress status is gone.
@@ -1049,7 +1174,7 @@ func TestNoScaleDownUnready(t *testing.T) {
 	scaleDown := NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2},
 		[]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, time.Now().Add(-5*time.Minute), nil)
-	result, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, time.Now())
+	result, err := scaleDown.TryToScaleDown(ctx.Background(), []*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 
 	assert.NoError(t, err)
@@ -1071,7 +1196,7 @@ func TestNoScaleDownUnready(t *testing.T) {
 	scaleDown = NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2}, []*apiv1.Node{n1, n2},
 		[]*apiv1.Pod{p2}, time.Now().Add(-2*time.Hour), nil)
-	result, err = scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, time.Now())
+	result, err = scaleDown.TryToScaleDown(ctx.Background(), []*apiv1.Node{n1, n2}, []*apiv1.Pod{p2}, nil, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 
 	assert.NoError(t, err)
@@ -1148,7 +1273,7 @@ func TestScaleDownNoMove(t *testing.T) {
 	scaleDown := NewScaleDown(&context, clusterStateRegistry)
 	scaleDown.UpdateUnneededNodes([]*apiv1.Node{n1, n2}, []*apiv1.Node{n1, n2},
 		[]*apiv1.Pod{p1, p2}, time.Now().Add(5*time.Minute), nil)
-	result, err := scaleDown.TryToScaleDown([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2}, nil, time.Now())
+	result, err := scaleDown.TryToScaleDown(ctx.Background(), []*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2}, nil, time.Now())
 	waitForDeleteToFinish(t, scaleDown)
 
 	assert.NoError(t, err)
@@ -1202,12 +1327,101 @@ func TestCleanToBeDeleted(t *testing.T) {
 	})
 	fakeRecorder := kube_util.CreateEventRecorder(fakeClient)
 
-	cleanToBeDeleted([]*apiv1.Node{n1, n2}, fakeClient, fakeRecorder)
+	cleanToBeDeleted([]*apiv1.Node{n1, n2}, NewDefaultActuator(fakeClient, fakeRecorder, NewTestDrainer(fakeClient, fakeRecorder, 0), ""), fakeRecorder)
 
 	assert.Equal(t, 0, len(n1.Spec.Taints))
 	assert.Equal(t, 0, len(n2.Spec.Taints))
 }
 
+func TestCleanDeletionCandidates(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 10)
+	n2 := BuildTestNode("n2", 1000, 10)
+	n2.Spec.Taints = []apiv1.Taint{{Key: deletetaint.DeletionCandidateTaint, Value: strconv.FormatInt(time.Now().Unix()-301, 10)}}
+
+	fakeClient, updatedNodes := buildSoftTaintFakeClient(n1, n2)
+	fakeRecorder := kube_util.CreateEventRecorder(fakeClient)
+
+	cleanDeletionCandidates([]*apiv1.Node{n1, n2}, NewDefaultActuator(fakeClient, fakeRecorder, NewTestDrainer(fakeClient, fakeRecorder, 0), ""), fakeRecorder)
+
+	assert.Equal(t, []string{"n2"}, updatedNodes())
+	assert.False(t, deletetaint.HasDeletionCandidateTaint(n2))
+}
+
+func TestSoftTaintUnneededNodes(t *testing.T) {
+	// Unneeded, not tainted yet.
+	n1 := BuildTestNode("n1", 1000, 10)
+	// Needed again, still tainted.
+	n2 := BuildTestNode("n2", 1000, 10)
+	n2.Spec.Taints = []apiv1.Taint{{Key: deletetaint.DeletionCandidateTaint, Value: "0", Effect: apiv1.TaintEffectPreferNoSchedule}}
+	// Unneeded and tainted already.
+	n3 := BuildTestNode("n3", 1000, 10)
+	n3.Spec.Taints = []apiv1.Taint{{Key: deletetaint.DeletionCandidateTaint, Value: "0", Effect: apiv1.TaintEffectPreferNoSchedule}}
+	// Unneeded, not tainted yet, over the budget.
+	n4 := BuildTestNode("n4", 1000, 10)
+	// Unneeded, being deleted.
+	n5 := BuildTestNode("n5", 1000, 10)
+	n5.Spec.Taints = []apiv1.Taint{{Key: deletetaint.ToBeDeletedTaint, Value: "0", Effect: apiv1.TaintEffectNoSchedule}}
+
+	fakeClient, updatedNodes := buildSoftTaintFakeClient(n1, n2, n3, n4, n5)
+	options := config.AutoscalingOptions{
+		MaxBulkSoftTaintCount: 2,
+		MaxBulkSoftTaintTime:  time.Minute,
+	}
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+	clusterStateRegistry := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	sd := NewScaleDown(&context, clusterStateRegistry)
+	now := time.Now()
+	sd.unneededNodes = map[string]time.Time{"n1": now, "n3": now, "n4": now, "n5": now}
+
+	sd.softTaintUnneededNodes([]*apiv1.Node{n1, n2, n3, n4, n5})
+	assert.Equal(t, []string{"n1", "n2"}, updatedNodes())
+	assert.True(t, deletetaint.HasDeletionCandidateTaint(n1))
+	assert.False(t, deletetaint.HasDeletionCandidateTaint(n2))
+	assert.False(t, deletetaint.HasDeletionCandidateTaint(n4))
+
+	// The remaining node is tainted in the next loop.
+	sd.softTaintUnneededNodes([]*apiv1.Node{n1, n2, n3, n4, n5})
+	assert.Equal(t, []string{"n4"}, updatedNodes())
+	assert.True(t, deletetaint.HasDeletionCandidateTaint(n4))
+
+	// Soft tainting is disabled by default.
+	sd.context.MaxBulkSoftTaintCount = 0
+	sd.unneededNodes = map[string]time.Time{}
+	sd.softTaintUnneededNodes([]*apiv1.Node{n1, n2, n3, n4, n5})
+	assert.Empty(t, updatedNodes())
+}
+
+// buildSoftTaintFakeClient returns a client serving the given nodes, which are updated in place,
+// and a function returning the names of the nodes updated since its previous call.
+func buildSoftTaintFakeClient(nodes ...*apiv1.Node) (*fake.Clientset, func() []string) {
+	nodesByName := make(map[string]*apiv1.Node)
+	for _, node := range nodes {
+		nodesByName[node.Name] = node
+	}
+	updated := make([]string, 0)
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("get", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		getAction := action.(core.GetAction)
+		if node, found := nodesByName[getAction.GetName()]; found {
+			return true, node, nil
+		}
+		return true, nil, fmt.Errorf("Wrong node: %v", getAction.GetName())
+	})
+	fakeClient.Fake.AddReactor("update", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		obj := action.(core.UpdateAction).GetObject().(*apiv1.Node)
+		nodesByName[obj.Name].Spec.Taints = obj.Spec.Taints
+		updated = append(updated, obj.Name)
+		return true, obj, nil
+	})
+	return fakeClient, func() []string {
+		result := updated
+		updated = make([]string, 0)
+		sort.Strings(result)
+		return result
+	}
+}
+
 func TestCalculateCoresAndMemoryTotal(t *testing.T) {
 	nodeConfigs := []nodeConfig{
 		{"n1", 2000, 7500 * MB, 0, true, "ng1"},
@@ -1282,6 +1496,37 @@ func TestFilterOutMasters(t *testing.T) {
 	assertEqualSet(t, []string{"n1", "n2", "n4", "n5", "n6"}, withoutMastersNames)
 }
 
+func TestScaleDownNodeResourceLimits(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.Status.Capacity["example.com/fpga"] = *resource.NewQuantity(2, resource.DecimalSI)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n2.Status.Capacity["example.com/fpga"] = *resource.NewQuantity(2, resource.DecimalSI)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	provider.AddNodeGroup("ng1", 1, 10, 3)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNode("ng1", n3)
+	ng1, _ := provider.NodeGroupForNode(n1)
+
+	options := config.AutoscalingOptions{
+		ResourceTotal: []config.ResourceLimits{{Name: "example.com/fpga", Min: 3, Max: 10}},
+	}
+	resourceLimiter := context.NewResourceLimiterFromAutoscalingOptions(options)
+
+	limits := computeScaleDownResourcesLeftLimits([]*apiv1.Node{n1, n2, n3}, resourceLimiter, provider, time.Now())
+	assert.Equal(t, scaleDownResourcesLimits{"example.com/fpga": 1}, limits)
+
+	delta, err := computeScaleDownResourcesDelta(n1, ng1, resourceLimiter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), delta["example.com/fpga"])
+	assert.Equal(t, scaleDownLimitsCheckResult{true, []string{"example.com/fpga"}}, limits.checkScaleDownDeltaWithinLimits(delta))
+
+	delta, err = computeScaleDownResourcesDelta(n3, ng1, resourceLimiter)
+	assert.NoError(t, err)
+	assert.Equal(t, scaleDownLimitsNotExceeded(), limits.checkScaleDownDeltaWithinLimits(delta))
+}
+
 func TestCheckScaleDownDeltaWithinLimits(t *testing.T) {
 	type testcase struct {
 		limits            scaleDownResourcesLimits
@@ -24,10 +24,13 @@ import (
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
 	"github.com/gardener/autoscaler/cluster-autoscaler/context"
+	"github.com/gardener/autoscaler/cluster-autoscaler/deletionhooks"
 	"github.com/gardener/autoscaler/cluster-autoscaler/expander/random"
 	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
 	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/labels"
 
 	"github.com/stretchr/testify/assert"
@@ -68,6 +71,8 @@ type scaleTestConfig struct {
 	scaleUpOptionToChoose  groupSizeChange   // this will be selected by assertingStrategy.BestOption
 	expectedFinalScaleUp   groupSizeChange   // we expect this to be delivered via scale-up event
 	expectedScaleDowns     []string
+	scaleDownOrderStrategy scaledownorder.Strategy
+	deletionHooks          *deletionhooks.Runner
 	options                config.AutoscalingOptions
 }
 
@@ -85,9 +90,16 @@ func NewScaleTestAutoscalingContext(options config.AutoscalingOptions, fakeClien
 		CloudProvider:    provider,
 		PredicateChecker: simulator.NewTestPredicateChecker(),
 		ExpanderStrategy: random.NewStrategy(),
+		Actuator:         NewDefaultActuator(fakeClient, fakeRecorder, NewTestDrainer(fakeClient, fakeRecorder, options.MaxGracefulTerminationSec), ""),
 	}
 }
 
+// NewTestDrainer creates a new Drainer for tests that sees no scheduled pods and no pod disruption budgets.
+func NewTestDrainer(fakeClient kube_client.Interface, recorder kube_record.EventRecorder, maxGracefulTerminationSec int) *Drainer {
+	return NewDrainer(fakeClient, recorder, kube_util.NewTestPodLister(nil), kube_util.NewTestPodDisruptionBudgetLister(nil),
+		maxGracefulTerminationSec)
+}
+
 type mockAutoprovisioningNodeGroupManager struct {
 	t *testing.T
 }
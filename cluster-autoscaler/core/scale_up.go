@@ -18,13 +18,15 @@ package core
 
 import (
 	"bytes"
+	ctx "context"
 	"fmt"
 	"math"
+	"reflect"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
-	extensionsv1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
 	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
 	"github.com/gardener/autoscaler/cluster-autoscaler/context"
@@ -33,13 +35,18 @@ import (
 	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "github.com/gardener/autoscaler/cluster-autoscaler/processors"
 	"github.com/gardener/autoscaler/cluster-autoscaler/processors/status"
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/tracing"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/glogx"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/gpu"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/nodegroupset"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 
 	"github.com/golang/glog"
+	"go.opencensus.io/trace"
 )
 
 type scaleUpResourcesLimits map[string]int64
@@ -49,16 +56,23 @@ type scaleUpResourcesDelta map[string]int64
 const scaleUpLimitUnknown = math.MaxInt64
 
 func computeScaleUpResourcesLeftLimits(
+	callCtx ctx.Context,
 	nodeGroups []cloudprovider.NodeGroup,
 	nodeInfos map[string]*schedulercache.NodeInfo,
 	nodesFromNotAutoscaledGroups []*apiv1.Node,
 	resourceLimiter *cloudprovider.ResourceLimiter) (scaleUpResourcesLimits, errors.AutoscalerError) {
-	totalCores, totalMem, errCoresMem := calculateScaleUpCoresMemoryTotal(nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups)
+	totalCores, totalMem, errCoresMem := calculateScaleUpCoresMemoryTotal(callCtx, nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups)
 
 	var totalGpus map[string]int64
 	var totalGpusErr error
-	if cloudprovider.ContainsGpuResources(resourceLimiter.GetResources()) {
-		totalGpus, totalGpusErr = calculateScaleUpGpusTotal(nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups)
+	if len(resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeGpu)) > 0 {
+		totalGpus, totalGpusErr = calculateScaleUpGpusTotal(callCtx, nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups)
+	}
+
+	var totalNodeResources map[string]int64
+	var totalNodeResourcesErr error
+	if nodeResources := resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeNode); len(nodeResources) > 0 {
+		totalNodeResources, totalNodeResourcesErr = calculateScaleUpNodeResourcesTotal(callCtx, nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups, nodeResources)
 	}
 
 	resultScaleUpLimits := make(scaleUpResourcesLimits)
@@ -71,28 +85,35 @@ func computeScaleUpResourcesLeftLimits(
 				// core resource info missing - no reason to proceed with scale up
 				return scaleUpResourcesLimits{}, errCoresMem
 			}
-			switch {
-			case resource == cloudprovider.ResourceNameCores:
+			switch resourceLimiter.GetResourceType(resource) {
+			case cloudprovider.ResourceTypeCores:
 				if errCoresMem != nil {
 					resultScaleUpLimits[resource] = scaleUpLimitUnknown
 				} else {
 					resultScaleUpLimits[resource] = computeBelowMax(totalCores, max)
 				}
 
-			case resource == cloudprovider.ResourceNameMemory:
+			case cloudprovider.ResourceTypeMemory:
 				if errCoresMem != nil {
 					resultScaleUpLimits[resource] = scaleUpLimitUnknown
 				} else {
 					resultScaleUpLimits[resource] = computeBelowMax(totalMem, max)
 				}
 
-			case cloudprovider.IsGpuResource(resource):
+			case cloudprovider.ResourceTypeGpu:
 				if totalGpusErr != nil {
 					resultScaleUpLimits[resource] = scaleUpLimitUnknown
 				} else {
 					resultScaleUpLimits[resource] = computeBelowMax(totalGpus[resource], max)
 				}
 
+			case cloudprovider.ResourceTypeNode:
+				if totalNodeResourcesErr != nil {
+					resultScaleUpLimits[resource] = scaleUpLimitUnknown
+				} else {
+					resultScaleUpLimits[resource] = computeBelowMax(totalNodeResources[resource], max)
+				}
+
 			default:
 				glog.Errorf("Scale up limits defined for unsupported resource '%s'", resource)
 			}
@@ -103,6 +124,7 @@ func computeScaleUpResourcesLeftLimits(
 }
 
 func calculateScaleUpCoresMemoryTotal(
+	callCtx ctx.Context,
 	nodeGroups []cloudprovider.NodeGroup,
 	nodeInfos map[string]*schedulercache.NodeInfo,
 	nodesFromNotAutoscaledGroups []*apiv1.Node) (int64, int64, errors.AutoscalerError) {
@@ -110,7 +132,7 @@ func calculateScaleUpCoresMemoryTotal(
 	var memoryTotal int64
 
 	for _, nodeGroup := range nodeGroups {
-		currentSize, err := nodeGroup.TargetSize()
+		currentSize, err := cloudprovider.NodeGroupWithContext(nodeGroup).TargetSizeWithContext(callCtx)
 		if err != nil {
 			return 0, 0, errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("Failed to get node group size of %v:", nodeGroup.Id())
 		}
@@ -135,13 +157,14 @@ func calculateScaleUpCoresMemoryTotal(
 }
 
 func calculateScaleUpGpusTotal(
+	callCtx ctx.Context,
 	nodeGroups []cloudprovider.NodeGroup,
 	nodeInfos map[string]*schedulercache.NodeInfo,
 	nodesFromNotAutoscaledGroups []*apiv1.Node) (map[string]int64, errors.AutoscalerError) {
 
 	result := make(map[string]int64)
 	for _, nodeGroup := range nodeGroups {
-		currentSize, err := nodeGroup.TargetSize()
+		currentSize, err := cloudprovider.NodeGroupWithContext(nodeGroup).TargetSizeWithContext(callCtx)
 		if err != nil {
 			return nil, errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("Failed to get node group size of %v:", nodeGroup.Id())
 		}
@@ -172,6 +195,39 @@ func calculateScaleUpGpusTotal(
 	return result, nil
 }
 
+func calculateScaleUpNodeResourcesTotal(
+	callCtx ctx.Context,
+	nodeGroups []cloudprovider.NodeGroup,
+	nodeInfos map[string]*schedulercache.NodeInfo,
+	nodesFromNotAutoscaledGroups []*apiv1.Node,
+	resources []string) (map[string]int64, errors.AutoscalerError) {
+
+	result := make(map[string]int64)
+	for _, nodeGroup := range nodeGroups {
+		currentSize, err := cloudprovider.NodeGroupWithContext(nodeGroup).TargetSizeWithContext(callCtx)
+		if err != nil {
+			return nil, errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("Failed to get node group size of %v:", nodeGroup.Id())
+		}
+		nodeInfo, found := nodeInfos[nodeGroup.Id()]
+		if !found {
+			return nil, errors.NewAutoscalerError(errors.CloudProviderError, "No node info for: %s", nodeGroup.Id())
+		}
+		if currentSize > 0 {
+			for _, resource := range resources {
+				result[resource] += int64(currentSize) * getNodeResource(nodeInfo.Node(), apiv1.ResourceName(resource))
+			}
+		}
+	}
+
+	for _, node := range nodesFromNotAutoscaledGroups {
+		for _, resource := range resources {
+			result[resource] += getNodeResource(node, apiv1.ResourceName(resource))
+		}
+	}
+
+	return result, nil
+}
+
 func computeBelowMax(total int64, max int64) int64 {
 	if total < max {
 		return max - total
@@ -186,7 +242,7 @@ func computeScaleUpResourcesDelta(nodeInfo *schedulercache.NodeInfo, nodeGroup c
 	resultScaleUpDelta[cloudprovider.ResourceNameCores] = nodeCPU
 	resultScaleUpDelta[cloudprovider.ResourceNameMemory] = nodeMemory
 
-	if cloudprovider.ContainsGpuResources(resourceLimiter.GetResources()) {
+	if len(resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeGpu)) > 0 {
 		gpuType, gpuCount, err := gpu.GetNodeTargetGpus(nodeInfo.Node(), nodeGroup)
 		if err != nil {
 			return scaleUpResourcesDelta{}, errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("Failed to get target gpu for node group %v:", nodeGroup.Id())
@@ -194,6 +250,10 @@ func computeScaleUpResourcesDelta(nodeInfo *schedulercache.NodeInfo, nodeGroup c
 		resultScaleUpDelta[gpuType] = gpuCount
 	}
 
+	for _, resource := range resourceLimiter.GetResourcesOfType(cloudprovider.ResourceTypeNode) {
+		resultScaleUpDelta[resource] = getNodeResource(nodeInfo.Node(), apiv1.ResourceName(resource))
+	}
+
 	return resultScaleUpDelta, nil
 }
 
@@ -243,9 +303,11 @@ var (
 
 // ScaleUp tries to scale the cluster up. Return true if it found a way to increase the size,
 // false if it didn't and error if an error occurred. Assumes that all nodes in the cluster are
-// ready and in sync with instance groups.
-func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.AutoscalingProcessors, clusterStateRegistry *clusterstate.ClusterStateRegistry, unschedulablePods []*apiv1.Pod,
-	nodes []*apiv1.Node, daemonSets []*extensionsv1.DaemonSet) (*status.ScaleUpStatus, errors.AutoscalerError) {
+// ready and in sync with instance groups. Node group size increases are abandoned once loopCtx is done.
+// nodeInfos are the template node infos of node groups, as returned by GetNodeInfosForGroups.
+// Scale-ups are registered in clusterStateRegistry as happening at now.
+func ScaleUp(loopCtx ctx.Context, context *context.AutoscalingContext, processors *ca_processors.AutoscalingProcessors, clusterStateRegistry *clusterstate.ClusterStateRegistry, unschedulablePods []*apiv1.Pod,
+	nodes []*apiv1.Node, nodeInfos map[string]*schedulercache.NodeInfo, now time.Time) (*status.ScaleUpStatus, errors.AutoscalerError) {
 	// From now on we only care about unschedulable pods that were marked after the newest
 	// node became available for the scheduler.
 	if len(unschedulablePods) == 0 {
@@ -253,22 +315,24 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 		return &status.ScaleUpStatus{ScaledUp: false}, nil
 	}
 
-	now := time.Now()
-
 	loggingQuota := glogx.PodsLoggingQuota()
 
 	podsRemainUnschedulable := make(map[*apiv1.Pod]map[string]status.Reasons)
 
+	uniquePods := make([]*apiv1.Pod, 0, len(unschedulablePods))
 	for _, pod := range unschedulablePods {
+		if _, repeated := podsRemainUnschedulable[pod]; repeated {
+			// This shouldn't really happen.
+			glog.Warningf("Pod %v appears multiple time on pods list, will only count it once in scale-up simulation", pod)
+			continue
+		}
 		glogx.V(1).UpTo(loggingQuota).Infof("Pod %s/%s is unschedulable", pod.Namespace, pod.Name)
 		podsRemainUnschedulable[pod] = make(map[string]status.Reasons)
+		uniquePods = append(uniquePods, pod)
 	}
 	glogx.V(1).Over(loggingQuota).Infof("%v other pods are also unschedulable", -loggingQuota.Left())
-	nodeInfos, err := GetNodeInfosForGroups(nodes, context.CloudProvider, context.ClientSet,
-		daemonSets, context.PredicateChecker)
-	if err != nil {
-		return nil, err.AddPrefix("failed to build node infos for node groups: ")
-	}
+	// Predicates and estimation run once per group of equivalent pods.
+	podEquivalenceGroups := simulator.BuildPodEquivalenceGroups(uniquePods)
 
 	nodesFromNotAutoscaledGroups, err := FilterOutNodesFromNotAutoscaledGroups(nodes, context.CloudProvider)
 	if err != nil {
@@ -284,7 +348,7 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			errCP)
 	}
 
-	scaleUpResourcesLeft, errLimits := computeScaleUpResourcesLeftLimits(nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups, resourceLimiter)
+	scaleUpResourcesLeft, errLimits := computeScaleUpResourcesLeftLimits(loopCtx, nodeGroups, nodeInfos, nodesFromNotAutoscaledGroups, resourceLimiter)
 	if errLimits != nil {
 		return nil, errLimits.AddPrefix("Could not compute total resources: ")
 	}
@@ -304,6 +368,12 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 	}
 	glog.V(4).Infof("Upcoming %d nodes", len(upcomingNodes))
 
+	volumeTopologies := getVolumeTopologies(podEquivalenceGroups, context.VolumeLister)
+	var nodeGroupZones map[string]sets.String
+	if len(volumeTopologies) > 0 {
+		nodeGroupZones = getNodeGroupZones(nodes, context.CloudProvider)
+	}
+
 	podsPassingPredicates := make(map[string][]*apiv1.Pod)
 	expansionOptions := make([]expander.Option, 0)
 
@@ -330,7 +400,7 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			continue
 		}
 
-		currentTargetSize, err := nodeGroup.TargetSize()
+		currentTargetSize, err := cloudprovider.NodeGroupWithContext(nodeGroup).TargetSizeWithContext(loopCtx)
 		if err != nil {
 			glog.Errorf("Failed to get node group size: %v", err)
 			skippedNodeGroups[nodeGroup.Id()] = notReadyReason
@@ -367,21 +437,43 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			Pods:      make([]*apiv1.Pod, 0),
 		}
 
-		schedulableOnNode := CheckPodsSchedulableOnNode(context, unschedulablePods, nodeGroup.Id(), nodeInfo)
-		for pod, err := range schedulableOnNode {
-			if err != nil {
+		// The template node of a group may be in another zone than the one new nodes are created in, so pods
+		// using zonal volumes are only checked against groups whose nodes are all in a matching zone.
+		candidateGroups := podEquivalenceGroups
+		if len(volumeTopologies) > 0 {
+			candidateGroups = make([]*simulator.PodEquivalenceGroup, 0, len(podEquivalenceGroups))
+			for _, group := range podEquivalenceGroups {
+				if topology, found := volumeTopologies[group]; found {
+					if err := topology.CheckNodeGroup(nodeInfo.Node(), nodeGroupZones[nodeGroup.Id()]); err != nil {
+						glog.V(4).Infof("Pod %s/%s can't use node group %s: %v", group.Representative().Namespace, group.Representative().Name, nodeGroup.Id(), err)
+						if nodeGroup.Exist() {
+							rejectPodGroup(podsRemainUnschedulable, group, nodeGroup.Id(), &skippedReasons{[]string{err.Error()}})
+						}
+						continue
+					}
+				}
+				candidateGroups = append(candidateGroups, group)
+			}
+		}
+
+		nodeGroupAttribute := trace.StringAttribute("node_group", nodeGroup.Id())
+		_, predicatesSpan := tracing.StartSpan(loopCtx, "CheckPodsSchedulableOnNode", nodeGroupAttribute)
+		schedulableOnNode := CheckPodGroupsSchedulableOnNode(context, candidateGroups, nodeGroup.Id(), nodeInfo)
+		tracing.EndSpan(predicatesSpan, nil)
+		schedulableGroups := make([]*simulator.PodEquivalenceGroup, 0)
+		for _, group := range candidateGroups {
+			if err := schedulableOnNode[group]; err != nil {
 				// Aggregate errors across existing node groups.
 				// TODO(aleksandra-malinowska): figure out how to communicate
 				// reasons NAP can't create a node-pool, if it's enabled.
 				if nodeGroup.Exist() {
-					if _, found := podsRemainUnschedulable[pod]; found {
-						podsRemainUnschedulable[pod][nodeGroup.Id()] = err
-					}
+					rejectPodGroup(podsRemainUnschedulable, group, nodeGroup.Id(), err)
 				}
 			} else {
-				option.Pods = append(option.Pods, pod)
+				schedulableGroups = append(schedulableGroups, group)
 			}
 		}
+		option.Pods = simulator.PodsOfGroups(schedulableGroups)
 		for _, pod := range option.Pods {
 			delete(podsRemainUnschedulable, pod)
 		}
@@ -390,9 +482,11 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 		podsPassingPredicates[nodeGroup.Id()] = passingPods
 
 		if len(option.Pods) > 0 {
+			_, estimateSpan := tracing.StartSpan(loopCtx, "Estimate", nodeGroupAttribute,
+				trace.StringAttribute("estimator", context.EstimatorName), trace.Int64Attribute("pods", int64(len(option.Pods))))
 			if context.EstimatorName == estimator.BinpackingEstimatorName {
 				binpackingEstimator := estimator.NewBinpackingNodeEstimator(context.PredicateChecker)
-				option.NodeCount = binpackingEstimator.Estimate(option.Pods, nodeInfo, upcomingNodes)
+				option.NodeCount = binpackingEstimator.EstimateGroups(schedulableGroups, nodeInfo, upcomingNodes)
 			} else if context.EstimatorName == estimator.BasicEstimatorName {
 				basicEstimator := estimator.NewBasicNodeEstimator()
 				for _, pod := range option.Pods {
@@ -402,6 +496,8 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			} else {
 				glog.Fatalf("Unrecognized estimator: %s", context.EstimatorName)
 			}
+			estimateSpan.AddAttributes(trace.Int64Attribute("node_count", int64(option.NodeCount)))
+			tracing.EndSpan(estimateSpan, nil)
 			if option.NodeCount > 0 {
 				expansionOptions = append(expansionOptions, option)
 			} else {
@@ -414,11 +510,17 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 
 	if len(expansionOptions) == 0 {
 		glog.V(1).Info("No expansion options")
+		context.AuditLog.Skip(auditlog.ScaleUpAction, "no node group can help the unschedulable pods")
 		return &status.ScaleUpStatus{ScaledUp: false, PodsRemainUnschedulable: getRemainingPods(podsRemainUnschedulable, skippedNodeGroups)}, nil
 	}
 
 	// Pick some expansion option.
+	_, expanderSpan := tracing.StartSpan(loopCtx, "BestOption", trace.Int64Attribute("options", int64(len(expansionOptions))))
 	bestOption := context.ExpanderStrategy.BestOption(expansionOptions, nodeInfos)
+	if bestOption != nil {
+		expanderSpan.AddAttributes(trace.StringAttribute("node_group", bestOption.NodeGroup.Id()))
+	}
+	tracing.EndSpan(expanderSpan, nil)
 	if bestOption != nil && bestOption.NodeCount > 0 {
 		glog.V(1).Infof("Best option to resize: %s", bestOption.NodeGroup.Id())
 		if len(bestOption.Debug) > 0 {
@@ -432,13 +534,16 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 			glog.V(1).Infof("Capping size to max cluster total size (%d)", context.MaxNodesTotal)
 			newNodes = context.MaxNodesTotal - len(nodes)
 			if newNodes < 1 {
+				context.AuditLog.Skip(auditlog.ScaleUpAction, fmt.Sprintf("max total nodes in cluster (%d) reached", context.MaxNodesTotal))
 				return nil, errors.NewAutoscalerError(
 					errors.TransientError,
 					"max node total count already reached")
 			}
 		}
 
-		if !bestOption.NodeGroup.Exist() {
+		if !bestOption.NodeGroup.Exist() && context.DryRun {
+			glog.V(0).Infof("Dry-run: node group %s would be created", bestOption.NodeGroup.Id())
+		} else if !bestOption.NodeGroup.Exist() {
 			oldId := bestOption.NodeGroup.Id()
 			bestOption.NodeGroup, err = processors.NodeGroupManager.CreateNodeGroup(context, bestOption.NodeGroup)
 			if err != nil {
@@ -505,12 +610,14 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 		}
 		glog.V(1).Infof("Final scale-up plan: %v", scaleUpInfos)
 		for _, info := range scaleUpInfos {
-			typedErr := executeScaleUp(context, clusterStateRegistry, info, gpu.GetGpuTypeForMetrics(nodeInfo.Node(), nil))
+			typedErr := executeScaleUp(loopCtx, context, clusterStateRegistry, info, gpu.GetGpuTypeForMetrics(nodeInfo.Node(), nil), now)
 			if typedErr != nil {
 				return nil, typedErr
 			}
 		}
 
+		context.AuditLog.ScaleUp(scaleUpAuditEntry(bestOption, expansionOptions, scaleUpInfos, skippedNodeGroups))
+
 		clusterStateRegistry.Recalculate()
 		return &status.ScaleUpStatus{
 				ScaledUp:                true,
@@ -524,6 +631,42 @@ func ScaleUp(context *context.AutoscalingContext, processors *ca_processors.Auto
 	return &status.ScaleUpStatus{ScaledUp: false, PodsRemainUnschedulable: getRemainingPods(podsRemainUnschedulable, skippedNodeGroups)}, nil
 }
 
+// scaleUpAuditEntry describes the scale-up for the audit log.
+func scaleUpAuditEntry(bestOption *expander.Option, options []expander.Option, scaleUpInfos []nodegroupset.ScaleUpInfo,
+	skippedNodeGroups map[string]status.Reasons) *auditlog.ScaleUp {
+	entry := &auditlog.ScaleUp{
+		Chosen:    auditScaleUpOption(*bestOption),
+		Options:   make([]auditlog.ScaleUpOption, 0, len(options)),
+		Increases: make([]auditlog.NodeGroupIncrease, 0, len(scaleUpInfos)),
+	}
+	for _, option := range options {
+		entry.Options = append(entry.Options, auditScaleUpOption(option))
+	}
+	for _, info := range scaleUpInfos {
+		entry.Increases = append(entry.Increases, auditlog.NodeGroupIncrease{
+			NodeGroup:   info.Group.Id(),
+			CurrentSize: info.CurrentSize,
+			NewSize:     info.NewSize,
+		})
+	}
+	if len(skippedNodeGroups) > 0 {
+		entry.SkippedNodeGroups = make(map[string][]string, len(skippedNodeGroups))
+		for nodeGroup, reasons := range skippedNodeGroups {
+			entry.SkippedNodeGroups[nodeGroup] = reasons.Reasons()
+		}
+	}
+	return entry
+}
+
+func auditScaleUpOption(option expander.Option) auditlog.ScaleUpOption {
+	return auditlog.ScaleUpOption{
+		NodeGroup: option.NodeGroup.Id(),
+		NodeCount: option.NodeCount,
+		Pods:      auditlog.PodNames(option.Pods),
+		Debug:     option.Debug,
+	}
+}
+
 func getRemainingPods(schedulingErrors map[*apiv1.Pod]map[string]status.Reasons, skipped map[string]status.Reasons) []status.NoScaleUpInfo {
 	remaining := []status.NoScaleUpInfo{}
 	for pod, errs := range schedulingErrors {
@@ -537,6 +680,55 @@ func getRemainingPods(schedulingErrors map[*apiv1.Pod]map[string]status.Reasons,
 	return remaining
 }
 
+// rejectPodGroup records why the pods of the group can't be helped by the node group.
+func rejectPodGroup(podsRemainUnschedulable map[*apiv1.Pod]map[string]status.Reasons, group *simulator.PodEquivalenceGroup,
+	nodeGroupId string, reasons status.Reasons) {
+	for _, pod := range group.Pods {
+		if _, found := podsRemainUnschedulable[pod]; found {
+			podsRemainUnschedulable[pod][nodeGroupId] = reasons
+		}
+	}
+}
+
+// getVolumeTopologies returns the topology of the bound volumes of the pods in each group that uses
+// volumes restricted to a part of the cluster. Equivalent pods use the same volumes.
+func getVolumeTopologies(groups []*simulator.PodEquivalenceGroup, volumeLister kube_util.VolumeLister) map[*simulator.PodEquivalenceGroup]*simulator.VolumeTopology {
+	result := make(map[*simulator.PodEquivalenceGroup]*simulator.VolumeTopology)
+	if volumeLister == nil {
+		return result
+	}
+	for _, group := range groups {
+		if topology := simulator.GetVolumeTopology(group.Representative(), volumeLister); topology != nil {
+			result[group] = topology
+		}
+	}
+	return result
+}
+
+// getNodeGroupZones returns the zones the existing nodes of each node group are in.
+func getNodeGroupZones(nodes []*apiv1.Node, cloudProvider cloudprovider.CloudProvider) map[string]sets.String {
+	result := make(map[string]sets.String)
+	for _, node := range nodes {
+		zone, found := node.Labels[kubeletapis.LabelZoneFailureDomain]
+		if !found {
+			continue
+		}
+		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			glog.Warningf("Failed to get node group for node %s: %v", node.Name, err)
+			continue
+		}
+		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			continue
+		}
+		if _, found := result[nodeGroup.Id()]; !found {
+			result[nodeGroup.Id()] = sets.NewString()
+		}
+		result[nodeGroup.Id()].Insert(zone)
+	}
+	return result
+}
+
 func getPodsAwaitingEvaluation(allPods []*apiv1.Pod, unschedulable map[*apiv1.Pod]map[string]status.Reasons, bestOption []*apiv1.Pod) []*apiv1.Pod {
 	awaitsEvaluation := make(map[*apiv1.Pod]bool, len(allPods))
 	for _, pod := range allPods {
@@ -580,25 +772,31 @@ groupsloop:
 	return result
 }
 
-func executeScaleUp(context *context.AutoscalingContext, clusterStateRegistry *clusterstate.ClusterStateRegistry, info nodegroupset.ScaleUpInfo, gpuType string) errors.AutoscalerError {
+func executeScaleUp(loopCtx ctx.Context, context *context.AutoscalingContext, clusterStateRegistry *clusterstate.ClusterStateRegistry, info nodegroupset.ScaleUpInfo, gpuType string, now time.Time) errors.AutoscalerError {
 	glog.V(0).Infof("Scale-up: setting group %s size to %d", info.Group.Id(), info.NewSize)
-	context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaledUpGroup",
-		"Scale-up: setting group %s size to %d", info.Group.Id(), info.NewSize)
+	if !context.DryRun {
+		context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaledUpGroup",
+			"Scale-up: setting group %s size to %d", info.Group.Id(), info.NewSize)
+	}
 	increase := info.NewSize - info.CurrentSize
-	if err := info.Group.IncreaseSize(increase); err != nil {
+	if err := context.Actuator.IncreaseSize(loopCtx, info.Group, increase); err != nil {
 		context.LogRecorder.Eventf(apiv1.EventTypeWarning, "FailedToScaleUpGroup", "Scale-up failed for group %s: %v", info.Group.Id(), err)
-		clusterStateRegistry.RegisterFailedScaleUp(info.Group.Id(), metrics.APIError)
+		clusterStateRegistry.RegisterFailedScaleUp(info.Group.Id(), metrics.APIError, now)
 		return errors.NewAutoscalerError(errors.CloudProviderError,
 			"failed to increase node group size: %v", err)
 	}
+	if context.DryRun {
+		// Nothing was changed, so no new nodes should be expected.
+		return nil
+	}
 	clusterStateRegistry.RegisterScaleUp(
 		&clusterstate.ScaleUpRequest{
 			NodeGroupName:   info.Group.Id(),
 			Increase:        increase,
-			Time:            time.Now(),
-			ExpectedAddTime: time.Now().Add(context.MaxNodeProvisionTime),
+			Time:            now,
+			ExpectedAddTime: now.Add(context.MaxNodeProvisionTime),
 		})
-	metrics.RegisterScaleUp(increase, gpuType)
+	metrics.RegisterScaleUp(info.Group.Id(), increase, gpuType)
 	context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ScaledUpGroup",
 		"Scale-up: group %s size set to %d", info.Group.Id(), info.NewSize)
 	return nil
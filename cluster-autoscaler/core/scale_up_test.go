@@ -17,33 +17,58 @@ limitations under the License.
 package core
 
 import (
+	"bytes"
+	ctx "context"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
 	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	testprovider "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
+	"github.com/gardener/autoscaler/cluster-autoscaler/context"
 	"github.com/gardener/autoscaler/cluster-autoscaler/estimator"
 	ca_processors "github.com/gardener/autoscaler/cluster-autoscaler/processors"
+	ca_status "github.com/gardener/autoscaler/cluster-autoscaler/processors/status"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/units"
 	kube_record "k8s.io/client-go/tools/record"
 
 	apiv1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/gardener/autoscaler/cluster-autoscaler/expander"
 )
 
+func readAuditLog(t *testing.T, buffer *bytes.Buffer) []auditlog.Entry {
+	entries := []auditlog.Entry{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry auditlog.Entry
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
 var defaultOptions = config.AutoscalingOptions{
 	EstimatorName:  estimator.BinpackingEstimatorName,
 	MaxCoresTotal:  config.DefaultMaxClusterCores,
@@ -436,7 +461,7 @@ func simpleScaleUpTest(t *testing.T, config *scaleTestConfig) {
 
 	processors := ca_processors.TestProcessors()
 
-	status, err := ScaleUp(&context, processors, clusterState, extraPods, nodes, []*extensionsv1.DaemonSet{})
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, extraPods, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
 	processors.ScaleUpStatusProcessor.Process(&context, status)
 	assert.NoError(t, err)
 	assert.True(t, status.ScaledUp)
@@ -533,7 +558,8 @@ func TestScaleUpNodeComingNoScale(t *testing.T) {
 
 	processors := ca_processors.TestProcessors()
 
-	status, err := ScaleUp(&context, processors, clusterState, []*apiv1.Pod{p3}, []*apiv1.Node{n1, n2}, []*extensionsv1.DaemonSet{})
+	nodes := []*apiv1.Node{n1, n2}
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, []*apiv1.Pod{p3}, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
 	assert.NoError(t, err)
 	// A node is already coming - no need for scale up.
 	assert.False(t, status.ScaledUp)
@@ -588,7 +614,8 @@ func TestScaleUpNodeComingHasScale(t *testing.T) {
 	p4 := BuildTestPod("p-new", 550, 0)
 
 	processors := ca_processors.TestProcessors()
-	status, err := ScaleUp(&context, processors, clusterState, []*apiv1.Pod{p3, p4}, []*apiv1.Node{n1, n2}, []*extensionsv1.DaemonSet{})
+	nodes := []*apiv1.Node{n1, n2}
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, []*apiv1.Pod{p3, p4}, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
 
 	assert.NoError(t, err)
 	// Two nodes needed but one node is already coming, so it should increase by one.
@@ -641,13 +668,112 @@ func TestScaleUpUnhealthy(t *testing.T) {
 	p3 := BuildTestPod("p-new", 550, 0)
 
 	processors := ca_processors.TestProcessors()
-	status, err := ScaleUp(&context, processors, clusterState, []*apiv1.Pod{p3}, []*apiv1.Node{n1, n2}, []*extensionsv1.DaemonSet{})
+	nodes := []*apiv1.Node{n1, n2}
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, []*apiv1.Pod{p3}, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
 
 	assert.NoError(t, err)
 	// Node group is unhealthy.
 	assert.False(t, status.ScaledUp)
 }
 
+func TestScaleUpDryRun(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Now())
+	p1 := BuildTestPod("p1", 800, 0)
+	p1.Spec.NodeName = "n1"
+
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &apiv1.PodList{Items: []apiv1.Pod{*p1}}, nil
+	})
+
+	provider := testprovider.NewTestCloudProvider(func(nodeGroup string, increase int) error {
+		t.Fatalf("No expansion is expected in dry-run mode, but increased %s by %d", nodeGroup, increase)
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+
+	options := config.AutoscalingOptions{
+		EstimatorName:  estimator.BinpackingEstimatorName,
+		MaxCoresTotal:  config.DefaultMaxClusterCores,
+		MaxMemoryTotal: config.DefaultMaxClusterMemory,
+		DryRun:         true,
+	}
+	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+	dryRunActuator := actuation.NewDryRunActuator(context.Recorder, context.LogRecorder)
+	context.Actuator = dryRunActuator
+	var auditBuffer bytes.Buffer
+	context.AuditLog = auditlog.NewWriterLog(&auditBuffer, true)
+
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	clusterState.UpdateNodes([]*apiv1.Node{n1}, time.Now())
+	p2 := BuildTestPod("p-new", 500, 0)
+
+	processors := ca_processors.TestProcessors()
+	nodes := []*apiv1.Node{n1}
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, []*apiv1.Pod{p2}, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
+
+	assert.NoError(t, err)
+	assert.True(t, status.ScaledUp)
+	assert.False(t, clusterState.IsNodeGroupScalingUp("ng1"))
+	actions := dryRunActuator.GetActions()
+	assert.Equal(t, 1, len(actions))
+	assert.Equal(t, actuation.IncreaseSizeAction, actions[0].Type)
+	assert.Equal(t, "ng1", actions[0].NodeGroup)
+	assert.Equal(t, 1, actions[0].Delta)
+
+	entries := readAuditLog(t, &auditBuffer)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, auditlog.ScaleUpDecision, entries[0].Decision)
+	assert.True(t, entries[0].DryRun)
+	assert.Equal(t, auditlog.ScaleUpOption{NodeGroup: "ng1", NodeCount: 1, Pods: []string{"default/p-new"}}, entries[0].ScaleUp.Chosen)
+	assert.Equal(t, []auditlog.ScaleUpOption{entries[0].ScaleUp.Chosen}, entries[0].ScaleUp.Options)
+	assert.Equal(t, []auditlog.NodeGroupIncrease{{NodeGroup: "ng1", CurrentSize: 1, NewSize: 2}}, entries[0].ScaleUp.Increases)
+}
+
+func TestScaleUpRegistersScaleUpAtGivenTime(t *testing.T) {
+	now := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, now.Add(-time.Hour))
+	p1 := BuildTestPod("p1", 800, 0)
+	p1.Spec.NodeName = "n1"
+
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &apiv1.PodList{Items: []apiv1.Pod{*p1}}, nil
+	})
+
+	provider := testprovider.NewTestCloudProvider(func(nodeGroup string, increase int) error {
+		return nil
+	}, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNode("ng1", n1)
+
+	options := config.AutoscalingOptions{
+		EstimatorName:        estimator.BinpackingEstimatorName,
+		MaxCoresTotal:        config.DefaultMaxClusterCores,
+		MaxMemoryTotal:       config.DefaultMaxClusterMemory,
+		MaxNodeProvisionTime: 15 * time.Minute,
+	}
+	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	clusterState.UpdateNodes([]*apiv1.Node{n1}, now)
+	p2 := BuildTestPod("p-new", 500, 0)
+
+	processors := ca_processors.TestProcessors()
+	nodes := []*apiv1.Node{n1}
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, []*apiv1.Pod{p2}, nodes, getTestNodeInfos(t, &context, nodes), now)
+
+	assert.NoError(t, err)
+	assert.True(t, status.ScaledUp)
+	history := clusterState.GetScaleUpHistory()
+	assert.Equal(t, 1, len(history))
+	assert.Equal(t, now, history[0].Time)
+	assert.Equal(t, now.Add(15*time.Minute), history[0].ExpectedAddTime)
+}
+
 func TestScaleUpNoHelp(t *testing.T) {
 	fakeClient := &fake.Clientset{}
 	n1 := BuildTestNode("n1", 100, 1000)
@@ -679,13 +805,16 @@ func TestScaleUpNoHelp(t *testing.T) {
 		MaxMemoryTotal: config.DefaultMaxClusterMemory,
 	}
 	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+	var auditBuffer bytes.Buffer
+	context.AuditLog = auditlog.NewWriterLog(&auditBuffer, false)
 
 	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
 	clusterState.UpdateNodes([]*apiv1.Node{n1}, time.Now())
 	p3 := BuildTestPod("p-new", 500, 0)
 
 	processors := ca_processors.TestProcessors()
-	status, err := ScaleUp(&context, processors, clusterState, []*apiv1.Pod{p3}, []*apiv1.Node{n1}, []*extensionsv1.DaemonSet{})
+	nodes := []*apiv1.Node{n1}
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, []*apiv1.Pod{p3}, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
 	processors.ScaleUpStatusProcessor.Process(&context, status)
 
 	assert.NoError(t, err)
@@ -697,6 +826,15 @@ func TestScaleUpNoHelp(t *testing.T) {
 		t.Fatal("No Event recorded, expected NotTriggerScaleUp event")
 	}
 	assert.Regexp(t, regexp.MustCompile("NotTriggerScaleUp"), event)
+
+	entries := readAuditLog(t, &auditBuffer)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, auditlog.SkippedDecision, entries[0].Decision)
+	assert.Equal(t, auditlog.ScaleUpAction, entries[0].Skipped.Action)
+	assert.Equal(t, auditlog.NoScaleUpDecision, entries[1].Decision)
+	assert.Equal(t, 1, len(entries[1].NoScaleUp))
+	assert.Equal(t, "default/p-new", entries[1].NoScaleUp[0].Pod)
+	assert.Contains(t, entries[1].NoScaleUp[0].Reasons, "Insufficient cpu")
 }
 
 func TestScaleUpBalanceGroups(t *testing.T) {
@@ -764,7 +902,7 @@ func TestScaleUpBalanceGroups(t *testing.T) {
 	}
 
 	processors := ca_processors.TestProcessors()
-	status, typedErr := ScaleUp(&context, processors, clusterState, pods, nodes, []*extensionsv1.DaemonSet{})
+	status, typedErr := ScaleUp(ctx.Background(), &context, processors, clusterState, pods, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
 
 	assert.NoError(t, typedErr)
 	assert.True(t, status.ScaledUp)
@@ -781,6 +919,156 @@ func TestScaleUpBalanceGroups(t *testing.T) {
 	assert.Equal(t, 2, ng3size)
 }
 
+func TestScaleUpVolumeZone(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &apiv1.PodList{Items: []apiv1.Pod{}}, nil
+	})
+	provider := testprovider.NewTestCloudProvider(func(string, int) error {
+		return nil
+	}, nil)
+
+	// Similar node groups: ng1 in zone a, ng2 in zone b and ng3 in both.
+	nodeZones := map[string][]string{
+		"ng1": {"a"},
+		"ng2": {"b"},
+		"ng3": {"a", "b"},
+	}
+	nodes := make([]*apiv1.Node, 0)
+	for _, gid := range []string{"ng1", "ng2", "ng3"} {
+		provider.AddNodeGroup(gid, 1, 10, len(nodeZones[gid]))
+		for i, zone := range nodeZones[gid] {
+			node := BuildTestNode(fmt.Sprintf("%v-node-%v", gid, i), 100, 1000)
+			node.Labels[kubeletapis.LabelZoneFailureDomain] = zone
+			SetNodeReadyState(node, true, time.Now())
+			nodes = append(nodes, node)
+			provider.AddNode(gid, node)
+		}
+	}
+
+	pvcs := []*apiv1.PersistentVolumeClaim{}
+	pvs := []*apiv1.PersistentVolume{}
+	pods := []*apiv1.Pod{}
+	for i, zone := range []string{"a", "a", "c"} {
+		name := fmt.Sprintf("data-%v", i)
+		pvcs = append(pvcs, &apiv1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       apiv1.PersistentVolumeClaimSpec{VolumeName: name},
+		})
+		pvs = append(pvs, &apiv1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: zone}},
+		})
+		pod := BuildTestPod(fmt.Sprintf("p-%v", zone), 80, 0)
+		pod.Name = fmt.Sprintf("p-%v-%v", zone, i)
+		pod.Spec.Volumes = []apiv1.Volume{{
+			Name:         "data",
+			VolumeSource: apiv1.VolumeSource{PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: name}},
+		}}
+		pods = append(pods, pod)
+	}
+
+	options := defaultOptions
+	options.BalanceSimilarNodeGroups = true
+	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+	context.VolumeLister = kube_util.NewTestVolumeLister(pvcs, pvs, nil)
+
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	clusterState.UpdateNodes(nodes, time.Now())
+
+	processors := ca_processors.TestProcessors()
+	status, typedErr := ScaleUp(ctx.Background(), &context, processors, clusterState, pods, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
+	assert.NoError(t, typedErr)
+	assert.True(t, status.ScaledUp)
+
+	// Only the node group in zone a is scaled up, not the similar ones that may create nodes in zone b.
+	assert.Equal(t, 1, len(status.ScaleUpInfos))
+	assert.Equal(t, "ng1", status.ScaleUpInfos[0].Group.Id())
+	assert.Equal(t, 3, status.ScaleUpInfos[0].NewSize)
+	assert.Equal(t, 2, len(status.PodsTriggeredScaleUp))
+
+	// There is no node group in zone c.
+	assert.Equal(t, 1, len(status.PodsRemainUnschedulable))
+	noScaleUp := status.PodsRemainUnschedulable[0]
+	assert.Equal(t, "p-c-2", noScaleUp.Pod.Name)
+	assert.Equal(t, []string{"volume zone conflict (bound volumes are in zone c)"}, noScaleUp.RejectedNodeGroups["ng1"].Reasons())
+	assert.Equal(t, []string{"volume zone conflict (bound volumes are in zone c)"}, noScaleUp.RejectedNodeGroups["ng2"].Reasons())
+	assert.Equal(t, []string{"volume zone conflict (bound volumes are in zone c)"}, noScaleUp.RejectedNodeGroups["ng3"].Reasons())
+	assert.Equal(t, "3 volume zone conflict (bound volumes are in zone c)", ca_status.ReasonsMessage(noScaleUp))
+}
+
+func TestScaleUpDelayedBindingVolumeZone(t *testing.T) {
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &apiv1.PodList{Items: []apiv1.Pod{}}, nil
+	})
+	provider := testprovider.NewTestCloudProvider(func(string, int) error {
+		return nil
+	}, nil)
+
+	nodes := make([]*apiv1.Node, 0)
+	for _, zone := range []string{"a", "b"} {
+		gid := "ng-" + zone
+		provider.AddNodeGroup(gid, 1, 10, 1)
+		node := BuildTestNode(gid+"-node", 100, 1000)
+		node.Labels[kubeletapis.LabelZoneFailureDomain] = zone
+		SetNodeReadyState(node, true, time.Now())
+		nodes = append(nodes, node)
+		provider.AddNode(gid, node)
+	}
+
+	delayed := storagev1.VolumeBindingWaitForFirstConsumer
+	classes := []*storagev1.StorageClass{}
+	pvcs := []*apiv1.PersistentVolumeClaim{}
+	pods := []*apiv1.Pod{}
+	for _, zone := range []string{"b", "c"} {
+		className := "zone-" + zone
+		classes = append(classes, &storagev1.StorageClass{
+			ObjectMeta:        metav1.ObjectMeta{Name: className},
+			Provisioner:       "kubernetes.io/aws-ebs",
+			VolumeBindingMode: &delayed,
+			AllowedTopologies: []apiv1.TopologySelectorTerm{{
+				MatchLabelExpressions: []apiv1.TopologySelectorLabelRequirement{{
+					Key:    kubeletapis.LabelZoneFailureDomain,
+					Values: []string{zone},
+				}},
+			}},
+		})
+		pvcs = append(pvcs, &apiv1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data-" + zone},
+			Spec:       apiv1.PersistentVolumeClaimSpec{StorageClassName: &className},
+		})
+		pod := BuildTestPod("p-"+zone, 80, 0)
+		pod.Spec.Volumes = []apiv1.Volume{{
+			Name:         "data",
+			VolumeSource: apiv1.VolumeSource{PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: "data-" + zone}},
+		}}
+		pods = append(pods, pod)
+	}
+
+	options := defaultOptions
+	options.BalanceSimilarNodeGroups = true
+	context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+	context.VolumeLister = kube_util.NewTestVolumeLister(pvcs, nil, classes)
+
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	clusterState.UpdateNodes(nodes, time.Now())
+
+	processors := ca_processors.TestProcessors()
+	status, typedErr := ScaleUp(ctx.Background(), &context, processors, clusterState, pods, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
+	assert.NoError(t, typedErr)
+	assert.True(t, status.ScaledUp)
+
+	// The claim can only be provisioned in zone b.
+	assert.Equal(t, 1, len(status.ScaleUpInfos))
+	assert.Equal(t, "ng-b", status.ScaleUpInfos[0].Group.Id())
+	assert.Equal(t, 2, status.ScaleUpInfos[0].NewSize)
+
+	assert.Equal(t, 1, len(status.PodsRemainUnschedulable))
+	noScaleUp := status.PodsRemainUnschedulable[0]
+	assert.Equal(t, "p-c", noScaleUp.Pod.Name)
+	assert.Equal(t, "2 storage class topology conflict (storage class zone-c)", ca_status.ReasonsMessage(noScaleUp))
+}
+
 func TestScaleUpAutoprovisionedNodeGroup(t *testing.T) {
 	createdGroups := make(chan string, 10)
 	expandedGroups := make(chan string, 10)
@@ -818,13 +1106,62 @@ func TestScaleUpAutoprovisionedNodeGroup(t *testing.T) {
 	processors.NodeGroupListProcessor = &mockAutoprovisioningNodeGroupListProcessor{t}
 	processors.NodeGroupManager = &mockAutoprovisioningNodeGroupManager{t}
 
-	status, err := ScaleUp(&context, processors, clusterState, []*apiv1.Pod{p1}, []*apiv1.Node{}, []*extensionsv1.DaemonSet{})
+	nodes := []*apiv1.Node{}
+	status, err := ScaleUp(ctx.Background(), &context, processors, clusterState, []*apiv1.Pod{p1}, nodes, getTestNodeInfos(t, &context, nodes), time.Now())
 	assert.NoError(t, err)
 	assert.True(t, status.ScaledUp)
 	assert.Equal(t, "autoprovisioned-T1", getStringFromChan(createdGroups))
 	assert.Equal(t, "autoprovisioned-T1-1", getStringFromChan(expandedGroups))
 }
 
+func getTestNodeInfos(t *testing.T, autoscalingContext *context.AutoscalingContext, nodes []*apiv1.Node) map[string]*schedulercache.NodeInfo {
+	nodeInfos, err := GetNodeInfosForGroups(nodes, autoscalingContext.CloudProvider, autoscalingContext.ClientSet, []*extensionsv1.DaemonSet{}, autoscalingContext.PredicateChecker)
+	assert.NoError(t, err)
+	return nodeInfos
+}
+
+func TestScaleUpNodeResourceLimits(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.Status.Capacity["example.com/fpga"] = *resource.NewQuantity(2, resource.DecimalSI)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n2.Status.Capacity["example.com/fpga"] = *resource.NewQuantity(2, resource.DecimalSI)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	n4 := BuildTestNode("n4", 1000, 1000)
+	n4.Status.Capacity["example.com/fpga"] = *resource.NewQuantity(1, resource.DecimalSI)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	provider.AddNode("ng2", n3)
+	ng1, _ := provider.NodeGroupForNode(n1)
+	ng2, _ := provider.NodeGroupForNode(n3)
+
+	nodeInfos := make(map[string]*schedulercache.NodeInfo)
+	for id, node := range map[string]*apiv1.Node{"ng1": n1, "ng2": n3} {
+		nodeInfos[id] = schedulercache.NewNodeInfo()
+		nodeInfos[id].SetNode(node)
+	}
+
+	resourceLimiter := cloudprovider.NewResourceLimiterWithTypes(
+		map[string]int64{},
+		map[string]int64{"example.com/fpga": 6},
+		map[string]cloudprovider.ResourceType{"example.com/fpga": cloudprovider.ResourceTypeNode})
+
+	limits, err := computeScaleUpResourcesLeftLimits(ctx.Background(), []cloudprovider.NodeGroup{ng1, ng2}, nodeInfos, []*apiv1.Node{n4}, resourceLimiter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), limits["example.com/fpga"])
+
+	delta, err := computeScaleUpResourcesDelta(nodeInfos["ng1"], ng1, resourceLimiter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), delta["example.com/fpga"])
+	assert.Equal(t, scaleUpLimitsCheckResult{true, []string{"example.com/fpga"}}, limits.checkScaleUpDeltaWithinLimits(delta))
+
+	delta, err = computeScaleUpResourcesDelta(nodeInfos["ng2"], ng2, resourceLimiter)
+	assert.NoError(t, err)
+	assert.Equal(t, scaleUpLimitsNotExceeded(), limits.checkScaleUpDeltaWithinLimits(delta))
+}
+
 func TestCheckScaleUpDeltaWithinLimits(t *testing.T) {
 	type testcase struct {
 		limits            scaleUpResourcesLimits
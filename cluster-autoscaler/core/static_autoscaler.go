@@ -17,27 +17,44 @@ limitations under the License.
 package core
 
 import (
+	ctx "context"
+	"fmt"
 	"time"
 
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
+	"github.com/gardener/autoscaler/cluster-autoscaler/checkpoint"
 	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
 	"github.com/gardener/autoscaler/cluster-autoscaler/context"
+	"github.com/gardener/autoscaler/cluster-autoscaler/debugging"
+	"github.com/gardener/autoscaler/cluster-autoscaler/deletionhooks"
 	"github.com/gardener/autoscaler/cluster-autoscaler/expander"
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
 	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "github.com/gardener/autoscaler/cluster-autoscaler/processors"
+	"github.com/gardener/autoscaler/cluster-autoscaler/processors/status"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
 	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/tracing"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/gpu"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/tpu"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/golang/glog"
+	"go.opencensus.io/trace"
 )
 
 const (
+	// How often StopInFlightActions checks whether node deletions are still in progress.
+	inFlightActionsPollInterval = 100 * time.Millisecond
+	// How long StopInFlightActions waits for aborted node deletions to clean up.
+	abortedActionsCleanUpTimeout = 30 * time.Second
 	// How old the oldest unschedulable pod should be before starting scale up.
 	unschedulablePodTimeBuffer = 2 * time.Second
 	// How old the oldest unschedulable pod with GPU should be before starting scale up.
@@ -60,13 +77,22 @@ type StaticAutoscaler struct {
 	lastScaleDownFailTime   time.Time
 	scaleDown               *ScaleDown
 	processors              *ca_processors.AutoscalingProcessors
+	loopRecorder            looprecorder.Recorder
+	debuggingServer         *debugging.Server
+	lastScaleUpStatus       *status.ScaleUpStatus
+	lastScaleUpAttemptTime  time.Time
+	stateStore              checkpoint.Store
+	stateRestored           bool
+	lastCheckpointTime      time.Time
 	initialized             bool
+	pendingPods             *pendingPodsTracker
 }
 
 // NewStaticAutoscaler creates an instance of Autoscaler filled with provided parameters
 func NewStaticAutoscaler(opts config.AutoscalingOptions, predicateChecker *simulator.PredicateChecker,
-	autoscalingKubeClients *context.AutoscalingKubeClients, processors *ca_processors.AutoscalingProcessors, cloudProvider cloudprovider.CloudProvider, expanderStrategy expander.Strategy) *StaticAutoscaler {
-	autoscalingContext := context.NewAutoscalingContext(opts, predicateChecker, autoscalingKubeClients, cloudProvider, expanderStrategy)
+	autoscalingKubeClients *context.AutoscalingKubeClients, processors *ca_processors.AutoscalingProcessors, cloudProvider cloudprovider.CloudProvider, expanderStrategy expander.Strategy,
+	scaleDownOrderStrategy scaledownorder.Strategy, actuator actuation.Actuator, deletionHooks *deletionhooks.Runner, auditLog *auditlog.Log, loopRecorder looprecorder.Recorder, debuggingServer *debugging.Server, stateStore checkpoint.Store) *StaticAutoscaler {
+	autoscalingContext := context.NewAutoscalingContext(opts, predicateChecker, autoscalingKubeClients, cloudProvider, expanderStrategy, scaleDownOrderStrategy, actuator, deletionHooks, auditLog)
 	clusterStateConfig := clusterstate.ClusterStateRegistryConfig{
 		MaxTotalUnreadyPercentage: opts.MaxTotalUnreadyPercentage,
 		OkTotalUnreadyCount:       opts.OkTotalUnreadyCount,
@@ -76,19 +102,24 @@ func NewStaticAutoscaler(opts config.AutoscalingOptions, predicateChecker *simul
 
 	scaleDown := NewScaleDown(autoscalingContext, clusterStateRegistry)
 
+	startTime := time.Now()
 	return &StaticAutoscaler{
 		AutoscalingContext:      autoscalingContext,
-		startTime:               time.Now(),
-		lastScaleUpTime:         time.Now(),
-		lastScaleDownDeleteTime: time.Now(),
-		lastScaleDownFailTime:   time.Now(),
+		startTime:               startTime,
+		lastScaleUpTime:         startTime,
+		lastScaleDownDeleteTime: startTime,
+		lastScaleDownFailTime:   startTime,
 		scaleDown:               scaleDown,
 		processors:              processors,
 		clusterStateRegistry:    clusterStateRegistry,
+		loopRecorder:            loopRecorder,
+		debuggingServer:         debuggingServer,
+		stateStore:              stateStore,
+		pendingPods:             newPendingPodsTracker(),
 	}
 }
 
-// cleanUpIfRequired removes ToBeDeleted taints added by a previous run of CA
+// cleanUpIfRequired removes ToBeDeleted and DeletionCandidate taints added by a previous run of CA
 // the taints are removed only once per runtime
 func (a *StaticAutoscaler) cleanUpIfRequired() {
 	if a.initialized {
@@ -99,13 +130,82 @@ func (a *StaticAutoscaler) cleanUpIfRequired() {
 	if readyNodes, err := a.ReadyNodeLister().List(); err != nil {
 		glog.Errorf("Failed to list ready nodes, not cleaning up taints: %v", err)
 	} else {
-		cleanToBeDeleted(readyNodes, a.AutoscalingContext.ClientSet, a.Recorder)
+		cleanToBeDeleted(readyNodes, a.Actuator, a.Recorder)
+		cleanDeletionCandidates(readyNodes, a.Actuator, a.Recorder)
 	}
 	a.initialized = true
 }
 
-// RunOnce iterates over node groups and scales them up/down if necessary
-func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError {
+// RunOnce iterates over node groups and scales them up/down if necessary. No new scaling actions
+// are started once runCtx is done.
+func (a *StaticAutoscaler) RunOnce(runCtx ctx.Context, currentTime time.Time) errors.AutoscalerError {
+	var record *looprecorder.Record
+	if a.loopRecorder != nil {
+		record = looprecorder.NewRecord(currentTime)
+		if pdbs, err := a.PodDisruptionBudgetLister().List(); err == nil {
+			record.SetPodDisruptionBudgets(pdbs)
+		}
+		if daemonsets, err := a.DaemonSetLister().List(); err == nil {
+			record.SetDaemonSets(daemonsets)
+		}
+	}
+	traceCtx, span := tracing.StartSpan(runCtx, "RunOnce")
+	typedErr := a.runOnce(traceCtx, currentTime, record)
+	tracing.EndSpan(span, typedErr)
+	if record != nil {
+		if typedErr != nil {
+			record.SetError(typedErr)
+		}
+		looprecorder.WriteOrLog(a.loopRecorder, record)
+	}
+	if a.debuggingServer != nil && a.debuggingServer.StateRequested() {
+		a.debuggingServer.Update(a.getDebuggingState(currentTime))
+	}
+	return typedErr
+}
+
+// getDebuggingState takes a snapshot of the internal state exposed by the debugging server.
+// It is only called when a debugging request is waiting for it.
+func (a *StaticAutoscaler) getDebuggingState(currentTime time.Time) *debugging.State {
+	state := &debugging.State{
+		Time:         currentTime,
+		ClusterState: a.clusterStateRegistry.GetDebugState(currentTime),
+		ScaleDown:    a.scaleDown.GetDebugState(),
+		UsageTracker: a.scaleDown.usageTracker.GetUsageInfos(),
+	}
+	if a.lastScaleUpStatus != nil {
+		lastScaleUpAttemptTime := a.lastScaleUpAttemptTime
+		state.LastScaleUpStatus = looprecorder.NewScaleUpStatus(a.lastScaleUpStatus)
+		state.LastScaleUpTime = &lastScaleUpAttemptTime
+	}
+
+	readyNodes, err := a.ReadyNodeLister().List()
+	if err != nil {
+		glog.Warningf("Failed to list ready nodes for debugging state: %v", err)
+		return state
+	}
+	daemonsets, err := a.DaemonSetLister().List()
+	if err != nil {
+		glog.Warningf("Failed to list daemonsets for debugging state: %v", err)
+		return state
+	}
+	nodeInfos, typedErr := GetNodeInfosForGroups(readyNodes, a.CloudProvider, a.ClientSet, daemonsets, a.PredicateChecker)
+	if typedErr != nil {
+		glog.Warningf("Failed to get template node infos for debugging state: %v", typedErr)
+		return state
+	}
+	state.TemplateNodeInfos = looprecorder.NewNodeInfos(nodeInfos)
+	return state
+}
+
+// runOnce performs a single iteration of the control loop. Inputs and decisions
+// are stored in the record, which is nil if recording is disabled.
+func (a *StaticAutoscaler) runOnce(runCtx ctx.Context, currentTime time.Time, record *looprecorder.Record) errors.AutoscalerError {
+	if stopping(runCtx, "loop") {
+		return nil
+	}
+	loopCtx, cancel := withLoopDeadline(runCtx, a.LoopDeadline)
+	defer cancel()
 	a.cleanUpIfRequired()
 
 	unschedulablePodLister := a.UnschedulablePodLister()
@@ -121,36 +221,57 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 	if typedErr != nil {
 		return typedErr
 	}
+	record.SetNodes(allNodes)
+	a.restoreStateIfRequired(allNodes, currentTime)
 	if a.actOnEmptyCluster(allNodes, readyNodes, currentTime) {
 		return nil
 	}
 
-	typedErr = a.updateClusterState(allNodes, currentTime)
+	stateCtx, stateSpan := tracing.StartSpan(loopCtx, "updateClusterState")
+	typedErr = a.updateClusterState(stateCtx, allNodes, currentTime)
+	tracing.EndSpan(stateSpan, typedErr)
 	if typedErr != nil {
 		return typedErr
 	}
+	if err := record.SetNodeGroups(a.CloudProvider); err != nil {
+		glog.Warningf("Failed to record node groups: %v", err)
+	}
+	record.SetUpcomingNodes(a.clusterStateRegistry.GetUpcomingNodes())
 	metrics.UpdateDurationFromStart(metrics.UpdateState, stateUpdateStart)
 
 	defer func() {
-		// Update status information when the loop is done (regardless of reason)
-		if autoscalingContext.WriteStatusConfigMap {
+		// Update per-node-group metrics and status information when the loop is done (regardless of reason)
+		metrics.UpdateNodeGroups(a.clusterStateRegistry.GetNodeGroupStates(currentTime))
+		if autoscalingContext.WriteStatusConfigMap || autoscalingContext.WriteStatusResource {
 			status := a.clusterStateRegistry.GetStatus(currentTime)
-			utils.WriteStatusConfigMap(autoscalingContext.ClientSet, autoscalingContext.ConfigNamespace,
-				status.GetReadableString(), a.AutoscalingContext.LogRecorder)
+			status.UnremovableNodesCount = a.scaleDown.GetUnremovableNodesCount()
+			if autoscalingContext.WriteStatusConfigMap {
+				utils.WriteStatusConfigMap(autoscalingContext.ClientSet, autoscalingContext.ConfigNamespace,
+					status.GetReadableString(), a.AutoscalingContext.LogRecorder)
+			}
+			if autoscalingContext.WriteStatusResource && autoscalingContext.StatusClient != nil {
+				resourceStatus := a.clusterStateRegistry.GetAutoscalerStatus(status)
+				resourceStatus.UnremovableNodes = a.scaleDown.GetUnremovableNodes()
+				utils.WriteStatusResource(autoscalingContext.StatusClient, autoscalingContext.ConfigNamespace, resourceStatus)
+			}
 		}
 
 		err := a.processors.AutoscalingStatusProcessor.Process(a.AutoscalingContext, a.clusterStateRegistry, currentTime)
 		if err != nil {
 			glog.Errorf("AutoscalingStatusProcessor error: %v.", err)
 		}
+
+		if currentTime.Sub(a.lastCheckpointTime) >= a.ScaleDownStateCheckpointInterval {
+			a.saveState(currentTime)
+		}
 	}()
 
 	// Check if there are any nodes that failed to register in Kubernetes
 	// master.
 	unregisteredNodes := a.clusterStateRegistry.GetUnregisteredNodes()
-	if len(unregisteredNodes) > 0 {
+	if len(unregisteredNodes) > 0 && !stopping(runCtx, "removal of unregistered nodes") {
 		glog.V(1).Infof("%d unregistered nodes present", len(unregisteredNodes))
-		removedAny, err := removeOldUnregisteredNodes(unregisteredNodes, autoscalingContext, currentTime, autoscalingContext.LogRecorder)
+		removedAny, err := removeOldUnregisteredNodes(loopCtx, unregisteredNodes, autoscalingContext, currentTime, autoscalingContext.LogRecorder)
 		// There was a problem with removing unregistered nodes. Retry in the next loop.
 		if err != nil {
 			if removedAny {
@@ -162,13 +283,16 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 			return errors.ToAutoscalerError(errors.CloudProviderError, err)
 		}
 		// Some nodes were removed. Let's skip this iteration, the next one should be better.
-		if removedAny {
+		// In dry-run mode nothing was actually removed, so there is no point in waiting.
+		if removedAny && !autoscalingContext.DryRun {
 			glog.V(0).Infof("Some unregistered nodes were removed, skipping iteration")
 			return nil
 		}
 	}
 	if !a.clusterStateRegistry.IsClusterHealthy() {
 		glog.Warning("Cluster is not ready for autoscaling")
+		a.AuditLog.Skip(auditlog.ScaleUpAction, "cluster is unhealthy")
+		a.AuditLog.Skip(auditlog.ScaleDownAction, "cluster is unhealthy")
 		scaleDown.CleanUpUnneededNodes()
 		autoscalingContext.LogRecorder.Eventf(apiv1.EventTypeWarning, "ClusterUnhealthy", "Cluster is unhealthy")
 		return nil
@@ -177,12 +301,15 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 	// Check if there has been a constant difference between the number of nodes in k8s and
 	// the number of nodes on the cloud provider side.
 	// TODO: andrewskim - add protection for ready AWS nodes.
-	fixedSomething, err := fixNodeGroupSize(autoscalingContext, a.clusterStateRegistry, currentTime)
+	if stopping(runCtx, "scaling") {
+		return nil
+	}
+	fixedSomething, err := fixNodeGroupSize(loopCtx, autoscalingContext, a.clusterStateRegistry, currentTime)
 	if err != nil {
 		glog.Errorf("Failed to fix node group sizes: %v", err)
 		return errors.ToAutoscalerError(errors.CloudProviderError, err)
 	}
-	if fixedSomething {
+	if fixedSomething && !autoscalingContext.DryRun {
 		glog.V(0).Infof("Some node group target size was fixed, skipping the iteration")
 		return nil
 	}
@@ -201,12 +328,14 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		glog.Errorf("Failed to list scheduled pods: %v", err)
 		return errors.ToAutoscalerError(errors.ApiCallError, err)
 	}
+	a.pendingPods.Update(allUnschedulablePods, allScheduled, currentTime)
 
 	allUnschedulablePods, allScheduled, err = a.processors.PodListProcessor.Process(a.AutoscalingContext, allUnschedulablePods, allScheduled, allNodes)
 	if err != nil {
 		glog.Errorf("Failed to process pod list: %v", err)
 		return errors.ToAutoscalerError(errors.InternalError, err)
 	}
+	record.SetPods(allScheduled, allUnschedulablePods)
 
 	ConfigurePredicateCheckerForLoop(allUnschedulablePods, allScheduled, a.PredicateChecker)
 
@@ -235,8 +364,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 
 	glog.V(4).Infof("Filtering out schedulables")
 	filterOutSchedulableStart := time.Now()
+	_, filterSpan := tracing.StartSpan(loopCtx, "FilterOutSchedulable", trace.Int64Attribute("pods", int64(len(unschedulablePods))))
 	unschedulablePodsToHelp := FilterOutSchedulable(unschedulablePods, readyNodes, allScheduled,
-		unschedulableWaitingForLowerPriorityPreemption, a.PredicateChecker, a.ExpendablePodsPriorityCutoff)
+		unschedulableWaitingForLowerPriorityPreemption, a.PredicateChecker, a.ExpendablePodsPriorityCutoff, a.SimulationParallelism)
+	tracing.EndSpan(filterSpan, nil)
 	metrics.UpdateDurationFromStart(metrics.FilterOutSchedulable, filterOutSchedulableStart)
 
 	if len(unschedulablePodsToHelp) != len(unschedulablePods) {
@@ -250,6 +381,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		glog.V(1).Info("No unschedulable pods")
 	} else if a.MaxNodesTotal > 0 && len(readyNodes) >= a.MaxNodesTotal {
 		glog.V(1).Info("Max total nodes in cluster reached")
+		a.AuditLog.Skip(auditlog.ScaleUpAction, fmt.Sprintf("max total nodes in cluster (%d) reached", a.MaxNodesTotal))
 	} else if allPodsAreNew(unschedulablePodsToHelp, currentTime) {
 		// The assumption here is that these pods have been created very recently and probably there
 		// is more pods to come. In theory we could check the newest pod time but then if pod were created
@@ -257,6 +389,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		// We also want to skip a real scale down (just like if the pods were handled).
 		scaleDownForbidden = true
 		glog.V(1).Info("Unschedulable pods are very new, waiting one iteration for more")
+		a.AuditLog.Skip(auditlog.ScaleUpAction, "unschedulable pods are very new, waiting one iteration for more")
 	} else {
 		daemonsets, err := a.ListerRegistry.DaemonSetLister().List()
 		if err != nil {
@@ -264,13 +397,31 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 			return errors.ToAutoscalerError(errors.ApiCallError, err)
 		}
 
+		nodeInfos, typedErr := GetNodeInfosForGroups(readyNodes, a.CloudProvider, a.ClientSet, daemonsets, a.PredicateChecker)
+		if typedErr != nil {
+			typedErr = typedErr.AddPrefix("failed to build node infos for node groups: ")
+			glog.Errorf("Failed to scale up: %v", typedErr)
+			return typedErr
+		}
+		record.SetTemplateNodeInfos(nodeInfos)
+
+		if stopping(runCtx, "scale up") {
+			return nil
+		}
 		scaleUpStart := time.Now()
 		metrics.UpdateLastTime(metrics.ScaleUp, scaleUpStart)
 
-		scaleUpStatus, typedErr := ScaleUp(autoscalingContext, a.processors, a.clusterStateRegistry, unschedulablePodsToHelp, readyNodes, daemonsets)
+		scaleUpCtx, scaleUpSpan := tracing.StartSpan(loopCtx, "ScaleUp", trace.Int64Attribute("pods", int64(len(unschedulablePodsToHelp))))
+		scaleUpStatus, typedErr := ScaleUp(scaleUpCtx, autoscalingContext, a.processors, a.clusterStateRegistry, unschedulablePodsToHelp, readyNodes, nodeInfos, currentTime)
+		tracing.EndSpan(scaleUpSpan, typedErr)
 
 		metrics.UpdateDurationFromStart(metrics.ScaleUp, scaleUpStart)
 
+		record.SetScaleUpStatus(scaleUpStatus)
+		if scaleUpStatus != nil {
+			a.lastScaleUpStatus = scaleUpStatus
+			a.lastScaleUpAttemptTime = currentTime
+		}
 		if typedErr != nil {
 			glog.Errorf("Failed to scale up: %v", typedErr)
 			return typedErr
@@ -279,6 +430,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 			a.processors.ScaleUpStatusProcessor.Process(autoscalingContext, scaleUpStatus)
 		}
 		if scaleUpStatus.ScaledUp {
+			a.pendingPods.RegisterScaleUp(scaleUpStatus, currentTime)
 			a.lastScaleUpTime = currentTime
 			// No scale down in this iteration.
 			return nil
@@ -299,13 +451,16 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 		scaleDown.CleanUp(currentTime)
 		potentiallyUnneeded := getPotentiallyUnneededNodes(autoscalingContext, allNodes)
 
+		_, unneededSpan := tracing.StartSpan(loopCtx, "FindUnneeded")
 		typedErr := scaleDown.UpdateUnneededNodes(allNodes, potentiallyUnneeded, append(allScheduled, unschedulableWaitingForLowerPriorityPreemption...), currentTime, pdbs)
+		tracing.EndSpan(unneededSpan, typedErr)
 		if typedErr != nil {
 			glog.Errorf("Failed to scale down: %v", typedErr)
 			return typedErr
 		}
 
 		metrics.UpdateDurationFromStart(metrics.FindUnneeded, unneededStart)
+		record.SetScaleDownCandidates(scaleDown.GetCandidatesForScaleDown())
 
 		if glog.V(4) {
 			for key, val := range scaleDown.unneededNodes {
@@ -325,16 +480,25 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 			calculateUnneededOnly, a.lastScaleUpTime, a.lastScaleDownDeleteTime, a.lastScaleDownFailTime,
 			scaleDownForbidden, scaleDown.nodeDeleteStatus.IsDeleteInProgress())
 
-		if !calculateUnneededOnly {
+		if calculateUnneededOnly && len(scaleDown.unneededNodes) > 0 {
+			a.AuditLog.Skip(auditlog.ScaleDownAction, a.scaleDownSkipReason(scaleDownForbidden, currentTime))
+		}
+
+		if !calculateUnneededOnly && !stopping(runCtx, "scale down") {
 			glog.V(4).Infof("Starting scale down")
 
 			// We want to delete unneeded Node Groups only if there was no recent scale up,
 			// and there is no current delete in progress and there was no recent errors.
-			a.processors.NodeGroupManager.RemoveUnneededNodeGroups(autoscalingContext)
+			// Node groups are never removed in dry-run mode.
+			if !autoscalingContext.DryRun {
+				a.processors.NodeGroupManager.RemoveUnneededNodeGroups(autoscalingContext)
+			}
 
 			scaleDownStart := time.Now()
 			metrics.UpdateLastTime(metrics.ScaleDown, scaleDownStart)
-			result, typedErr := scaleDown.TryToScaleDown(allNodes, allScheduled, pdbs, currentTime)
+			scaleDownCtx, scaleDownSpan := tracing.StartSpan(loopCtx, "ScaleDown")
+			result, typedErr := scaleDown.TryToScaleDown(scaleDownCtx, allNodes, allScheduled, pdbs, currentTime)
+			tracing.EndSpan(scaleDownSpan, typedErr)
 			metrics.UpdateDurationFromStart(metrics.ScaleDown, scaleDownStart)
 
 			if typedErr != nil {
@@ -350,10 +514,156 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) errors.AutoscalerError
 	return nil
 }
 
-// ExitCleanUp performs all necessary clean-ups when the autoscaler's exiting.
-func (a *StaticAutoscaler) ExitCleanUp() {
+// scaleDownSkipReason explains why only unneeded nodes were calculated in this loop.
+func (a *StaticAutoscaler) scaleDownSkipReason(scaleDownForbidden bool, currentTime time.Time) string {
+	switch {
+	case scaleDownForbidden:
+		return "unschedulable pods can be scheduled on existing nodes or are very new"
+	case a.lastScaleUpTime.Add(a.ScaleDownDelayAfterAdd).After(currentTime):
+		return fmt.Sprintf("scale-down delay after scale-up (%v)", a.ScaleDownDelayAfterAdd)
+	case a.lastScaleDownFailTime.Add(a.ScaleDownDelayAfterFailure).After(currentTime):
+		return fmt.Sprintf("scale-down delay after failure (%v)", a.ScaleDownDelayAfterFailure)
+	case a.lastScaleDownDeleteTime.Add(a.ScaleDownDelayAfterDelete).After(currentTime):
+		return fmt.Sprintf("scale-down delay after deletion (%v)", a.ScaleDownDelayAfterDelete)
+	default:
+		return "node deletion in progress"
+	}
+}
+
+// stopping checks whether runCtx is done, in which case the given phase of the loop is skipped.
+func stopping(runCtx ctx.Context, phase string) bool {
+	if runCtx.Err() == nil {
+		return false
+	}
+	glog.V(1).Infof("Autoscaler is stopping, skipping %s", phase)
+	return true
+}
+
+// StopInFlightActions waits until waitCtx is done for a node deletion started by scale-down to finish.
+// If it doesn't, the drain is aborted, which removes the ToBeDeleted taint from the node. Returns false
+// if the deletion had to be aborted.
+func (a *StaticAutoscaler) StopInFlightActions(waitCtx ctx.Context) bool {
+	if a.waitForScaleDown(waitCtx) {
+		return true
+	}
+	glog.Warningf("Node deletion still in progress, aborting it")
+	a.Actuator.AbortDrains()
+	cleanUpCtx, cancel := ctx.WithTimeout(ctx.Background(), abortedActionsCleanUpTimeout)
+	defer cancel()
+	if !a.waitForScaleDown(cleanUpCtx) {
+		glog.Errorf("Aborted node deletion didn't finish within %v", abortedActionsCleanUpTimeout)
+	}
+	return false
+}
+
+func (a *StaticAutoscaler) waitForScaleDown(waitCtx ctx.Context) bool {
+	for a.IsScaleDownInProgress() {
+		select {
+		case <-waitCtx.Done():
+			return false
+		case <-time.After(inFlightActionsPollInterval):
+		}
+	}
+	return true
+}
+
+// IsScaleDownInProgress returns true if a node deletion started by scale-down is still in progress.
+func (a *StaticAutoscaler) IsScaleDownInProgress() bool {
+	return a.scaleDown.nodeDeleteStatus.IsDeleteInProgress()
+}
+
+// restoreStateIfRequired restores scale-down bookkeeping and node group backoff saved by
+// a previous run of CA, possibly a different leader. Information about nodes and node
+// groups that are gone is dropped. The state is restored only once per runtime.
+func (a *StaticAutoscaler) restoreStateIfRequired(allNodes []*apiv1.Node, currentTime time.Time) {
+	if a.stateStore == nil || a.stateRestored {
+		return
+	}
+	state, err := a.stateStore.Load()
+	if err != nil {
+		// Retry in the next loop, saving is blocked until then so that the state isn't overwritten.
+		glog.Errorf("Failed to load saved state: %v", err)
+		return
+	}
+	a.stateRestored = true
+	if state == nil {
+		glog.V(1).Info("No saved state found")
+		return
+	}
+	if state.IsStale(currentTime, a.ScaleDownStateMaxAge) {
+		glog.Warningf("Ignoring state saved at %v, it is older than %v", state.Time, a.ScaleDownStateMaxAge)
+		return
+	}
+
+	nodeNames := sets.NewString()
+	for _, node := range allNodes {
+		nodeNames.Insert(node.Name)
+	}
+	nodeGroupIds := sets.NewString()
+	for _, nodeGroup := range a.CloudProvider.NodeGroups() {
+		nodeGroupIds.Insert(nodeGroup.Id())
+	}
+	state.Prune(nodeNames, nodeGroupIds, currentTime)
+	a.scaleDown.RestoreCheckpointState(state)
+	a.clusterStateRegistry.RestoreNodeGroupBackoff(state.NodeGroupBackoff)
+	a.restoreLastTime(&a.lastScaleUpTime, state.LastScaleUpTime)
+	a.restoreLastTime(&a.lastScaleDownDeleteTime, state.LastScaleDownDeleteTime)
+	a.restoreLastTime(&a.lastScaleDownFailTime, state.LastScaleDownFailTime)
+	glog.V(1).Infof("Restored state saved at %v: %d unneeded nodes, %d unremovable nodes, %d backed off node groups, "+
+		"lastScaleUpTime=%v lastScaleDownDeleteTime=%v lastScaleDownFailTime=%v",
+		state.Time, len(state.UnneededNodes), len(state.UnremovableNodes), len(state.NodeGroupBackoff),
+		a.lastScaleUpTime, a.lastScaleDownDeleteTime, a.lastScaleDownFailTime)
+}
+
+// restoreLastTime replaces a scale-down delay timer with the saved one, unless the timer was set
+// later by this run. Timers still at the start time replace it, so that restarts don't delay scale-down.
+func (a *StaticAutoscaler) restoreLastTime(lastTime *time.Time, saved time.Time) {
+	if saved.IsZero() {
+		return
+	}
+	if lastTime.Equal(a.startTime) || saved.After(*lastTime) {
+		*lastTime = saved
+	}
+}
+
+// saveState checkpoints scale-down bookkeeping and node group backoff so that they survive
+// restarts and leader changes.
+func (a *StaticAutoscaler) saveState(currentTime time.Time) {
+	if a.stateStore == nil || !a.stateRestored || a.DryRun {
+		return
+	}
+	state := &checkpoint.State{
+		Time:                    currentTime,
+		NodeGroupBackoff:        a.clusterStateRegistry.GetNodeGroupBackoff(),
+		LastScaleUpTime:         a.lastScaleUpTime,
+		LastScaleDownDeleteTime: a.lastScaleDownDeleteTime,
+		LastScaleDownFailTime:   a.lastScaleDownFailTime,
+	}
+	a.scaleDown.GetCheckpointState(state)
+	if err := a.stateStore.Save(state); err != nil {
+		glog.Errorf("Failed to save state: %v", err)
+		return
+	}
+	a.lastCheckpointTime = currentTime
+}
+
+// ExitCleanUp performs all necessary clean-ups when the autoscaler's exiting. The state is saved
+// and the status objects are deleted only if this instance is still leading, otherwise they
+// belong to the new leader.
+func (a *StaticAutoscaler) ExitCleanUp(leading bool) {
 	a.processors.CleanUp()
+	if err := a.AuditLog.Close(); err != nil {
+		glog.Warningf("Failed to close audit log: %v", err)
+	}
+	if !leading {
+		glog.V(1).Info("Leadership lost, leaving the saved state and status to the new leader")
+		return
+	}
+	a.saveState(time.Now())
 
+	if a.AutoscalingContext.WriteStatusResource && a.AutoscalingContext.StatusClient != nil {
+		utils.DeleteStatusResource(a.AutoscalingContext.StatusClient, a.AutoscalingContext.ConfigNamespace)
+	}
 	if !a.AutoscalingContext.WriteStatusConfigMap {
 		return
 	}
@@ -397,14 +707,15 @@ func (a *StaticAutoscaler) actOnEmptyCluster(allNodes, readyNodes []*apiv1.Node,
 	return false
 }
 
-func (a *StaticAutoscaler) updateClusterState(allNodes []*apiv1.Node, currentTime time.Time) errors.AutoscalerError {
-	err := a.AutoscalingContext.CloudProvider.Refresh()
+func (a *StaticAutoscaler) updateClusterState(loopCtx ctx.Context, allNodes []*apiv1.Node, currentTime time.Time) errors.AutoscalerError {
+	cloudProvider := a.AutoscalingContext.CloudProvider
+	err := callCloudProvider(loopCtx, cloudProvider.Name(), "Refresh", cloudprovider.CloudProviderWithContext(cloudProvider).RefreshWithContext)
 	if err != nil {
 		glog.Errorf("Failed to refresh cloud provider config: %v", err)
 		return errors.ToAutoscalerError(errors.CloudProviderError, err)
 	}
 
-	err = a.clusterStateRegistry.UpdateNodes(allNodes, currentTime)
+	err = a.clusterStateRegistry.UpdateNodesWithContext(loopCtx, allNodes, currentTime)
 	if err != nil {
 		glog.Errorf("Failed to update node registry: %v", err)
 		a.scaleDown.CleanUpUnneededNodes()
@@ -17,15 +17,21 @@ limitations under the License.
 package core
 
 import (
+	ctx "context"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/gardener/autoscaler/cluster-autoscaler/checkpoint"
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	testprovider "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
 	"github.com/gardener/autoscaler/cluster-autoscaler/estimator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "github.com/gardener/autoscaler/cluster-autoscaler/processors"
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
 	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
 	scheduler_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/scheduler"
 	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
@@ -33,6 +39,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
 	policyv1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 
@@ -198,7 +205,7 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 	unschedulablePodMock.On("List").Return([]*apiv1.Pod{p2}, nil).Once()
 	podDisruptionBudgetListerMock.On("List").Return([]*policyv1.PodDisruptionBudget{}, nil).Once()
 
-	err := autoscaler.RunOnce(time.Now())
+	err := autoscaler.RunOnce(ctx.Background(), time.Now())
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -212,7 +219,7 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 	onScaleUpMock.On("ScaleUp", "ng1", 1).Return(nil).Once()
 
 	context.MaxNodesTotal = 10
-	err = autoscaler.RunOnce(time.Now().Add(time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(time.Hour))
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -227,7 +234,7 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 	provider.AddNode("ng1", n2)
 	ng1.SetTargetSize(2)
 
-	err = autoscaler.RunOnce(time.Now().Add(2 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(2*time.Hour))
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -240,7 +247,7 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 	podDisruptionBudgetListerMock.On("List").Return([]*policyv1.PodDisruptionBudget{}, nil).Once()
 	onScaleDownMock.On("ScaleDown", "ng1", "n2").Return(nil).Once()
 
-	err = autoscaler.RunOnce(time.Now().Add(3 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(3*time.Hour))
 	waitForDeleteToFinish(t, autoscaler.scaleDown)
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
@@ -256,7 +263,7 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 	provider.AddNode("ng1", n3)
 	ng1.SetTargetSize(3)
 
-	err = autoscaler.RunOnce(time.Now().Add(4 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(4*time.Hour))
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -266,7 +273,7 @@ func TestStaticAutoscalerRunOnce(t *testing.T) {
 	allNodeListerMock.On("List").Return([]*apiv1.Node{n1, n2}, nil).Once()
 	onScaleDownMock.On("ScaleDown", "ng1", "n3").Return(nil).Once()
 
-	err = autoscaler.RunOnce(time.Now().Add(5 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(5*time.Hour))
 	waitForDeleteToFinish(t, autoscaler.scaleDown)
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
@@ -378,7 +385,7 @@ func TestStaticAutoscalerRunOnceWithAutoprovisionedEnabled(t *testing.T) {
 	onNodeGroupCreateMock.On("Create", "autoprovisioned-TN2").Return(nil).Once()
 	onScaleUpMock.On("ScaleUp", "autoprovisioned-TN2", 1).Return(nil).Once()
 
-	err := autoscaler.RunOnce(time.Now().Add(time.Hour))
+	err := autoscaler.RunOnce(ctx.Background(), time.Now().Add(time.Hour))
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -397,7 +404,7 @@ func TestStaticAutoscalerRunOnceWithAutoprovisionedEnabled(t *testing.T) {
 	provider.AddAutoprovisionedNodeGroup("autoprovisioned-TN2", 0, 10, 1, "TN1")
 	provider.AddNode("autoprovisioned-TN2", n2)
 
-	err = autoscaler.RunOnce(time.Now().Add(1 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(1*time.Hour))
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -412,7 +419,7 @@ func TestStaticAutoscalerRunOnceWithAutoprovisionedEnabled(t *testing.T) {
 		"TN1").Return(nil).Once()
 	onScaleDownMock.On("ScaleDown", "autoprovisioned-TN2", "n2").Return(nil).Once()
 
-	err = autoscaler.RunOnce(time.Now().Add(2 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(2*time.Hour))
 	waitForDeleteToFinish(t, autoscaler.scaleDown)
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
@@ -506,7 +513,7 @@ func TestStaticAutoscalerRunOnceWithALongUnregisteredNode(t *testing.T) {
 	daemonSetListerMock.On("List").Return([]*extensionsv1.DaemonSet{}, nil).Once()
 	onScaleUpMock.On("ScaleUp", "ng1", 1).Return(nil).Once()
 
-	err := autoscaler.RunOnce(later.Add(time.Hour))
+	err := autoscaler.RunOnce(ctx.Background(), later.Add(time.Hour))
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -519,7 +526,7 @@ func TestStaticAutoscalerRunOnceWithALongUnregisteredNode(t *testing.T) {
 	allNodeListerMock.On("List").Return([]*apiv1.Node{n1, n2}, nil).Once()
 	onScaleDownMock.On("ScaleDown", "ng1", "broken").Return(nil).Once()
 
-	err = autoscaler.RunOnce(later.Add(2 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), later.Add(2*time.Hour))
 	waitForDeleteToFinish(t, autoscaler.scaleDown)
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
@@ -635,7 +642,7 @@ func TestStaticAutoscalerRunOncePodsWithPriorities(t *testing.T) {
 	daemonSetListerMock.On("List").Return([]*extensionsv1.DaemonSet{}, nil).Once()
 	onScaleUpMock.On("ScaleUp", "ng2", 1).Return(nil).Once()
 
-	err := autoscaler.RunOnce(time.Now())
+	err := autoscaler.RunOnce(ctx.Background(), time.Now())
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -649,7 +656,7 @@ func TestStaticAutoscalerRunOncePodsWithPriorities(t *testing.T) {
 
 	ng2.SetTargetSize(2)
 
-	err = autoscaler.RunOnce(time.Now().Add(2 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(2*time.Hour))
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
@@ -664,10 +671,315 @@ func TestStaticAutoscalerRunOncePodsWithPriorities(t *testing.T) {
 
 	p4.Spec.NodeName = "n2"
 
-	err = autoscaler.RunOnce(time.Now().Add(3 * time.Hour))
+	err = autoscaler.RunOnce(ctx.Background(), time.Now().Add(3*time.Hour))
 	waitForDeleteToFinish(t, autoscaler.scaleDown)
 	assert.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
 		podDisruptionBudgetListerMock, daemonSetListerMock, onScaleUpMock, onScaleDownMock)
 
 }
+
+func TestStaticAutoscalerSaveAndRestoreState(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+
+	options := config.AutoscalingOptions{
+		ScaleDownUnneededTime:            10 * time.Minute,
+		ScaleDownStateCheckpointInterval: time.Minute,
+		ScaleDownStateMaxAge:             30 * time.Minute,
+	}
+	store := checkpoint.NewConfigMapStore(fake.NewSimpleClientset(), "kube-system", "cluster-autoscaler-state")
+	newAutoscaler := func() *StaticAutoscaler {
+		context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, provider)
+		clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+		return &StaticAutoscaler{
+			AutoscalingContext:   &context,
+			clusterStateRegistry: clusterState,
+			scaleDown:            NewScaleDown(&context, clusterState),
+			processors:           ca_processors.TestProcessors(),
+			stateStore:           store,
+		}
+	}
+	now := time.Now()
+
+	autoscaler := newAutoscaler()
+	// Nothing is saved until the previous state is loaded.
+	autoscaler.saveState(now)
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	autoscaler.restoreStateIfRequired([]*apiv1.Node{n1, n2}, now)
+	assert.True(t, autoscaler.stateRestored)
+	autoscaler.scaleDown.unneededNodes["n1"] = now.Add(-5 * time.Minute)
+	autoscaler.scaleDown.unremovableNodes["n2"] = now.Add(5 * time.Minute)
+	autoscaler.scaleDown.unremovableReasons["n2"] = &simulator.UnremovableNode{Node: n2, Reason: simulator.NoPlaceToMovePods}
+	autoscaler.clusterStateRegistry.RegisterFailedScaleUp("ng1", metrics.Timeout, now)
+	autoscaler.lastScaleUpTime = now.Add(-3 * time.Minute)
+	autoscaler.lastScaleDownDeleteTime = now.Add(-2 * time.Minute)
+	autoscaler.lastScaleDownFailTime = now.Add(-time.Minute)
+	autoscaler.saveState(now)
+	assert.Equal(t, now, autoscaler.lastCheckpointTime)
+
+	// A new leader sees n2 gone.
+	restored := newAutoscaler()
+	restored.restoreStateIfRequired([]*apiv1.Node{n1}, now.Add(time.Minute))
+	assert.Equal(t, 1, len(restored.scaleDown.unneededNodes))
+	assert.True(t, now.Add(-5*time.Minute).Equal(restored.scaleDown.unneededNodes["n1"]))
+	assert.Empty(t, restored.scaleDown.unremovableNodes)
+	assert.Empty(t, restored.scaleDown.unremovableReasons)
+	assert.Contains(t, restored.clusterStateRegistry.GetNodeGroupBackoff(), "ng1")
+	assert.True(t, now.Add(-3*time.Minute).Equal(restored.lastScaleUpTime))
+	assert.True(t, now.Add(-2*time.Minute).Equal(restored.lastScaleDownDeleteTime))
+	assert.True(t, now.Add(-time.Minute).Equal(restored.lastScaleDownFailTime))
+
+	// Timers set by the new leader before the state could be loaded are kept if they are later.
+	scaledUp := newAutoscaler()
+	scaledUp.startTime = now.Add(-10 * time.Minute)
+	scaledUp.lastScaleUpTime = now
+	scaledUp.lastScaleDownDeleteTime = scaledUp.startTime
+	scaledUp.lastScaleDownFailTime = scaledUp.startTime
+	scaledUp.restoreStateIfRequired([]*apiv1.Node{n1, n2}, now.Add(time.Minute))
+	assert.True(t, now.Equal(scaledUp.lastScaleUpTime))
+	assert.True(t, now.Add(-2*time.Minute).Equal(scaledUp.lastScaleDownDeleteTime))
+	assert.True(t, now.Add(-time.Minute).Equal(scaledUp.lastScaleDownFailTime))
+
+	// Stale state is ignored.
+	stale := newAutoscaler()
+	stale.restoreStateIfRequired([]*apiv1.Node{n1, n2}, now.Add(time.Hour))
+	assert.True(t, stale.stateRestored)
+	assert.Empty(t, stale.scaleDown.unneededNodes)
+	assert.Empty(t, stale.clusterStateRegistry.GetNodeGroupBackoff())
+}
+
+func TestStaticAutoscalerExitCleanUp(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	options := config.AutoscalingOptions{
+		ConfigNamespace:      "kube-system",
+		WriteStatusConfigMap: true,
+	}
+	newAutoscaler := func() (*StaticAutoscaler, *fake.Clientset, checkpoint.Store) {
+		statusConfigMap := &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: utils.StatusConfigMapName, Namespace: "kube-system"}}
+		fakeClient := fake.NewSimpleClientset(statusConfigMap)
+		store := checkpoint.NewConfigMapStore(fakeClient, "kube-system", "cluster-autoscaler-state")
+		context := NewScaleTestAutoscalingContext(options, fakeClient, provider)
+		clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+		return &StaticAutoscaler{
+			AutoscalingContext:   &context,
+			clusterStateRegistry: clusterState,
+			scaleDown:            NewScaleDown(&context, clusterState),
+			processors:           ca_processors.TestProcessors(),
+			stateStore:           store,
+			stateRestored:        true,
+		}, fakeClient, store
+	}
+
+	// Leadership lost, the new leader's state and status are left alone.
+	autoscaler, fakeClient, store := newAutoscaler()
+	autoscaler.ExitCleanUp(false)
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+	_, err = fakeClient.CoreV1().ConfigMaps("kube-system").Get(utils.StatusConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	// Shutdown while leading.
+	autoscaler, fakeClient, store = newAutoscaler()
+	autoscaler.ExitCleanUp(true)
+	state, err = store.Load()
+	assert.NoError(t, err)
+	assert.NotNil(t, state)
+	_, err = fakeClient.CoreV1().ConfigMaps("kube-system").Get(utils.StatusConfigMapName, metav1.GetOptions{})
+	assert.Error(t, err)
+}
+
+func TestStaticAutoscalerRunOnceStopped(t *testing.T) {
+	readyNodeListerMock := &nodeListerMock{}
+	allNodeListerMock := &nodeListerMock{}
+	scheduledPodMock := &podListerMock{}
+	unschedulablePodMock := &podListerMock{}
+	podDisruptionBudgetListerMock := &podDisruptionBudgetListerMock{}
+	daemonSetListerMock := &daemonSetListerMock{}
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	context := NewScaleTestAutoscalingContext(config.AutoscalingOptions{ScaleDownEnabled: true}, &fake.Clientset{}, provider)
+	context.ListerRegistry = kube_util.NewListerRegistry(allNodeListerMock, readyNodeListerMock, scheduledPodMock,
+		unschedulablePodMock, podDisruptionBudgetListerMock, daemonSetListerMock)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	autoscaler := &StaticAutoscaler{
+		AutoscalingContext:   &context,
+		clusterStateRegistry: clusterState,
+		scaleDown:            NewScaleDown(&context, clusterState),
+		processors:           ca_processors.TestProcessors(),
+		initialized:          true,
+	}
+
+	runCtx, cancel := ctx.WithCancel(ctx.Background())
+	cancel()
+	// Listers have no expectations set, so any call would fail the test.
+	err := autoscaler.RunOnce(runCtx, time.Now())
+	assert.NoError(t, err)
+	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock)
+}
+
+// hangingCloudProvider wraps the node groups of a test cloud provider so that size increases
+// hang until they are cancelled.
+type hangingCloudProvider struct {
+	*testprovider.TestCloudProvider
+}
+
+func (p *hangingCloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	var result []cloudprovider.NodeGroup
+	for _, nodeGroup := range p.TestCloudProvider.NodeGroups() {
+		result = append(result, &hangingNodeGroup{nodeGroup})
+	}
+	return result
+}
+
+func (p *hangingCloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	nodeGroup, err := p.TestCloudProvider.NodeGroupForNode(node)
+	if err != nil || nodeGroup == nil {
+		return nodeGroup, err
+	}
+	return &hangingNodeGroup{nodeGroup}, nil
+}
+
+type hangingNodeGroup struct {
+	cloudprovider.NodeGroup
+}
+
+func (ng *hangingNodeGroup) TargetSizeWithContext(ctx.Context) (int, error) {
+	return ng.TargetSize()
+}
+
+func (ng *hangingNodeGroup) IncreaseSizeWithContext(callCtx ctx.Context, delta int) error {
+	<-callCtx.Done()
+	return callCtx.Err()
+}
+
+func (ng *hangingNodeGroup) DeleteNodesWithContext(callCtx ctx.Context, nodes []*apiv1.Node) error {
+	return ng.DeleteNodes(nodes)
+}
+
+func (ng *hangingNodeGroup) DecreaseTargetSizeWithContext(callCtx ctx.Context, delta int) error {
+	return ng.DecreaseTargetSize(delta)
+}
+
+func (ng *hangingNodeGroup) NodesWithContext(ctx.Context) ([]string, error) {
+	return ng.Nodes()
+}
+
+func TestStaticAutoscalerRunOnceHangingScaleUp(t *testing.T) {
+	readyNodeListerMock := &nodeListerMock{}
+	allNodeListerMock := &nodeListerMock{}
+	scheduledPodMock := &podListerMock{}
+	unschedulablePodMock := &podListerMock{}
+	podDisruptionBudgetListerMock := &podDisruptionBudgetListerMock{}
+	daemonSetListerMock := &daemonSetListerMock{}
+
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Now())
+	p1 := BuildTestPod("p1", 600, 100)
+	p1.Spec.NodeName = "n1"
+	p2 := BuildTestPod("p2", 600, 100)
+
+	testProvider := testprovider.NewTestCloudProvider(func(string, int) error {
+		t.Fatalf("Size increase is expected to go through IncreaseSizeWithContext")
+		return nil
+	}, nil)
+	testProvider.AddNodeGroup("ng1", 1, 10, 1)
+	testProvider.AddNode("ng1", n1)
+	provider := &hangingCloudProvider{testProvider}
+
+	options := config.AutoscalingOptions{
+		EstimatorName:  estimator.BinpackingEstimatorName,
+		MaxNodesTotal:  10,
+		MaxCoresTotal:  10,
+		MaxMemoryTotal: 100000,
+		LoopDeadline:   100 * time.Millisecond,
+	}
+	context := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, provider)
+	context.ListerRegistry = kube_util.NewListerRegistry(allNodeListerMock, readyNodeListerMock, scheduledPodMock,
+		unschedulablePodMock, podDisruptionBudgetListerMock, daemonSetListerMock)
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{OkTotalUnreadyCount: 1}, context.LogRecorder)
+	clusterState.UpdateNodes([]*apiv1.Node{n1}, time.Now())
+	autoscaler := &StaticAutoscaler{
+		AutoscalingContext:   &context,
+		clusterStateRegistry: clusterState,
+		scaleDown:            NewScaleDown(&context, clusterState),
+		processors:           ca_processors.TestProcessors(),
+		initialized:          true,
+	}
+
+	readyNodeListerMock.On("List").Return([]*apiv1.Node{n1}, nil).Once()
+	allNodeListerMock.On("List").Return([]*apiv1.Node{n1}, nil).Once()
+	scheduledPodMock.On("List").Return([]*apiv1.Pod{p1}, nil).Once()
+	unschedulablePodMock.On("List").Return([]*apiv1.Pod{p2}, nil).Once()
+	daemonSetListerMock.On("List").Return([]*extensionsv1.DaemonSet{}, nil).Once()
+
+	start := time.Now()
+	err := autoscaler.RunOnce(ctx.Background(), time.Now())
+	assert.True(t, time.Since(start) < 5*time.Second)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "IncreaseSize timed out")
+	assert.False(t, clusterState.IsNodeGroupSafeToScaleUp("ng1", time.Now()))
+	mock.AssertExpectationsForObjects(t, readyNodeListerMock, allNodeListerMock, scheduledPodMock, unschedulablePodMock,
+		daemonSetListerMock)
+}
+
+func TestStopInFlightActions(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	fakeClient := &fake.Clientset{}
+	context := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, provider)
+	drainer := NewTestDrainer(fakeClient, context.Recorder, 0)
+	context.Actuator = NewDefaultActuator(fakeClient, context.Recorder, drainer, "")
+	clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder)
+	autoscaler := &StaticAutoscaler{
+		AutoscalingContext:   &context,
+		clusterStateRegistry: clusterState,
+		scaleDown:            NewScaleDown(&context, clusterState),
+		processors:           ca_processors.TestProcessors(),
+	}
+
+	// Nothing in progress.
+	assert.True(t, autoscaler.StopInFlightActions(ctx.Background()))
+
+	// Deletion finishes within the timeout.
+	autoscaler.scaleDown.nodeDeleteStatus.SetDeleteInProgress(true)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		autoscaler.scaleDown.nodeDeleteStatus.SetDeleteInProgress(false)
+	}()
+	waitCtx, cancel := ctx.WithTimeout(ctx.Background(), 5*time.Second)
+	defer cancel()
+	assert.True(t, autoscaler.StopInFlightActions(waitCtx))
+
+	// Deletion only finishes once the drain is aborted.
+	autoscaler.scaleDown.nodeDeleteStatus.SetDeleteInProgress(true)
+	go func() {
+		<-drainer.stop
+		autoscaler.scaleDown.nodeDeleteStatus.SetDeleteInProgress(false)
+	}()
+	waitCtx, cancel = ctx.WithTimeout(ctx.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.False(t, autoscaler.StopInFlightActions(waitCtx))
+	assert.False(t, autoscaler.IsScaleDownInProgress())
+
+	// Leadership lost, the drain is aborted right away.
+	drainer = NewTestDrainer(fakeClient, context.Recorder, 0)
+	context.Actuator = NewDefaultActuator(fakeClient, context.Recorder, drainer, "")
+	autoscaler.scaleDown.nodeDeleteStatus.SetDeleteInProgress(true)
+	go func() {
+		<-drainer.stop
+		autoscaler.scaleDown.nodeDeleteStatus.SetDeleteInProgress(false)
+	}()
+	lostCtx, lose := ctx.WithCancel(ctx.Background())
+	lose()
+	start := time.Now()
+	assert.False(t, autoscaler.StopInFlightActions(lostCtx))
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package core
 
 import (
+	ctx "context"
 	"fmt"
 	"math/rand"
 	"reflect"
@@ -30,16 +31,15 @@ import (
 	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/daemonset"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/deletetaint"
-	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/glogx"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/gpu"
 	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
 	scheduler_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/scheduler"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/workers"
 
 	apiv1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
-	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	kube_client "k8s.io/client-go/kubernetes"
 	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
@@ -52,96 +52,40 @@ const (
 	ReschedulerTaintKey = "CriticalAddonsOnly"
 )
 
-// Following data structure is used to avoid running predicates #pending_pods * #nodes
-// times (which turned out to be very expensive if there are thousands of pending pods).
-// This optimization is based on the assumption that if there are that many pods they're
-// likely created by controllers (deployment, replication controller, ...).
-// So instead of running all predicates for every pod we first check whether we've
-// already seen identical pod (in this step we're not binpacking, just checking if
-// the pod would fit anywhere right now) and if so we use the result we already
-// calculated.
-// To decide if two pods are similar enough we check if they have identical label
-// and spec and are owned by the same controller. The problem is the whole
-// podSchedulableInfo struct is not hashable and keeping a list and running deep
-// equality checks would likely also be expensive. So instead we use controller
-// UID as a key in initial lookup and only run full comparison on a set of
-// podSchedulableInfos created for pods owned by this controller.
-type podSchedulableInfo struct {
-	spec            apiv1.PodSpec
-	labels          map[string]string
-	schedulingError *simulator.PredicateError
-}
-
-type podSchedulableMap map[string][]podSchedulableInfo
-
-func (psi *podSchedulableInfo) match(pod *apiv1.Pod) bool {
-	return reflect.DeepEqual(pod.Labels, psi.labels) && apiequality.Semantic.DeepEqual(pod.Spec, psi.spec)
-}
-
-func (podMap podSchedulableMap) get(pod *apiv1.Pod) (*simulator.PredicateError, bool) {
-	ref := drain.ControllerRef(pod)
-	if ref == nil {
-		return nil, false
-	}
-	uid := string(ref.UID)
-	if infos, found := podMap[uid]; found {
-		for _, info := range infos {
-			if info.match(pod) {
-				return info.schedulingError, true
-			}
-		}
-	}
-	return nil, false
-}
-
-func (podMap podSchedulableMap) set(pod *apiv1.Pod, err *simulator.PredicateError) {
-	ref := drain.ControllerRef(pod)
-	if ref == nil {
-		return
-	}
-	uid := string(ref.UID)
-	podMap[uid] = append(podMap[uid], podSchedulableInfo{
-		spec:            pod.Spec,
-		labels:          pod.Labels,
-		schedulingError: err,
-	})
-}
-
 // FilterOutSchedulable checks whether pods from <unschedulableCandidates> marked as unschedulable
 // by Scheduler actually can't be scheduled on any node and filter out the ones that can.
-// It takes into account pods that are bound to node and will be scheduled after lower priority pod preemption.
+// Predicates are checked once for every group of equivalent pods, by up to parallelism goroutines.
 func FilterOutSchedulable(unschedulableCandidates []*apiv1.Pod, nodes []*apiv1.Node, allScheduled []*apiv1.Pod, podsWaitingForLowerPriorityPreemption []*apiv1.Pod,
-	predicateChecker *simulator.PredicateChecker, expendablePodsPriorityCutoff int) []*apiv1.Pod {
+	predicateChecker *simulator.PredicateChecker, expendablePodsPriorityCutoff int, parallelism int) []*apiv1.Pod {
 	unschedulablePods := []*apiv1.Pod{}
 	nonExpendableScheduled := FilterOutExpendablePods(allScheduled, expendablePodsPriorityCutoff)
-	nodeNameToNodeInfo := scheduler_util.CreateNodeNameToInfoMap(append(nonExpendableScheduled, podsWaitingForLowerPriorityPreemption...), nodes)
-	podSchedulable := make(podSchedulableMap)
+	snapshot := simulator.NewClusterSnapshot(nodes, append(nonExpendableScheduled, podsWaitingForLowerPriorityPreemption...))
 	loggingQuota := glogx.PodsLoggingQuota()
 
-	for _, pod := range unschedulableCandidates {
-		cachedError, found := podSchedulable.get(pod)
-		// Try to get result from cache.
-		if found {
-			if cachedError != nil {
-				unschedulablePods = append(unschedulablePods, pod)
+	// Checks don't modify the snapshot, so they can run in parallel.
+	groups := simulator.BuildPodEquivalenceGroups(unschedulableCandidates)
+	fitNodes := make([]string, len(groups))
+	fitErrors := make([]error, len(groups))
+	workers.Run(workers.Count(parallelism), len(groups), func(_, piece int) {
+		fitNodes[piece], fitErrors[piece] = predicateChecker.FitsAny(groups[piece].Representative(), snapshot.NodeInfos())
+	})
+
+	unschedulable := make(map[*apiv1.Pod]bool)
+	for i, group := range groups {
+		for j, pod := range group.Pods {
+			if fitErrors[i] != nil {
+				unschedulable[pod] = true
+			} else if j == 0 {
+				glogx.V(4).UpTo(loggingQuota).Infof("Pod %s marked as unschedulable can be scheduled on %s. Ignoring in scale up.", pod.Name, fitNodes[i])
 			} else {
 				glogx.V(4).UpTo(loggingQuota).Infof("Pod %s marked as unschedulable can be scheduled (based on simulation run for other pod owned by the same controller). Ignoring in scale up.", pod.Name)
 			}
-			continue
 		}
-
-		// Not found in cache, have to run the predicates.
-		nodeName, err := predicateChecker.FitsAny(pod, nodeNameToNodeInfo)
-		// err returned from FitsAny isn't a PredicateError.
-		// Hello, ugly hack. I wish you weren't here.
-		var predicateError *simulator.PredicateError
-		if err != nil {
-			predicateError = simulator.NewPredicateError("FitsAny", err, nil)
+	}
+	for _, pod := range unschedulableCandidates {
+		if unschedulable[pod] {
 			unschedulablePods = append(unschedulablePods, pod)
-		} else {
-			glogx.V(4).UpTo(loggingQuota).Infof("Pod %s marked as unschedulable can be scheduled on %s. Ignoring in scale up.", pod.Name, nodeName)
 		}
-		podSchedulable.set(pod, predicateError)
 	}
 
 	glogx.V(4).Over(loggingQuota).Infof("%v other pods marked as unschedulable can be scheduled.", -loggingQuota.Left())
@@ -181,33 +125,36 @@ func FilterOutExpendablePods(pods []*apiv1.Pod, expendablePodsPriorityCutoff int
 // CheckPodsSchedulableOnNode checks if pods can be scheduled on the given node.
 func CheckPodsSchedulableOnNode(context *context.AutoscalingContext, pods []*apiv1.Pod, nodeGroupId string, nodeInfo *schedulercache.NodeInfo) map[*apiv1.Pod]*simulator.PredicateError {
 	schedulingErrors := map[*apiv1.Pod]*simulator.PredicateError{}
+	groups := simulator.BuildPodEquivalenceGroups(pods)
+	for group, err := range CheckPodGroupsSchedulableOnNode(context, groups, nodeGroupId, nodeInfo) {
+		for _, pod := range group.Pods {
+			// Check if pod isn't repeated before overwriting result for it.
+			if _, repeated := schedulingErrors[pod]; repeated {
+				// This shouldn't really happen.
+				glog.Warningf("Pod %v appears multiple time on pods list, will only count it once in scale-up simulation", pod)
+			}
+			schedulingErrors[pod] = err
+		}
+	}
+	return schedulingErrors
+}
+
+// CheckPodGroupsSchedulableOnNode checks if pods of the given equivalence groups can be scheduled on the given node.
+// Predicates are checked once for every group.
+func CheckPodGroupsSchedulableOnNode(context *context.AutoscalingContext, groups []*simulator.PodEquivalenceGroup, nodeGroupId string, nodeInfo *schedulercache.NodeInfo) map[*simulator.PodEquivalenceGroup]*simulator.PredicateError {
+	schedulingErrors := map[*simulator.PodEquivalenceGroup]*simulator.PredicateError{}
 	loggingQuota := glogx.PodsLoggingQuota()
-	podSchedulable := make(podSchedulableMap)
 
-	for _, pod := range pods {
-		// Check if pod isn't repeated before overwriting result for it.
-		if _, repeated := schedulingErrors[pod]; repeated {
-			// This shouldn't really happen.
-			glog.Warningf("Pod %v appears multiple time on pods list, will only count it once in scale-up simulation", pod)
-		}
-		// Try to get result from cache.
-		err, found := podSchedulable.get(pod)
-		if found {
-			schedulingErrors[pod] = err
-			if err != nil {
+	for _, group := range groups {
+		err := context.PredicateChecker.CheckPredicates(group.Representative(), nil, nodeInfo)
+		schedulingErrors[group] = err
+		if err != nil {
+			// Always log for the first pod in a group.
+			glog.V(2).Infof("Pod %s can't be scheduled on %s, predicate failed: %v", group.Representative().Name, nodeGroupId, err.VerboseError())
+			for _, pod := range group.Pods[1:] {
 				glogx.V(2).UpTo(loggingQuota).Infof("Pod %s can't be scheduled on %s. Used cached predicate check results", pod.Name, nodeGroupId)
 			}
 		}
-		// Not found in cache, have to run the predicates.
-		if !found {
-			err = context.PredicateChecker.CheckPredicates(pod, nil, nodeInfo)
-			podSchedulable.set(pod, err)
-			schedulingErrors[pod] = err
-			if err != nil {
-				// Always log for the first pod in a controller.
-				glog.V(2).Infof("Pod %s can't be scheduled on %s, predicate failed: %v", pod.Name, nodeGroupId, err.VerboseError())
-			}
-		}
 	}
 
 	glogx.V(2).Over(loggingQuota).Infof("%v other pods can't be scheduled on %s.", -loggingQuota.Left(), nodeGroupId)
@@ -380,7 +327,7 @@ func sanitizeTemplateNode(node *apiv1.Node, nodeGroup string) (*apiv1.Node, erro
 }
 
 // Removes unregistered nodes if needed. Returns true if anything was removed and error if such occurred.
-func removeOldUnregisteredNodes(unregisteredNodes []clusterstate.UnregisteredNode, context *context.AutoscalingContext,
+func removeOldUnregisteredNodes(loopCtx ctx.Context, unregisteredNodes []clusterstate.UnregisteredNode, context *context.AutoscalingContext,
 	currentTime time.Time, logRecorder *utils.LogEventRecorder) (bool, error) {
 	removedAny := false
 	for _, unregisteredNode := range unregisteredNodes {
@@ -395,7 +342,7 @@ func removeOldUnregisteredNodes(unregisteredNodes []clusterstate.UnregisteredNod
 				glog.Warningf("No node group for node %s, skipping", unregisteredNode.Node.Name)
 				continue
 			}
-			size, err := nodeGroup.TargetSize()
+			size, err := cloudprovider.NodeGroupWithContext(nodeGroup).TargetSizeWithContext(loopCtx)
 			if err != nil {
 				glog.Warningf("Failed to get node group size, err: %v", err)
 				continue
@@ -406,7 +353,7 @@ func removeOldUnregisteredNodes(unregisteredNodes []clusterstate.UnregisteredNod
 			}
 			logRecorder.Eventf(apiv1.EventTypeNormal, "DeleteUnregistered",
 				"Removing unregistered node %v", unregisteredNode.Node.Name)
-			err = nodeGroup.DeleteNodes([]*apiv1.Node{unregisteredNode.Node})
+			err = context.Actuator.DeleteNodes(loopCtx, nodeGroup, []*apiv1.Node{unregisteredNode.Node})
 			if err != nil {
 				glog.Warningf("Failed to remove node %s: %v", unregisteredNode.Node.Name, err)
 				return removedAny, err
@@ -420,7 +367,7 @@ func removeOldUnregisteredNodes(unregisteredNodes []clusterstate.UnregisteredNod
 // Sets the target size of node groups to the current number of nodes in them
 // if the difference was constant for a prolonged time. Returns true if managed
 // to fix something.
-func fixNodeGroupSize(context *context.AutoscalingContext, clusterStateRegistry *clusterstate.ClusterStateRegistry, currentTime time.Time) (bool, error) {
+func fixNodeGroupSize(loopCtx ctx.Context, context *context.AutoscalingContext, clusterStateRegistry *clusterstate.ClusterStateRegistry, currentTime time.Time) (bool, error) {
 	fixed := false
 	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
 		incorrectSize := clusterStateRegistry.GetIncorrectNodeGroupSize(nodeGroup.Id())
@@ -434,8 +381,8 @@ func fixNodeGroupSize(context *context.AutoscalingContext, clusterStateRegistry
 					incorrectSize.ExpectedSize,
 					incorrectSize.CurrentSize,
 					delta)
-				if err := nodeGroup.DecreaseTargetSize(delta); err != nil {
-					return fixed, fmt.Errorf("Failed to decrease %s: %v", nodeGroup.Id(), err)
+				if err := context.Actuator.DecreaseTargetSize(loopCtx, nodeGroup, delta); err != nil {
+					return fixed, errors.NewAutoscalerError(errors.CloudProviderError, "Failed to decrease %s: %v", nodeGroup.Id(), err)
 				}
 				fixed = true
 			}
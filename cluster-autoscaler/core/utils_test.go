@@ -17,6 +17,7 @@ limitations under the License.
 package core
 
 import (
+	ctx "context"
 	"fmt"
 	"testing"
 	"time"
@@ -45,83 +46,6 @@ import (
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 )
 
-func TestPodSchedulableMap(t *testing.T) {
-	rc1 := apiv1.ReplicationController{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "rc1",
-			Namespace: "default",
-			SelfLink:  testapi.Default.SelfLink("replicationcontrollers", "rc"),
-			UID:       "12345678-1234-1234-1234-123456789012",
-		},
-	}
-
-	rc2 := apiv1.ReplicationController{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "rc2",
-			Namespace: "default",
-			SelfLink:  testapi.Default.SelfLink("replicationcontrollers", "rc"),
-			UID:       "12345678-1234-1234-1234-12345678901a",
-		},
-	}
-
-	pMap := make(podSchedulableMap)
-
-	podInRc1_1 := BuildTestPod("podInRc1_1", 500, 1000)
-	podInRc1_1.OwnerReferences = GenerateOwnerReferences(rc1.Name, "ReplicationController", "extensions/v1beta1", rc1.UID)
-
-	podInRc2 := BuildTestPod("podInRc2", 500, 1000)
-	podInRc2.OwnerReferences = GenerateOwnerReferences(rc2.Name, "ReplicationController", "extensions/v1beta1", rc2.UID)
-
-	// Basic sanity checks
-	_, found := pMap.get(podInRc1_1)
-	assert.False(t, found)
-	pMap.set(podInRc1_1, nil)
-	err, found := pMap.get(podInRc1_1)
-	assert.True(t, found)
-	assert.Nil(t, err)
-
-	cpuErr := &simulator.PredicateError{}
-
-	// Pod in different RC
-	_, found = pMap.get(podInRc2)
-	assert.False(t, found)
-	pMap.set(podInRc2, cpuErr)
-	err, found = pMap.get(podInRc2)
-	assert.True(t, found)
-	assert.Equal(t, cpuErr, err)
-
-	// Another replica in rc1
-	podInRc1_2 := BuildTestPod("podInRc1_1", 500, 1000)
-	podInRc1_2.OwnerReferences = GenerateOwnerReferences(rc1.Name, "ReplicationController", "extensions/v1beta1", rc1.UID)
-	err, found = pMap.get(podInRc1_2)
-	assert.True(t, found)
-	assert.Nil(t, err)
-
-	// A pod in rc1, but with different requests
-	differentPodInRc1 := BuildTestPod("differentPodInRc1", 1000, 1000)
-	differentPodInRc1.OwnerReferences = GenerateOwnerReferences(rc1.Name, "ReplicationController", "extensions/v1beta1", rc1.UID)
-	_, found = pMap.get(differentPodInRc1)
-	assert.False(t, found)
-	pMap.set(differentPodInRc1, cpuErr)
-	err, found = pMap.get(differentPodInRc1)
-	assert.True(t, found)
-	assert.Equal(t, cpuErr, err)
-
-	// A non-replicated pod
-	nonReplicatedPod := BuildTestPod("nonReplicatedPod", 1000, 1000)
-	_, found = pMap.get(nonReplicatedPod)
-	assert.False(t, found)
-	pMap.set(nonReplicatedPod, err)
-	_, found = pMap.get(nonReplicatedPod)
-	assert.False(t, found)
-
-	// Verify information about first pod has not been overwritten by adding
-	// other pods
-	err, found = pMap.get(podInRc1_1)
-	assert.True(t, found)
-	assert.Nil(t, err)
-}
-
 func TestFilterOutSchedulable(t *testing.T) {
 	rc1 := apiv1.ReplicationController{
 		ObjectMeta: metav1.ObjectMeta{
@@ -171,22 +95,27 @@ func TestFilterOutSchedulable(t *testing.T) {
 
 	predicateChecker := simulator.NewTestPredicateChecker()
 
-	res := FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod3}, []*apiv1.Pod{}, predicateChecker, 10)
+	res := FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod3}, []*apiv1.Pod{}, predicateChecker, 10, 1)
 	assert.Equal(t, 2, len(res))
 	assert.Equal(t, p2_1, res[0])
 	assert.Equal(t, p2_2, res[1])
 
-	res2 := FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod2, scheduledPod3}, []*apiv1.Pod{}, predicateChecker, 10)
+	res2 := FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod2, scheduledPod3}, []*apiv1.Pod{}, predicateChecker, 10, 1)
 	assert.Equal(t, 3, len(res2))
 	assert.Equal(t, p1, res2[0])
 	assert.Equal(t, p2_1, res2[1])
 	assert.Equal(t, p2_2, res2[2])
 
-	res3 := FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod3}, []*apiv1.Pod{podWaitingForPreemption}, predicateChecker, 10)
+	res3 := FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod3}, []*apiv1.Pod{podWaitingForPreemption}, predicateChecker, 10, 1)
 	assert.Equal(t, 3, len(res3))
 	assert.Equal(t, p1, res3[0])
 	assert.Equal(t, p2_1, res3[1])
 	assert.Equal(t, p2_2, res3[2])
+
+	for _, parallelism := range []int{0, 2, 8} {
+		assert.Equal(t, res, FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod3}, []*apiv1.Pod{}, predicateChecker, 10, parallelism))
+		assert.Equal(t, res2, FilterOutSchedulable(unschedulablePods, []*apiv1.Node{node}, []*apiv1.Pod{scheduledPod1, scheduledPod2, scheduledPod3}, []*apiv1.Pod{}, predicateChecker, 10, parallelism))
+	}
 }
 
 func TestFilterOutExpendableAndSplit(t *testing.T) {
@@ -395,17 +324,18 @@ func TestRemoveOldUnregisteredNodes(t *testing.T) {
 			MaxNodeProvisionTime: 45 * time.Minute,
 		},
 		CloudProvider: provider,
+		Actuator:      NewDefaultActuator(fake.NewSimpleClientset(), kube_record.NewFakeRecorder(5), nil, ""),
 	}
 	unregisteredNodes := clusterState.GetUnregisteredNodes()
 	assert.Equal(t, 1, len(unregisteredNodes))
 
 	// Nothing should be removed. The unregistered node is not old enough.
-	removed, err := removeOldUnregisteredNodes(unregisteredNodes, context, now.Add(-50*time.Minute), fakeLogRecorder)
+	removed, err := removeOldUnregisteredNodes(ctx.Background(), unregisteredNodes, context, now.Add(-50*time.Minute), fakeLogRecorder)
 	assert.NoError(t, err)
 	assert.False(t, removed)
 
 	// ng1_2 should be removed.
-	removed, err = removeOldUnregisteredNodes(unregisteredNodes, context, now, fakeLogRecorder)
+	removed, err = removeOldUnregisteredNodes(ctx.Background(), unregisteredNodes, context, now, fakeLogRecorder)
 	assert.NoError(t, err)
 	assert.True(t, removed)
 	deletedNode := getStringFromChan(deletedNodes)
@@ -492,15 +422,16 @@ func TestRemoveFixNodeTargetSize(t *testing.T) {
 			MaxNodeProvisionTime: 45 * time.Minute,
 		},
 		CloudProvider: provider,
+		Actuator:      NewDefaultActuator(fake.NewSimpleClientset(), kube_record.NewFakeRecorder(5), nil, ""),
 	}
 
 	// Nothing should be fixed. The incorrect size state is not old enough.
-	removed, err := fixNodeGroupSize(context, clusterState, now.Add(-50*time.Minute))
+	removed, err := fixNodeGroupSize(ctx.Background(), context, clusterState, now.Add(-50*time.Minute))
 	assert.NoError(t, err)
 	assert.False(t, removed)
 
 	// Node group should be decreased.
-	removed, err = fixNodeGroupSize(context, clusterState, now)
+	removed, err = fixNodeGroupSize(ctx.Background(), context, clusterState, now)
 	assert.NoError(t, err)
 	assert.True(t, removed)
 	change := getStringFromChan(sizeChanges)
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugging
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PathPrefix is the prefix of the paths of all debugging endpoints.
+	PathPrefix = "/debug/autoscaler/"
+	// defaultWaitTimeout is how long a request waits for the autoscaler loop to build a fresh state.
+	defaultWaitTimeout = 30 * time.Second
+)
+
+// Server exposes the State of the autoscaler as read-only JSON endpoints. The state is only built
+// by the autoscaler loop when a request is waiting for it.
+type Server struct {
+	mutex       sync.Mutex
+	state       *State
+	requested   bool
+	updated     chan struct{}
+	token       string
+	waitTimeout time.Duration
+}
+
+// NewServer builds new Server. If token is not empty, requests must carry it as a bearer token.
+func NewServer(token string) *Server {
+	return &Server{
+		updated:     make(chan struct{}),
+		token:       token,
+		waitTimeout: defaultWaitTimeout,
+	}
+}
+
+// StateRequested returns true if a request is waiting for a fresh state.
+func (s *Server) StateRequested() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requested
+}
+
+// Update replaces the exposed state and releases the requests waiting for it.
+func (s *Server) Update(state *State) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state = state
+	s.requested = false
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+// waitForState asks the autoscaler loop for a fresh state and waits for it. If the loop doesn't
+// deliver it in time, the last state is returned, which may be nil.
+func (s *Server) waitForState(r *http.Request) *State {
+	s.mutex.Lock()
+	s.requested = true
+	updated := s.updated
+	s.mutex.Unlock()
+
+	select {
+	case <-updated:
+	case <-r.Context().Done():
+	case <-time.After(s.waitTimeout):
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state
+}
+
+// RegisterHandlers registers the debugging endpoints in the given mux.
+func (s *Server) RegisterHandlers(mux *http.ServeMux) {
+	mux.Handle(PathPrefix, s)
+}
+
+// ServeHTTP implements http.Handler interface. The section of the state is selected by the path:
+// state, clusterstate, scaledown, scaleup, usagetracker or templates.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(405)
+		return
+	}
+
+	state := s.waitForState(r)
+	if state == nil {
+		w.WriteHeader(503)
+		w.Write([]byte("Autoscaler state not available yet"))
+		return
+	}
+
+	var section interface{}
+	switch strings.TrimPrefix(r.URL.Path, PathPrefix) {
+	case "state":
+		section = state
+	case "clusterstate":
+		section = state.ClusterState
+	case "scaledown":
+		section = state.ScaleDown
+	case "scaleup":
+		section = &State{Time: state.Time, LastScaleUpStatus: state.LastScaleUpStatus, LastScaleUpTime: state.LastScaleUpTime}
+	case "usagetracker":
+		section = state.UsageTracker
+	case "templates":
+		section = state.TemplateNodeInfos
+	default:
+		w.WriteHeader(404)
+		return
+	}
+
+	body, err := json.Marshal(section)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(body)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	expected := "Bearer " + s.token
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
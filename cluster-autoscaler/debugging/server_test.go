@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestResponse(server *Server, path string, token string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	server.RegisterHandlers(mux)
+	req := httptest.NewRequest("GET", path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+// newTestServer builds a Server that doesn't wait for the autoscaler loop, so it serves the last state.
+func newTestServer(token string) *Server {
+	server := NewServer(token)
+	server.waitTimeout = 0
+	return server
+}
+
+func buildTestState(now time.Time) *State {
+	return &State{
+		Time: now,
+		ClusterState: &clusterstate.DebugState{
+			Healthy:    true,
+			NodeGroups: map[string]clusterstate.NodeGroupDebugState{"ng1": {UpcomingNodes: 2}},
+		},
+		ScaleDown: &ScaleDownState{
+			UnneededNodes:    map[string]time.Time{"n1": now},
+			UnremovableNodes: map[string]UnremovableNode{"n2": {RecheckTime: now, Reason: "pdb"}},
+			Utilization:      map[string]float64{"n1": 0.1, "n2": 0.2},
+		},
+		LastScaleUpStatus: &looprecorder.ScaleUpStatus{ScaledUp: true},
+		LastScaleUpTime:   &now,
+	}
+}
+
+func TestServerNoState(t *testing.T) {
+	w := getTestResponse(newTestServer(""), PathPrefix+"state", "")
+	assert.Equal(t, 503, w.Code)
+}
+
+func TestServerSections(t *testing.T) {
+	server := newTestServer("")
+	now := time.Now()
+	server.Update(buildTestState(now))
+
+	w := getTestResponse(server, PathPrefix+"clusterstate", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	clusterState := &clusterstate.DebugState{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), clusterState))
+	assert.True(t, clusterState.Healthy)
+	assert.Equal(t, 2, clusterState.NodeGroups["ng1"].UpcomingNodes)
+
+	w = getTestResponse(server, PathPrefix+"scaledown", "")
+	assert.Equal(t, 200, w.Code)
+	scaleDown := &ScaleDownState{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), scaleDown))
+	assert.Equal(t, "pdb", scaleDown.UnremovableNodes["n2"].Reason)
+	assert.Equal(t, 0.1, scaleDown.Utilization["n1"])
+
+	w = getTestResponse(server, PathPrefix+"scaleup", "")
+	assert.Equal(t, 200, w.Code)
+	scaleUp := &State{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), scaleUp))
+	assert.True(t, scaleUp.LastScaleUpStatus.ScaledUp)
+	assert.Nil(t, scaleUp.ScaleDown)
+
+	w = getTestResponse(server, PathPrefix+"state", "")
+	assert.Equal(t, 200, w.Code)
+	state := &State{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), state))
+	assert.NotNil(t, state.ClusterState)
+	assert.NotNil(t, state.ScaleDown)
+
+	w = getTestResponse(server, PathPrefix+"unknown", "")
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestServerToken(t *testing.T) {
+	server := newTestServer("secret")
+	server.Update(buildTestState(time.Now()))
+
+	assert.Equal(t, 401, getTestResponse(server, PathPrefix+"state", "").Code)
+	assert.Equal(t, 401, getTestResponse(server, PathPrefix+"state", "wrong").Code)
+	assert.Equal(t, 200, getTestResponse(server, PathPrefix+"state", "secret").Code)
+}
+
+func TestServerWaitsForFreshState(t *testing.T) {
+	server := NewServer("")
+	now := time.Now()
+	server.Update(buildTestState(now.Add(-time.Minute)))
+	assert.False(t, server.StateRequested())
+
+	responses := make(chan *httptest.ResponseRecorder)
+	go func() {
+		responses <- getTestResponse(server, PathPrefix+"state", "")
+	}()
+	for !server.StateRequested() {
+		time.Sleep(time.Millisecond)
+	}
+	server.Update(buildTestState(now))
+	assert.False(t, server.StateRequested())
+
+	w := <-responses
+	assert.Equal(t, 200, w.Code)
+	state := &State{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), state))
+	assert.True(t, now.Equal(state.Time))
+}
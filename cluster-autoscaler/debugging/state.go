@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugging
+
+import (
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate"
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+)
+
+// UnremovableNode describes a node that was found unremovable by scale-down.
+type UnremovableNode struct {
+	// RecheckTime is the time after which scale-down checks the node again.
+	RecheckTime time.Time `json:"recheckTime"`
+	Reason      string    `json:"reason,omitempty"`
+	// BlockingPod is the namespace/name of the pod that prevents the removal, if any.
+	BlockingPod       string `json:"blockingPod,omitempty"`
+	BlockingPodReason string `json:"blockingPodReason,omitempty"`
+}
+
+// ScaleDownState is a copy of the internal state of scale-down.
+type ScaleDownState struct {
+	// UnneededNodes holds the time since when each node has been unneeded.
+	UnneededNodes    map[string]time.Time       `json:"unneededNodes"`
+	UnremovableNodes map[string]UnremovableNode `json:"unremovableNodes"`
+	// Utilization holds the utilization of the nodes checked in the last loop.
+	Utilization        map[string]float64 `json:"utilization"`
+	DeletionInProgress bool               `json:"deletionInProgress"`
+}
+
+// State is a snapshot of the internal state of the autoscaler, taken after a loop.
+type State struct {
+	Time         time.Time                `json:"time"`
+	ClusterState *clusterstate.DebugState `json:"clusterState,omitempty"`
+	ScaleDown    *ScaleDownState          `json:"scaleDown,omitempty"`
+	// LastScaleUpStatus is the result of the last scale-up attempt, taken at LastScaleUpTime.
+	LastScaleUpStatus *looprecorder.ScaleUpStatus      `json:"lastScaleUpStatus,omitempty"`
+	LastScaleUpTime   *time.Time                       `json:"lastScaleUpTime,omitempty"`
+	UsageTracker      map[string]simulator.UsageInfo   `json:"usageTracker,omitempty"`
+	TemplateNodeInfos map[string]looprecorder.NodeInfo `json:"templateNodeInfos,omitempty"`
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletionhooks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// DefaultTimeout is used for webhooks that don't set TimeoutSeconds.
+const DefaultTimeout = 10 * time.Second
+
+// Config is the content of the file passed with --node-deletion-hooks-config. Both YAML and JSON are accepted.
+type Config struct {
+	Webhooks []WebhookConfig `json:"webhooks"`
+}
+
+// WebhookConfig configures a single webhook.
+type WebhookConfig struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Phases in which the webhook is called. Defaults to PreDeletion only.
+	Phases []Phase `json:"phases,omitempty"`
+	// TimeoutSeconds is the timeout of a single call. Defaults to DefaultTimeout.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// FailurePolicy defaults to Fail.
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// LoadConfig reads hooks configuration from the given file and builds a Runner out of it.
+func LoadConfig(path string) (*Runner, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRunnerFromConfig(data)
+}
+
+// NewRunnerFromConfig builds a Runner out of YAML or JSON encoded Config.
+func NewRunnerFromConfig(data []byte) (*Runner, error) {
+	config := &Config{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse node deletion hooks config: %v", err)
+	}
+	runner := NewRunner()
+	names := make(map[string]bool)
+	for _, webhookConfig := range config.Webhooks {
+		if err := validateWebhookConfig(&webhookConfig); err != nil {
+			return nil, fmt.Errorf("invalid webhook %q: %v", webhookConfig.Name, err)
+		}
+		if names[webhookConfig.Name] {
+			return nil, fmt.Errorf("duplicate webhook name %q", webhookConfig.Name)
+		}
+		names[webhookConfig.Name] = true
+
+		phases := webhookConfig.Phases
+		if len(phases) == 0 {
+			phases = []Phase{PreDeletion}
+		}
+		timeout := DefaultTimeout
+		if webhookConfig.TimeoutSeconds > 0 {
+			timeout = time.Duration(webhookConfig.TimeoutSeconds) * time.Second
+		}
+		failurePolicy := webhookConfig.FailurePolicy
+		if failurePolicy == "" {
+			failurePolicy = Fail
+		}
+		runner.Register(NewWebhook(webhookConfig.Name, webhookConfig.URL, timeout), phases, failurePolicy)
+	}
+	return runner, nil
+}
+
+func validateWebhookConfig(webhookConfig *WebhookConfig) error {
+	if webhookConfig.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	parsed, err := url.Parse(webhookConfig.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("url must use http or https scheme")
+	}
+	for _, phase := range webhookConfig.Phases {
+		if phase != PreDeletion && phase != PostDeletion {
+			return fmt.Errorf("unknown phase %q", phase)
+		}
+	}
+	if webhookConfig.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeoutSeconds must not be negative")
+	}
+	switch webhookConfig.FailurePolicy {
+	case "", Fail, Ignore:
+	default:
+		return fmt.Errorf("unknown failure policy %q", webhookConfig.FailurePolicy)
+	}
+	return nil
+}
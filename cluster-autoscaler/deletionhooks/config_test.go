@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletionhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunnerFromConfig(t *testing.T) {
+	config := `
+webhooks:
+- name: storage
+  url: http://storage.example.com/detach
+- name: approval
+  url: https://approval.example.com/node
+  phases: [PreDeletion, PostDeletion]
+  timeoutSeconds: 3
+  failurePolicy: Ignore
+`
+	runner, err := NewRunnerFromConfig([]byte(config))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(runner.hooks))
+
+	storage := runner.hooks[0]
+	assert.Equal(t, "storage", storage.hook.Name())
+	assert.Equal(t, map[Phase]bool{PreDeletion: true}, storage.phases)
+	assert.Equal(t, Fail, storage.failurePolicy)
+	assert.Equal(t, DefaultTimeout, storage.hook.(*webhook).client.Timeout)
+
+	approval := runner.hooks[1]
+	assert.Equal(t, "approval", approval.hook.Name())
+	assert.Equal(t, map[Phase]bool{PreDeletion: true, PostDeletion: true}, approval.phases)
+	assert.Equal(t, Ignore, approval.failurePolicy)
+	assert.Equal(t, 3*time.Second, approval.hook.(*webhook).client.Timeout)
+	assert.Equal(t, "https://approval.example.com/node", approval.hook.(*webhook).url)
+}
+
+func TestNewRunnerFromConfigInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"missing name":   `{"webhooks": [{"url": "http://a"}]}`,
+		"bad scheme":     `{"webhooks": [{"name": "a", "url": "ftp://a"}]}`,
+		"unknown phase":  `{"webhooks": [{"name": "a", "url": "http://a", "phases": ["Sometimes"]}]}`,
+		"bad policy":     `{"webhooks": [{"name": "a", "url": "http://a", "failurePolicy": "Retry"}]}`,
+		"negative time":  `{"webhooks": [{"name": "a", "url": "http://a", "timeoutSeconds": -1}]}`,
+		"duplicate name": `{"webhooks": [{"name": "a", "url": "http://a"}, {"name": "a", "url": "http://b"}]}`,
+		"not parseable":  `webhooks: [`,
+	}
+	for name, config := range testCases {
+		_, err := NewRunnerFromConfig([]byte(config))
+		assert.Error(t, err, name)
+	}
+}
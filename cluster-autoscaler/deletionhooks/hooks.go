@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletionhooks
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// Phase identifies the moment of node removal at which a hook is called.
+type Phase string
+
+const (
+	// PreDeletion hooks are called before the node is tainted, drained and removed. They can veto
+	// the removal or ask to retry it later.
+	PreDeletion Phase = "PreDeletion"
+	// PostDeletion hooks are called after the node was removed from the cloud provider. Their
+	// decision is ignored.
+	PostDeletion Phase = "PostDeletion"
+)
+
+// Decision is the answer of a hook.
+type Decision string
+
+const (
+	// Allow lets the node removal proceed.
+	Allow Decision = "Allow"
+	// Deny vetoes the node removal.
+	Deny Decision = "Deny"
+	// RetryLater postpones the node removal.
+	RetryLater Decision = "RetryLater"
+)
+
+// FailurePolicy defines what happens when a hook can't be called or returns an invalid answer.
+type FailurePolicy string
+
+const (
+	// Fail treats a hook failure as a veto.
+	Fail FailurePolicy = "Fail"
+	// Ignore treats a hook failure as an approval.
+	Ignore FailurePolicy = "Ignore"
+)
+
+// Request is passed to hooks. Webhooks receive it JSON encoded.
+type Request struct {
+	Phase Phase       `json:"phase"`
+	Node  *apiv1.Node `json:"node"`
+	// Pods that are going to be (or were) evicted from the node.
+	Pods []*apiv1.Pod `json:"pods,omitempty"`
+}
+
+// Response is returned by hooks. Webhooks return it JSON encoded.
+type Response struct {
+	Decision Decision `json:"decision"`
+	// Message is a human readable explanation of the decision.
+	Message string `json:"message,omitempty"`
+	// RetryAfterSeconds is the minimal time after which the removal may be retried.
+	// Used only with Deny and RetryLater decisions.
+	RetryAfterSeconds int `json:"retryAfterSeconds,omitempty"`
+}
+
+// Hook is called around removal of a node by scale down. Implementations have to be safe
+// for concurrent use.
+type Hook interface {
+	// Name identifies the hook in events, logs and metrics.
+	Name() string
+	// Call runs the hook. An error is handled according to the hook's FailurePolicy.
+	Call(request *Request) (*Response, error)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletionhooks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+
+	apiv1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// callFailed is the metric outcome of a hook that couldn't be called or returned an invalid answer.
+	callFailed = "Failed"
+)
+
+type registeredHook struct {
+	hook          Hook
+	phases        map[Phase]bool
+	failurePolicy FailurePolicy
+}
+
+// Result is the combined outcome of the pre-deletion hooks called for a node.
+type Result struct {
+	// Decision is Allow only if all hooks allowed the removal.
+	Decision Decision
+	// Hook is the name of the hook that denied or postponed the removal.
+	Hook string
+	// Message explains the decision.
+	Message string
+	// RetryAfter is the time requested by the hook before the removal is attempted again. Zero if not set.
+	RetryAfter time.Duration
+}
+
+// Runner calls the registered hooks in registration order.
+type Runner struct {
+	hooks []*registeredHook
+}
+
+// NewRunner builds a Runner without any hooks.
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+// Register adds a hook called in the given phases.
+func (r *Runner) Register(hook Hook, phases []Phase, failurePolicy FailurePolicy) {
+	registered := &registeredHook{
+		hook:          hook,
+		phases:        make(map[Phase]bool),
+		failurePolicy: failurePolicy,
+	}
+	for _, phase := range phases {
+		registered.phases[phase] = true
+	}
+	r.hooks = append(r.hooks, registered)
+}
+
+// RunPreDeletion calls pre-deletion hooks for the node until one of them doesn't allow the removal.
+func (r *Runner) RunPreDeletion(node *apiv1.Node, pods []*apiv1.Pod) *Result {
+	request := &Request{Phase: PreDeletion, Node: node, Pods: pods}
+	for _, registered := range r.hooks {
+		if !registered.phases[PreDeletion] {
+			continue
+		}
+		name := registered.hook.Name()
+		response, err := call(registered.hook, request)
+		if err != nil {
+			if registered.failurePolicy == Ignore {
+				glog.Warningf("Pre-deletion hook %s failed for node %s, ignoring: %v", name, node.Name, err)
+				continue
+			}
+			return &Result{Decision: Deny, Hook: name, Message: fmt.Sprintf("hook failed: %v", err)}
+		}
+		if response.Decision != Allow {
+			return &Result{
+				Decision:   response.Decision,
+				Hook:       name,
+				Message:    response.Message,
+				RetryAfter: time.Duration(response.RetryAfterSeconds) * time.Second,
+			}
+		}
+	}
+	return &Result{Decision: Allow}
+}
+
+// RunPostDeletion calls all post-deletion hooks for the node. Failures are returned but
+// have no other effect, as the node is already gone.
+func (r *Runner) RunPostDeletion(node *apiv1.Node, pods []*apiv1.Pod) error {
+	request := &Request{Phase: PostDeletion, Node: node, Pods: pods}
+	var errs []error
+	for _, registered := range r.hooks {
+		if !registered.phases[PostDeletion] {
+			continue
+		}
+		if _, err := call(registered.hook, request); err != nil {
+			errs = append(errs, fmt.Errorf("hook %s failed: %v", registered.hook.Name(), err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// call calls the hook, validates its response and records the outcome.
+func call(hook Hook, request *Request) (*Response, error) {
+	start := time.Now()
+	response, err := hook.Call(request)
+	if err == nil {
+		err = validate(response)
+	}
+	outcome := callFailed
+	if err == nil {
+		outcome = string(response.Decision)
+	}
+	metrics.RegisterDeletionHookCall(hook.Name(), string(request.Phase), outcome, time.Now().Sub(start))
+	glog.V(2).Infof("%s hook %s for node %s: %s", request.Phase, hook.Name(), request.Node.Name, outcome)
+	return response, err
+}
+
+func validate(response *Response) error {
+	if response == nil {
+		return fmt.Errorf("empty response")
+	}
+	switch response.Decision {
+	case Allow, Deny, RetryLater:
+	default:
+		return fmt.Errorf("unknown decision %q", response.Decision)
+	}
+	if response.RetryAfterSeconds < 0 {
+		return fmt.Errorf("negative retryAfterSeconds %d", response.RetryAfterSeconds)
+	}
+	return nil
+}
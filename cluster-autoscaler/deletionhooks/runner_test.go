@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletionhooks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHook struct {
+	name     string
+	response *Response
+	err      error
+	calls    []Phase
+}
+
+func (f *fakeHook) Name() string {
+	return f.name
+}
+
+func (f *fakeHook) Call(request *Request) (*Response, error) {
+	f.calls = append(f.calls, request.Phase)
+	return f.response, f.err
+}
+
+func TestRunPreDeletion(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 1000)
+	allow := &Response{Decision: Allow}
+	testCases := []struct {
+		name           string
+		hooks          []*fakeHook
+		failurePolicy  FailurePolicy
+		expectedResult *Result
+		expectedCalls  []int
+	}{
+		{
+			name:           "no hooks",
+			expectedResult: &Result{Decision: Allow},
+		},
+		{
+			name:           "all allow",
+			hooks:          []*fakeHook{{name: "a", response: allow}, {name: "b", response: allow}},
+			expectedResult: &Result{Decision: Allow},
+			expectedCalls:  []int{1, 1},
+		},
+		{
+			name: "deny stops further hooks",
+			hooks: []*fakeHook{
+				{name: "a", response: &Response{Decision: Deny, Message: "attached"}},
+				{name: "b", response: allow},
+			},
+			expectedResult: &Result{Decision: Deny, Hook: "a", Message: "attached"},
+			expectedCalls:  []int{1, 0},
+		},
+		{
+			name: "retry later",
+			hooks: []*fakeHook{
+				{name: "a", response: allow},
+				{name: "b", response: &Response{Decision: RetryLater, Message: "busy", RetryAfterSeconds: 60}},
+			},
+			expectedResult: &Result{Decision: RetryLater, Hook: "b", Message: "busy", RetryAfter: time.Minute},
+			expectedCalls:  []int{1, 1},
+		},
+		{
+			name:           "failure with fail policy",
+			hooks:          []*fakeHook{{name: "a", err: fmt.Errorf("connection refused")}},
+			failurePolicy:  Fail,
+			expectedResult: &Result{Decision: Deny, Hook: "a", Message: "hook failed: connection refused"},
+			expectedCalls:  []int{1},
+		},
+		{
+			name:           "failure with ignore policy",
+			hooks:          []*fakeHook{{name: "a", err: fmt.Errorf("connection refused")}, {name: "b", response: allow}},
+			failurePolicy:  Ignore,
+			expectedResult: &Result{Decision: Allow},
+			expectedCalls:  []int{1, 1},
+		},
+		{
+			name:           "unknown decision is a failure",
+			hooks:          []*fakeHook{{name: "a", response: &Response{Decision: "Maybe"}}},
+			failurePolicy:  Fail,
+			expectedResult: &Result{Decision: Deny, Hook: "a", Message: "hook failed: unknown decision \"Maybe\""},
+			expectedCalls:  []int{1},
+		},
+	}
+	for _, tc := range testCases {
+		runner := NewRunner()
+		for _, hook := range tc.hooks {
+			runner.Register(hook, []Phase{PreDeletion}, tc.failurePolicy)
+		}
+		assert.Equal(t, tc.expectedResult, runner.RunPreDeletion(node, nil), tc.name)
+		for i, hook := range tc.hooks {
+			assert.Equal(t, tc.expectedCalls[i], len(hook.calls), "%s: calls of %s", tc.name, hook.name)
+		}
+	}
+}
+
+func TestRunnerPhases(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 1000)
+	pre := &fakeHook{name: "pre", response: &Response{Decision: Allow}}
+	post := &fakeHook{name: "post", response: &Response{Decision: Allow}}
+	both := &fakeHook{name: "both", response: &Response{Decision: Allow}}
+	runner := NewRunner()
+	runner.Register(pre, []Phase{PreDeletion}, Fail)
+	runner.Register(post, []Phase{PostDeletion}, Fail)
+	runner.Register(both, []Phase{PreDeletion, PostDeletion}, Fail)
+
+	runner.RunPreDeletion(node, nil)
+	assert.NoError(t, runner.RunPostDeletion(node, nil))
+
+	assert.Equal(t, []Phase{PreDeletion}, pre.calls)
+	assert.Equal(t, []Phase{PostDeletion}, post.calls)
+	assert.Equal(t, []Phase{PreDeletion, PostDeletion}, both.calls)
+}
+
+func TestRunPostDeletionCallsAllHooks(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 1000)
+	failing := &fakeHook{name: "failing", err: fmt.Errorf("timeout")}
+	denying := &fakeHook{name: "denying", response: &Response{Decision: Deny}}
+	runner := NewRunner()
+	runner.Register(failing, []Phase{PostDeletion}, Fail)
+	runner.Register(denying, []Phase{PostDeletion}, Fail)
+
+	err := runner.RunPostDeletion(node, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failing")
+	assert.Equal(t, 1, len(failing.calls))
+	assert.Equal(t, 1, len(denying.calls))
+}
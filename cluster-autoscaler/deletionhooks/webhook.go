@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletionhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// maxResponseBytes caps the size of a webhook response that is read.
+const maxResponseBytes = 64 * 1024
+
+type webhook struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a Hook that POSTs the JSON encoded Request to the given url and expects
+// a JSON encoded Response with status 200. Calls taking longer than timeout fail.
+func NewWebhook(name, url string, timeout time.Duration) Hook {
+	return &webhook{
+		name:   name,
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Hook interface.
+func (w *webhook) Name() string {
+	return w.name
+}
+
+// Call implements Hook interface.
+func (w *webhook) Call(request *Request) (*Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %v", err)
+	}
+	httpResponse, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(httpResponse.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d: %s", httpResponse.StatusCode, string(data))
+	}
+	response := &Response{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return response, nil
+}
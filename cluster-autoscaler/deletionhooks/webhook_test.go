@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletionhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookCall(t *testing.T) {
+	var received *Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received = &Request{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(received))
+		w.Write([]byte(`{"decision": "RetryLater", "message": "busy", "retryAfterSeconds": 30}`))
+	}))
+	defer server.Close()
+
+	node := BuildTestNode("n1", 1000, 1000)
+	pod := BuildTestPod("p1", 100, 0)
+	response, err := NewWebhook("lb", server.URL, time.Second).Call(&Request{Phase: PreDeletion, Node: node, Pods: []*apiv1.Pod{pod}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Response{Decision: RetryLater, Message: "busy", RetryAfterSeconds: 30}, response)
+	assert.Equal(t, PreDeletion, received.Phase)
+	assert.Equal(t, "n1", received.Node.Name)
+	assert.Equal(t, 1, len(received.Pods))
+	assert.Equal(t, "p1", received.Pods[0].Name)
+}
+
+func TestWebhookCallErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "unexpected status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "invalid body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("not json"))
+			},
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				w.Write([]byte(`{"decision": "Allow"}`))
+			},
+		},
+	}
+	for _, tc := range testCases {
+		server := httptest.NewServer(tc.handler)
+		node := BuildTestNode("n1", 1000, 1000)
+		_, err := NewWebhook("lb", server.URL, 50*time.Millisecond).Call(&Request{Phase: PreDeletion, Node: node})
+		assert.Error(t, err, tc.name)
+		server.Close()
+	}
+}
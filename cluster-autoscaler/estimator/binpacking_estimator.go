@@ -17,6 +17,7 @@ limitations under the License.
 package estimator
 
 import (
+	"fmt"
 	"sort"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -25,10 +26,11 @@ import (
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 )
 
-// podInfo contains Pod and score that corresponds to how important it is to handle the pod first.
+// podInfo contains Pod, its equivalence group and score that corresponds to how important it is to handle the pod first.
 type podInfo struct {
 	score float64
 	pod   *apiv1.Pod
+	group *simulator.PodEquivalenceGroup
 }
 
 // BinpackingNodeEstimator estimates the number of needed nodes to handle the given amount of pods.
@@ -52,36 +54,59 @@ func NewBinpackingNodeEstimator(predicateChecker *simulator.PredicateChecker) *B
 // Returns the number of nodes needed to accommodate all pods from the list.
 func (estimator *BinpackingNodeEstimator) Estimate(pods []*apiv1.Pod, nodeTemplate *schedulercache.NodeInfo,
 	comingNodes []*schedulercache.NodeInfo) int {
+	return estimator.EstimateGroups(simulator.BuildPodEquivalenceGroups(pods), nodeTemplate, comingNodes)
+}
 
-	podInfos := calculatePodScore(pods, nodeTemplate)
-	sort.Slice(podInfos, func(i, j int) bool { return podInfos[i].score > podInfos[j].score })
+// EstimateGroups works like Estimate for pods grouped into equivalence groups. Pods of a group are
+// packed one after another. A pod doesn't fit on the nodes that an equivalent pod packed before it
+// didn't fit on, so the predicates are only checked from the node the previous pod landed on.
+func (estimator *BinpackingNodeEstimator) EstimateGroups(groups []*simulator.PodEquivalenceGroup, nodeTemplate *schedulercache.NodeInfo,
+	comingNodes []*schedulercache.NodeInfo) int {
 
-	// nodeWithPod function returns NodeInfo, which is a copy of nodeInfo argument with an additional pod scheduled on it.
-	nodeWithPod := func(nodeInfo *schedulercache.NodeInfo, pod *apiv1.Pod) *schedulercache.NodeInfo {
-		podsOnNode := nodeInfo.Pods()
-		podsOnNode = append(podsOnNode, pod)
-		newNodeInfo := schedulercache.NewNodeInfo(podsOnNode...)
-		newNodeInfo.SetNode(nodeInfo.Node())
-		return newNodeInfo
+	representatives := make([]*apiv1.Pod, 0, len(groups))
+	for _, group := range groups {
+		representatives = append(representatives, group.Representative())
+	}
+	podInfos := calculatePodScore(representatives, nodeTemplate)
+	for i := range podInfos {
+		podInfos[i].group = groups[i]
+	}
+	sort.SliceStable(podInfos, func(i, j int) bool { return podInfos[i].score > podInfos[j].score })
+
+	// Coming nodes are often copies of the same template, so nodes are kept in the snapshot under their position.
+	snapshot := simulator.NewEmptyClusterSnapshot()
+	newNodeNames := make([]string, 0, len(comingNodes))
+	addNode := func(nodeInfo *schedulercache.NodeInfo) string {
+		nodeName := fmt.Sprintf("estimator-node-%d", len(newNodeNames))
+		// Names are unique, adding can't fail.
+		snapshot.AddNodeInfo(nodeName, nodeInfo)
+		newNodeNames = append(newNodeNames, nodeName)
+		return nodeName
+	}
+	for _, nodeInfo := range comingNodes {
+		addNode(nodeInfo)
 	}
-
-	newNodes := make([]*schedulercache.NodeInfo, 0)
-	newNodes = append(newNodes, comingNodes...)
 
 	for _, podInfo := range podInfos {
-		found := false
-		for i, nodeInfo := range newNodes {
-			if err := estimator.predicateChecker.CheckPredicates(podInfo.pod, nil, nodeInfo); err == nil {
-				found = true
-				newNodes[i] = nodeWithPod(nodeInfo, podInfo.pod)
-				break
+		firstNode := 0
+		for _, pod := range podInfo.group.Pods {
+			found := false
+			for i := firstNode; i < len(newNodeNames); i++ {
+				nodeInfo, _ := snapshot.GetNodeInfo(newNodeNames[i])
+				if err := estimator.predicateChecker.CheckPredicates(pod, nil, nodeInfo); err == nil {
+					found = true
+					firstNode = i
+					snapshot.AddPod(pod, newNodeNames[i])
+					break
+				}
+			}
+			if !found {
+				firstNode = len(newNodeNames)
+				snapshot.AddPod(pod, addNode(nodeTemplate))
 			}
-		}
-		if !found {
-			newNodes = append(newNodes, nodeWithPod(nodeTemplate, podInfo.pod))
 		}
 	}
-	return len(newNodes) - len(comingNodes)
+	return len(newNodeNames) - len(comingNodes)
 }
 
 // Calculates score for all pods and returns podInfo structure.
@@ -120,3 +120,42 @@ func TestBinpackingEstimateWithPorts(t *testing.T) {
 	estimate := estimator.Estimate(pods, nodeInfo, []*schedulercache.NodeInfo{})
 	assert.Equal(t, 8, estimate)
 }
+
+func TestBinpackingEstimateGroups(t *testing.T) {
+	estimator := NewBinpackingNodeEstimator(simulator.NewTestPredicateChecker())
+
+	memoryPerPod := int64(100 * 1024 * 1024)
+	large := GenerateOwnerReferences("large", "ReplicaSet", "extensions/v1beta1", "large-uid")
+	small := GenerateOwnerReferences("small", "ReplicaSet", "extensions/v1beta1", "small-uid")
+	pods := make([]*apiv1.Pod, 0)
+	for i := 0; i < 6; i++ {
+		smallPod := makePod(300, memoryPerPod)
+		smallPod.OwnerReferences = small
+		largePod := makePod(600, memoryPerPod)
+		largePod.OwnerReferences = large
+		pods = append(pods, smallPod, largePod)
+	}
+	node := &apiv1.Node{
+		Status: apiv1.NodeStatus{
+			Capacity: apiv1.ResourceList{
+				apiv1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+				apiv1.ResourceMemory: *resource.NewQuantity(10*memoryPerPod, resource.DecimalSI),
+				apiv1.ResourcePods:   *resource.NewQuantity(10, resource.DecimalSI),
+			},
+		},
+	}
+	node.Status.Allocatable = node.Status.Capacity
+	SetNodeReadyState(node, true, time.Time{})
+
+	nodeInfo := schedulercache.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	groups := simulator.BuildPodEquivalenceGroups(pods)
+	assert.Len(t, groups, 2)
+	// Every large pod needs its own node, small pods fit next to them.
+	assert.Equal(t, 6, estimator.EstimateGroups(groups, nodeInfo, []*schedulercache.NodeInfo{}))
+	assert.Equal(t, 6, estimator.Estimate(pods, nodeInfo, []*schedulercache.NodeInfo{}))
+	// 6 - 2 nodes that are coming, one of them already running a large pod.
+	comingNode := schedulercache.NewNodeInfo(makePod(600, memoryPerPod))
+	comingNode.SetNode(node)
+	assert.Equal(t, 5, estimator.EstimateGroups(groups, nodeInfo, []*schedulercache.NodeInfo{comingNode, nodeInfo}))
+}
@@ -0,0 +1,31 @@
+#!/bin/bash
+
+# Copyright 2018 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Regenerates deepcopy functions and the clientset for apis/clusterautoscaler.
+# Requires k8s.io/code-generator in GOPATH.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+CA_ROOT=$(dirname "${BASH_SOURCE}")/..
+CODEGEN_PKG=${CODEGEN_PKG:-${GOPATH}/src/k8s.io/code-generator}
+
+${CODEGEN_PKG}/generate-groups.sh "deepcopy,client" \
+  github.com/gardener/autoscaler/cluster-autoscaler/client \
+  github.com/gardener/autoscaler/cluster-autoscaler/apis \
+  clusterautoscaler:v1alpha1 \
+  --go-header-file ${CA_ROOT}/../hack/boilerplate/boilerplate.go.txt
@@ -0,0 +1,311 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package looprecorder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
+	"github.com/gardener/autoscaler/cluster-autoscaler/processors/status"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+// NodeGroup is the recorded state of a node group.
+type NodeGroup struct {
+	Id         string   `json:"id"`
+	MinSize    int      `json:"minSize"`
+	MaxSize    int      `json:"maxSize"`
+	TargetSize int      `json:"targetSize"`
+	Nodes      []string `json:"nodes,omitempty"`
+}
+
+// NodeInfo is a recorded node template.
+type NodeInfo struct {
+	Node *apiv1.Node  `json:"node"`
+	Pods []*apiv1.Pod `json:"pods,omitempty"`
+}
+
+// ScaleUpInfo describes a recorded node group size increase.
+type ScaleUpInfo struct {
+	NodeGroup   string `json:"nodeGroup"`
+	CurrentSize int    `json:"currentSize"`
+	NewSize     int    `json:"newSize"`
+	MaxSize     int    `json:"maxSize"`
+}
+
+// NoScaleUpInfo describes why a pod didn't trigger scale-up.
+type NoScaleUpInfo struct {
+	Pod                string              `json:"pod"`
+	RejectedNodeGroups map[string][]string `json:"rejectedNodeGroups,omitempty"`
+	SkippedNodeGroups  map[string][]string `json:"skippedNodeGroups,omitempty"`
+}
+
+// ScaleUpStatus is the recorded result of a scale-up attempt.
+type ScaleUpStatus struct {
+	ScaledUp                bool            `json:"scaledUp"`
+	ScaleUpInfos            []ScaleUpInfo   `json:"scaleUpInfos,omitempty"`
+	PodsTriggeredScaleUp    []string        `json:"podsTriggeredScaleUp,omitempty"`
+	PodsRemainUnschedulable []NoScaleUpInfo `json:"podsRemainUnschedulable,omitempty"`
+	PodsAwaitEvaluation     []string        `json:"podsAwaitEvaluation,omitempty"`
+}
+
+// Record holds the inputs and decisions of a single autoscaler loop. All setters
+// do nothing on a nil Record, so callers don't need to check whether recording is enabled.
+type Record struct {
+	Time                 time.Time                       `json:"time"`
+	Nodes                []*apiv1.Node                   `json:"nodes"`
+	ScheduledPods        []*apiv1.Pod                    `json:"scheduledPods"`
+	UnschedulablePods    []*apiv1.Pod                    `json:"unschedulablePods"`
+	PodDisruptionBudgets []*policyv1.PodDisruptionBudget `json:"podDisruptionBudgets,omitempty"`
+	DaemonSets           []*extensionsv1.DaemonSet       `json:"daemonSets,omitempty"`
+	NodeGroups           []NodeGroup                     `json:"nodeGroups"`
+	UpcomingNodes        map[string]int                  `json:"upcomingNodes,omitempty"`
+	TemplateNodeInfos    map[string]NodeInfo             `json:"templateNodeInfos,omitempty"`
+	ScaleUpStatus        *ScaleUpStatus                  `json:"scaleUpStatus,omitempty"`
+	ScaleDownCandidates  []string                        `json:"scaleDownCandidates,omitempty"`
+	Error                string                          `json:"error,omitempty"`
+}
+
+// NewRecord creates an empty record of the loop started at the given time.
+func NewRecord(loopTime time.Time) *Record {
+	return &Record{Time: loopTime}
+}
+
+// SetNodes records all nodes of the cluster.
+func (r *Record) SetNodes(nodes []*apiv1.Node) {
+	if r == nil {
+		return
+	}
+	r.Nodes = nodes
+}
+
+// SetPods records the scheduled and unschedulable pods.
+func (r *Record) SetPods(scheduled []*apiv1.Pod, unschedulable []*apiv1.Pod) {
+	if r == nil {
+		return
+	}
+	r.ScheduledPods = scheduled
+	r.UnschedulablePods = unschedulable
+}
+
+// SetPodDisruptionBudgets records the pod disruption budgets.
+func (r *Record) SetPodDisruptionBudgets(pdbs []*policyv1.PodDisruptionBudget) {
+	if r == nil {
+		return
+	}
+	r.PodDisruptionBudgets = pdbs
+}
+
+// SetDaemonSets records the daemon sets.
+func (r *Record) SetDaemonSets(daemonSets []*extensionsv1.DaemonSet) {
+	if r == nil {
+		return
+	}
+	r.DaemonSets = daemonSets
+}
+
+// SetNodeGroups records the node groups of the cloud provider and the nodes that belong to them.
+func (r *Record) SetNodeGroups(cloudProvider cloudprovider.CloudProvider) error {
+	if r == nil {
+		return nil
+	}
+	r.NodeGroups = make([]NodeGroup, 0)
+	for _, nodeGroup := range cloudProvider.NodeGroups() {
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			return err
+		}
+		r.NodeGroups = append(r.NodeGroups, NodeGroup{
+			Id:         nodeGroup.Id(),
+			MinSize:    nodeGroup.MinSize(),
+			MaxSize:    nodeGroup.MaxSize(),
+			TargetSize: targetSize,
+		})
+	}
+	sort.Slice(r.NodeGroups, func(i, j int) bool { return r.NodeGroups[i].Id < r.NodeGroups[j].Id })
+
+	index := make(map[string]int)
+	for i, ng := range r.NodeGroups {
+		index[ng.Id] = i
+	}
+	for _, node := range r.Nodes {
+		nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			return err
+		}
+		if nodeGroup == nil {
+			continue
+		}
+		if i, found := index[nodeGroup.Id()]; found {
+			r.NodeGroups[i].Nodes = append(r.NodeGroups[i].Nodes, node.Name)
+		}
+	}
+	return nil
+}
+
+// SetUpcomingNodes records the number of nodes that are being provisioned in each node group.
+func (r *Record) SetUpcomingNodes(upcomingNodes map[string]int) {
+	if r == nil {
+		return
+	}
+	r.UpcomingNodes = upcomingNodes
+}
+
+// SetTemplateNodeInfos records the node templates used by scale-up.
+func (r *Record) SetTemplateNodeInfos(nodeInfos map[string]*schedulercache.NodeInfo) {
+	if r == nil {
+		return
+	}
+	r.TemplateNodeInfos = NewNodeInfos(nodeInfos)
+}
+
+// SetScaleUpStatus records the result of scale-up.
+func (r *Record) SetScaleUpStatus(scaleUpStatus *status.ScaleUpStatus) {
+	if r == nil {
+		return
+	}
+	r.ScaleUpStatus = NewScaleUpStatus(scaleUpStatus)
+}
+
+// SetScaleDownCandidates records the nodes considered unneeded by scale-down.
+func (r *Record) SetScaleDownCandidates(nodes []*apiv1.Node) {
+	if r == nil {
+		return
+	}
+	r.ScaleDownCandidates = make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		r.ScaleDownCandidates = append(r.ScaleDownCandidates, node.Name)
+	}
+}
+
+// SetError records the error returned by the loop.
+func (r *Record) SetError(err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.Error = err.Error()
+}
+
+// Marshal serializes the record to gzipped JSON.
+func (r *Record) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if err := json.NewEncoder(writer).Encode(r); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalRecord deserializes a record written by Marshal. Plain JSON is accepted as well.
+func UnmarshalRecord(data []byte) (*Record, error) {
+	if reader, err := gzip.NewReader(bytes.NewReader(data)); err == nil {
+		data, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress record: %v", err)
+		}
+	}
+	record := &Record{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("failed to parse record: %v", err)
+	}
+	return record, nil
+}
+
+// LoadRecord reads a record from a file.
+func LoadRecord(path string) (*Record, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalRecord(data)
+}
+
+// NewNodeInfos converts node infos to their serializable form.
+func NewNodeInfos(nodeInfos map[string]*schedulercache.NodeInfo) map[string]NodeInfo {
+	result := make(map[string]NodeInfo, len(nodeInfos))
+	for id, nodeInfo := range nodeInfos {
+		result[id] = NodeInfo{Node: nodeInfo.Node(), Pods: nodeInfo.Pods()}
+	}
+	return result
+}
+
+// NewScaleUpStatus converts the result of scale-up to its serializable form.
+func NewScaleUpStatus(scaleUpStatus *status.ScaleUpStatus) *ScaleUpStatus {
+	if scaleUpStatus == nil {
+		return nil
+	}
+	result := &ScaleUpStatus{
+		ScaledUp:             scaleUpStatus.ScaledUp,
+		PodsTriggeredScaleUp: podNames(scaleUpStatus.PodsTriggeredScaleUp),
+		PodsAwaitEvaluation:  podNames(scaleUpStatus.PodsAwaitEvaluation),
+	}
+	for _, info := range scaleUpStatus.ScaleUpInfos {
+		result.ScaleUpInfos = append(result.ScaleUpInfos, ScaleUpInfo{
+			NodeGroup:   info.Group.Id(),
+			CurrentSize: info.CurrentSize,
+			NewSize:     info.NewSize,
+			MaxSize:     info.MaxSize,
+		})
+	}
+	for _, info := range scaleUpStatus.PodsRemainUnschedulable {
+		result.PodsRemainUnschedulable = append(result.PodsRemainUnschedulable, NoScaleUpInfo{
+			Pod:                podName(info.Pod),
+			RejectedNodeGroups: reasons(info.RejectedNodeGroups),
+			SkippedNodeGroups:  reasons(info.SkippedNodeGroups),
+		})
+	}
+	return result
+}
+
+func podName(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+func podNames(pods []*apiv1.Pod) []string {
+	if len(pods) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		result = append(result, podName(pod))
+	}
+	return result
+}
+
+func reasons(nodeGroupReasons map[string]status.Reasons) map[string][]string {
+	if len(nodeGroupReasons) == 0 {
+		return nil
+	}
+	result := make(map[string][]string)
+	for id, r := range nodeGroupReasons {
+		result[id] = r.Reasons()
+	}
+	return result
+}
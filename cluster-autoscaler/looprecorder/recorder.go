@@ -0,0 +1,213 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package looprecorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+
+	"github.com/golang/glog"
+)
+
+const (
+	recordFilePrefix = "loop-"
+	recordFileSuffix = ".json.gz"
+	// ConfigMapNextKey is the key of the loop record ConfigMap holding the slot of the next record.
+	ConfigMapNextKey         = "next"
+	configMapRecordKeyPrefix = "record-"
+)
+
+// Recorder persists loop records.
+type Recorder interface {
+	// Write persists the record.
+	Write(record *Record) error
+}
+
+// DirectoryRecorder writes each record to a separate file in a directory and removes
+// the oldest files once their total size exceeds the limit.
+type DirectoryRecorder struct {
+	dir      string
+	maxBytes int64
+}
+
+// NewDirectoryRecorder builds new DirectoryRecorder. The directory is created if needed.
+func NewDirectoryRecorder(dir string, maxBytes int64) (*DirectoryRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create loop record directory %s: %v", dir, err)
+	}
+	return &DirectoryRecorder{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// Write persists the record in a new file and rotates the directory.
+func (r *DirectoryRecorder) Write(record *Record) error {
+	data, err := record.Marshal()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s%020d%s", recordFilePrefix, record.Time.UnixNano(), recordFileSuffix)
+	if err := ioutil.WriteFile(filepath.Join(r.dir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write loop record: %v", err)
+	}
+	return r.rotate()
+}
+
+// ListFiles returns the paths of the recorded files, oldest first.
+func (r *DirectoryRecorder) ListFiles() ([]string, error) {
+	files, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		if isRecordFile(file) {
+			result = append(result, filepath.Join(r.dir, file.Name()))
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// rotate removes the oldest records until the total size fits the limit. The newest
+// record is always kept.
+func (r *DirectoryRecorder) rotate() error {
+	files, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	records := make([]os.FileInfo, 0, len(files))
+	var total int64
+	for _, file := range files {
+		if isRecordFile(file) {
+			records = append(records, file)
+			total += file.Size()
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name() < records[j].Name() })
+	for i := 0; i < len(records)-1 && total > r.maxBytes; i++ {
+		if err := os.Remove(filepath.Join(r.dir, records[i].Name())); err != nil {
+			return fmt.Errorf("failed to remove old loop record: %v", err)
+		}
+		total -= records[i].Size()
+	}
+	return nil
+}
+
+func isRecordFile(file os.FileInfo) bool {
+	return !file.IsDir() && strings.HasPrefix(file.Name(), recordFilePrefix) && strings.HasSuffix(file.Name(), recordFileSuffix)
+}
+
+// ConfigMapRecorder keeps the most recent records in a ConfigMap used as a ring buffer.
+// ConfigMaps are limited to 1MB, so this is suitable for small clusters only.
+type ConfigMapRecorder struct {
+	client    kube_client.Interface
+	namespace string
+	name      string
+	size      int
+}
+
+// NewConfigMapRecorder builds new ConfigMapRecorder keeping up to size records.
+func NewConfigMapRecorder(client kube_client.Interface, namespace, name string, size int) *ConfigMapRecorder {
+	return &ConfigMapRecorder{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		size:      size,
+	}
+}
+
+// Write stores the record in the next slot of the ring buffer.
+func (r *ConfigMapRecorder) Write(record *Record) error {
+	data, err := record.Marshal()
+	if err != nil {
+		return err
+	}
+	maps := r.client.CoreV1().ConfigMaps(r.namespace)
+	configMap, err := maps.Get(r.name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		configMap = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: r.namespace,
+				Name:      r.name,
+			},
+			Data:       map[string]string{ConfigMapNextKey: "1"},
+			BinaryData: map[string][]byte{configMapRecordKeyPrefix + "0": data},
+		}
+		_, err = maps.Create(configMap)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get loop record configmap: %v", err)
+	}
+
+	next, err := strconv.Atoi(configMap.Data[ConfigMapNextKey])
+	if err != nil || next < 0 || next >= r.size {
+		next = 0
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	if configMap.BinaryData == nil {
+		configMap.BinaryData = make(map[string][]byte)
+	}
+	configMap.BinaryData[configMapRecordKeyPrefix+strconv.Itoa(next)] = data
+	configMap.Data[ConfigMapNextKey] = strconv.Itoa((next + 1) % r.size)
+	_, err = maps.Update(configMap)
+	return err
+}
+
+// ReadConfigMapRecords returns the records kept in the given ConfigMap, oldest first.
+func ReadConfigMapRecords(client kube_client.Interface, namespace, name string) ([]*Record, error) {
+	configMap, err := client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Record, 0, len(configMap.BinaryData))
+	for key, data := range configMap.BinaryData {
+		if !strings.HasPrefix(key, configMapRecordKeyPrefix) {
+			continue
+		}
+		record, err := UnmarshalRecord(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", key, err)
+		}
+		result = append(result, record)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result, nil
+}
+
+// WriteOrLog persists the record and logs a warning on failure. Recording must never
+// break the autoscaler loop.
+func WriteOrLog(recorder Recorder, record *Record) {
+	start := time.Now()
+	if err := recorder.Write(record); err != nil {
+		glog.Warningf("Failed to record loop: %v", err)
+		return
+	}
+	glog.V(4).Infof("Recorded loop in %v", time.Now().Sub(start))
+}
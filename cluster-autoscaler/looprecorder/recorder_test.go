@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package looprecorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTestRecord(loopTime time.Time) *Record {
+	record := NewRecord(loopTime)
+	record.SetNodes([]*apiv1.Node{BuildTestNode("n1", 1000, 1000)})
+	record.SetPods([]*apiv1.Pod{BuildTestPod("p1", 100, 0)}, []*apiv1.Pod{BuildTestPod("p2", 100, 0)})
+	record.SetError(fmt.Errorf("loop failed"))
+	return record
+}
+
+func TestMarshalRecord(t *testing.T) {
+	now := time.Now().Round(time.Second)
+	record := buildTestRecord(now)
+	data, err := record.Marshal()
+	assert.NoError(t, err)
+
+	unmarshaled, err := UnmarshalRecord(data)
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(unmarshaled.Time))
+	assert.Equal(t, "n1", unmarshaled.Nodes[0].Name)
+	assert.Equal(t, "p1", unmarshaled.ScheduledPods[0].Name)
+	assert.Equal(t, "p2", unmarshaled.UnschedulablePods[0].Name)
+	assert.Equal(t, "loop failed", unmarshaled.Error)
+
+	unmarshaled, err = UnmarshalRecord([]byte(`{"error": "plain"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", unmarshaled.Error)
+
+	_, err = UnmarshalRecord([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestNilRecord(t *testing.T) {
+	var record *Record
+	record.SetNodes(nil)
+	record.SetPods(nil, nil)
+	record.SetError(fmt.Errorf("ignored"))
+	assert.NoError(t, record.SetNodeGroups(nil))
+}
+
+func TestDirectoryRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "looprecorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	data, err := buildTestRecord(time.Now()).Marshal()
+	assert.NoError(t, err)
+	// Keep at most two records.
+	recorder, err := NewDirectoryRecorder(filepath.Join(dir, "records"), int64(2*len(data)+len(data)/2))
+	assert.NoError(t, err)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, recorder.Write(buildTestRecord(start.Add(time.Duration(i)*time.Second))))
+	}
+	files, err := recorder.ListFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+
+	record, err := LoadRecord(files[1])
+	assert.NoError(t, err)
+	assert.True(t, start.Add(3*time.Second).Equal(record.Time))
+}
+
+func TestDirectoryRecorderKeepsNewest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "looprecorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recorder, err := NewDirectoryRecorder(dir, 1)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, recorder.Write(buildTestRecord(time.Now().Add(time.Duration(i)*time.Second))))
+	}
+	files, err := recorder.ListFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+}
+
+func TestConfigMapRecorder(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	recorder := NewConfigMapRecorder(client, "kube-system", "loop-records", 2)
+
+	start := time.Now().Round(time.Second)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, recorder.Write(buildTestRecord(start.Add(time.Duration(i)*time.Second))))
+	}
+
+	configMap, err := client.CoreV1().ConfigMaps("kube-system").Get("loop-records", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", configMap.Data[ConfigMapNextKey])
+	assert.Equal(t, 2, len(configMap.BinaryData))
+
+	records, err := ReadConfigMapRecords(client, "kube-system", "loop-records")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.True(t, start.Add(time.Second).Equal(records[0].Time))
+	assert.True(t, start.Add(2*time.Second).Equal(records[1].Time))
+}
@@ -20,6 +20,7 @@ import (
 	ctx "context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -29,17 +30,31 @@ import (
 	"syscall"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apiserverconfig "k8s.io/apiserver/pkg/apis/config"
 	kube_flag "k8s.io/apiserver/pkg/util/flag"
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
+	"github.com/gardener/autoscaler/cluster-autoscaler/checkpoint"
+	ca_clientset "github.com/gardener/autoscaler/cluster-autoscaler/client/clientset/versioned"
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
 	cloudBuilder "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/builder"
 	"github.com/gardener/autoscaler/cluster-autoscaler/config"
+	"github.com/gardener/autoscaler/cluster-autoscaler/context"
 	"github.com/gardener/autoscaler/cluster-autoscaler/core"
+	"github.com/gardener/autoscaler/cluster-autoscaler/debugging"
+	"github.com/gardener/autoscaler/cluster-autoscaler/deletionhooks"
 	"github.com/gardener/autoscaler/cluster-autoscaler/estimator"
 	"github.com/gardener/autoscaler/cluster-autoscaler/expander"
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
 	"github.com/gardener/autoscaler/cluster-autoscaler/metrics"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
+	"github.com/gardener/autoscaler/cluster-autoscaler/tracing"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
 	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+	ca_leaderelection "github.com/gardener/autoscaler/cluster-autoscaler/utils/leaderelection"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/units"
 	kube_client "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -47,6 +62,7 @@ import (
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/kubernetes/pkg/client/leaderelectionconfig"
+	schedulerfactory "k8s.io/kubernetes/pkg/scheduler/factory"
 
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
@@ -93,6 +109,10 @@ var (
 		"How long an unready node should be unneeded before it is eligible for scale down")
 	scaleDownUtilizationThreshold = flag.Float64("scale-down-utilization-threshold", 0.5,
 		"Node utilization level, defined as sum of requested resources divided by capacity, below which a node can be considered for scale down")
+	utilizationResourceWeights = flag.String("utilization-resource-weights", "",
+		"Comma separated weights of resources in node utilization, in the format <resource_name>=<weight>, e.g. ephemeral-storage=0.5,example.com/fpga=0. "+
+			"Node utilization is the maximum of weighted utilization of cpu, memory, ephemeral-storage, hugepages and extended resources. "+
+			"Resources without a weight have weight 1, a weight of 0 leaves a resource out.")
 	scaleDownNonEmptyCandidatesCount = flag.Int("scale-down-non-empty-candidates-count", 30,
 		"Maximum number of non empty nodes considered in one iteration as candidates for scale down with drain."+
 			"Lower value means better CA responsiveness but possible slower scale down latency."+
@@ -114,9 +134,12 @@ var (
 	coresTotal        = flag.String("cores-total", minMaxFlagString(0, config.DefaultMaxClusterCores), "Minimum and maximum number of cores in cluster, in the format <min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers.")
 	memoryTotal       = flag.String("memory-total", minMaxFlagString(0, config.DefaultMaxClusterMemory), "Minimum and maximum number of gigabytes of memory in cluster, in the format <min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers.")
 	gpuTotal          = multiStringFlag("gpu-total", "Minimum and maximum number of different GPUs in cluster, in the format <gpu_type>:<min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers. Can be passed multiple times. CURRENTLY THIS FLAG ONLY WORKS ON GKE.")
+	resourceTotal     = multiStringFlag("resource-total", "Minimum and maximum amount of a node resource other than cpu, memory and GPUs in cluster, in the format <resource_name>:<min>:<max>, e.g. example.com/fpga:0:16 or ephemeral-storage:0:10Ti. Cluster autoscaler will not scale the cluster beyond these numbers. Can be passed multiple times.")
 	cloudProviderFlag = flag.String("cloud-provider", cloudBuilder.DefaultCloudProvider,
 		"Cloud provider type. Available values: ["+strings.Join(cloudBuilder.AvailableCloudProviders, ",")+"]")
 	maxEmptyBulkDeleteFlag     = flag.Int("max-empty-bulk-delete", 10, "Maximum number of empty nodes that can be deleted at the same time.")
+	maxBulkSoftTaintCount      = flag.Int("max-bulk-soft-taint-count", 0, "Maximum number of nodes that can be tainted/untainted PreferNoSchedule at the same time. Set to 0 to turn off such tainting.")
+	maxBulkSoftTaintTime       = flag.Duration("max-bulk-soft-taint-time", 3*time.Second, "Maximum duration of tainting/untainting nodes as PreferNoSchedule at the same time.")
 	maxGracefulTerminationFlag = flag.Int("max-graceful-termination-sec", 10*60, "Maximum number of seconds CA waits for pod termination when trying to scale down a node.")
 	maxTotalUnreadyPercentage  = flag.Float64("max-total-unready-percentage", 45, "Maximum percentage of unready nodes in the cluster.  After this is exceeded, CA halts operations")
 	okTotalUnreadyCount        = flag.Int("ok-total-unready-count", 3, "Number of allowed unready nodes, irrespective of max-total-unready-percentage")
@@ -137,8 +160,11 @@ var (
 
 	expanderFlag = flag.String("expander", expander.RandomExpanderName,
 		"Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]")
+	scaleDownOrderFlag = flag.String("scale-down-order", scaledownorder.NoneName,
+		"Order in which nodes are considered for removal in scale down. Available values: ["+strings.Join(scaledownorder.AvailableStrategies, ",")+"]")
 
 	writeStatusConfigMapFlag         = flag.Bool("write-status-configmap", true, "Should CA write status information to a configmap")
+	writeStatusResourceFlag          = flag.Bool("write-status-resource", false, "Should CA write structured status information to a ClusterAutoscalerStatus custom resource. The CRD has to be installed in the cluster")
 	maxInactivityTimeFlag            = flag.Duration("max-inactivity", 10*time.Minute, "Maximum time from last recorded autoscaler activity before automatic restart")
 	maxFailingTimeFlag               = flag.Duration("max-failing-time", 15*time.Minute, "Maximum time from last recorded successful autoscaler run before automatic restart")
 	balanceSimilarNodeGroupsFlag     = flag.Bool("balance-similar-node-groups", false, "Detect similar node groups and balance the number of nodes between them")
@@ -146,8 +172,49 @@ var (
 	maxAutoprovisionedNodeGroupCount = flag.Int("max-autoprovisioned-node-group-count", 15, "The maximum number of autoprovisioned groups in the cluster.")
 
 	unremovableNodeRecheckTimeout = flag.Duration("unremovable-node-recheck-timeout", 5*time.Minute, "The timeout before we check again a node that couldn't be removed before")
+	unremovableNodeEventInterval  = flag.Duration("unremovable-node-event-interval", 30*time.Minute, "Minimal interval between events explaining why a node can't be removed. 0 disables the events")
 	expendablePodsPriorityCutoff  = flag.Int("expendable-pods-priority-cutoff", -10, "Pods with priority below cutoff will be expendable. They can be killed without any consideration during scale down and they don't cause scale up. Pods with null priority (PodPriority disabled) are non expendable.")
 	regional                      = flag.Bool("regional", false, "Cluster is regional.")
+	dryRun                        = flag.Bool("dry-run", false, "Compute scaling decisions without executing them. Intended actions are exposed via events, metrics and the /dry-run-actions endpoint.")
+
+	loopRecordDir           = flag.String("loop-record-dir", "", "Directory to record the inputs and decisions of each autoscaler loop in. Recorded loops can be replayed with the simulate command.")
+	loopRecordMaxBytes      = flag.Int64("loop-record-max-bytes", 100*1024*1024, "Maximum total size of the loop records kept in --loop-record-dir. The oldest records are removed first.")
+	loopRecordConfigMap     = flag.String("loop-record-configmap", "", "Name of the ConfigMap to record the most recent autoscaler loops in, if --loop-record-dir is not set.")
+	loopRecordConfigMapSize = flag.Int("loop-record-configmap-size", 3, "Number of loop records kept in --loop-record-configmap.")
+
+	enableDebuggingAPI    = flag.Bool("enable-debugging-api", false, "Expose the internal state of the autoscaler as JSON under /debug/autoscaler/ on --address. The state is built by the first loop after a request arrives.")
+	debuggingAPITokenFile = flag.String("debugging-api-token-file", "", "Path to a file holding a bearer token required to access the debugging API. If empty, the debugging API is not protected.")
+
+	stateConfigMap          = flag.String("state-configmap", "", "Name of the ConfigMap to periodically save scale-down bookkeeping, scale-down delay timers and node group backoff in. The state is restored on start, so that restarts and leader changes don't reset the unneeded time of nodes or the scale-down delays. Disabled if empty.")
+	stateCheckpointInterval = flag.Duration("state-checkpoint-interval", time.Minute, "How often the state is saved to --state-configmap.")
+	stateMaxAge             = flag.Duration("state-max-age", 30*time.Minute, "Maximum age of the state in --state-configmap that is still restored on start.")
+
+	shutdownGracePeriod       = flag.Duration("shutdown-grace-period", time.Minute, "How long CA waits for node deletions in progress to finish when it stops. Deletions still in progress afterwards, or when CA loses leadership, are aborted and their nodes untainted.")
+	loopDeadlineScanIntervals = flag.Int("loop-deadline-scan-intervals", 6, "How many --scan-interval periods the cloud provider calls made in one loop may take before they time out. Background node deletions get the same deadline per node. 0 disables the deadline.")
+
+	nodeDeletionHooksConfig = flag.String("node-deletion-hooks-config", "", "Path to a YAML or JSON file configuring webhooks called before and after a node is removed by scale down. No hooks are called if empty.")
+
+	safeToEvictLocalVolumes         = multiStringFlag("safe-to-evict-local-volume", "Name of an emptyDir volume whose data may be lost when its pod is evicted in scale down. Such volumes don't block scale down even with --skip-nodes-with-local-storage. Can be used multiple times.")
+	replicatedControllerKinds       = multiStringFlag("replicated-controller-kind", "Kind of a custom controller whose pods are treated as replicated in scale down, in the format <kind>.<group>, e.g. Workflow.argoproj.io, or <kind> to match any API group. Can be used multiple times.")
+	drainPolicyConfigMap            = flag.String("drain-policy-configmap", "", "Name of the ConfigMap in --namespace holding namespace-level drain policies under the \"policy\" key. Disabled if empty.")
+	drainPolicyNamespaceAnnotations = flag.Bool("drain-policy-namespace-annotations", false, "Should the safe-to-evict and safe-to-evict-local-volumes annotations on namespaces apply to all pods of the namespace. Requires permissions to list and watch namespaces.")
+
+	traceExporter       = flag.String("trace-exporter", tracing.NoExporter, "Where traces of autoscaler loops are exported. Available values: "+strings.Join(tracing.AvailableExporters, ","))
+	traceFile           = flag.String("trace-file", "", "File the spans are appended to, one JSON object per line, if --trace-exporter=file.")
+	traceAgentAddress   = flag.String("trace-agent-address", "localhost:55678", "Address of the OpenCensus agent the spans are sent to if --trace-exporter=ocagent.")
+	traceSampleFraction = flag.Float64("trace-sample-fraction", 1.0, "Fraction of autoscaler loops that are traced.")
+
+	auditLogFile       = flag.String("audit-log-file", "", "File every scaling decision is appended to, one JSON object per line. \"-\" writes the decisions to stdout. Disabled if empty.")
+	auditLogMaxBytes   = flag.Int64("audit-log-max-bytes", 100*1024*1024, "Size over which --audit-log-file is rotated.")
+	auditLogMaxBackups = flag.Int("audit-log-max-backups", 5, "Number of rotated --audit-log-file files kept.")
+
+	simulationParallelism = flag.Int("simulation-parallelism", 4, "Number of goroutines checking scale-down candidates and unschedulable pods in parallel. Results don't depend on it.")
+	simulationSeed        = flag.Int64("simulation-seed", 0, "Seed for the random choices made in scale-down simulations. A fixed seed makes them reproducible, 0 picks a random one.")
+
+	schedulerPolicyFile        = flag.String("scheduler-policy-file", "", "File with the kube-scheduler Policy whose predicates are used in simulations, so that they match the scheduler of the cluster.")
+	schedulerPolicyConfigMap   = flag.String("scheduler-policy-configmap", "", "Name of the ConfigMap holding the kube-scheduler Policy under the \"policy.cfg\" key. Alternative to --scheduler-policy-file.")
+	schedulerPolicyNamespace   = flag.String("scheduler-policy-namespace", "kube-system", "Namespace of --scheduler-policy-configmap.")
+	schedulerAlgorithmProvider = flag.String("scheduler-algorithm-provider", schedulerfactory.DefaultProvider, "Scheduler algorithm provider whose predicates are used if no scheduler policy is given or the policy doesn't list predicates.")
 )
 
 func createAutoscalingOptions() config.AutoscalingOptions {
@@ -167,6 +234,21 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 	if err != nil {
 		glog.Fatalf("Failed to parse flags: %v", err)
 	}
+	parsedResourceTotal, err := parseMultipleResourceLimits(*resourceTotal)
+	if err != nil {
+		glog.Fatalf("Failed to parse flags: %v", err)
+	}
+	parsedUtilizationWeights, err := parseUtilizationResourceWeights(*utilizationResourceWeights)
+	if err != nil {
+		glog.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if _, err := drain.NewReplicatedKindsResolver(*replicatedControllerKinds); err != nil {
+		glog.Fatalf("Failed to parse flags: %v", err)
+	}
+	if *loopDeadlineScanIntervals < 0 {
+		glog.Fatalf("Failed to parse flags: --loop-deadline-scan-intervals must not be negative")
+	}
 
 	return config.AutoscalingOptions{
 		CloudConfig:                      *cloudConfig,
@@ -176,7 +258,10 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		OkTotalUnreadyCount:              *okTotalUnreadyCount,
 		EstimatorName:                    *estimatorFlag,
 		ExpanderName:                     *expanderFlag,
+		ScaleDownOrderName:               *scaleDownOrderFlag,
 		MaxEmptyBulkDelete:               *maxEmptyBulkDeleteFlag,
+		MaxBulkSoftTaintCount:            *maxBulkSoftTaintCount,
+		MaxBulkSoftTaintTime:             *maxBulkSoftTaintTime,
 		MaxGracefulTerminationSec:        *maxGracefulTerminationFlag,
 		MaxNodeProvisionTime:             *maxNodeProvisionTime,
 		MaxNodesTotal:                    *maxNodesTotal,
@@ -185,26 +270,44 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		MaxMemoryTotal:                   maxMemoryTotal,
 		MinMemoryTotal:                   minMemoryTotal,
 		GpuTotal:                         parsedGpuTotal,
+		ResourceTotal:                    parsedResourceTotal,
 		NodeGroups:                       *nodeGroupsFlag,
 		ScaleDownDelayAfterAdd:           *scaleDownDelayAfterAdd,
 		ScaleDownDelayAfterDelete:        *scaleDownDelayAfterDelete,
 		ScaleDownDelayAfterFailure:       *scaleDownDelayAfterFailure,
 		ScaleDownEnabled:                 *scaleDownEnabled,
 		ScaleDownUnneededTime:            *scaleDownUnneededTime,
+		ScaleDownStateCheckpointInterval: *stateCheckpointInterval,
+		ScaleDownStateMaxAge:             *stateMaxAge,
 		ScaleDownUnreadyTime:             *scaleDownUnreadyTime,
 		ScaleDownUtilizationThreshold:    *scaleDownUtilizationThreshold,
+		UtilizationResourceWeights:       parsedUtilizationWeights,
 		ScaleDownNonEmptyCandidatesCount: *scaleDownNonEmptyCandidatesCount,
 		ScaleDownCandidatesPoolRatio:     *scaleDownCandidatesPoolRatio,
 		ScaleDownCandidatesPoolMinCount:  *scaleDownCandidatesPoolMinCount,
 		WriteStatusConfigMap:             *writeStatusConfigMapFlag,
+		WriteStatusResource:              *writeStatusResourceFlag,
 		BalanceSimilarNodeGroups:         *balanceSimilarNodeGroupsFlag,
 		ConfigNamespace:                  *namespace,
 		ClusterName:                      *clusterName,
 		NodeAutoprovisioningEnabled:      *nodeAutoprovisioningEnabled,
 		MaxAutoprovisionedNodeGroupCount: *maxAutoprovisionedNodeGroupCount,
 		UnremovableNodeRecheckTimeout:    *unremovableNodeRecheckTimeout,
+		UnremovableNodeEventInterval:     *unremovableNodeEventInterval,
 		ExpendablePodsPriorityCutoff:     *expendablePodsPriorityCutoff,
 		Regional:                         *regional,
+		DryRun:                           *dryRun,
+		SafeToEvictLocalVolumes:          *safeToEvictLocalVolumes,
+		ReplicatedControllerKinds:        *replicatedControllerKinds,
+		DrainPolicyConfigMap:             *drainPolicyConfigMap,
+		DrainPolicyNamespaceAnnotations:  *drainPolicyNamespaceAnnotations,
+		LoopDeadline:                     time.Duration(*loopDeadlineScanIntervals) * *scanInterval,
+		SimulationParallelism:            *simulationParallelism,
+		SimulationSeed:                   *simulationSeed,
+		SchedulerPolicyFile:              *schedulerPolicyFile,
+		SchedulerPolicyConfigMap:         *schedulerPolicyConfigMap,
+		SchedulerPolicyNamespace:         *schedulerPolicyNamespace,
+		SchedulerAlgorithmProvider:       *schedulerAlgorithmProvider,
 	}
 }
 
@@ -235,29 +338,90 @@ func createKubeClient(kubeConfig *rest.Config) kube_client.Interface {
 	return kube_client.NewForConfigOrDie(kubeConfig)
 }
 
-func registerSignalHandlers(autoscaler core.Autoscaler) {
+// registerSignalHandlers cancels the returned context when the process is asked to terminate.
+func registerSignalHandlers() ctx.Context {
+	signalCtx, cancel := ctx.WithCancel(ctx.Background())
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGQUIT)
 	glog.V(1).Info("Registered cleanup signal handler")
 
 	go func() {
 		<-sigs
-		glog.V(1).Info("Received signal, attempting cleanup")
-		autoscaler.ExitCleanUp()
-		glog.V(1).Info("Cleaned up, exiting...")
-		glog.Flush()
-		os.Exit(0)
+		glog.V(1).Info("Received signal, stopping")
+		cancel()
 	}()
+	return signalCtx
 }
 
 func buildAutoscaler() (core.Autoscaler, error) {
 	// Create basic config from flags.
 	autoscalingOptions := createAutoscalingOptions()
-	kubeClient := createKubeClient(getKubeConfig())
+	kubeConfig := getKubeConfig()
+	kubeClient := createKubeClient(kubeConfig)
 	opts := core.AutoscalerOptions{
 		AutoscalingOptions: autoscalingOptions,
 		KubeClient:         kubeClient,
 	}
+	if autoscalingOptions.WriteStatusResource {
+		opts.StatusClient = ca_clientset.NewForConfigOrDie(kubeConfig)
+	}
+
+	if autoscalingOptions.DryRun {
+		glog.V(0).Infof("Running in dry-run mode, no changes will be made to the cluster")
+		opts.AutoscalingKubeClients = context.NewAutoscalingKubeClients(autoscalingOptions, kubeClient)
+		dryRunActuator := actuation.NewDryRunActuator(opts.AutoscalingKubeClients.Recorder, opts.AutoscalingKubeClients.LogRecorder)
+		http.Handle("/dry-run-actions", dryRunActuator)
+		opts.Actuator = dryRunActuator
+	}
+
+	if *loopRecordDir != "" {
+		recorder, err := looprecorder.NewDirectoryRecorder(*loopRecordDir, *loopRecordMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		opts.LoopRecorder = recorder
+	} else if *loopRecordConfigMap != "" {
+		if *loopRecordConfigMapSize <= 0 {
+			return nil, fmt.Errorf("--loop-record-configmap-size must be positive")
+		}
+		opts.LoopRecorder = looprecorder.NewConfigMapRecorder(kubeClient, autoscalingOptions.ConfigNamespace, *loopRecordConfigMap, *loopRecordConfigMapSize)
+	}
+
+	if *auditLogFile == "-" {
+		opts.AuditLog = auditlog.NewWriterLog(os.Stdout, autoscalingOptions.DryRun)
+	} else if *auditLogFile != "" {
+		auditLog, err := auditlog.NewFileLog(*auditLogFile, *auditLogMaxBytes, *auditLogMaxBackups, autoscalingOptions.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		opts.AuditLog = auditLog
+	}
+
+	if *stateConfigMap != "" {
+		opts.StateStore = checkpoint.NewConfigMapStore(kubeClient, autoscalingOptions.ConfigNamespace, *stateConfigMap)
+	}
+
+	if *nodeDeletionHooksConfig != "" {
+		deletionHooks, err := deletionhooks.LoadConfig(*nodeDeletionHooksConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load node deletion hooks: %v", err)
+		}
+		opts.DeletionHooks = deletionHooks
+	}
+
+	if *enableDebuggingAPI {
+		token := ""
+		if *debuggingAPITokenFile != "" {
+			data, err := ioutil.ReadFile(*debuggingAPITokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read debugging API token: %v", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		debuggingServer := debugging.NewServer(token)
+		debuggingServer.RegisterHandlers(http.DefaultServeMux)
+		opts.DebuggingServer = debuggingServer
+	}
 
 	// This metric should be published only once.
 	metrics.UpdateNapEnabled(autoscalingOptions.NodeAutoprovisioningEnabled)
@@ -266,7 +430,10 @@ func buildAutoscaler() (core.Autoscaler, error) {
 	return core.NewAutoscaler(opts)
 }
 
-func run(healthCheck *metrics.HealthCheck) {
+// run runs the autoscaler until stopCtx or leaderCtx is done. When stopping, node deletions in progress
+// are given up to --shutdown-grace-period to finish before they are aborted. When the leadership is lost,
+// they are aborted right away, since another instance may already be acting on the same nodes.
+func run(stopCtx ctx.Context, leaderCtx ctx.Context, healthCheck *metrics.HealthCheck) {
 	metrics.RegisterAll()
 
 	autoscaler, err := buildAutoscaler()
@@ -274,22 +441,41 @@ func run(healthCheck *metrics.HealthCheck) {
 		glog.Fatalf("Failed to create autoscaler: %v", err)
 	}
 
-	// Register signal handlers for graceful shutdown.
-	registerSignalHandlers(autoscaler)
+	runCtx, cancel := ctx.WithCancel(leaderCtx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCtx.Done():
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
 
 	// Start updating health check endpoint.
 	healthCheck.StartMonitoring()
 
-	// Autoscale ad infinitum.
+	// Autoscale until stopped.
 	for {
 		select {
+		case <-runCtx.Done():
+			glog.V(1).Info("Stopping autoscaler")
+			// waitCtx is done right away if the leadership is lost.
+			waitCtx, cancelWait := ctx.WithTimeout(leaderCtx, *shutdownGracePeriod)
+			if !autoscaler.StopInFlightActions(waitCtx) {
+				glog.Warningf("Node deletions in progress were aborted")
+			}
+			cancelWait()
+			// The saved state and status are left to the new leader if the leadership was lost.
+			autoscaler.ExitCleanUp(leaderCtx.Err() == nil)
+			glog.V(1).Info("Cleaned up")
+			return
 		case <-time.After(*scanInterval):
 			{
 				loopStart := time.Now()
 				metrics.UpdateLastTime(metrics.Main, loopStart)
 				healthCheck.UpdateLastActivity(loopStart)
 
-				err := autoscaler.RunOnce(loopStart)
+				err := autoscaler.RunOnce(runCtx, loopStart)
 				if err != nil && err.Type() != errors.TransientError {
 					metrics.RegisterError(err)
 				} else {
@@ -307,6 +493,8 @@ func main() {
 	leaderElection.LeaderElect = true
 
 	leaderelectionconfig.BindFlags(&leaderElection, pflag.CommandLine)
+	pflag.CommandLine.Lookup("leader-elect-resource-lock").Usage = "The type of resource object that is used for locking during " +
+		"leader election. Supported options are `endpoints` (default), `configmaps` and `leases`."
 	kube_flag.InitFlags()
 	healthCheck := metrics.NewHealthCheck(*maxInactivityTimeFlag, *maxFailingTimeFlag)
 
@@ -322,6 +510,22 @@ func main() {
 		glog.Fatalf("Unrecognized estimator: %v", *estimatorFlag)
 	}
 
+	if pflag.Arg(0) == "simulate" {
+		runSimulation()
+		return
+	}
+
+	stopTracing, err := tracing.Setup(tracing.Options{
+		Exporter:       *traceExporter,
+		File:           *traceFile,
+		AgentAddress:   *traceAgentAddress,
+		SampleFraction: *traceSampleFraction,
+	})
+	if err != nil {
+		glog.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer stopTracing()
+
 	go func() {
 		http.Handle("/metrics", prometheus.Handler())
 		http.Handle("/health-check", healthCheck)
@@ -329,53 +533,59 @@ func main() {
 		glog.Fatalf("Failed to start metrics: %v", err)
 	}()
 
+	signalCtx := registerSignalHandlers()
 	if !leaderElection.LeaderElect {
-		run(healthCheck)
-	} else {
-		id, err := os.Hostname()
-		if err != nil {
-			glog.Fatalf("Unable to get hostname: %v", err)
-		}
+		run(signalCtx, ctx.Background(), healthCheck)
+		glog.Flush()
+		return
+	}
 
-		kubeClient := createKubeClient(getKubeConfig())
+	id, err := os.Hostname()
+	if err != nil {
+		glog.Fatalf("Unable to get hostname: %v", err)
+	}
 
-		// Validate that the client is ok.
-		_, err = kubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
-		if err != nil {
-			glog.Fatalf("Failed to get nodes from apiserver: %v", err)
-		}
+	kubeClient := createKubeClient(getKubeConfig())
 
-		lock, err := resourcelock.New(
-			leaderElection.ResourceLock,
-			*namespace,
-			"cluster-autoscaler",
-			kubeClient.CoreV1(),
-			resourcelock.ResourceLockConfig{
-				Identity:      id,
-				EventRecorder: kube_util.CreateEventRecorder(kubeClient),
-			},
-		)
-		if err != nil {
-			glog.Fatalf("Unable to create leader election lock: %v", err)
-		}
+	// Validate that the client is ok.
+	_, err = kubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		glog.Fatalf("Failed to get nodes from apiserver: %v", err)
+	}
 
-		leaderelection.RunOrDie(ctx.TODO(), leaderelection.LeaderElectionConfig{
-			Lock:          lock,
-			LeaseDuration: leaderElection.LeaseDuration.Duration,
-			RenewDeadline: leaderElection.RenewDeadline.Duration,
-			RetryPeriod:   leaderElection.RetryPeriod.Duration,
-			Callbacks: leaderelection.LeaderCallbacks{
-				OnStartedLeading: func(_ ctx.Context) {
-					// Since we are committing a suicide after losing
-					// mastership, we can safely ignore the argument.
-					run(healthCheck)
-				},
-				OnStoppedLeading: func() {
-					glog.Fatalf("lost master")
-				},
-			},
-		})
+	lock, err := ca_leaderelection.NewResourceLock(
+		leaderElection.ResourceLock,
+		*namespace,
+		"cluster-autoscaler",
+		kubeClient,
+		resourcelock.ResourceLockConfig{
+			Identity:      id,
+			EventRecorder: kube_util.CreateEventRecorder(kubeClient),
+		},
+	)
+	if err != nil {
+		glog.Fatalf("Unable to create leader election lock: %v", err)
+	}
+
+	err = ca_leaderelection.Run(signalCtx, leaderelection.LeaderElectionConfig{
+		LeaseDuration: leaderElection.LeaseDuration.Duration,
+		RenewDeadline: leaderElection.RenewDeadline.Duration,
+		RetryPeriod:   leaderElection.RetryPeriod.Duration,
+	}, lock, func(leaderCtx ctx.Context) {
+		// leaderCtx is done when the leadership is lost. The lease is renewed until run returns, so that
+		// node deletions in progress can finish before a standby instance takes over.
+		run(signalCtx, leaderCtx, healthCheck)
+	})
+	if err != nil {
+		glog.Errorf("Leader election failed: %v", err)
 	}
+	if signalCtx.Err() == nil {
+		glog.Errorf("lost master")
+		stopTracing()
+		glog.Flush()
+		os.Exit(1)
+	}
+	glog.Flush()
 }
 
 func defaultLeaderElectionConfiguration() apiserverconfig.LeaderElectionConfiguration {
@@ -474,3 +684,71 @@ func parseSingleGpuLimit(limits string) (config.GpuLimits, error) {
 	}
 	return parsedGpuLimits, nil
 }
+
+func parseMultipleResourceLimits(flags MultiStringFlag) ([]config.ResourceLimits, error) {
+	parsedFlags := make([]config.ResourceLimits, 0, len(flags))
+	for _, flag := range flags {
+		parsedFlag, err := parseSingleResourceLimit(flag)
+		if err != nil {
+			return nil, err
+		}
+		parsedFlags = append(parsedFlags, parsedFlag)
+	}
+	return parsedFlags, nil
+}
+
+func parseSingleResourceLimit(limits string) (config.ResourceLimits, error) {
+	parts := strings.Split(limits, ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return config.ResourceLimits{}, fmt.Errorf("Incorrect resource limit specification: %v", limits)
+	}
+	name := parts[0]
+	if name == cloudprovider.ResourceNameCores || name == cloudprovider.ResourceNameMemory {
+		return config.ResourceLimits{}, fmt.Errorf("Incorrect resource limit - use --cores-total or --memory-total for %s: %v", name, limits)
+	}
+	minVal, err := resource.ParseQuantity(parts[1])
+	if err != nil {
+		return config.ResourceLimits{}, fmt.Errorf("Incorrect resource limit - min is not a quantity: %v", limits)
+	}
+	maxVal, err := resource.ParseQuantity(parts[2])
+	if err != nil {
+		return config.ResourceLimits{}, fmt.Errorf("Incorrect resource limit - max is not a quantity: %v", limits)
+	}
+	if minVal.Sign() < 0 {
+		return config.ResourceLimits{}, fmt.Errorf("Incorrect resource limit - min is less than 0; %v", limits)
+	}
+	if maxVal.Sign() < 0 {
+		return config.ResourceLimits{}, fmt.Errorf("Incorrect resource limit - max is less than 0; %v", limits)
+	}
+	if minVal.Cmp(maxVal) > 0 {
+		return config.ResourceLimits{}, fmt.Errorf("Incorrect resource limit - min is greater than max; %v", limits)
+	}
+	parsedResourceLimits := config.ResourceLimits{
+		Name: name,
+		Min:  minVal.Value(),
+		Max:  maxVal.Value(),
+	}
+	return parsedResourceLimits, nil
+}
+
+func parseUtilizationResourceWeights(weights string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	if weights == "" {
+		return result, nil
+	}
+	for _, weight := range strings.Split(weights, ",") {
+		parts := strings.Split(weight, "=")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Incorrect utilization resource weight specification: %v", weight)
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Incorrect utilization resource weight - not a number: %v", weight)
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("Incorrect utilization resource weight - less than 0: %v", weight)
+		}
+		result[parts[0]] = value
+	}
+	return result, nil
+}
@@ -91,3 +91,87 @@ func TestParseSingleGpuLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSingleResourceLimit(t *testing.T) {
+	type testcase struct {
+		input                string
+		expectError          bool
+		expectedLimits       config.ResourceLimits
+		expectedErrorMessage string
+	}
+
+	testcases := []testcase{
+		{
+			input:       "example.com/fpga:1:10",
+			expectError: false,
+			expectedLimits: config.ResourceLimits{
+				Name: "example.com/fpga",
+				Min:  1,
+				Max:  10,
+			},
+		},
+		{
+			input:       "ephemeral-storage:0:1Ki",
+			expectError: false,
+			expectedLimits: config.ResourceLimits{
+				Name: "ephemeral-storage",
+				Min:  0,
+				Max:  1024,
+			},
+		},
+		{
+			input:                "example.com/fpga:1",
+			expectError:          true,
+			expectedErrorMessage: "Incorrect resource limit specification: example.com/fpga:1",
+		},
+		{
+			input:                "cpu:1:10",
+			expectError:          true,
+			expectedErrorMessage: "Incorrect resource limit - use --cores-total or --memory-total for cpu: cpu:1:10",
+		},
+		{
+			input:                "example.com/fpga:x:10",
+			expectError:          true,
+			expectedErrorMessage: "Incorrect resource limit - min is not a quantity: example.com/fpga:x:10",
+		},
+		{
+			input:                "example.com/fpga:1:-10",
+			expectError:          true,
+			expectedErrorMessage: "Incorrect resource limit - max is less than 0; example.com/fpga:1:-10",
+		},
+		{
+			input:                "example.com/fpga:10:1",
+			expectError:          true,
+			expectedErrorMessage: "Incorrect resource limit - min is greater than max; example.com/fpga:10:1",
+		},
+	}
+
+	for _, testcase := range testcases {
+		limits, err := parseSingleResourceLimit(testcase.input)
+		if testcase.expectError {
+			assert.NotNil(t, err)
+			if err != nil {
+				assert.Equal(t, testcase.expectedErrorMessage, err.Error())
+			}
+		} else {
+			assert.Equal(t, testcase.expectedLimits, limits)
+		}
+	}
+}
+
+func TestParseUtilizationResourceWeights(t *testing.T) {
+	weights, err := parseUtilizationResourceWeights("")
+	assert.NoError(t, err)
+	assert.Empty(t, weights)
+
+	weights, err = parseUtilizationResourceWeights("ephemeral-storage=0.5,example.com/fpga=0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"ephemeral-storage": 0.5, "example.com/fpga": 0}, weights)
+
+	_, err = parseUtilizationResourceWeights("ephemeral-storage")
+	assert.Error(t, err)
+	_, err = parseUtilizationResourceWeights("ephemeral-storage=x")
+	assert.Error(t, err)
+	_, err = parseUtilizationResourceWeights("ephemeral-storage=-1")
+	assert.Error(t, err)
+}
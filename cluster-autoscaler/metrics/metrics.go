@@ -17,6 +17,8 @@ limitations under the License.
 package metrics
 
 import (
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
@@ -37,6 +39,9 @@ type FailedScaleUpReason string
 // we measure duration
 type FunctionLabel string
 
+// PendingPodOutcome describes how a pending pod stopped being pending
+type PendingPodOutcome string
+
 // NodeGroupType describes node group relation to CA
 type NodeGroupType string
 
@@ -60,6 +65,11 @@ const (
 	// Timeout was encountered when trying to scale-up
 	Timeout FailedScaleUpReason = "timeout"
 
+	// PodScheduled means the pending pod was scheduled
+	PodScheduled PendingPodOutcome = "scheduled"
+	// PodAbandoned means the pending pod was deleted or stopped being unschedulable without being scheduled
+	PodAbandoned PendingPodOutcome = "abandoned"
+
 	// autoscaledGroup is managed by CA
 	autoscaledGroup NodeGroupType = "autoscaled"
 	// autoprovisionedGroup have been created by CA (Node Autoprovisioning),
@@ -89,6 +99,9 @@ const (
 	Autoscaling                FunctionLabel = "autoscaling"
 )
 
+// Buckets of histograms measuring pending pods latency, from a second to an hour.
+var pendingPodBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 900, 1800, 3600}
+
 var (
 	/**** Metrics related to cluster state ****/
 	clusterSafeToAutoscale = prometheus.NewGauge(
@@ -206,6 +219,185 @@ var (
 		},
 	)
 
+	unremovableNodesCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "unremovable_nodes_count",
+			Help:      "Number of nodes currently considered unremovable by CA, by reason.",
+		}, []string{"reason"},
+	)
+
+	deletionHookCallsCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "node_deletion_hook_calls_total",
+			Help:      "Number of node deletion hook calls, by hook, phase and outcome.",
+		}, []string{"hook", "phase", "outcome"},
+	)
+
+	deletionHookCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "node_deletion_hook_duration_seconds",
+			Help:      "Time taken by node deletion hook calls, by hook and phase.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0},
+		}, []string{"hook", "phase"},
+	)
+
+	dryRunActionsCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "dry_run_actions_total",
+			Help:      "Number of actions CA would have taken if it wasn't running in dry-run mode, by action.",
+		}, []string{"action"},
+	)
+
+	cloudProviderCallsCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "cloud_provider_calls_total",
+			Help:      "Number of cloud provider calls, by provider, method and outcome.",
+		}, []string{"provider", "method", "outcome"},
+	)
+
+	cloudProviderCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "cloud_provider_call_duration_seconds",
+			Help:      "Time taken by cloud provider calls, by provider and method.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0, 120.0, 300.0},
+		}, []string{"provider", "method"},
+	)
+
+	/**** Metrics related to pending pods latency ****/
+	podScaleUpDecisionDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "pod_scale_up_decision_duration_seconds",
+			Help:      "Time from CA first seeing an unschedulable pod to triggering a scale-up for it.",
+			Buckets:   pendingPodBuckets,
+		},
+	)
+
+	pendingPodDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "pending_pod_duration_seconds",
+			Help:      "Time from CA first seeing an unschedulable pod until it stopped being pending, by outcome and whether it triggered a scale-up.",
+			Buckets:   pendingPodBuckets,
+		}, []string{"outcome", "triggered_scale_up"},
+	)
+
+	/**** Metrics related to individual node groups ****/
+	nodeGroupMinSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_min_size",
+			Help:      "Minimum size of the node group.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupMaxSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_max_size",
+			Help:      "Maximum size of the node group.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupTargetSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_target_size",
+			Help:      "Target size of the node group on the cloud provider side.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupCurrentSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_current_size",
+			Help:      "Number of nodes of the node group registered in Kubernetes.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupNodesCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_nodes_count",
+			Help:      "Number of nodes in the node group by state.",
+		}, []string{"node_group", "state"},
+	)
+
+	nodeGroupUpcomingNodesCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_upcoming_nodes_count",
+			Help:      "Number of nodes that will be added to the node group shortly or should become ready soon.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupUnneededNodesCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_unneeded_nodes_count",
+			Help:      "Number of nodes of the node group currently considered unneeded by CA.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupBackoff = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_backoff",
+			Help:      "Whether scale-up of the node group is backed off after failures. 1 if it is, 0 otherwise.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupBackoffRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_backoff_remaining_seconds",
+			Help:      "Time left until the scale-up backoff of the node group ends.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupScaleUpCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_scaled_up_nodes_total",
+			Help:      "Number of nodes added to the node group by CA.",
+		}, []string{"node_group"},
+	)
+
+	nodeGroupFailedScaleUpCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_failed_scale_ups_total",
+			Help:      "Number of times scale-up of the node group failed, by reason.",
+		}, []string{"node_group", "reason"},
+	)
+
+	nodeGroupScaleUpNodeReadyDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "scale_up_node_ready_duration_seconds",
+			Help:      "Time from a scale-up of the node group until all requested nodes became ready.",
+			Buckets:   pendingPodBuckets,
+		}, []string{"node_group"},
+	)
+
+	nodeGroupScaleDownCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "node_group_scaled_down_nodes_total",
+			Help:      "Number of nodes removed from the node group by CA, by reason.",
+		}, []string{"node_group", "reason"},
+	)
+
+	// Node groups whose series were set by UpdateNodeGroups or the node group counters.
+	knownNodeGroups     = make(map[string]bool)
+	knownNodeGroupsLock sync.Mutex
+
 	/**** Metrics related to NodeAutoprovisioning ****/
 	napEnabled = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -248,6 +440,27 @@ func RegisterAll() {
 	prometheus.MustRegister(gpuScaleDownCount)
 	prometheus.MustRegister(evictionsCount)
 	prometheus.MustRegister(unneededNodesCount)
+	prometheus.MustRegister(unremovableNodesCount)
+	prometheus.MustRegister(nodeGroupMinSize)
+	prometheus.MustRegister(nodeGroupMaxSize)
+	prometheus.MustRegister(nodeGroupTargetSize)
+	prometheus.MustRegister(nodeGroupCurrentSize)
+	prometheus.MustRegister(nodeGroupNodesCount)
+	prometheus.MustRegister(nodeGroupUpcomingNodesCount)
+	prometheus.MustRegister(nodeGroupUnneededNodesCount)
+	prometheus.MustRegister(nodeGroupBackoff)
+	prometheus.MustRegister(nodeGroupBackoffRemaining)
+	prometheus.MustRegister(nodeGroupScaleUpCount)
+	prometheus.MustRegister(nodeGroupFailedScaleUpCount)
+	prometheus.MustRegister(nodeGroupScaleUpNodeReadyDuration)
+	prometheus.MustRegister(nodeGroupScaleDownCount)
+	prometheus.MustRegister(podScaleUpDecisionDuration)
+	prometheus.MustRegister(pendingPodDuration)
+	prometheus.MustRegister(deletionHookCallsCount)
+	prometheus.MustRegister(deletionHookCallDuration)
+	prometheus.MustRegister(dryRunActionsCount)
+	prometheus.MustRegister(cloudProviderCallsCount)
+	prometheus.MustRegister(cloudProviderCallDuration)
 	prometheus.MustRegister(napEnabled)
 	prometheus.MustRegister(nodeGroupCreationCount)
 	prometheus.MustRegister(nodeGroupDeletionCount)
@@ -311,26 +524,124 @@ func RegisterError(err errors.AutoscalerError) {
 }
 
 // RegisterScaleUp records number of nodes added by scale up
-func RegisterScaleUp(nodesCount int, gpuType string) {
+func RegisterScaleUp(nodeGroup string, nodesCount int, gpuType string) {
 	scaleUpCount.Add(float64(nodesCount))
+	markNodeGroupKnown(nodeGroup)
+	nodeGroupScaleUpCount.WithLabelValues(nodeGroup).Add(float64(nodesCount))
 	if gpuType != gpu.MetricsNoGPU {
 		gpuScaleUpCount.WithLabelValues(gpuType).Add(float64(nodesCount))
 	}
 }
 
 // RegisterFailedScaleUp records a failed scale-up operation
-func RegisterFailedScaleUp(reason FailedScaleUpReason) {
+func RegisterFailedScaleUp(nodeGroup string, reason FailedScaleUpReason) {
 	failedScaleUpCount.WithLabelValues(string(reason)).Inc()
+	markNodeGroupKnown(nodeGroup)
+	nodeGroupFailedScaleUpCount.WithLabelValues(nodeGroup, string(reason)).Inc()
 }
 
 // RegisterScaleDown records number of nodes removed by scale down
-func RegisterScaleDown(nodesCount int, gpuType string, reason NodeScaleDownReason) {
+func RegisterScaleDown(nodeGroup string, nodesCount int, gpuType string, reason NodeScaleDownReason) {
 	scaleDownCount.WithLabelValues(string(reason)).Add(float64(nodesCount))
+	markNodeGroupKnown(nodeGroup)
+	nodeGroupScaleDownCount.WithLabelValues(nodeGroup, string(reason)).Add(float64(nodesCount))
 	if gpuType != gpu.MetricsNoGPU {
 		gpuScaleDownCount.WithLabelValues(string(reason), gpuType).Add(float64(nodesCount))
 	}
 }
 
+// RegisterScaleUpNodeReady records the time from a scale-up of the node group until the requested nodes became ready
+func RegisterScaleUpNodeReady(nodeGroup string, duration time.Duration) {
+	markNodeGroupKnown(nodeGroup)
+	nodeGroupScaleUpNodeReadyDuration.WithLabelValues(nodeGroup).Observe(duration.Seconds())
+}
+
+// RegisterPodScaleUpDecision records the time from first seeing a pending pod to triggering a scale-up for it
+func RegisterPodScaleUpDecision(duration time.Duration) {
+	podScaleUpDecisionDuration.Observe(duration.Seconds())
+}
+
+// RegisterPendingPod records the time a pod was pending, by how it stopped being pending
+func RegisterPendingPod(outcome PendingPodOutcome, triggeredScaleUp bool, duration time.Duration) {
+	pendingPodDuration.WithLabelValues(string(outcome), strconv.FormatBool(triggeredScaleUp)).Observe(duration.Seconds())
+}
+
+// NodeGroupState is the state of a single node group exposed in per-node-group metrics.
+type NodeGroupState struct {
+	MinSize    int
+	MaxSize    int
+	TargetSize int
+	// CurrentSize is the number of nodes registered in Kubernetes.
+	CurrentSize      int
+	Ready            int
+	Unready          int
+	NotStarted       int
+	LongUnregistered int
+	Unregistered     int
+	Upcoming         int
+	Unneeded         int
+	// BackoffRemaining is the time left until scale-up backoff ends, zero if the node group isn't backed off.
+	BackoffRemaining time.Duration
+}
+
+// UpdateNodeGroups records the state of all node groups, by node group id. Series of node groups
+// that are no longer present are removed.
+func UpdateNodeGroups(states map[string]NodeGroupState) {
+	knownNodeGroupsLock.Lock()
+	defer knownNodeGroupsLock.Unlock()
+	for nodeGroup := range knownNodeGroups {
+		if _, found := states[nodeGroup]; !found {
+			deleteNodeGroupSeries(nodeGroup)
+			delete(knownNodeGroups, nodeGroup)
+		}
+	}
+	for nodeGroup, state := range states {
+		knownNodeGroups[nodeGroup] = true
+		nodeGroupMinSize.WithLabelValues(nodeGroup).Set(float64(state.MinSize))
+		nodeGroupMaxSize.WithLabelValues(nodeGroup).Set(float64(state.MaxSize))
+		nodeGroupTargetSize.WithLabelValues(nodeGroup).Set(float64(state.TargetSize))
+		nodeGroupCurrentSize.WithLabelValues(nodeGroup).Set(float64(state.CurrentSize))
+		nodeGroupNodesCount.WithLabelValues(nodeGroup, readyLabel).Set(float64(state.Ready))
+		nodeGroupNodesCount.WithLabelValues(nodeGroup, unreadyLabel).Set(float64(state.Unready))
+		nodeGroupNodesCount.WithLabelValues(nodeGroup, startingLabel).Set(float64(state.NotStarted))
+		nodeGroupNodesCount.WithLabelValues(nodeGroup, longUnregisteredLabel).Set(float64(state.LongUnregistered))
+		nodeGroupNodesCount.WithLabelValues(nodeGroup, unregisteredLabel).Set(float64(state.Unregistered))
+		nodeGroupUpcomingNodesCount.WithLabelValues(nodeGroup).Set(float64(state.Upcoming))
+		nodeGroupUnneededNodesCount.WithLabelValues(nodeGroup).Set(float64(state.Unneeded))
+		if state.BackoffRemaining > 0 {
+			nodeGroupBackoff.WithLabelValues(nodeGroup).Set(1)
+		} else {
+			nodeGroupBackoff.WithLabelValues(nodeGroup).Set(0)
+		}
+		nodeGroupBackoffRemaining.WithLabelValues(nodeGroup).Set(state.BackoffRemaining.Seconds())
+	}
+}
+
+func markNodeGroupKnown(nodeGroup string) {
+	knownNodeGroupsLock.Lock()
+	defer knownNodeGroupsLock.Unlock()
+	knownNodeGroups[nodeGroup] = true
+}
+
+// To be executed under knownNodeGroupsLock.
+func deleteNodeGroupSeries(nodeGroup string) {
+	for _, gauge := range []*prometheus.GaugeVec{nodeGroupMinSize, nodeGroupMaxSize, nodeGroupTargetSize, nodeGroupCurrentSize,
+		nodeGroupUpcomingNodesCount, nodeGroupUnneededNodesCount, nodeGroupBackoff, nodeGroupBackoffRemaining} {
+		gauge.DeleteLabelValues(nodeGroup)
+	}
+	for _, state := range []string{readyLabel, unreadyLabel, startingLabel, longUnregisteredLabel, unregisteredLabel} {
+		nodeGroupNodesCount.DeleteLabelValues(nodeGroup, state)
+	}
+	nodeGroupScaleUpCount.DeleteLabelValues(nodeGroup)
+	nodeGroupScaleUpNodeReadyDuration.DeleteLabelValues(nodeGroup)
+	for _, reason := range []FailedScaleUpReason{APIError, Timeout} {
+		nodeGroupFailedScaleUpCount.DeleteLabelValues(nodeGroup, string(reason))
+	}
+	for _, reason := range []NodeScaleDownReason{Underutilized, Empty, Unready} {
+		nodeGroupScaleDownCount.DeleteLabelValues(nodeGroup, string(reason))
+	}
+}
+
 // RegisterEvictions records number of evicted pods
 func RegisterEvictions(podsCount int) {
 	evictionsCount.Add(float64(podsCount))
@@ -341,6 +652,31 @@ func UpdateUnneededNodesCount(nodesCount int) {
 	unneededNodesCount.Set(float64(nodesCount))
 }
 
+// UpdateUnremovableNodesCount records number of currently unremovable nodes per reason
+func UpdateUnremovableNodesCount(countByReason map[string]int) {
+	unremovableNodesCount.Reset()
+	for reason, count := range countByReason {
+		unremovableNodesCount.WithLabelValues(reason).Set(float64(count))
+	}
+}
+
+// RegisterDeletionHookCall records a call of a node deletion hook
+func RegisterDeletionHookCall(hook, phase, outcome string, duration time.Duration) {
+	deletionHookCallsCount.WithLabelValues(hook, phase, outcome).Inc()
+	deletionHookCallDuration.WithLabelValues(hook, phase).Observe(duration.Seconds())
+}
+
+// RegisterDryRunAction records an action skipped because of dry-run mode
+func RegisterDryRunAction(action string) {
+	dryRunActionsCount.WithLabelValues(action).Inc()
+}
+
+// RegisterCloudProviderCall records a call of a cloud provider method
+func RegisterCloudProviderCall(provider, method, outcome string, duration time.Duration) {
+	cloudProviderCallsCount.WithLabelValues(provider, method, outcome).Inc()
+	cloudProviderCallDuration.WithLabelValues(provider, method).Observe(duration.Seconds())
+}
+
 // UpdateNapEnabled records if NodeAutoprovisioning is enabled
 func UpdateNapEnabled(enabled bool) {
 	if enabled {
@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func seriesCount(collector prometheus.Collector) int {
+	ch := make(chan prometheus.Metric, 100)
+	collector.Collect(ch)
+	close(ch)
+	return len(ch)
+}
+
+func TestUpdateNodeGroupsRemovesStaleSeries(t *testing.T) {
+	UpdateNodeGroups(map[string]NodeGroupState{
+		"ng1": {MinSize: 1, MaxSize: 10, TargetSize: 3, BackoffRemaining: time.Minute},
+		"ng2": {MinSize: 0, MaxSize: 5},
+	})
+	RegisterScaleUp("ng1", 2, "")
+	RegisterScaleDown("ng2", 1, "", Empty)
+	RegisterFailedScaleUp("ng3", APIError)
+	assert.Equal(t, 2, seriesCount(nodeGroupTargetSize))
+	assert.Equal(t, 10, seriesCount(nodeGroupNodesCount))
+	assert.Equal(t, 1, seriesCount(nodeGroupScaleUpCount))
+	assert.Equal(t, 1, seriesCount(nodeGroupScaleDownCount))
+	assert.Equal(t, 1, seriesCount(nodeGroupFailedScaleUpCount))
+
+	// ng2 and ng3 are gone.
+	UpdateNodeGroups(map[string]NodeGroupState{
+		"ng1": {MinSize: 1, MaxSize: 10, TargetSize: 3},
+	})
+	assert.Equal(t, 1, seriesCount(nodeGroupTargetSize))
+	assert.Equal(t, 1, seriesCount(nodeGroupBackoff))
+	assert.Equal(t, 5, seriesCount(nodeGroupNodesCount))
+	assert.Equal(t, 1, seriesCount(nodeGroupScaleUpCount))
+	assert.Equal(t, 0, seriesCount(nodeGroupScaleDownCount))
+	assert.Equal(t, 0, seriesCount(nodeGroupFailedScaleUpCount))
+
+	UpdateNodeGroups(map[string]NodeGroupState{})
+	assert.Equal(t, 0, seriesCount(nodeGroupTargetSize))
+	assert.Equal(t, 0, seriesCount(nodeGroupScaleUpCount))
+}
@@ -21,21 +21,27 @@ import (
 	"strings"
 
 	apiv1 "k8s.io/api/core/v1"
+	"github.com/gardener/autoscaler/cluster-autoscaler/auditlog"
 	"github.com/gardener/autoscaler/cluster-autoscaler/context"
 )
 
 // EventingScaleUpStatusProcessor processes the state of the cluster after
 // a scale-up by emitting relevant events for pods depending on their post
-// scale-up status.
+// scale-up status. Reasons why pods didn't trigger scale-up are also recorded
+// in the audit log.
 type EventingScaleUpStatusProcessor struct{}
 
 // Process processes the state of the cluster after a scale-up by emitting
 // relevant events for pods depending on their post scale-up status.
 func (p *EventingScaleUpStatusProcessor) Process(context *context.AutoscalingContext, status *ScaleUpStatus) {
+	noScaleUp := make([]auditlog.PodNoScaleUp, 0, len(status.PodsRemainUnschedulable))
 	for _, noScaleUpInfo := range status.PodsRemainUnschedulable {
+		reasons := ReasonsMessage(noScaleUpInfo)
 		context.Recorder.Event(noScaleUpInfo.Pod, apiv1.EventTypeNormal, "NotTriggerScaleUp",
-			fmt.Sprintf("pod didn't trigger scale-up (it wouldn't fit if a new node is added): %s", ReasonsMessage(noScaleUpInfo)))
+			fmt.Sprintf("pod didn't trigger scale-up (it wouldn't fit if a new node is added): %s", reasons))
+		noScaleUp = append(noScaleUp, auditlog.PodNoScaleUp{Pod: auditlog.PodName(noScaleUpInfo.Pod), Reasons: reasons})
 	}
+	context.AuditLog.NoScaleUp(noScaleUp)
 	if len(status.ScaleUpInfos) > 0 {
 		for _, pod := range status.PodsTriggeredScaleUp {
 			context.Recorder.Eventf(pod, apiv1.EventTypeNormal, "TriggeredScaleUp",
@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package factory
+
+import (
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder/none"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder/oldest"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder/price"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder/utilization"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder/zonebalance"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+)
+
+// ScaleDownOrderStrategyFromString creates a scaledownorder.Strategy according to its name.
+// An empty name selects the none strategy, so that options recorded before the flag existed keep working.
+func ScaleDownOrderStrategyFromString(name string, cloudProvider cloudprovider.CloudProvider) (scaledownorder.Strategy, errors.AutoscalerError) {
+	switch name {
+	case "", scaledownorder.NoneName:
+		return none.NewStrategy(), nil
+	case scaledownorder.LeastUtilizedName:
+		return utilization.NewStrategy(), nil
+	case scaledownorder.OldestName:
+		return oldest.NewStrategy(), nil
+	case scaledownorder.MostExpensiveName:
+		pricing, err := cloudProvider.Pricing()
+		if err != nil {
+			return nil, err
+		}
+		return price.NewStrategy(pricing), nil
+	case scaledownorder.ZoneBalancedName:
+		return zonebalance.NewStrategy(), nil
+	}
+	return nil, errors.NewAutoscalerError(errors.InternalError, "Scale-down order %s not supported", name)
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package none
+
+import (
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+type none struct{}
+
+// NewStrategy returns a scale-down ordering strategy that keeps candidates in the order they are listed
+func NewStrategy() scaledownorder.Strategy {
+	return &none{}
+}
+
+// Order returns a copy of candidates in unchanged order
+func (n *none) Order(candidates []*apiv1.Node, allNodes []*apiv1.Node, utilization map[string]float64) []*apiv1.Node {
+	result := make([]*apiv1.Node, len(candidates))
+	copy(result, candidates)
+	return result
+}
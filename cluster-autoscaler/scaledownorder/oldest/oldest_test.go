@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oldest
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOldestOrder(t *testing.T) {
+	now := time.Now()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n1.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n2.CreationTimestamp = metav1.NewTime(now.Add(-3 * time.Hour))
+	n3 := BuildTestNode("n3", 1000, 1000)
+	n3.CreationTimestamp = metav1.NewTime(now.Add(-2 * time.Hour))
+	candidates := []*apiv1.Node{n1, n2, n3}
+
+	result := NewStrategy().Order(candidates, candidates, nil)
+	assert.Equal(t, []*apiv1.Node{n2, n3, n1}, result)
+	assert.Equal(t, []*apiv1.Node{n1, n2, n3}, candidates)
+}
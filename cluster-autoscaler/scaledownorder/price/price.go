@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package price
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/golang/glog"
+)
+
+// pricingPeriod is the period for which node prices are compared.
+const pricingPeriod = time.Hour
+
+type mostExpensive struct {
+	pricingModel cloudprovider.PricingModel
+	now          func() time.Time
+}
+
+// NewStrategy returns a scale-down ordering strategy that removes the most expensive nodes first
+func NewStrategy(pricingModel cloudprovider.PricingModel) scaledownorder.Strategy {
+	return &mostExpensive{
+		pricingModel: pricingModel,
+		now:          time.Now,
+	}
+}
+
+// Order sorts candidates by decreasing price. Nodes whose price cannot be computed go last.
+func (m *mostExpensive) Order(candidates []*apiv1.Node, allNodes []*apiv1.Node, utilization map[string]float64) []*apiv1.Node {
+	now := m.now()
+	prices := make(map[string]float64, len(candidates))
+	for _, node := range candidates {
+		price, err := m.pricingModel.NodePrice(node, now, now.Add(pricingPeriod))
+		if err != nil {
+			glog.Warningf("Failed to calculate price for node %s: %v", node.Name, err)
+			price = -1
+		}
+		prices[node.Name] = price
+	}
+
+	result := make([]*apiv1.Node, len(candidates))
+	copy(result, candidates)
+	sort.SliceStable(result, func(i, j int) bool {
+		return prices[result[i].Name] > prices[result[j].Name]
+	})
+	return result
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package price
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPricingModel struct {
+	nodePrice map[string]float64
+}
+
+func (tpm *testPricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := tpm.nodePrice[node.Name]; found {
+		return price, nil
+	}
+	return 0.0, fmt.Errorf("price for node %v not found", node.Name)
+}
+
+func (tpm *testPricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0.0, fmt.Errorf("price for pod %v not found", pod.Name)
+}
+
+func TestMostExpensiveOrder(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 4000, 1000)
+	n3 := BuildTestNode("n3", 2000, 1000)
+	n4 := BuildTestNode("n4", 2000, 1000)
+	candidates := []*apiv1.Node{n1, n2, n3, n4}
+
+	strategy := NewStrategy(&testPricingModel{
+		nodePrice: map[string]float64{"n1": 1.0, "n2": 4.0, "n4": 2.0},
+	})
+	result := strategy.Order(candidates, candidates, nil)
+	assert.Equal(t, []*apiv1.Node{n2, n4, n1, n3}, result)
+	assert.Equal(t, []*apiv1.Node{n1, n2, n3, n4}, candidates)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaledownorder
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+)
+
+var (
+	// AvailableStrategies is a list of available scale-down ordering options
+	AvailableStrategies = []string{NoneName, LeastUtilizedName, OldestName, MostExpensiveName, ZoneBalancedName}
+	// NoneName keeps nodes in the order in which they are listed
+	NoneName = "none"
+	// LeastUtilizedName removes nodes with the lowest utilization first
+	LeastUtilizedName = "least-utilized"
+	// OldestName removes the oldest nodes first, which allows gradually refreshing the node image
+	OldestName = "oldest"
+	// MostExpensiveName removes the nodes that are the most expensive to run first
+	MostExpensiveName = "most-expensive"
+	// ZoneBalancedName removes nodes from the zones that have the most nodes first
+	ZoneBalancedName = "zone-balanced"
+)
+
+// Strategy describes an interface for choosing the order in which nodes are considered for removal
+// during scale down. It is the scale-down counterpart of expander.Strategy.
+type Strategy interface {
+	// Order returns candidates sorted from the most to the least preferred node to remove. allNodes
+	// holds all nodes in the cluster and utilization maps node names to their utilization.
+	// Implementations must not modify the passed slices.
+	Order(candidates []*apiv1.Node, allNodes []*apiv1.Node, utilization map[string]float64) []*apiv1.Node
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utilization
+
+import (
+	"sort"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+type leastUtilized struct{}
+
+// NewStrategy returns a scale-down ordering strategy that removes the least utilized nodes first
+func NewStrategy() scaledownorder.Strategy {
+	return &leastUtilized{}
+}
+
+// Order sorts candidates by increasing utilization. Nodes with unknown utilization go last.
+func (l *leastUtilized) Order(candidates []*apiv1.Node, allNodes []*apiv1.Node, utilization map[string]float64) []*apiv1.Node {
+	result := make([]*apiv1.Node, len(candidates))
+	copy(result, candidates)
+	sort.SliceStable(result, func(i, j int) bool {
+		ui, foundI := utilization[result[i].Name]
+		uj, foundJ := utilization[result[j].Name]
+		if foundI != foundJ {
+			return foundI
+		}
+		return ui < uj
+	})
+	return result
+}
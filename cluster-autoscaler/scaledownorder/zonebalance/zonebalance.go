@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonebalance
+
+import (
+	"github.com/gardener/autoscaler/cluster-autoscaler/scaledownorder"
+
+	apiv1 "k8s.io/api/core/v1"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+type zoneBalanced struct{}
+
+// NewStrategy returns a scale-down ordering strategy that keeps the number of nodes
+// in each zone as balanced as possible
+func NewStrategy() scaledownorder.Strategy {
+	return &zoneBalanced{}
+}
+
+// Order repeatedly picks a candidate from the zone that would have the most nodes left,
+// so that removing any prefix of the result keeps zones balanced. Candidates within
+// a zone keep their relative order.
+func (z *zoneBalanced) Order(candidates []*apiv1.Node, allNodes []*apiv1.Node, utilization map[string]float64) []*apiv1.Node {
+	zoneSize := make(map[string]int)
+	for _, node := range allNodes {
+		zoneSize[zoneOf(node)]++
+	}
+
+	pending := make(map[string][]*apiv1.Node)
+	var zones []string
+	for _, node := range candidates {
+		zone := zoneOf(node)
+		if _, found := pending[zone]; !found {
+			zones = append(zones, zone)
+		}
+		pending[zone] = append(pending[zone], node)
+	}
+
+	result := make([]*apiv1.Node, 0, len(candidates))
+	for len(result) < len(candidates) {
+		best := ""
+		bestSize := -1
+		for _, zone := range zones {
+			if len(pending[zone]) > 0 && zoneSize[zone] > bestSize {
+				best = zone
+				bestSize = zoneSize[zone]
+			}
+		}
+		result = append(result, pending[best][0])
+		pending[best] = pending[best][1:]
+		zoneSize[best]--
+	}
+	return result
+}
+
+func zoneOf(node *apiv1.Node) string {
+	return node.Labels[kubeletapis.LabelZoneFailureDomain]
+}
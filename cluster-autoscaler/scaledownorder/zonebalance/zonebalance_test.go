@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonebalance
+
+import (
+	"testing"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildZonalNode(name, zone string) *apiv1.Node {
+	node := BuildTestNode(name, 1000, 1000)
+	node.Labels = map[string]string{kubeletapis.LabelZoneFailureDomain: zone}
+	return node
+}
+
+func TestZoneBalancedOrder(t *testing.T) {
+	a1 := buildZonalNode("a1", "a")
+	a2 := buildZonalNode("a2", "a")
+	a3 := buildZonalNode("a3", "a")
+	b1 := buildZonalNode("b1", "b")
+	b2 := buildZonalNode("b2", "b")
+	c1 := buildZonalNode("c1", "c")
+	allNodes := []*apiv1.Node{a1, a2, a3, b1, b2, c1}
+	candidates := []*apiv1.Node{c1, b1, b2, a1, a2}
+
+	result := NewStrategy().Order(candidates, allNodes, nil)
+	// Ties are broken by the order in which zones first appear among candidates.
+	assert.Equal(t, []*apiv1.Node{a1, b1, a2, c1, b2}, result)
+	assert.Equal(t, []*apiv1.Node{c1, b1, b2, a1, a2}, candidates)
+}
+
+func TestZoneBalancedOrderNoZones(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	candidates := []*apiv1.Node{n2, n1}
+
+	result := NewStrategy().Order(candidates, candidates, nil)
+	assert.Equal(t, []*apiv1.Node{n2, n1}, result)
+}
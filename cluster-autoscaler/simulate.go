@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulation"
+
+	"github.com/golang/glog"
+)
+
+var (
+	simulationSnapshot          = flag.String("simulation-snapshot", "", "Path to a YAML or JSON cluster snapshot replayed by the simulate command.")
+	simulationRecord            = flag.String("simulation-record", "", "Path to a loop record written with --loop-record-dir, replayed by the simulate command instead of --simulation-snapshot.")
+	simulationIterations        = flag.Int("simulation-iterations", 10, "Number of autoscaler loops run by the simulate command.")
+	simulationTimeStep          = flag.Duration("simulation-time-step", 0, "Virtual time between two loops of the simulate command. Defaults to --scan-interval.")
+	simulationNodeProvisionTime = flag.Duration("simulation-node-provision-time", time.Minute, "Virtual time it takes for a new node to join the cluster in the simulate command.")
+	simulationOutput            = flag.String("simulation-output", "text", "Format of the timeline printed by the simulate command: text or json.")
+)
+
+// runSimulation implements the simulate command. It replays a cluster snapshot or a recorded
+// loop through the autoscaler configured by the regular flags, without connecting to any cluster, and prints
+// the timeline of scaling decisions.
+func runSimulation() {
+	if (*simulationSnapshot == "") == (*simulationRecord == "") {
+		glog.Fatalf("Exactly one of --simulation-snapshot and --simulation-record is required by the simulate command")
+	}
+	if *simulationOutput != "text" && *simulationOutput != "json" {
+		glog.Fatalf("Unrecognized simulation output format: %v", *simulationOutput)
+	}
+	snapshot, err := loadSimulationSnapshot()
+	if err != nil {
+		glog.Fatalf("Failed to load snapshot: %v", err)
+	}
+
+	timeStep := *simulationTimeStep
+	if timeStep == 0 {
+		timeStep = *scanInterval
+	}
+	sim, err := simulation.NewSimulation(snapshot, simulation.Config{
+		Options:           createAutoscalingOptions(),
+		Iterations:        *simulationIterations,
+		TimeStep:          timeStep,
+		NodeProvisionTime: *simulationNodeProvisionTime,
+	})
+	if err != nil {
+		glog.Fatalf("Failed to create simulation: %v", err)
+	}
+	timeline := sim.Run()
+
+	if *simulationOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(timeline)
+	} else {
+		err = timeline.WriteText(os.Stdout)
+	}
+	if err != nil {
+		glog.Fatalf("Failed to write timeline: %v", err)
+	}
+}
+
+func loadSimulationSnapshot() (*simulation.Snapshot, error) {
+	if *simulationSnapshot != "" {
+		return simulation.LoadSnapshot(*simulationSnapshot)
+	}
+	record, err := looprecorder.LoadRecord(*simulationRecord)
+	if err != nil {
+		return nil, err
+	}
+	return simulation.SnapshotFromRecord(record)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	ctx "context"
+	"sync"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/deletetaint"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// simulationActuator applies the autoscaler decisions to the simulated cloud provider
+// and cluster immediately and records them for the timeline.
+type simulationActuator struct {
+	sync.Mutex
+	cluster *cluster
+	actions []actuation.Action
+}
+
+func newSimulationActuator(c *cluster) *simulationActuator {
+	return &simulationActuator{
+		cluster: c,
+		actions: make([]actuation.Action, 0),
+	}
+}
+
+// IncreaseSize increases the size of the simulated node group.
+func (a *simulationActuator) IncreaseSize(_ ctx.Context, nodeGroup cloudprovider.NodeGroup, delta int) error {
+	if err := nodeGroup.IncreaseSize(delta); err != nil {
+		return err
+	}
+	a.record(actuation.Action{Type: actuation.IncreaseSizeAction, NodeGroup: nodeGroup.Id(), Delta: delta})
+	return nil
+}
+
+// DecreaseTargetSize decreases the target size of the simulated node group.
+func (a *simulationActuator) DecreaseTargetSize(_ ctx.Context, nodeGroup cloudprovider.NodeGroup, delta int) error {
+	if err := nodeGroup.DecreaseTargetSize(delta); err != nil {
+		return err
+	}
+	a.record(actuation.Action{Type: actuation.DecreaseTargetSizeAction, NodeGroup: nodeGroup.Id(), Delta: delta})
+	return nil
+}
+
+// DeleteNodes removes the nodes from the simulated node group and cluster.
+func (a *simulationActuator) DeleteNodes(_ ctx.Context, nodeGroup cloudprovider.NodeGroup, nodes []*apiv1.Node) error {
+	if err := nodeGroup.DeleteNodes(nodes); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	a.record(actuation.Action{Type: actuation.DeleteNodesAction, NodeGroup: nodeGroup.Id(), Nodes: names})
+	return nil
+}
+
+// MarkToBeDeleted taints the simulated node.
+func (a *simulationActuator) MarkToBeDeleted(node *apiv1.Node) error {
+	if err := a.cluster.addTaint(node.Name, deletetaint.ToBeDeletedTaint, apiv1.TaintEffectNoSchedule); err != nil {
+		return err
+	}
+	a.record(actuation.Action{Type: actuation.MarkToBeDeletedAction, Nodes: []string{node.Name}})
+	return nil
+}
+
+// CleanToBeDeleted removes the deletion taint from the simulated node.
+func (a *simulationActuator) CleanToBeDeleted(node *apiv1.Node) (bool, error) {
+	cleaned := a.cluster.cleanTaint(node.Name, deletetaint.ToBeDeletedTaint)
+	if cleaned {
+		a.record(actuation.Action{Type: actuation.CleanToBeDeletedAction, Nodes: []string{node.Name}})
+	}
+	return cleaned, nil
+}
+
+// MarkDeletionCandidate soft-taints the simulated node.
+func (a *simulationActuator) MarkDeletionCandidate(node *apiv1.Node) error {
+	if err := a.cluster.addTaint(node.Name, deletetaint.DeletionCandidateTaint, apiv1.TaintEffectPreferNoSchedule); err != nil {
+		return err
+	}
+	a.record(actuation.Action{Type: actuation.MarkDeletionCandidateAction, Nodes: []string{node.Name}})
+	return nil
+}
+
+// CleanDeletionCandidate removes the soft taint from the simulated node.
+func (a *simulationActuator) CleanDeletionCandidate(node *apiv1.Node) (bool, error) {
+	cleaned := a.cluster.cleanTaint(node.Name, deletetaint.DeletionCandidateTaint)
+	if cleaned {
+		a.record(actuation.Action{Type: actuation.CleanDeletionCandidateAction, Nodes: []string{node.Name}})
+	}
+	return cleaned, nil
+}
+
+// DrainNode makes the pods pending again, as if they were evicted and recreated by their controllers.
+func (a *simulationActuator) DrainNode(_ ctx.Context, node *apiv1.Node, pods []*apiv1.Pod) errors.AutoscalerError {
+	a.cluster.evictPods(pods)
+	podNames := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		podNames = append(podNames, podKey(pod))
+	}
+	a.record(actuation.Action{Type: actuation.DrainNodeAction, Nodes: []string{node.Name}, Pods: podNames})
+	return nil
+}
+
+// AbortDrains does nothing, simulated drains finish immediately.
+func (a *simulationActuator) AbortDrains() {}
+
+// takeActions returns the actions recorded since the previous call.
+func (a *simulationActuator) takeActions() []actuation.Action {
+	a.Lock()
+	defer a.Unlock()
+	result := a.actions
+	a.actions = make([]actuation.Action, 0)
+	return result
+}
+
+func (a *simulationActuator) record(action actuation.Action) {
+	a.cluster.Lock()
+	action.Time = a.cluster.now
+	a.cluster.Unlock()
+
+	a.Lock()
+	defer a.Unlock()
+	a.actions = append(a.actions, action)
+}
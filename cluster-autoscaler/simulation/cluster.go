@@ -0,0 +1,411 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/daemonset"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/deletetaint"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+const (
+	hostnameLabel = "kubernetes.io/hostname"
+)
+
+// provisioningNode is a node requested from the simulated cloud provider that
+// hasn't registered in the cluster yet.
+type provisioningNode struct {
+	nodeGroup string
+	readyAt   time.Time
+}
+
+// cluster is the simulated state of the Kubernetes cluster. It backs the listers used
+// by the autoscaler and is modified by the simulation actuator and the simulated scheduler.
+type cluster struct {
+	sync.Mutex
+	now          time.Time
+	nodes        map[string]*apiv1.Node
+	pods         map[string]*apiv1.Pod
+	pdbs         []*policyv1.PodDisruptionBudget
+	daemonSets   []*extensionsv1.DaemonSet
+	templates    map[string]*apiv1.Node
+	provisioning []provisioningNode
+	nodeCounter  map[string]int
+}
+
+func newCluster(snapshot *Snapshot, now time.Time) *cluster {
+	c := &cluster{
+		now:         now,
+		nodes:       make(map[string]*apiv1.Node),
+		pods:        make(map[string]*apiv1.Pod),
+		templates:   make(map[string]*apiv1.Node),
+		nodeCounter: make(map[string]int),
+	}
+	for _, node := range snapshot.Nodes {
+		nodeCopy := node.DeepCopy()
+		// Test cloud provider identifies nodes by name.
+		nodeCopy.Spec.ProviderID = nodeCopy.Name
+		c.nodes[nodeCopy.Name] = nodeCopy
+	}
+	for _, pod := range snapshot.Pods {
+		podCopy := pod.DeepCopy()
+		c.pods[podKey(podCopy)] = podCopy
+	}
+	for _, pdb := range snapshot.PodDisruptionBudgets {
+		c.pdbs = append(c.pdbs, pdb.DeepCopy())
+	}
+	for _, ds := range snapshot.DaemonSets {
+		c.daemonSets = append(c.daemonSets, ds.DeepCopy())
+	}
+	for _, ng := range snapshot.NodeGroups {
+		if ng.Template != nil {
+			c.templates[ng.Name] = ng.Template.DeepCopy()
+		} else {
+			c.templates[ng.Name] = c.nodes[ng.Nodes[0]].DeepCopy()
+		}
+	}
+	return c
+}
+
+func podKey(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+func (c *cluster) setTime(now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = now
+}
+
+func (c *cluster) listNodes(filter func(*apiv1.Node) bool) []*apiv1.Node {
+	c.Lock()
+	defer c.Unlock()
+	result := make([]*apiv1.Node, 0, len(c.nodes))
+	for _, name := range c.sortedNodeNames() {
+		node := c.nodes[name]
+		if filter == nil || filter(node) {
+			result = append(result, node.DeepCopy())
+		}
+	}
+	return result
+}
+
+func (c *cluster) listPods(filter func(*apiv1.Pod) bool) []*apiv1.Pod {
+	c.Lock()
+	defer c.Unlock()
+	result := make([]*apiv1.Pod, 0, len(c.pods))
+	for _, pod := range c.sortedPods() {
+		if filter == nil || filter(pod) {
+			result = append(result, pod.DeepCopy())
+		}
+	}
+	return result
+}
+
+func (c *cluster) listPodDisruptionBudgets() []*policyv1.PodDisruptionBudget {
+	c.Lock()
+	defer c.Unlock()
+	result := make([]*policyv1.PodDisruptionBudget, 0, len(c.pdbs))
+	for _, pdb := range c.pdbs {
+		result = append(result, pdb.DeepCopy())
+	}
+	return result
+}
+
+func (c *cluster) listDaemonSets() []*extensionsv1.DaemonSet {
+	c.Lock()
+	defer c.Unlock()
+	result := make([]*extensionsv1.DaemonSet, 0, len(c.daemonSets))
+	for _, ds := range c.daemonSets {
+		result = append(result, ds.DeepCopy())
+	}
+	return result
+}
+
+// sortedNodeNames must be called with the lock held.
+func (c *cluster) sortedNodeNames() []string {
+	names := make([]string, 0, len(c.nodes))
+	for name := range c.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedPods must be called with the lock held.
+func (c *cluster) sortedPods() []*apiv1.Pod {
+	keys := make([]string, 0, len(c.pods))
+	for key := range c.pods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*apiv1.Pod, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, c.pods[key])
+	}
+	return result
+}
+
+// requestNodes registers delta new nodes of the given node group. They join the
+// cluster once provisionTime passes.
+func (c *cluster) requestNodes(nodeGroup string, delta int, provisionTime time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	for i := 0; i < delta; i++ {
+		c.provisioning = append(c.provisioning, provisioningNode{nodeGroup: nodeGroup, readyAt: c.now.Add(provisionTime)})
+	}
+}
+
+// cancelNodes drops up to count not yet provisioned nodes of the given node group,
+// most recently requested first.
+func (c *cluster) cancelNodes(nodeGroup string, count int) {
+	c.Lock()
+	defer c.Unlock()
+	for i := len(c.provisioning) - 1; i >= 0 && count > 0; i-- {
+		if c.provisioning[i].nodeGroup == nodeGroup {
+			c.provisioning = append(c.provisioning[:i], c.provisioning[i+1:]...)
+			count--
+		}
+	}
+}
+
+// provisionNodes adds to the cluster all requested nodes that are due. Returns the new
+// nodes mapped to their node groups.
+func (c *cluster) provisionNodes(predicateChecker *simulator.PredicateChecker) map[string]string {
+	c.Lock()
+	defer c.Unlock()
+	result := make(map[string]string)
+	remaining := make([]provisioningNode, 0, len(c.provisioning))
+	for _, pn := range c.provisioning {
+		if pn.readyAt.After(c.now) {
+			remaining = append(remaining, pn)
+			continue
+		}
+		node := c.buildNode(pn.nodeGroup)
+		c.nodes[node.Name] = node
+		result[node.Name] = pn.nodeGroup
+
+		nodeInfo := schedulercache.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		for _, ds := range c.daemonSets {
+			for _, pod := range daemonset.GetDaemonSetPodsForNode(nodeInfo, []*extensionsv1.DaemonSet{ds}, predicateChecker) {
+				pod.Name = fmt.Sprintf("%s-%s", ds.Name, node.Name)
+				pod.OwnerReferences = []metav1.OwnerReference{
+					*metav1.NewControllerRef(ds, extensionsv1.SchemeGroupVersion.WithKind("DaemonSet")),
+				}
+				c.pods[podKey(pod)] = pod
+			}
+		}
+	}
+	c.provisioning = remaining
+	return result
+}
+
+// buildNode must be called with the lock held.
+func (c *cluster) buildNode(nodeGroup string) *apiv1.Node {
+	var name string
+	for {
+		c.nodeCounter[nodeGroup]++
+		name = fmt.Sprintf("%s-sim-%d", nodeGroup, c.nodeCounter[nodeGroup])
+		if _, found := c.nodes[name]; !found {
+			break
+		}
+	}
+	node := c.templates[nodeGroup].DeepCopy()
+	node.ObjectMeta = metav1.ObjectMeta{
+		Name:              name,
+		Labels:            node.Labels,
+		Annotations:       node.Annotations,
+		CreationTimestamp: metav1.NewTime(c.now),
+	}
+	if node.Labels == nil {
+		node.Labels = make(map[string]string)
+	}
+	node.Labels[hostnameLabel] = name
+	node.Spec.ProviderID = name
+	node.Spec.Unschedulable = false
+	taints := make([]apiv1.Taint, 0, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		if taint.Key != deletetaint.ToBeDeletedTaint {
+			taints = append(taints, taint)
+		}
+	}
+	node.Spec.Taints = taints
+	if node.Status.Allocatable == nil {
+		node.Status.Allocatable = node.Status.Capacity
+	}
+	node.Status.Conditions = []apiv1.NodeCondition{
+		{
+			Type:               apiv1.NodeReady,
+			Status:             apiv1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(c.now),
+		},
+	}
+	return node
+}
+
+// schedulePendingPods binds pending pods to nodes they fit on. Nodes are tried in name
+// order, so the result is deterministic. Returns the number of scheduled pods.
+func (c *cluster) schedulePendingPods(predicateChecker *simulator.PredicateChecker) int {
+	c.Lock()
+	defer c.Unlock()
+
+	nodes := make([]*apiv1.Node, 0, len(c.nodes))
+	for _, name := range c.sortedNodeNames() {
+		nodes = append(nodes, c.nodes[name])
+	}
+	scheduled := make([]*apiv1.Pod, 0, len(c.pods))
+	pending := make([]*apiv1.Pod, 0)
+	for _, pod := range c.sortedPods() {
+		if pod.Spec.NodeName != "" {
+			scheduled = append(scheduled, pod)
+		} else {
+			pending = append(pending, pod)
+		}
+	}
+	nodeInfos := schedulercache.CreateNodeNameToInfoMap(scheduled, nodes)
+
+	count := 0
+	for _, pod := range pending {
+		for _, node := range nodes {
+			if !kube_util.IsNodeReadyAndSchedulable(node) || deletetaint.HasToBeDeletedTaint(node) {
+				continue
+			}
+			nodeInfo := nodeInfos[node.Name]
+			if err := predicateChecker.CheckPredicates(pod, nil, nodeInfo); err == nil {
+				pod.Spec.NodeName = node.Name
+				nodeInfo.AddPod(pod)
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+func (c *cluster) addTaint(nodeName string, taintKey string, effect apiv1.TaintEffect) error {
+	c.Lock()
+	defer c.Unlock()
+	node, found := c.nodes[nodeName]
+	if !found {
+		return fmt.Errorf("node %s not found", nodeName)
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == taintKey {
+			return nil
+		}
+	}
+	node.Spec.Taints = append(node.Spec.Taints, apiv1.Taint{
+		Key:    taintKey,
+		Value:  strconv.FormatInt(c.now.Unix(), 10),
+		Effect: effect,
+	})
+	return nil
+}
+
+func (c *cluster) cleanTaint(nodeName string, taintKey string) bool {
+	c.Lock()
+	defer c.Unlock()
+	node, found := c.nodes[nodeName]
+	if !found {
+		return false
+	}
+	taints := make([]apiv1.Taint, 0, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		if taint.Key != taintKey {
+			taints = append(taints, taint)
+		}
+	}
+	if len(taints) == len(node.Spec.Taints) {
+		return false
+	}
+	node.Spec.Taints = taints
+	return true
+}
+
+// evictPods makes the given pods pending again, as if their controllers recreated them.
+func (c *cluster) evictPods(pods []*apiv1.Pod) {
+	c.Lock()
+	defer c.Unlock()
+	for _, pod := range pods {
+		if stored, found := c.pods[podKey(pod)]; found {
+			c.resetPod(stored)
+		}
+	}
+}
+
+// deleteNode removes the node from the cluster. Pods left on it are recreated as pending
+// if they are managed by a controller other than a DaemonSet and are dropped otherwise.
+func (c *cluster) deleteNode(nodeName string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.nodes, nodeName)
+	for key, pod := range c.pods {
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+		controllerRef := metav1.GetControllerOf(pod)
+		if controllerRef == nil || controllerRef.Kind == "DaemonSet" {
+			delete(c.pods, key)
+		} else {
+			c.resetPod(pod)
+		}
+	}
+}
+
+// resetPod must be called with the lock held.
+func (c *cluster) resetPod(pod *apiv1.Pod) {
+	pod.Spec.NodeName = ""
+	pod.CreationTimestamp = metav1.NewTime(c.now)
+	pod.Status = apiv1.PodStatus{
+		Phase: apiv1.PodPending,
+		Conditions: []apiv1.PodCondition{
+			{
+				Type:   apiv1.PodScheduled,
+				Status: apiv1.ConditionFalse,
+				Reason: apiv1.PodReasonUnschedulable,
+			},
+		},
+	}
+}
+
+// counts returns the number of nodes, scheduled pods and pending pods in the cluster.
+func (c *cluster) counts() (nodes, scheduledPods, pendingPods int) {
+	c.Lock()
+	defer c.Unlock()
+	for _, pod := range c.pods {
+		if pod.Spec.NodeName != "" {
+			scheduledPods++
+		} else {
+			pendingPods++
+		}
+	}
+	return len(c.nodes), scheduledPods, pendingPods
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+)
+
+type nodeLister struct {
+	cluster *cluster
+	filter  func(*apiv1.Node) bool
+}
+
+// List returns the nodes of the simulated cluster that pass the filter.
+func (l *nodeLister) List() ([]*apiv1.Node, error) {
+	return l.cluster.listNodes(l.filter), nil
+}
+
+type podLister struct {
+	cluster *cluster
+	filter  func(*apiv1.Pod) bool
+}
+
+// List returns the pods of the simulated cluster that pass the filter.
+func (l *podLister) List() ([]*apiv1.Pod, error) {
+	return l.cluster.listPods(l.filter), nil
+}
+
+type podDisruptionBudgetLister struct {
+	cluster *cluster
+}
+
+// List returns the pod disruption budgets of the simulated cluster.
+func (l *podDisruptionBudgetLister) List() ([]*policyv1.PodDisruptionBudget, error) {
+	return l.cluster.listPodDisruptionBudgets(), nil
+}
+
+type daemonSetLister struct {
+	cluster *cluster
+}
+
+// List returns the daemon sets of the simulated cluster.
+func (l *daemonSetLister) List() ([]*extensionsv1.DaemonSet, error) {
+	return l.cluster.listDaemonSets(), nil
+}
+
+// newListerRegistry builds a ListerRegistry backed by the simulated cluster.
+func newListerRegistry(c *cluster) kube_util.ListerRegistry {
+	return kube_util.NewListerRegistry(
+		&nodeLister{cluster: c},
+		&nodeLister{cluster: c, filter: kube_util.IsNodeReadyAndSchedulable},
+		&podLister{cluster: c, filter: func(pod *apiv1.Pod) bool { return pod.Spec.NodeName != "" }},
+		&podLister{cluster: c, filter: func(pod *apiv1.Pod) bool { return pod.Spec.NodeName == "" }},
+		&podDisruptionBudgetLister{cluster: c},
+		&daemonSetLister{cluster: c})
+}
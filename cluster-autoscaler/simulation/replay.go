@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// SnapshotFromRecord builds a snapshot of the cluster seen by a recorded autoscaler loop,
+// so that the loop can be replayed with Simulation. The internal state of the autoscaler
+// (like the time since nodes became unneeded) is not recorded, so scale-down decisions
+// that depend on it need more than one replayed iteration.
+func SnapshotFromRecord(record *looprecorder.Record) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Nodes:                record.Nodes,
+		PodDisruptionBudgets: record.PodDisruptionBudgets,
+		DaemonSets:           record.DaemonSets,
+	}
+	snapshot.Pods = make([]*apiv1.Pod, 0, len(record.ScheduledPods)+len(record.UnschedulablePods))
+	snapshot.Pods = append(snapshot.Pods, record.ScheduledPods...)
+	snapshot.Pods = append(snapshot.Pods, record.UnschedulablePods...)
+	for _, ng := range record.NodeGroups {
+		targetSize := ng.TargetSize
+		nodeGroup := NodeGroup{
+			Name:       ng.Id,
+			MinSize:    ng.MinSize,
+			MaxSize:    ng.MaxSize,
+			TargetSize: &targetSize,
+			Nodes:      ng.Nodes,
+		}
+		if template, found := record.TemplateNodeInfos[ng.Id]; found {
+			nodeGroup.Template = template.Node
+		}
+		snapshot.NodeGroups = append(snapshot.NodeGroups, nodeGroup)
+	}
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"testing"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryRecorder struct {
+	records [][]byte
+}
+
+func (r *memoryRecorder) Write(record *looprecorder.Record) error {
+	data, err := record.Marshal()
+	if err != nil {
+		return err
+	}
+	r.records = append(r.records, data)
+	return nil
+}
+
+func TestReplayRecordedLoop(t *testing.T) {
+	snapshot := buildTestSnapshot(t, "- name: ng1\n  minSize: 1\n  maxSize: 5\n  nodes: [n1]",
+		[]string{"n1"}, [][3]string{{"p1", "n1", "600m"}, {"p2", "", "600m"}, {"p3", "", "600m"}})
+
+	recorder := &memoryRecorder{}
+	cfg := testConfig()
+	cfg.Iterations = 1
+	cfg.LoopRecorder = recorder
+	simulation, err := NewSimulation(snapshot, cfg)
+	assert.NoError(t, err)
+	timeline := simulation.Run()
+	assert.Equal(t, 1, len(recorder.records))
+
+	record, err := looprecorder.UnmarshalRecord(recorder.records[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(record.Nodes))
+	assert.Equal(t, 1, len(record.ScheduledPods))
+	assert.Equal(t, 2, len(record.UnschedulablePods))
+	assert.Equal(t, []looprecorder.NodeGroup{{Id: "ng1", MinSize: 1, MaxSize: 5, TargetSize: 1, Nodes: []string{"n1"}}}, record.NodeGroups)
+	assert.NotNil(t, record.TemplateNodeInfos["ng1"].Node)
+	assert.NotNil(t, record.ScaleUpStatus)
+	assert.True(t, record.ScaleUpStatus.ScaledUp)
+	assert.Equal(t, []looprecorder.ScaleUpInfo{{NodeGroup: "ng1", CurrentSize: 1, NewSize: 3, MaxSize: 5}}, record.ScaleUpStatus.ScaleUpInfos)
+	assert.ElementsMatch(t, []string{"default/p2", "default/p3"}, record.ScaleUpStatus.PodsTriggeredScaleUp)
+
+	replayedSnapshot, err := SnapshotFromRecord(record)
+	assert.NoError(t, err)
+	cfg.LoopRecorder = nil
+	replay, err := NewSimulation(replayedSnapshot, cfg)
+	assert.NoError(t, err)
+	replayed := replay.Run()
+	assert.Equal(t, 1, len(timeline[0].Actions))
+	assert.Equal(t, 1, len(replayed[0].Actions))
+	assert.Equal(t, actuation.IncreaseSizeAction, replayed[0].Actions[0].Type)
+	assert.Equal(t, timeline[0].Actions[0].NodeGroup, replayed[0].Actions[0].NodeGroup)
+	assert.Equal(t, timeline[0].Actions[0].Delta, replayed[0].Actions[0].Delta)
+}
+
+func TestSnapshotFromRecordInvalid(t *testing.T) {
+	record := &looprecorder.Record{
+		NodeGroups: []looprecorder.NodeGroup{{Id: "ng1", MinSize: 0, MaxSize: 3}},
+	}
+	_, err := SnapshotFromRecord(record)
+	assert.Error(t, err)
+}
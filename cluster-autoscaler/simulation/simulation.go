@@ -0,0 +1,359 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	ctx "context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	testprovider "github.com/gardener/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"github.com/gardener/autoscaler/cluster-autoscaler/clusterstate/utils"
+	"github.com/gardener/autoscaler/cluster-autoscaler/config"
+	"github.com/gardener/autoscaler/cluster-autoscaler/context"
+	"github.com/gardener/autoscaler/cluster-autoscaler/core"
+	"github.com/gardener/autoscaler/cluster-autoscaler/looprecorder"
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core_testing "k8s.io/client-go/testing"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+const (
+	// scaleDownWaitTimeout is the maximum time to wait for a node deletion started by
+	// scale-down to finish before the next iteration.
+	scaleDownWaitTimeout = time.Minute
+)
+
+// Config configures a Simulation.
+type Config struct {
+	// Options are the options of the simulated autoscaler.
+	Options config.AutoscalingOptions
+	// Iterations is the number of autoscaler loops to simulate.
+	Iterations int
+	// TimeStep is the virtual time between two autoscaler loops.
+	TimeStep time.Duration
+	// NodeProvisionTime is the virtual time it takes for a new node to join the cluster.
+	NodeProvisionTime time.Duration
+	// StartTime is the virtual time of the first loop. Defaults to the current time.
+	StartTime time.Time
+	// PredicateChecker is used by the autoscaler and the simulated scheduler. Defaults to
+	// a predicate checker with the default scheduler predicates.
+	PredicateChecker *simulator.PredicateChecker
+	// LoopRecorder, if set, records the inputs and decisions of each simulated loop.
+	LoopRecorder looprecorder.Recorder
+}
+
+// TimelineEntry describes a single simulated autoscaler loop.
+type TimelineEntry struct {
+	Iteration int       `json:"iteration"`
+	Time      time.Time `json:"time"`
+	// NodesAdded are the nodes that joined the cluster before the loop.
+	NodesAdded []string `json:"nodesAdded,omitempty"`
+	// PodsScheduled is the number of pending pods scheduled before the loop.
+	PodsScheduled int `json:"podsScheduled"`
+	// Actions are the decisions taken by the autoscaler in the loop.
+	Actions []actuation.Action `json:"actions,omitempty"`
+	// Error is the error returned by the loop, if any.
+	Error string `json:"error,omitempty"`
+	// Nodes, ScheduledPods, PendingPods and NodeGroupSizes describe the cluster after the loop.
+	Nodes          int            `json:"nodes"`
+	ScheduledPods  int            `json:"scheduledPods"`
+	PendingPods    int            `json:"pendingPods"`
+	NodeGroupSizes map[string]int `json:"nodeGroupSizes"`
+}
+
+// Timeline is the result of a simulation.
+type Timeline []TimelineEntry
+
+// WriteText writes a human readable form of the timeline.
+func (t Timeline) WriteText(w io.Writer) error {
+	for _, entry := range t {
+		offset := time.Duration(0)
+		if len(t) > 0 {
+			offset = entry.Time.Sub(t[0].Time)
+		}
+		groups := make([]string, 0, len(entry.NodeGroupSizes))
+		for name, size := range entry.NodeGroupSizes {
+			groups = append(groups, fmt.Sprintf("%s=%d", name, size))
+		}
+		sort.Strings(groups)
+		if _, err := fmt.Fprintf(w, "iteration %d (+%v): nodes %d, pods %d scheduled / %d pending, node groups %s\n",
+			entry.Iteration, offset, entry.Nodes, entry.ScheduledPods, entry.PendingPods, strings.Join(groups, " ")); err != nil {
+			return err
+		}
+		lines := make([]string, 0)
+		for _, node := range entry.NodesAdded {
+			lines = append(lines, fmt.Sprintf("node %s joined the cluster", node))
+		}
+		if entry.PodsScheduled > 0 {
+			lines = append(lines, fmt.Sprintf("%d pending pods scheduled", entry.PodsScheduled))
+		}
+		for _, action := range entry.Actions {
+			lines = append(lines, formatAction(action))
+		}
+		if entry.Error != "" {
+			lines = append(lines, fmt.Sprintf("error: %s", entry.Error))
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatAction(action actuation.Action) string {
+	parts := []string{string(action.Type)}
+	if action.NodeGroup != "" {
+		parts = append(parts, "group="+action.NodeGroup)
+	}
+	if action.Delta != 0 {
+		parts = append(parts, fmt.Sprintf("delta=%d", action.Delta))
+	}
+	if len(action.Nodes) > 0 {
+		parts = append(parts, "nodes="+strings.Join(action.Nodes, ","))
+	}
+	if len(action.Pods) > 0 {
+		parts = append(parts, "pods="+strings.Join(action.Pods, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Simulation replays a cluster snapshot through StaticAutoscaler. The cloud provider and
+// the cluster are simulated: new nodes join the cluster after NodeProvisionTime and pending
+// pods are scheduled on the first node they fit on between autoscaler loops.
+type Simulation struct {
+	config           Config
+	cluster          *cluster
+	provider         *testprovider.TestCloudProvider
+	predicateChecker *simulator.PredicateChecker
+	actuator         *simulationActuator
+	autoscaler       *core.StaticAutoscaler
+}
+
+// NewSimulation builds a Simulation of the given snapshot.
+func NewSimulation(snapshot *Snapshot, cfg Config) (*Simulation, error) {
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.StartTime.IsZero() {
+		cfg.StartTime = time.Now()
+	}
+	// Test cloud provider can't create node groups on its own.
+	cfg.Options.NodeAutoprovisioningEnabled = false
+
+	c := newCluster(snapshot, cfg.StartTime)
+	fakeClient := newFakeClient(snapshot, c)
+
+	predicateChecker := cfg.PredicateChecker
+	if predicateChecker == nil {
+		var err error
+		predicateChecker, err = simulator.NewPredicateChecker(fakeClient, make(chan struct{}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create predicate checker: %v", err)
+		}
+	}
+
+	templates := make(map[string]*schedulercache.NodeInfo)
+	for name, node := range c.templates {
+		nodeInfo := schedulercache.NewNodeInfo()
+		if err := nodeInfo.SetNode(node); err != nil {
+			return nil, fmt.Errorf("invalid template of node group %s: %v", name, err)
+		}
+		templates[name] = nodeInfo
+	}
+	var provider *testprovider.TestCloudProvider
+	provider = testprovider.NewTestAutoprovisioningCloudProvider(
+		func(nodeGroup string, delta int) error {
+			if delta > 0 {
+				c.requestNodes(nodeGroup, delta, cfg.NodeProvisionTime)
+			} else {
+				c.cancelNodes(nodeGroup, -delta)
+			}
+			return nil
+		}, func(nodeGroup string, node string) error {
+			provider.DeleteNode(node)
+			c.deleteNode(node)
+			return nil
+		}, nil, nil, nil, templates)
+	provider.SetResourceLimiter(context.NewResourceLimiterFromAutoscalingOptions(cfg.Options))
+	for _, ng := range snapshot.NodeGroups {
+		targetSize := len(ng.Nodes)
+		if ng.TargetSize != nil {
+			targetSize = *ng.TargetSize
+		}
+		provider.AddNodeGroup(ng.Name, ng.MinSize, ng.MaxSize, targetSize)
+		for _, name := range ng.Nodes {
+			provider.AddNode(ng.Name, c.nodes[name])
+		}
+	}
+
+	recorder := kube_util.CreateEventRecorder(fakeClient)
+	logRecorder, err := utils.NewStatusMapRecorder(fakeClient, cfg.Options.ConfigNamespace, recorder, cfg.Options.WriteStatusConfigMap)
+	if err != nil {
+		return nil, err
+	}
+	actuator := newSimulationActuator(c)
+	autoscaler, typedErr := core.NewAutoscaler(core.AutoscalerOptions{
+		AutoscalingOptions: cfg.Options,
+		KubeClient:         fakeClient,
+		AutoscalingKubeClients: &context.AutoscalingKubeClients{
+			ListerRegistry: newListerRegistry(c),
+			ClientSet:      fakeClient,
+			Recorder:       recorder,
+			LogRecorder:    logRecorder,
+		},
+		CloudProvider:    provider,
+		PredicateChecker: predicateChecker,
+		Actuator:         actuator,
+		LoopRecorder:     cfg.LoopRecorder,
+	})
+	if typedErr != nil {
+		return nil, typedErr
+	}
+	staticAutoscaler, ok := autoscaler.(*core.StaticAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("unexpected autoscaler type %T", autoscaler)
+	}
+
+	return &Simulation{
+		config:           cfg,
+		cluster:          c,
+		provider:         provider,
+		predicateChecker: predicateChecker,
+		actuator:         actuator,
+		autoscaler:       staticAutoscaler,
+	}, nil
+}
+
+// Run simulates the configured number of autoscaler loops and returns their timeline.
+func (s *Simulation) Run() Timeline {
+	timeline := make(Timeline, 0, s.config.Iterations)
+	for i := 0; i < s.config.Iterations; i++ {
+		now := s.config.StartTime.Add(time.Duration(i) * s.config.TimeStep)
+		s.cluster.setTime(now)
+
+		nodesAdded := make([]string, 0)
+		for name, nodeGroup := range s.cluster.provisionNodes(s.predicateChecker) {
+			s.provider.AddNode(nodeGroup, &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			nodesAdded = append(nodesAdded, name)
+		}
+		sort.Strings(nodesAdded)
+		podsScheduled := s.cluster.schedulePendingPods(s.predicateChecker)
+
+		entry := TimelineEntry{
+			Iteration:     i + 1,
+			Time:          now,
+			NodesAdded:    nodesAdded,
+			PodsScheduled: podsScheduled,
+		}
+		if err := s.autoscaler.RunOnce(ctx.Background(), now); err != nil {
+			entry.Error = err.Error()
+		}
+		s.waitForScaleDown()
+		entry.Actions = s.actuator.takeActions()
+		entry.Nodes, entry.ScheduledPods, entry.PendingPods = s.cluster.counts()
+		entry.NodeGroupSizes = make(map[string]int)
+		for _, nodeGroup := range s.provider.NodeGroups() {
+			size, _ := nodeGroup.TargetSize()
+			entry.NodeGroupSizes[nodeGroup.Id()] = size
+		}
+		timeline = append(timeline, entry)
+	}
+	return timeline
+}
+
+// waitForScaleDown waits until the node deletion started by the last loop, if any, finishes,
+// so that each loop sees the results of the previous one.
+func (s *Simulation) waitForScaleDown() {
+	for start := time.Now(); time.Now().Sub(start) < scaleDownWaitTimeout; time.Sleep(10 * time.Millisecond) {
+		if !s.autoscaler.IsScaleDownInProgress() {
+			return
+		}
+	}
+}
+
+// newFakeClient builds a fake clientset with the objects of the snapshot and the controllers
+// of its pods, so that scale-down can verify pod ownership. Pods are listed from the simulated cluster.
+func newFakeClient(snapshot *Snapshot, c *cluster) *fake.Clientset {
+	objects := make([]runtime.Object, 0)
+	for _, pdb := range snapshot.PodDisruptionBudgets {
+		objects = append(objects, pdb.DeepCopy())
+	}
+	daemonSets := make(map[string]bool)
+	for _, ds := range snapshot.DaemonSets {
+		objects = append(objects, ds.DeepCopy())
+		daemonSets[ds.Namespace+"/"+ds.Name] = true
+	}
+
+	controllers := make(map[string]bool)
+	for _, pod := range snapshot.Pods {
+		ref := metav1.GetControllerOf(pod)
+		if ref == nil {
+			continue
+		}
+		key := ref.Kind + "/" + pod.Namespace + "/" + ref.Name
+		if controllers[key] {
+			continue
+		}
+		controllers[key] = true
+		meta := metav1.ObjectMeta{Namespace: pod.Namespace, Name: ref.Name, UID: ref.UID}
+		switch ref.Kind {
+		case "ReplicationController":
+			objects = append(objects, &apiv1.ReplicationController{ObjectMeta: meta})
+		case "ReplicaSet":
+			objects = append(objects, &extensionsv1.ReplicaSet{ObjectMeta: meta})
+		case "StatefulSet":
+			objects = append(objects, &appsv1beta1.StatefulSet{ObjectMeta: meta})
+		case "Job":
+			objects = append(objects, &batchv1.Job{ObjectMeta: meta})
+		case "DaemonSet":
+			if !daemonSets[pod.Namespace+"/"+ref.Name] {
+				objects = append(objects, &extensionsv1.DaemonSet{ObjectMeta: meta})
+			}
+		}
+	}
+
+	fakeClient := fake.NewSimpleClientset(objects...)
+	fakeClient.Fake.PrependReactor("list", "pods", func(action core_testing.Action) (bool, runtime.Object, error) {
+		restrictions := action.(core_testing.ListAction).GetListRestrictions()
+		nodeName, found := restrictions.Fields.RequiresExactMatch("spec.nodeName")
+		pods := c.listPods(func(pod *apiv1.Pod) bool {
+			return !found || pod.Spec.NodeName == nodeName
+		})
+		podList := &apiv1.PodList{}
+		for _, pod := range pods {
+			podList.Items = append(podList.Items, *pod)
+		}
+		return true, podList, nil
+	})
+	return fakeClient
+}
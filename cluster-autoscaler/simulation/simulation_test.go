@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/actuation"
+	"github.com/gardener/autoscaler/cluster-autoscaler/config"
+	"github.com/gardener/autoscaler/cluster-autoscaler/estimator"
+	"github.com/gardener/autoscaler/cluster-autoscaler/expander"
+	"github.com/gardener/autoscaler/cluster-autoscaler/simulator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testNodeYAML = `
+- metadata:
+    name: %s
+  status:
+    capacity:
+      cpu: "1"
+      memory: 1Gi
+      pods: "110"
+    allocatable:
+      cpu: "1"
+      memory: 1Gi
+      pods: "110"
+    conditions:
+    - type: Ready
+      status: "True"
+      lastTransitionTime: "2018-01-01T00:00:00Z"`
+
+const testPodYAML = `
+- metadata:
+    name: %s
+    namespace: default
+    creationTimestamp: "2018-01-01T00:00:00Z"
+    ownerReferences:
+    - apiVersion: extensions/v1beta1
+      kind: ReplicaSet
+      name: rs
+      uid: rs-uid
+      controller: true
+  spec:
+    nodeName: "%s"
+    containers:
+    - name: c
+      resources:
+        requests:
+          cpu: %s`
+
+func buildTestSnapshot(t *testing.T, nodeGroups string, nodes []string, pods [][3]string) *Snapshot {
+	var data bytes.Buffer
+	data.WriteString("nodeGroups:\n" + nodeGroups + "\nnodes:")
+	for _, node := range nodes {
+		data.WriteString(fmt.Sprintf(testNodeYAML, node))
+	}
+	data.WriteString("\npods:")
+	for _, pod := range pods {
+		data.WriteString(fmt.Sprintf(testPodYAML, pod[0], pod[1], pod[2]))
+	}
+	snapshot, err := ParseSnapshot(data.Bytes())
+	assert.NoError(t, err)
+	return snapshot
+}
+
+func testConfig() Config {
+	return Config{
+		Options: config.AutoscalingOptions{
+			EstimatorName:                    estimator.BinpackingEstimatorName,
+			ExpanderName:                     expander.RandomExpanderName,
+			MaxCoresTotal:                    config.DefaultMaxClusterCores,
+			MaxMemoryTotal:                   config.DefaultMaxClusterMemory * 1024 * 1024 * 1024,
+			MaxNodesTotal:                    100,
+			ScaleDownEnabled:                 true,
+			ScaleDownUtilizationThreshold:    0.5,
+			ScaleDownUnneededTime:            2 * time.Minute,
+			ScaleDownUnreadyTime:             20 * time.Minute,
+			ScaleDownDelayAfterAdd:           time.Minute,
+			MaxNodeProvisionTime:             15 * time.Minute,
+			MaxGracefulTerminationSec:        60,
+			MaxEmptyBulkDelete:               10,
+			ScaleDownCandidatesPoolRatio:     1,
+			ScaleDownNonEmptyCandidatesCount: 30,
+			MaxTotalUnreadyPercentage:        45,
+			OkTotalUnreadyCount:              3,
+		},
+		Iterations:        3,
+		TimeStep:          time.Minute,
+		NodeProvisionTime: time.Minute,
+		PredicateChecker:  simulator.NewTestPredicateChecker(),
+	}
+}
+
+func TestParseSnapshotValidation(t *testing.T) {
+	_, err := ParseSnapshot([]byte("nodeGroups:\n- name: ng1\n  minSize: 0\n  maxSize: 1\n  nodes: [n1]\n"))
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "n1"))
+
+	_, err = ParseSnapshot([]byte("nodeGroups:\n- name: ng1\n  minSize: 2\n  maxSize: 1\n"))
+	assert.Error(t, err)
+
+	_, err = ParseSnapshot([]byte("nodeGroups:\n- name: ng1\n  minSize: 0\n  maxSize: 1\n"))
+	assert.Error(t, err)
+}
+
+func TestSimulationScaleUp(t *testing.T) {
+	snapshot := buildTestSnapshot(t, "- name: ng1\n  minSize: 1\n  maxSize: 5\n  nodes: [n1]",
+		[]string{"n1"}, [][3]string{{"p1", "n1", "600m"}, {"p2", "", "600m"}})
+
+	simulation, err := NewSimulation(snapshot, testConfig())
+	assert.NoError(t, err)
+	timeline := simulation.Run()
+	assert.Equal(t, 3, len(timeline))
+
+	assert.Equal(t, "", timeline[0].Error)
+	assert.Equal(t, 1, len(timeline[0].Actions))
+	assert.Equal(t, actuation.IncreaseSizeAction, timeline[0].Actions[0].Type)
+	assert.Equal(t, "ng1", timeline[0].Actions[0].NodeGroup)
+	assert.Equal(t, 1, timeline[0].Actions[0].Delta)
+	assert.Equal(t, 1, timeline[0].PendingPods)
+	assert.Equal(t, map[string]int{"ng1": 2}, timeline[0].NodeGroupSizes)
+
+	assert.Equal(t, []string{"ng1-sim-1"}, timeline[1].NodesAdded)
+	assert.Equal(t, 1, timeline[1].PodsScheduled)
+	assert.Equal(t, 0, len(timeline[1].Actions))
+	assert.Equal(t, 2, timeline[1].Nodes)
+	assert.Equal(t, 0, timeline[1].PendingPods)
+
+	var out bytes.Buffer
+	assert.NoError(t, timeline.WriteText(&out))
+	assert.True(t, strings.Contains(out.String(), "IncreaseSize group=ng1 delta=1"), out.String())
+	assert.True(t, strings.Contains(out.String(), "node ng1-sim-1 joined the cluster"), out.String())
+}
+
+func TestSimulationScaleDown(t *testing.T) {
+	snapshot := buildTestSnapshot(t, "- name: ng1\n  minSize: 1\n  maxSize: 5\n  nodes: [n1, n2]",
+		[]string{"n1", "n2"}, [][3]string{{"p1", "n1", "500m"}, {"p2", "n2", "100m"}})
+
+	cfg := testConfig()
+	cfg.Iterations = 6
+	simulation, err := NewSimulation(snapshot, cfg)
+	assert.NoError(t, err)
+	timeline := simulation.Run()
+
+	deleted := ""
+	for _, entry := range timeline {
+		assert.Equal(t, "", entry.Error)
+		for _, action := range entry.Actions {
+			if action.Type == actuation.DeleteNodesAction {
+				deleted = strings.Join(action.Nodes, ",")
+			}
+		}
+	}
+	assert.Equal(t, "n2", deleted)
+	last := timeline[len(timeline)-1]
+	assert.Equal(t, 1, last.Nodes)
+	assert.Equal(t, 2, last.ScheduledPods)
+	assert.Equal(t, 0, last.PendingPods)
+	assert.Equal(t, map[string]int{"ng1": 1}, last.NodeGroupSizes)
+}
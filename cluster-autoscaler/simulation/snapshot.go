@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+)
+
+// NodeGroup describes a node group of the recorded cluster.
+type NodeGroup struct {
+	// Name is the id of the node group.
+	Name string `json:"name"`
+	// MinSize is the minimum size of the node group.
+	MinSize int `json:"minSize"`
+	// MaxSize is the maximum size of the node group.
+	MaxSize int `json:"maxSize"`
+	// TargetSize is the target size of the node group. Defaults to the number of nodes.
+	TargetSize *int `json:"targetSize,omitempty"`
+	// Nodes are the names of the nodes that belong to the node group.
+	Nodes []string `json:"nodes,omitempty"`
+	// Template is used to build new nodes of the node group. Defaults to the first node of the group.
+	Template *apiv1.Node `json:"template,omitempty"`
+}
+
+// Snapshot is a recorded state of a cluster that can be replayed through the autoscaler.
+type Snapshot struct {
+	NodeGroups           []NodeGroup                     `json:"nodeGroups"`
+	Nodes                []*apiv1.Node                   `json:"nodes"`
+	Pods                 []*apiv1.Pod                    `json:"pods"`
+	PodDisruptionBudgets []*policyv1.PodDisruptionBudget `json:"podDisruptionBudgets,omitempty"`
+	DaemonSets           []*extensionsv1.DaemonSet       `json:"daemonSets,omitempty"`
+}
+
+// LoadSnapshot reads a snapshot from a YAML or JSON file.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %v", path, err)
+	}
+	return ParseSnapshot(data)
+}
+
+// ParseSnapshot parses a YAML or JSON snapshot and validates it.
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := yaml.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %v", err)
+	}
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Validate checks that the snapshot is consistent.
+func (s *Snapshot) Validate() error {
+	nodeNames := make(map[string]bool)
+	for _, node := range s.Nodes {
+		if node.Name == "" {
+			return fmt.Errorf("node without a name in snapshot")
+		}
+		if nodeNames[node.Name] {
+			return fmt.Errorf("duplicated node %s in snapshot", node.Name)
+		}
+		nodeNames[node.Name] = true
+	}
+
+	groupNames := make(map[string]bool)
+	nodeGroups := make(map[string]string)
+	for _, ng := range s.NodeGroups {
+		if ng.Name == "" {
+			return fmt.Errorf("node group without a name in snapshot")
+		}
+		if groupNames[ng.Name] {
+			return fmt.Errorf("duplicated node group %s in snapshot", ng.Name)
+		}
+		groupNames[ng.Name] = true
+		if ng.MinSize < 0 || ng.MaxSize < ng.MinSize {
+			return fmt.Errorf("invalid size limits of node group %s: min %d, max %d", ng.Name, ng.MinSize, ng.MaxSize)
+		}
+		for _, name := range ng.Nodes {
+			if !nodeNames[name] {
+				return fmt.Errorf("node %s of node group %s not found in snapshot", name, ng.Name)
+			}
+			if other, found := nodeGroups[name]; found {
+				return fmt.Errorf("node %s belongs to both %s and %s", name, other, ng.Name)
+			}
+			nodeGroups[name] = ng.Name
+		}
+		if ng.Template == nil && len(ng.Nodes) == 0 {
+			return fmt.Errorf("node group %s has neither nodes nor a template", ng.Name)
+		}
+	}
+
+	for _, pod := range s.Pods {
+		if pod.Name == "" {
+			return fmt.Errorf("pod without a name in snapshot")
+		}
+		if pod.Spec.NodeName != "" && !nodeNames[pod.Spec.NodeName] {
+			return fmt.Errorf("pod %s/%s is bound to unknown node %s", pod.Namespace, pod.Name, pod.Spec.NodeName)
+		}
+	}
+	return nil
+}
@@ -19,19 +19,24 @@ package simulator
 import (
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"math/rand"
+	"sort"
 	"time"
 
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/errors"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/glogx"
 	scheduler_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/scheduler"
 	"github.com/gardener/autoscaler/cluster-autoscaler/utils/tpu"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/workers"
 
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	client "k8s.io/client-go/kubernetes"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 
@@ -57,70 +62,196 @@ type NodeToBeRemoved struct {
 	PodsToReschedule []*apiv1.Pod
 }
 
+// UnremovableReason represents a reason why a node can't be removed by scale down.
+type UnremovableReason string
+
+const (
+	// ScaleDownDisabledAnnotation - node can't be removed because it has a "scale down disabled" annotation.
+	ScaleDownDisabledAnnotation UnremovableReason = "ScaleDownDisabledAnnotation"
+	// NotAutoscaled - node can't be removed because it doesn't belong to an autoscaled node group.
+	NotAutoscaled UnremovableReason = "NotAutoscaled"
+	// NodeGroupMinSizeReached - node can't be removed because its node group is at its minimal size already.
+	NodeGroupMinSizeReached UnremovableReason = "NodeGroupMinSizeReached"
+	// MinimalResourceLimitExceeded - node can't be removed because it would violate cluster-wide minimal resource limits.
+	MinimalResourceLimitExceeded UnremovableReason = "MinimalResourceLimitExceeded"
+	// CurrentlyBeingDeleted - node can't be removed because it's already in the process of being deleted.
+	CurrentlyBeingDeleted UnremovableReason = "CurrentlyBeingDeleted"
+	// NotUnderutilized - node can't be removed because its utilization is above the scale down threshold.
+	NotUnderutilized UnremovableReason = "NotUnderutilized"
+	// NoPlaceToMovePods - node can't be removed because there's no place to move its pods to.
+	NoPlaceToMovePods UnremovableReason = "NoPlaceToMovePods"
+	// BlockedByPod - node can't be removed because a pod running on it can't be moved.
+	BlockedByPod UnremovableReason = "BlockedByPod"
+	// DeletionHookDenied - node can't be removed because a pre-deletion hook vetoed the removal.
+	DeletionHookDenied UnremovableReason = "DeletionHookDenied"
+	// DeletionHookRetryLater - node can't be removed yet because a pre-deletion hook asked to retry later.
+	DeletionHookRetryLater UnremovableReason = "DeletionHookRetryLater"
+	// UnexpectedError - node can't be removed because of an unexpected error.
+	UnexpectedError UnremovableReason = "UnexpectedError"
+)
+
+// UnremovableNode contains information about a node that can't be removed.
+type UnremovableNode struct {
+	// Node that can't be removed.
+	Node *apiv1.Node
+	// Reason describes why the node can't be removed.
+	Reason UnremovableReason
+	// BlockingPod is the pod that prevents the removal, set if Reason is BlockedByPod.
+	BlockingPod *drain.BlockingPod
+}
+
+// Message returns a human readable description of why the node can't be removed.
+func (n *UnremovableNode) Message() string {
+	if n.BlockingPod != nil && n.BlockingPod.Pod != nil {
+		return fmt.Sprintf("%s: pod %s", n.Reason, n.BlockingPod)
+	}
+	return string(n.Reason)
+}
+
+// candidateEvaluation is the result of checking whether a single candidate node can be removed.
+type candidateEvaluation struct {
+	podsToRemove []*apiv1.Pod
+	// unremovable is set if the pods on the node can't be moved at all.
+	unremovable *UnremovableNode
+	// findProblems is set if there is no place for some of the pods.
+	findProblems error
+	// hints hold the places found for the pods, by pod key.
+	hints map[string]string
+}
+
 // FindNodesToRemove finds nodes that can be removed. Returns also an information about good
 // rescheduling location for each of the pods.
+// Candidates are evaluated independently by up to parallelism goroutines. The result doesn't depend
+// on parallelism; nodes are shuffled for every candidate with a random source derived from seed and
+// the candidate name, so a fixed seed makes the result reproducible. Zero seed picks a random one.
 func FindNodesToRemove(candidates []*apiv1.Node, allNodes []*apiv1.Node, pods []*apiv1.Pod,
 	client client.Interface, predicateChecker *PredicateChecker, maxCount int,
 	fastCheck bool, oldHints map[string]string, usageTracker *UsageTracker,
 	timestamp time.Time,
 	podDisruptionBudgets []*policyv1.PodDisruptionBudget,
-) (nodesToRemove []NodeToBeRemoved, unremovableNodes []*apiv1.Node, podReschedulingHints map[string]string, finalError errors.AutoscalerError) {
+	drainPolicy *drain.Policy,
+	parallelism int, seed int64,
+) (nodesToRemove []NodeToBeRemoved, unremovableNodes []*UnremovableNode, podReschedulingHints map[string]string, finalError errors.AutoscalerError) {
 
-	nodeNameToNodeInfo := scheduler_util.CreateNodeNameToInfoMap(pods, allNodes)
+	baseSnapshot := NewClusterSnapshot(allNodes, pods)
 	result := make([]NodeToBeRemoved, 0)
-	unremovable := make([]*apiv1.Node, 0)
+	unremovable := make([]*UnremovableNode, 0)
 
 	evaluationType := "Detailed evaluation"
 	if fastCheck {
 		evaluationType = "Fast evaluation"
 	}
 	newHints := make(map[string]string, len(oldHints))
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
 
-candidateloop:
-	for _, node := range candidates {
-		glog.V(2).Infof("%s: %s for removal", evaluationType, node.Name)
+	// Every worker has its own view of the cluster, sharing NodeInfos with the base snapshot.
+	workerCount := workers.Count(parallelism)
+	snapshots := make([]*ClusterSnapshot, workerCount)
+	for i := range snapshots {
+		snapshots[i] = NewClusterSnapshotFromNodeInfos(baseSnapshot.NodeInfos())
+	}
+
+	evaluate := func(node *apiv1.Node, snapshot *ClusterSnapshot) candidateEvaluation {
+		nodeInfo, found := snapshot.GetNodeInfo(node.Name)
+		if !found {
+			glog.V(2).Infof("%s: nodeInfo for %s not found", evaluationType, node.Name)
+			return candidateEvaluation{unremovable: &UnremovableNode{Node: node, Reason: UnexpectedError}}
+		}
 
 		var podsToRemove []*apiv1.Pod
+		var blockingPod *drain.BlockingPod
 		var err error
+		if fastCheck {
+			podsToRemove, blockingPod, err = FastGetPodsToMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage,
+				podDisruptionBudgets, drainPolicy)
+		} else {
+			podsToRemove, blockingPod, err = DetailedGetPodsForMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage, client, int32(*minReplicaCount),
+				podDisruptionBudgets, drainPolicy)
+		}
+		if err != nil {
+			glog.V(2).Infof("%s: node %s cannot be removed: %v", evaluationType, node.Name, err)
+			if blockingPod != nil {
+				return candidateEvaluation{unremovable: &UnremovableNode{Node: node, Reason: BlockedByPod, BlockingPod: blockingPod}}
+			}
+			return candidateEvaluation{unremovable: &UnremovableNode{Node: node, Reason: UnexpectedError}}
+		}
 
-		if nodeInfo, found := nodeNameToNodeInfo[node.Name]; found {
-			if fastCheck {
-				podsToRemove, err = FastGetPodsToMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage,
-					podDisruptionBudgets)
-			} else {
-				podsToRemove, err = DetailedGetPodsForMove(nodeInfo, *skipNodesWithSystemPods, *skipNodesWithLocalStorage, client, int32(*minReplicaCount),
-					podDisruptionBudgets)
+		// Every candidate is evaluated against the cluster as it is, without pods moved for other candidates.
+		evaluation := candidateEvaluation{podsToRemove: podsToRemove, hints: make(map[string]string)}
+		snapshot.Fork()
+		evaluation.findProblems = findPlaceFor(node.Name, podsToRemove, allNodes, snapshot, predicateChecker, oldHints, evaluation.hints,
+			candidateRand(seed, node.Name))
+		if err := snapshot.Revert(); err != nil {
+			evaluation.unremovable = &UnremovableNode{Node: node, Reason: UnexpectedError}
+			glog.Errorf("%s: failed to revert snapshot for %s: %v", evaluationType, node.Name, err)
+		}
+		return evaluation
+	}
+
+	// Candidates are evaluated in batches of as many nodes as are still needed, and the results are applied
+	// in candidate order, exactly as if the candidates were evaluated one after another. With the fast check,
+	// batches are filled up to the number of workers. The detailed check calls the API server, so no more
+	// candidates are evaluated than may be needed.
+	for start := 0; start < len(candidates) && len(result) < maxCount; {
+		batchSize := maxCount - len(result)
+		if fastCheck && batchSize < workerCount {
+			batchSize = workerCount
+		}
+		if start+batchSize > len(candidates) {
+			batchSize = len(candidates) - start
+		}
+		batch := candidates[start : start+batchSize]
+		start += batchSize
+
+		evaluations := make([]candidateEvaluation, len(batch))
+		workers.Run(workerCount, len(batch), func(worker, piece int) {
+			evaluations[piece] = evaluate(batch[piece], snapshots[worker])
+		})
+
+		for i, node := range batch {
+			evaluation := evaluations[i]
+			glog.V(2).Infof("%s: %s for removal", evaluationType, node.Name)
+			if evaluation.unremovable != nil {
+				unremovable = append(unremovable, evaluation.unremovable)
+				continue
 			}
-			if err != nil {
-				glog.V(2).Infof("%s: node %s cannot be removed: %v", evaluationType, node.Name, err)
-				unremovable = append(unremovable, node)
-				continue candidateloop
+			for _, pod := range evaluation.podsToRemove {
+				if target, found := evaluation.hints[podKey(pod)]; found {
+					newHints[podKey(pod)] = target
+					usageTracker.RegisterUsage(node.Name, target, timestamp)
+				}
 			}
-		} else {
-			glog.V(2).Infof("%s: nodeInfo for %s not found", evaluationType, node.Name)
-			unremovable = append(unremovable, node)
-			continue candidateloop
-		}
-		findProblems := findPlaceFor(node.Name, podsToRemove, allNodes, nodeNameToNodeInfo, predicateChecker, oldHints, newHints,
-			usageTracker, timestamp)
-
-		if findProblems == nil {
-			result = append(result, NodeToBeRemoved{
-				Node:             node,
-				PodsToReschedule: podsToRemove,
-			})
-			glog.V(2).Infof("%s: node %s may be removed", evaluationType, node.Name)
-			if len(result) >= maxCount {
-				break candidateloop
+			if evaluation.findProblems == nil {
+				result = append(result, NodeToBeRemoved{
+					Node:             node,
+					PodsToReschedule: evaluation.podsToRemove,
+				})
+				glog.V(2).Infof("%s: node %s may be removed", evaluationType, node.Name)
+				if len(result) >= maxCount {
+					break
+				}
+			} else {
+				glog.V(2).Infof("%s: node %s is not suitable for removal: %v", evaluationType, node.Name, evaluation.findProblems)
+				unremovable = append(unremovable, &UnremovableNode{Node: node, Reason: NoPlaceToMovePods})
 			}
-		} else {
-			glog.V(2).Infof("%s: node %s is not suitable for removal: %v", evaluationType, node.Name, findProblems)
-			unremovable = append(unremovable, node)
 		}
 	}
 	return result, unremovable, newHints, nil
 }
 
+// candidateRand returns the random source used when evaluating the given candidate node.
+func candidateRand(seed int64, nodeName string) *rand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(nodeName))
+	return rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+}
+
+func podKey(pod *apiv1.Pod) string {
+	return fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+}
+
 // FindEmptyNodesToRemove finds empty nodes that can be removed.
 func FindEmptyNodesToRemove(candidates []*apiv1.Node, pods []*apiv1.Pod) []*apiv1.Node {
 	nodeNameToNodeInfo := scheduler_util.CreateNodeNameToInfoMap(pods, candidates)
@@ -128,7 +259,7 @@ func FindEmptyNodesToRemove(candidates []*apiv1.Node, pods []*apiv1.Pod) []*apiv
 	for _, node := range candidates {
 		if nodeInfo, found := nodeNameToNodeInfo[node.Name]; found {
 			// Should block on all pods.
-			podsToRemove, err := FastGetPodsToMove(nodeInfo, true, true, nil)
+			podsToRemove, _, err := FastGetPodsToMove(nodeInfo, true, true, nil, nil)
 			if err == nil && len(podsToRemove) == 0 {
 				result = append(result, node)
 			}
@@ -140,18 +271,48 @@ func FindEmptyNodesToRemove(candidates []*apiv1.Node, pods []*apiv1.Pod) []*apiv
 	return result
 }
 
-// CalculateUtilization calculates utilization of a node, defined as maximum of (cpu, memory) utilization.
-// Per resource utilization is the sum of requests for it divided by allocatable.
-func CalculateUtilization(node *apiv1.Node, nodeInfo *schedulercache.NodeInfo) (float64, error) {
-	cpu, err := calculateUtilizationOfResource(node, nodeInfo, apiv1.ResourceCPU)
-	if err != nil {
-		return 0, err
+// CalculateUtilization calculates utilization of a node, defined as maximum of weighted per resource utilization.
+// Per resource utilization is the sum of requests for it divided by allocatable. Cpu and memory are always
+// taken into account, ephemeral-storage, hugepages and extended resources only if the node has them allocatable.
+// Resources missing from weights have weight 1, resources with weight 0 are ignored.
+func CalculateUtilization(node *apiv1.Node, nodeInfo *schedulercache.NodeInfo, weights map[string]float64) (float64, error) {
+	result := 0.0
+	for _, resourceName := range getUtilizationResources(node) {
+		weight, found := weights[string(resourceName)]
+		if !found {
+			weight = 1
+		}
+		if weight == 0 {
+			continue
+		}
+		utilization, err := calculateUtilizationOfResource(node, nodeInfo, resourceName)
+		if err != nil {
+			return 0, err
+		}
+		result = math.Max(result, weight*utilization)
 	}
-	mem, err := calculateUtilizationOfResource(node, nodeInfo, apiv1.ResourceMemory)
-	if err != nil {
-		return 0, err
+	return result, nil
+}
+
+// getUtilizationResources returns cpu and memory followed by all other node resources that count
+// towards utilization and are allocatable on the node, sorted by name.
+func getUtilizationResources(node *apiv1.Node) []apiv1.ResourceName {
+	others := make([]string, 0)
+	for resourceName, allocatable := range node.Status.Allocatable {
+		if resourceName == apiv1.ResourceCPU || resourceName == apiv1.ResourceMemory || allocatable.IsZero() {
+			continue
+		}
+		if resourceName == apiv1.ResourceEphemeralStorage || v1helper.IsHugePageResourceName(resourceName) ||
+			v1helper.IsExtendedResourceName(resourceName) {
+			others = append(others, string(resourceName))
+		}
+	}
+	sort.Strings(others)
+	result := []apiv1.ResourceName{apiv1.ResourceCPU, apiv1.ResourceMemory}
+	for _, resourceName := range others {
+		result = append(result, apiv1.ResourceName(resourceName))
 	}
-	return math.Max(cpu, mem), nil
+	return result
 }
 
 func calculateUtilizationOfResource(node *apiv1.Node, nodeInfo *schedulercache.NodeInfo, resourceName apiv1.ResourceName) (float64, error) {
@@ -174,23 +335,15 @@ func calculateUtilizationOfResource(node *apiv1.Node, nodeInfo *schedulercache.N
 }
 
 // TODO: We don't need to pass list of nodes here as they are already available in nodeInfos.
-func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, nodeInfos map[string]*schedulercache.NodeInfo,
-	predicateChecker *PredicateChecker, oldHints map[string]string, newHints map[string]string, usageTracker *UsageTracker,
-	timestamp time.Time) error {
-
-	newNodeInfos := make(map[string]*schedulercache.NodeInfo)
-	for k, v := range nodeInfos {
-		newNodeInfos[k] = v
-	}
-
-	podKey := func(pod *apiv1.Pod) string {
-		return fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
-	}
+// findPlaceFor places the pods in the snapshot and records the chosen nodes in newHints, in the order
+// of pods. The caller is responsible for forking the snapshot if the placement should be undone.
+func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, snapshot *ClusterSnapshot,
+	predicateChecker *PredicateChecker, oldHints map[string]string, newHints map[string]string, rng *rand.Rand) error {
 
 	loggingQuota := glogx.PodsLoggingQuota()
 
 	tryNodeForPod := func(nodename string, pod *apiv1.Pod, predicateMeta algorithm.PredicateMetadata) bool {
-		nodeInfo, found := newNodeInfos[nodename]
+		nodeInfo, found := snapshot.GetNodeInfo(nodename)
 		if found {
 			if nodeInfo.Node() == nil {
 				// NodeInfo is generated based on pods. It is possible that node is removed from
@@ -203,13 +356,11 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 			if err != nil {
 				glogx.V(4).UpTo(loggingQuota).Infof("Evaluation %s for %s/%s -> %v", nodename, pod.Namespace, pod.Name, err.VerboseError())
 			} else {
-				// TODO(mwielgus): Optimize it.
 				glog.V(4).Infof("Pod %s/%s can be moved to %s", pod.Namespace, pod.Name, nodename)
-				podsOnNode := nodeInfo.Pods()
-				podsOnNode = append(podsOnNode, pod)
-				newNodeInfo := schedulercache.NewNodeInfo(podsOnNode...)
-				newNodeInfo.SetNode(nodeInfo.Node())
-				newNodeInfos[nodename] = newNodeInfo
+				if err := snapshot.AddPod(pod, nodename); err != nil {
+					glog.Errorf("Failed to add pod %s/%s to snapshot: %v", pod.Namespace, pod.Name, err)
+					return false
+				}
 				newHints[podKey(pod)] = nodename
 				return true
 			}
@@ -219,7 +370,7 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 
 	// TODO: come up with a better semi-random semi-utilization sorted
 	// layout.
-	shuffledNodes := shuffleNodes(nodes)
+	shuffledNodes := shuffleNodes(nodes, rng)
 
 	pods = tpu.ClearTPURequests(pods)
 	for _, podptr := range pods {
@@ -228,8 +379,7 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 		pod := &newpod
 
 		foundPlace := false
-		targetNode := ""
-		predicateMeta := predicateChecker.GetPredicateMetadata(pod, newNodeInfos)
+		predicateMeta := predicateChecker.GetPredicateMetadata(pod, snapshot.NodeInfos())
 		loggingQuota.Reset()
 
 		glog.V(5).Infof("Looking for place for %s/%s", pod.Namespace, pod.Name)
@@ -238,7 +388,6 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 		if hasHint {
 			if hintedNode != removedNode && tryNodeForPod(hintedNode, pod, predicateMeta) {
 				foundPlace = true
-				targetNode = hintedNode
 			}
 		}
 		if !foundPlace {
@@ -248,7 +397,6 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 				}
 				if tryNodeForPod(node.Name, pod, predicateMeta) {
 					foundPlace = true
-					targetNode = node.Name
 					break
 				}
 			}
@@ -257,19 +405,17 @@ func findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes []*apiv1.Node, no
 				return fmt.Errorf("failed to find place for %s", podKey(pod))
 			}
 		}
-
-		usageTracker.RegisterUsage(removedNode, targetNode, timestamp)
 	}
 	return nil
 }
 
-func shuffleNodes(nodes []*apiv1.Node) []*apiv1.Node {
+func shuffleNodes(nodes []*apiv1.Node, rng *rand.Rand) []*apiv1.Node {
 	result := make([]*apiv1.Node, len(nodes))
 	for i := range nodes {
 		result[i] = nodes[i]
 	}
 	for i := range result {
-		j := rand.Intn(len(result))
+		j := rng.Intn(len(result))
 		result[i], result[j] = result[j], result[i]
 	}
 	return result
@@ -0,0 +1,246 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+
+	scheduler_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/scheduler"
+
+	apiv1 "k8s.io/api/core/v1"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+// ClusterSnapshot is a mutable view of the nodes and pods of a cluster used in scheduling simulations.
+// Pods and nodes are added and removed incrementally, so that placing a pod costs as much as updating
+// a single NodeInfo instead of rebuilding it from all of its pods.
+//
+// Changes can be grouped with Fork. Changes made after Fork are undone by Revert or kept by Commit.
+// Forks can be nested. NodeInfos passed to the snapshot are never modified; they are copied the
+// first time they are changed in a fork.
+type ClusterSnapshot struct {
+	nodeInfos map[string]*schedulercache.NodeInfo
+	// layers[0] holds the base state, every Fork pushes a new layer.
+	layers []*snapshotLayer
+}
+
+// snapshotLayer records the changes made to the snapshot since the matching Fork.
+type snapshotLayer struct {
+	// original holds the NodeInfos from before the first change of a node in this layer.
+	// A nil value means that the node did not exist.
+	original map[string]*schedulercache.NodeInfo
+	// owned holds the names of nodes whose NodeInfos were created in this layer and can be
+	// changed in place.
+	owned map[string]bool
+}
+
+func newSnapshotLayer() *snapshotLayer {
+	return &snapshotLayer{
+		original: make(map[string]*schedulercache.NodeInfo),
+		owned:    make(map[string]bool),
+	}
+}
+
+// NewEmptyClusterSnapshot builds a ClusterSnapshot without any nodes.
+func NewEmptyClusterSnapshot() *ClusterSnapshot {
+	return &ClusterSnapshot{
+		nodeInfos: make(map[string]*schedulercache.NodeInfo),
+		layers:    []*snapshotLayer{newSnapshotLayer()},
+	}
+}
+
+// NewClusterSnapshot builds a ClusterSnapshot of the given nodes and pods scheduled on them.
+// Pods waiting for preemption are assigned to their nominated nodes.
+func NewClusterSnapshot(nodes []*apiv1.Node, pods []*apiv1.Pod) *ClusterSnapshot {
+	snapshot := NewEmptyClusterSnapshot()
+	snapshot.nodeInfos = scheduler_util.CreateNodeNameToInfoMap(pods, nodes)
+	for name := range snapshot.nodeInfos {
+		snapshot.layers[0].owned[name] = true
+	}
+	return snapshot
+}
+
+// NewClusterSnapshotFromNodeInfos builds a ClusterSnapshot of the given NodeInfos. The NodeInfos
+// are shared with the caller and are never modified by the snapshot.
+func NewClusterSnapshotFromNodeInfos(nodeInfos map[string]*schedulercache.NodeInfo) *ClusterSnapshot {
+	snapshot := NewEmptyClusterSnapshot()
+	for name, nodeInfo := range nodeInfos {
+		snapshot.nodeInfos[name] = nodeInfo
+	}
+	return snapshot
+}
+
+// NodeInfos returns the current NodeInfos by node name. The map and the NodeInfos must not be
+// modified by the caller and are only valid until the next change of the snapshot.
+func (s *ClusterSnapshot) NodeInfos() map[string]*schedulercache.NodeInfo {
+	return s.nodeInfos
+}
+
+// GetNodeInfo returns the current NodeInfo of the given node. It must not be modified by the caller.
+func (s *ClusterSnapshot) GetNodeInfo(nodeName string) (*schedulercache.NodeInfo, bool) {
+	nodeInfo, found := s.nodeInfos[nodeName]
+	return nodeInfo, found
+}
+
+// AddNode adds a node without pods to the snapshot.
+func (s *ClusterSnapshot) AddNode(node *apiv1.Node) error {
+	nodeInfo := schedulercache.NewNodeInfo()
+	if err := nodeInfo.SetNode(node); err != nil {
+		return err
+	}
+	if err := s.addNodeInfo(node.Name, nodeInfo); err != nil {
+		return err
+	}
+	s.top().owned[node.Name] = true
+	return nil
+}
+
+// AddNodeInfo adds a node with its pods to the snapshot under the given name. The name doesn't have to
+// match the name of the node, which allows adding many nodes built from the same template. The NodeInfo
+// is shared with the caller and is never modified by the snapshot.
+func (s *ClusterSnapshot) AddNodeInfo(nodeName string, nodeInfo *schedulercache.NodeInfo) error {
+	return s.addNodeInfo(nodeName, nodeInfo)
+}
+
+func (s *ClusterSnapshot) addNodeInfo(nodeName string, nodeInfo *schedulercache.NodeInfo) error {
+	if _, found := s.nodeInfos[nodeName]; found {
+		return fmt.Errorf("node %s already in snapshot", nodeName)
+	}
+	s.recordOriginal(nodeName)
+	s.nodeInfos[nodeName] = nodeInfo
+	return nil
+}
+
+// RemoveNode removes a node with all of its pods from the snapshot.
+func (s *ClusterSnapshot) RemoveNode(nodeName string) error {
+	if _, found := s.nodeInfos[nodeName]; !found {
+		return fmt.Errorf("node %s not found in snapshot", nodeName)
+	}
+	s.recordOriginal(nodeName)
+	delete(s.nodeInfos, nodeName)
+	delete(s.top().owned, nodeName)
+	return nil
+}
+
+// AddPod adds a pod to the given node. The pod is added as is, its NodeName is not updated.
+func (s *ClusterSnapshot) AddPod(pod *apiv1.Pod, nodeName string) error {
+	nodeInfo, err := s.nodeInfoForUpdate(nodeName)
+	if err != nil {
+		return err
+	}
+	nodeInfo.AddPod(pod)
+	return nil
+}
+
+// RemovePod removes the pod with the namespace and name of the given pod from the given node.
+func (s *ClusterSnapshot) RemovePod(pod *apiv1.Pod, nodeName string) error {
+	current, found := s.nodeInfos[nodeName]
+	if !found {
+		return fmt.Errorf("node %s not found in snapshot", nodeName)
+	}
+	remaining := make([]*apiv1.Pod, 0, len(current.Pods()))
+	for _, podOnNode := range current.Pods() {
+		if podOnNode.Namespace != pod.Namespace || podOnNode.Name != pod.Name {
+			remaining = append(remaining, podOnNode)
+		}
+	}
+	if len(remaining) == len(current.Pods()) {
+		return fmt.Errorf("pod %s/%s not found on node %s", pod.Namespace, pod.Name, nodeName)
+	}
+	// NodeInfo.RemovePod matches pods by UID, which simulated pods often don't have.
+	nodeInfo := schedulercache.NewNodeInfo(remaining...)
+	if err := nodeInfo.SetNode(current.Node()); err != nil {
+		return err
+	}
+	s.recordOriginal(nodeName)
+	s.nodeInfos[nodeName] = nodeInfo
+	s.top().owned[nodeName] = true
+	return nil
+}
+
+// Fork starts a new group of changes that can be undone with Revert or kept with Commit.
+func (s *ClusterSnapshot) Fork() {
+	s.layers = append(s.layers, newSnapshotLayer())
+}
+
+// Revert undoes all changes made since the last Fork.
+func (s *ClusterSnapshot) Revert() error {
+	if len(s.layers) < 2 {
+		return fmt.Errorf("snapshot not forked")
+	}
+	for name, nodeInfo := range s.top().original {
+		if nodeInfo == nil {
+			delete(s.nodeInfos, name)
+		} else {
+			s.nodeInfos[name] = nodeInfo
+		}
+	}
+	s.layers = s.layers[:len(s.layers)-1]
+	return nil
+}
+
+// Commit keeps all changes made since the last Fork, merging them into the enclosing fork if there is one.
+func (s *ClusterSnapshot) Commit() error {
+	if len(s.layers) < 2 {
+		return fmt.Errorf("snapshot not forked")
+	}
+	committed := s.top()
+	s.layers = s.layers[:len(s.layers)-1]
+	parent := s.top()
+	for name, nodeInfo := range committed.original {
+		if _, found := parent.original[name]; !found {
+			parent.original[name] = nodeInfo
+		}
+	}
+	for name := range committed.owned {
+		parent.owned[name] = true
+	}
+	return nil
+}
+
+func (s *ClusterSnapshot) top() *snapshotLayer {
+	return s.layers[len(s.layers)-1]
+}
+
+// recordOriginal remembers the NodeInfo of the node before its first change in the current fork.
+func (s *ClusterSnapshot) recordOriginal(nodeName string) {
+	if len(s.layers) < 2 {
+		return
+	}
+	layer := s.top()
+	if _, found := layer.original[nodeName]; !found {
+		layer.original[nodeName] = s.nodeInfos[nodeName]
+	}
+}
+
+// nodeInfoForUpdate returns a NodeInfo of the node that can be changed in place, copying it if
+// it is shared with the caller or with an enclosing fork.
+func (s *ClusterSnapshot) nodeInfoForUpdate(nodeName string) (*schedulercache.NodeInfo, error) {
+	nodeInfo, found := s.nodeInfos[nodeName]
+	if !found {
+		return nil, fmt.Errorf("node %s not found in snapshot", nodeName)
+	}
+	layer := s.top()
+	if layer.owned[nodeName] {
+		return nodeInfo, nil
+	}
+	s.recordOriginal(nodeName)
+	nodeInfo = nodeInfo.Clone()
+	s.nodeInfos[nodeName] = nodeInfo
+	layer.owned[nodeName] = true
+	return nodeInfo, nil
+}
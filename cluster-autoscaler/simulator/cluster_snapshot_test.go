@@ -0,0 +1,228 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func podNamesOn(t *testing.T, snapshot *ClusterSnapshot, nodeName string) []string {
+	nodeInfo, found := snapshot.GetNodeInfo(nodeName)
+	assert.True(t, found)
+	names := []string{}
+	for _, pod := range nodeInfo.Pods() {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestClusterSnapshotAddAndRemove(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Spec.NodeName = "n1"
+	snapshot := NewClusterSnapshot([]*apiv1.Node{n1}, []*apiv1.Pod{p1})
+
+	assert.Equal(t, []string{"p1"}, podNamesOn(t, snapshot, "n1"))
+
+	n2 := BuildTestNode("n2", 1000, 2000000)
+	assert.NoError(t, snapshot.AddNode(n2))
+	assert.Error(t, snapshot.AddNode(n2))
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p2", 200, 0), "n2"))
+	assert.Error(t, snapshot.AddPod(BuildTestPod("p3", 200, 0), "n3"))
+
+	nodeInfo, _ := snapshot.GetNodeInfo("n2")
+	assert.Equal(t, int64(200), nodeInfo.RequestedResource().MilliCPU)
+
+	assert.NoError(t, snapshot.RemovePod(p1, "n1"))
+	assert.Error(t, snapshot.RemovePod(p1, "n1"))
+	assert.Empty(t, podNamesOn(t, snapshot, "n1"))
+
+	assert.NoError(t, snapshot.RemoveNode("n1"))
+	assert.Error(t, snapshot.RemoveNode("n1"))
+	assert.Len(t, snapshot.NodeInfos(), 1)
+}
+
+func TestClusterSnapshotForkRevert(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	n2 := BuildTestNode("n2", 1000, 2000000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Spec.NodeName = "n1"
+	snapshot := NewClusterSnapshot([]*apiv1.Node{n1, n2}, []*apiv1.Pod{p1})
+	base, _ := snapshot.GetNodeInfo("n1")
+
+	snapshot.Fork()
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p2", 100, 0), "n1"))
+	assert.NoError(t, snapshot.RemoveNode("n2"))
+	assert.NoError(t, snapshot.AddNode(BuildTestNode("n3", 1000, 2000000)))
+	assert.Equal(t, []string{"p1", "p2"}, podNamesOn(t, snapshot, "n1"))
+	assert.Len(t, base.Pods(), 1)
+
+	assert.NoError(t, snapshot.Revert())
+	assert.Equal(t, []string{"p1"}, podNamesOn(t, snapshot, "n1"))
+	_, found := snapshot.GetNodeInfo("n2")
+	assert.True(t, found)
+	_, found = snapshot.GetNodeInfo("n3")
+	assert.False(t, found)
+
+	assert.Error(t, snapshot.Revert())
+	assert.Error(t, snapshot.Commit())
+}
+
+func TestClusterSnapshotNestedForks(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	snapshot := NewClusterSnapshot([]*apiv1.Node{n1}, []*apiv1.Pod{})
+
+	snapshot.Fork()
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p1", 100, 0), "n1"))
+	snapshot.Fork()
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p2", 100, 0), "n1"))
+	snapshot.Fork()
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p3", 100, 0), "n1"))
+	assert.NoError(t, snapshot.Revert())
+	assert.Equal(t, []string{"p1", "p2"}, podNamesOn(t, snapshot, "n1"))
+
+	assert.NoError(t, snapshot.Commit())
+	assert.Equal(t, []string{"p1", "p2"}, podNamesOn(t, snapshot, "n1"))
+
+	assert.NoError(t, snapshot.Revert())
+	assert.Empty(t, podNamesOn(t, snapshot, "n1"))
+}
+
+func TestClusterSnapshotDoesNotModifyNodeInfos(t *testing.T) {
+	template := schedulercache.NewNodeInfo(BuildTestPod("p1", 100, 0))
+	template.SetNode(BuildTestNode("template", 1000, 2000000))
+	snapshot := NewClusterSnapshotFromNodeInfos(map[string]*schedulercache.NodeInfo{"n1": template})
+
+	assert.NoError(t, snapshot.AddNodeInfo("n2", template))
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p2", 100, 0), "n1"))
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p3", 100, 0), "n2"))
+	assert.NoError(t, snapshot.AddPod(BuildTestPod("p4", 100, 0), "n2"))
+
+	assert.Equal(t, []string{"p1", "p2"}, podNamesOn(t, snapshot, "n1"))
+	assert.Equal(t, []string{"p1", "p3", "p4"}, podNamesOn(t, snapshot, "n2"))
+	assert.Len(t, template.Pods(), 1)
+	assert.Equal(t, int64(100), template.RequestedResource().MilliCPU)
+}
+
+const (
+	benchmarkNodes       = 1000
+	benchmarkPodsPerNode = 30
+)
+
+// buildBenchmarkCluster builds a synthetic cluster of 1000 nodes running 30000 pods.
+func buildBenchmarkCluster() ([]*apiv1.Node, []*apiv1.Pod) {
+	nodes := make([]*apiv1.Node, 0, benchmarkNodes)
+	pods := make([]*apiv1.Pod, 0, benchmarkNodes*benchmarkPodsPerNode)
+	for i := 0; i < benchmarkNodes; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 64000, 256*1024*1024*1024)
+		nodes = append(nodes, node)
+		for j := 0; j < benchmarkPodsPerNode; j++ {
+			pod := BuildTestPod(fmt.Sprintf("p%d-%d", i, j), 100, 100*1024*1024)
+			pod.Spec.NodeName = node.Name
+			pods = append(pods, pod)
+		}
+	}
+	return nodes, pods
+}
+
+// BenchmarkAddPodsRebuild places a pod on every node the way simulations did before ClusterSnapshot:
+// copying the NodeInfo map and rebuilding the NodeInfo from all of its pods.
+func BenchmarkAddPodsRebuild(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	snapshot := NewClusterSnapshot(nodes, pods)
+	nodeInfos := snapshot.NodeInfos()
+	newPod := BuildTestPod("new", 100, 100*1024*1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newNodeInfos := make(map[string]*schedulercache.NodeInfo, len(nodeInfos))
+		for k, v := range nodeInfos {
+			newNodeInfos[k] = v
+		}
+		for _, node := range nodes {
+			nodeInfo := newNodeInfos[node.Name]
+			newNodeInfo := schedulercache.NewNodeInfo(append(nodeInfo.Pods(), newPod)...)
+			newNodeInfo.SetNode(nodeInfo.Node())
+			newNodeInfos[node.Name] = newNodeInfo
+		}
+	}
+}
+
+// BenchmarkAddPodsSnapshot places a pod on every node in a fork of ClusterSnapshot and reverts it.
+func BenchmarkAddPodsSnapshot(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	snapshot := NewClusterSnapshot(nodes, pods)
+	newPod := BuildTestPod("new", 100, 100*1024*1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		snapshot.Fork()
+		for _, node := range nodes {
+			if err := snapshot.AddPod(newPod, node.Name); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := snapshot.Revert(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFillNodeRebuild fills a single node with pods, rebuilding its NodeInfo after every pod.
+func BenchmarkFillNodeRebuild(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	nodeInfos := NewClusterSnapshot(nodes, pods).NodeInfos()
+	newPod := BuildTestPod("new", 100, 100*1024*1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nodeInfo := nodeInfos["n0"]
+		for j := 0; j < benchmarkPodsPerNode*10; j++ {
+			newNodeInfo := schedulercache.NewNodeInfo(append(nodeInfo.Pods(), newPod)...)
+			newNodeInfo.SetNode(nodeInfo.Node())
+			nodeInfo = newNodeInfo
+		}
+	}
+}
+
+// BenchmarkFillNodeSnapshot fills a single node with pods in a fork of ClusterSnapshot and reverts it.
+func BenchmarkFillNodeSnapshot(b *testing.B) {
+	nodes, pods := buildBenchmarkCluster()
+	snapshot := NewClusterSnapshot(nodes, pods)
+	newPod := BuildTestPod("new", 100, 100*1024*1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		snapshot.Fork()
+		for j := 0; j < benchmarkPodsPerNode*10; j++ {
+			if err := snapshot.AddPod(newPod, "n0"); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := snapshot.Revert(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -18,11 +18,17 @@ package simulator
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1beta1"
+	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
 	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
@@ -38,16 +44,55 @@ func TestUtilization(t *testing.T) {
 	node := BuildTestNode("node1", 2000, 2000000)
 	SetNodeReadyState(node, true, time.Time{})
 
-	utilization, err := CalculateUtilization(node, nodeInfo)
+	utilization, err := CalculateUtilization(node, nodeInfo, nil)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilization, 0.01)
 
 	node2 := BuildTestNode("node1", 2000, -1)
 
-	_, err = CalculateUtilization(node2, nodeInfo)
+	_, err = CalculateUtilization(node2, nodeInfo, nil)
 	assert.Error(t, err)
 }
 
+func TestUtilizationOfOtherResources(t *testing.T) {
+	pod := BuildTestPod("p1", 100, 200000)
+	pod.Spec.Containers[0].Resources.Requests[apiv1.ResourceEphemeralStorage] = *resource.NewQuantity(8*1024*1024*1024, resource.BinarySI)
+	pod.Spec.Containers[0].Resources.Requests["hugepages-2Mi"] = *resource.NewQuantity(2*1024*1024, resource.BinarySI)
+	pod.Spec.Containers[0].Resources.Requests["example.com/fpga"] = *resource.NewQuantity(1, resource.DecimalSI)
+
+	nodeInfo := schedulercache.NewNodeInfo(pod)
+	node := BuildTestNode("node1", 2000, 2000000)
+	node.Status.Allocatable[apiv1.ResourceEphemeralStorage] = *resource.NewQuantity(10*1024*1024*1024, resource.BinarySI)
+	node.Status.Allocatable["hugepages-2Mi"] = *resource.NewQuantity(4*1024*1024, resource.BinarySI)
+	node.Status.Allocatable["example.com/fpga"] = *resource.NewQuantity(4, resource.DecimalSI)
+	node.Status.Allocatable["example.com/unused"] = *resource.NewQuantity(0, resource.DecimalSI)
+	node.Status.Allocatable["attachable-volumes-aws-ebs"] = *resource.NewQuantity(39, resource.DecimalSI)
+
+	utilization, err := CalculateUtilization(node, nodeInfo, nil)
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 0.8, utilization, 0.01)
+
+	utilization, err = CalculateUtilization(node, nodeInfo, map[string]float64{"ephemeral-storage": 0})
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 0.5, utilization, 0.01)
+
+	utilization, err = CalculateUtilization(node, nodeInfo, map[string]float64{"ephemeral-storage": 0, "hugepages-2Mi": 0.5, "example.com/fpga": 2})
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 0.5, utilization, 0.01)
+
+	utilization, err = CalculateUtilization(node, nodeInfo, map[string]float64{"ephemeral-storage": 0.5, "hugepages-2Mi": 0, "example.com/fpga": 0})
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 0.4, utilization, 0.01)
+
+	// A node without cpu can still be evaluated if cpu is left out.
+	node2 := BuildTestNode("node2", -1, 2000000)
+	_, err = CalculateUtilization(node2, nodeInfo, nil)
+	assert.Error(t, err)
+	utilization, err = CalculateUtilization(node2, nodeInfo, map[string]float64{"cpu": 0})
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 0.1, utilization, 0.01)
+}
+
 func TestFindPlaceAllOk(t *testing.T) {
 	pod1 := BuildTestPod("p1", 300, 500000)
 	new1 := BuildTestPod("p2", 600, 500000)
@@ -66,14 +111,13 @@ func TestFindPlaceAllOk(t *testing.T) {
 
 	oldHints := make(map[string]string)
 	newHints := make(map[string]string)
-	tracker := NewUsageTracker()
 
 	err := findPlaceFor(
 		"x",
 		[]*apiv1.Pod{new1, new2},
 		[]*apiv1.Node{node1, node2},
-		nodeInfos, NewTestPredicateChecker(),
-		oldHints, newHints, tracker, time.Now())
+		NewClusterSnapshotFromNodeInfos(nodeInfos), NewTestPredicateChecker(),
+		oldHints, newHints, rand.New(rand.NewSource(1)))
 
 	assert.Len(t, newHints, 2)
 	assert.Contains(t, newHints, new1.Namespace+"/"+new1.Name)
@@ -105,14 +149,13 @@ func TestFindPlaceAllBas(t *testing.T) {
 
 	oldHints := make(map[string]string)
 	newHints := make(map[string]string)
-	tracker := NewUsageTracker()
 
 	err := findPlaceFor(
 		"nbad",
 		[]*apiv1.Pod{new1, new2, new3},
 		[]*apiv1.Node{nodebad, node1, node2},
-		nodeInfos, NewTestPredicateChecker(),
-		oldHints, newHints, tracker, time.Now())
+		NewClusterSnapshotFromNodeInfos(nodeInfos), NewTestPredicateChecker(),
+		oldHints, newHints, rand.New(rand.NewSource(1)))
 
 	assert.Error(t, err)
 	assert.True(t, len(newHints) == 2)
@@ -140,11 +183,10 @@ func TestFindNone(t *testing.T) {
 		"x",
 		[]*apiv1.Pod{},
 		[]*apiv1.Node{node1, node2},
-		nodeInfos, NewTestPredicateChecker(),
+		NewClusterSnapshotFromNodeInfos(nodeInfos), NewTestPredicateChecker(),
 		make(map[string]string),
 		make(map[string]string),
-		NewUsageTracker(),
-		time.Now())
+		rand.New(rand.NewSource(1)))
 	assert.NoError(t, err)
 }
 
@@ -154,8 +196,9 @@ func TestShuffleNodes(t *testing.T) {
 		BuildTestNode("n2", 0, 0),
 		BuildTestNode("n3", 0, 0)}
 	gotPermutation := false
+	rng := rand.New(rand.NewSource(1))
 	for i := 0; i < 10000; i++ {
-		shuffled := shuffleNodes(nodes)
+		shuffled := shuffleNodes(nodes, rng)
 		if shuffled[0].Name == "n2" && shuffled[1].Name == "n3" && shuffled[2].Name == "n1" {
 			gotPermutation = true
 			break
@@ -285,11 +328,88 @@ func TestFindNodesToRemove(t *testing.T) {
 		toRemove, unremovable, _, err := FindNodesToRemove(
 			test.candidates, test.allNodes, pods, nil,
 			predicateChecker, len(test.allNodes), true, map[string]string{},
-			tracker, time.Now(), []*policyv1.PodDisruptionBudget{})
+			tracker, time.Now(), []*policyv1.PodDisruptionBudget{}, nil, 1, 0)
 		assert.NoError(t, err)
 		fmt.Printf("Test scenario: %s, found len(toRemove)=%v, expected len(test.toRemove)=%v\n", test.name, len(toRemove), len(test.toRemove))
 		assert.Equal(t, toRemove, test.toRemove)
-		assert.Equal(t, unremovable, test.unremovable)
+		unremovableNodes := make([]*apiv1.Node, 0, len(unremovable))
+		for _, node := range unremovable {
+			if node.Node == nonDrainableNode {
+				assert.Equal(t, BlockedByPod, node.Reason)
+				assert.Equal(t, &drain.BlockingPod{Pod: pod3, Reason: drain.NotReplicated}, node.BlockingPod)
+			} else {
+				assert.Equal(t, NoPlaceToMovePods, node.Reason)
+				assert.Nil(t, node.BlockingPod)
+			}
+			unremovableNodes = append(unremovableNodes, node.Node)
+		}
+		assert.Equal(t, unremovableNodes, test.unremovable)
 	}
 
 }
+
+func TestFindNodesToRemoveParallelismIsDeterministic(t *testing.T) {
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	nodes := []*apiv1.Node{}
+	pods := []*apiv1.Pod{}
+	for i := 0; i < 30; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 1000, 2000000)
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+		for j := 0; j < i%4; j++ {
+			pod := BuildTestPod(fmt.Sprintf("p%d-%d", i, j), 200, 100000)
+			pod.OwnerReferences = ownerRefs
+			pod.Spec.NodeName = node.Name
+			pods = append(pods, pod)
+		}
+	}
+
+	for _, maxCount := range []int{1, 5, len(nodes)} {
+		run := func(parallelism int) ([]NodeToBeRemoved, []*UnremovableNode, map[string]string, *UsageTracker) {
+			tracker := NewUsageTracker()
+			toRemove, unremovable, hints, err := FindNodesToRemove(
+				nodes, nodes, pods, nil, NewTestPredicateChecker(), maxCount, true, map[string]string{},
+				tracker, time.Unix(1000, 0), []*policyv1.PodDisruptionBudget{}, nil, parallelism, 42)
+			assert.NoError(t, err)
+			return toRemove, unremovable, hints, tracker
+		}
+		toRemove, unremovable, hints, tracker := run(1)
+		assert.Len(t, toRemove, maxCount)
+		for _, parallelism := range []int{2, 8} {
+			parallelToRemove, parallelUnremovable, parallelHints, parallelTracker := run(parallelism)
+			assert.Equal(t, toRemove, parallelToRemove)
+			assert.Equal(t, unremovable, parallelUnremovable)
+			assert.Equal(t, hints, parallelHints)
+			assert.Equal(t, tracker, parallelTracker)
+		}
+	}
+}
+
+func TestFindNodesToRemoveDetailedCheckEvaluatesOnlyNeededCandidates(t *testing.T) {
+	replicas := int32(4)
+	replicaSet := &extensionsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default"},
+		Spec:       extensionsv1.ReplicaSetSpec{Replicas: &replicas},
+	}
+	client := fake.NewSimpleClientset(replicaSet)
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	nodes := []*apiv1.Node{}
+	pods := []*apiv1.Pod{}
+	for i := 0; i < 4; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 1000, 2000000)
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+		pod := BuildTestPod(fmt.Sprintf("p%d", i), 100, 100000)
+		pod.OwnerReferences = ownerRefs
+		pod.Namespace = "default"
+		pod.Spec.NodeName = node.Name
+		pods = append(pods, pod)
+	}
+
+	toRemove, _, _, err := FindNodesToRemove(nodes, nodes, pods, client, NewTestPredicateChecker(), 1, false,
+		map[string]string{}, NewUsageTracker(), time.Now(), []*policyv1.PodDisruptionBudget{}, nil, 4, 0)
+	assert.NoError(t, err)
+	assert.Len(t, toRemove, 1)
+	// Only the first candidate is checked against the API server.
+	assert.Len(t, client.Actions(), 1)
+}
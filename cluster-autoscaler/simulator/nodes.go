@@ -45,7 +45,7 @@ func GetRequiredPodsForNode(nodename string, client kube_client.Interface) ([]*a
 		allPods = append(allPods, &podListResult.Items[i])
 	}
 
-	podsToRemoveList, err := drain.GetPodsForDeletionOnNodeDrain(
+	podsToRemoveList, _, err := drain.GetPodsForDeletionOnNodeDrain(
 		allPods,
 		[]*policyv1.PodDisruptionBudget{}, // PDBs are irrelevant when considering new node.
 		true, // Force all removals.
@@ -54,7 +54,8 @@ func GetRequiredPodsForNode(nodename string, client kube_client.Interface) ([]*a
 		false, // Setting this to true requires client to be not-null.
 		nil,
 		0,
-		time.Now())
+		time.Now(),
+		nil)
 	if err != nil {
 		return []*apiv1.Pod{}, errors.ToAutoscalerError(errors.InternalError, err)
 	}
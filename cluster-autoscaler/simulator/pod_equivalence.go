@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/gardener/autoscaler/cluster-autoscaler/utils/drain"
+
+	apiv1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	hashutil "k8s.io/kubernetes/pkg/util/hash"
+)
+
+// PodEquivalenceGroup is a group of pods owned by the same controller that the predicates can't
+// tell apart, so checking one of them gives the result for all of them.
+type PodEquivalenceGroup struct {
+	// Pods are the pods of the group, in the order they were passed to BuildPodEquivalenceGroups.
+	Pods         []*apiv1.Pod
+	requirements schedulingRequirements
+}
+
+// Representative returns the pod that is checked on behalf of the group.
+func (g *PodEquivalenceGroup) Representative() *apiv1.Pod {
+	return g.Pods[0]
+}
+
+// schedulingRequirements are the parts of a pod that scheduling predicates look at.
+type schedulingRequirements struct {
+	Namespace      string
+	Labels         map[string]string
+	NodeName       string
+	NodeSelector   map[string]string
+	Affinity       *apiv1.Affinity
+	Tolerations    []apiv1.Toleration
+	Volumes        []apiv1.Volume
+	HostNetwork    bool
+	Containers     []containerRequirements
+	InitContainers []containerRequirements
+}
+
+type containerRequirements struct {
+	Resources apiv1.ResourceRequirements
+	Ports     []apiv1.ContainerPort
+}
+
+func getSchedulingRequirements(pod *apiv1.Pod) schedulingRequirements {
+	containers := func(containers []apiv1.Container) []containerRequirements {
+		result := make([]containerRequirements, 0, len(containers))
+		for _, container := range containers {
+			result = append(result, containerRequirements{Resources: container.Resources, Ports: container.Ports})
+		}
+		return result
+	}
+	return schedulingRequirements{
+		Namespace:      pod.Namespace,
+		Labels:         pod.Labels,
+		NodeName:       pod.Spec.NodeName,
+		NodeSelector:   pod.Spec.NodeSelector,
+		Affinity:       pod.Spec.Affinity,
+		Tolerations:    pod.Spec.Tolerations,
+		Volumes:        pod.Spec.Volumes,
+		HostNetwork:    pod.Spec.HostNetwork,
+		Containers:     containers(pod.Spec.Containers),
+		InitContainers: containers(pod.Spec.InitContainers),
+	}
+}
+
+// BuildPodEquivalenceGroups groups pods by their controller and scheduling requirements: resource
+// requests and limits, ports, node selector, affinity, tolerations, volumes, namespace and labels.
+// Pods without a controller are never grouped with other pods. Groups are returned in the order
+// of their first pods.
+func BuildPodEquivalenceGroups(pods []*apiv1.Pod) []*PodEquivalenceGroup {
+	groups := make([]*PodEquivalenceGroup, 0)
+	groupsByKey := make(map[string][]*PodEquivalenceGroup)
+
+podloop:
+	for _, pod := range pods {
+		requirements := getSchedulingRequirements(pod)
+		ref := drain.ControllerRef(pod)
+		if ref == nil {
+			groups = append(groups, &PodEquivalenceGroup{Pods: []*apiv1.Pod{pod}, requirements: requirements})
+			continue
+		}
+
+		hash := fnv.New32a()
+		hashutil.DeepHashObject(hash, requirements)
+		key := fmt.Sprintf("%s/%x", ref.UID, hash.Sum32())
+		// Pods with the same key are almost always equivalent, but hashes may collide.
+		for _, group := range groupsByKey[key] {
+			if apiequality.Semantic.DeepEqual(group.requirements, requirements) {
+				group.Pods = append(group.Pods, pod)
+				continue podloop
+			}
+		}
+		group := &PodEquivalenceGroup{Pods: []*apiv1.Pod{pod}, requirements: requirements}
+		groupsByKey[key] = append(groupsByKey[key], group)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// PodsOfGroups returns the pods of all groups, group after group.
+func PodsOfGroups(groups []*PodEquivalenceGroup) []*apiv1.Pod {
+	pods := make([]*apiv1.Pod, 0, len(groups))
+	for _, group := range groups {
+		pods = append(pods, group.Pods...)
+	}
+	return pods
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPodEquivalenceGroups(t *testing.T) {
+	rc1 := GenerateOwnerReferences("rc1", "ReplicationController", "extensions/v1beta1", types.UID("12345678-1234-1234-1234-123456789012"))
+	rc2 := GenerateOwnerReferences("rc2", "ReplicationController", "extensions/v1beta1", types.UID("12345678-1234-1234-1234-12345678901a"))
+
+	podInRc1_1 := BuildTestPod("podInRc1_1", 500, 1000)
+	podInRc1_1.OwnerReferences = rc1
+	podInRc1_2 := BuildTestPod("podInRc1_2", 500, 1000)
+	podInRc1_2.OwnerReferences = rc1
+	// Environment doesn't matter for scheduling.
+	podInRc1_2.Spec.Containers[0].Env = []apiv1.EnvVar{{Name: "KEY", Value: "value"}}
+	podInRc2 := BuildTestPod("podInRc2", 500, 1000)
+	podInRc2.OwnerReferences = rc2
+	differentPodInRc1 := BuildTestPod("differentPodInRc1", 1000, 1000)
+	differentPodInRc1.OwnerReferences = rc1
+	toleratingPodInRc1 := BuildTestPod("toleratingPodInRc1", 500, 1000)
+	toleratingPodInRc1.OwnerReferences = rc1
+	toleratingPodInRc1.Spec.Tolerations = []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists}}
+	labeledPodInRc1 := BuildTestPod("labeledPodInRc1", 500, 1000)
+	labeledPodInRc1.OwnerReferences = rc1
+	labeledPodInRc1.Labels = map[string]string{"app": "other"}
+	nonReplicatedPod1 := BuildTestPod("nonReplicatedPod1", 500, 1000)
+	nonReplicatedPod2 := BuildTestPod("nonReplicatedPod2", 500, 1000)
+	podInRc1_3 := BuildTestPod("podInRc1_3", 500, 1000)
+	podInRc1_3.OwnerReferences = rc1
+
+	groups := BuildPodEquivalenceGroups([]*apiv1.Pod{podInRc1_1, podInRc2, podInRc1_2, differentPodInRc1, toleratingPodInRc1,
+		labeledPodInRc1, nonReplicatedPod1, nonReplicatedPod2, podInRc1_3})
+
+	groupedPods := [][]*apiv1.Pod{}
+	for _, group := range groups {
+		groupedPods = append(groupedPods, group.Pods)
+	}
+	assert.Equal(t, [][]*apiv1.Pod{
+		{podInRc1_1, podInRc1_2, podInRc1_3},
+		{podInRc2},
+		{differentPodInRc1},
+		{toleratingPodInRc1},
+		{labeledPodInRc1},
+		{nonReplicatedPod1},
+		{nonReplicatedPod2},
+	}, groupedPods)
+	assert.Equal(t, podInRc1_1, groups[0].Representative())
+	assert.Equal(t, []*apiv1.Pod{podInRc1_1, podInRc1_2, podInRc1_3, podInRc2, differentPodInRc1, toleratingPodInRc1,
+		labeledPodInRc1, nonReplicatedPod1, nonReplicatedPod2}, PodsOfGroups(groups))
+}
@@ -18,14 +18,18 @@ package simulator
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	apiv1 "k8s.io/api/core/v1"
 	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/apimachinery/pkg/util/sets"
 	informers "k8s.io/client-go/informers"
 	kube_client "k8s.io/client-go/kubernetes"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 	"k8s.io/kubernetes/pkg/scheduler/factory"
 
@@ -51,14 +55,27 @@ type PredicateChecker struct {
 	predicates                []predicateInfo
 	predicateMetadataProducer algorithm.PredicateMetadataProducer
 	enableAffinityPredicate   bool
+	volumeLister              kube_util.VolumeLister
 }
 
 // There are no const arrays in Go, this is meant to be used as a const.
 var priorityPredicates = []string{"PodFitsResources", "GeneralPredicates", "PodToleratesNodeTaints"}
 
-// NewPredicateChecker builds PredicateChecker.
+// NewPredicateChecker builds PredicateChecker using the predicates of the default algorithm provider.
 func NewPredicateChecker(kubeClient kube_client.Interface, stop <-chan struct{}) (*PredicateChecker, error) {
-	provider, err := factory.GetAlgorithmProvider(factory.DefaultProvider)
+	return NewPredicateCheckerWithPolicy(kubeClient, factory.DefaultProvider, nil, stop)
+}
+
+// NewPredicateCheckerWithPolicy builds PredicateChecker using the predicates listed in the scheduler policy or,
+// if the policy is nil or doesn't list predicates, the predicates of the named algorithm provider.
+// Predicates from the policy that are not known to Cluster Autoscaler are logged and skipped.
+func NewPredicateCheckerWithPolicy(kubeClient kube_client.Interface, providerName string, policy *schedulerapi.Policy,
+	stop <-chan struct{}) (*PredicateChecker, error) {
+	hardPodAffinitySymmetricWeight := apiv1.DefaultHardPodAffinitySymmetricWeight
+	if policy != nil && policy.HardPodAffinitySymmetricWeight != 0 {
+		hardPodAffinitySymmetricWeight = policy.HardPodAffinitySymmetricWeight
+	}
+	predicateKeys, err := getPredicateKeys(providerName, policy)
 	if err != nil {
 		return nil, err
 	}
@@ -77,19 +94,22 @@ func NewPredicateChecker(kubeClient kube_client.Interface, stop <-chan struct{})
 		ServiceInformer:                informerFactory.Core().V1().Services(),
 		PdbInformer:                    informerFactory.Policy().V1beta1().PodDisruptionBudgets(),
 		StorageClassInformer:           informerFactory.Storage().V1().StorageClasses(),
-		HardPodAffinitySymmetricWeight: apiv1.DefaultHardPodAffinitySymmetricWeight,
+		HardPodAffinitySymmetricWeight: hardPodAffinitySymmetricWeight,
 	})
+	volumeLister := kube_util.NewVolumeLister(informerFactory)
 	informerFactory.Start(stop)
 
 	metadataProducer, err := schedulerConfigFactory.GetPredicateMetadataProducer()
 	if err != nil {
 		return nil, err
 	}
-	predicateMap, err := schedulerConfigFactory.GetPredicates(provider.FitPredicateKeys)
-	predicateMap["ready"] = isNodeReadyAndSchedulablePredicate
+	predicateMap, err := schedulerConfigFactory.GetPredicates(sets.NewString(predicateKeys...))
 	if err != nil {
 		return nil, err
 	}
+	predicateMap["ready"] = isNodeReadyAndSchedulablePredicate
+	replaceVolumePredicates(predicateMap, informerFactory.Core().V1().PersistentVolumeClaims().Lister(),
+		informerFactory.Core().V1().PersistentVolumes().Lister(), informerFactory.Storage().V1().StorageClasses().Lister())
 	// We always want to have PodFitsResources as a first predicate we run
 	// as this is cheap to check and it should be enough to fail predicates
 	// in most of our simulations (especially binpacking).
@@ -97,18 +117,18 @@ func NewPredicateChecker(kubeClient kube_client.Interface, stop <-chan struct{})
 		predicateMap["PodFitsResources"] = predicates.PodFitsResources
 	}
 
+	// The cheap predicates go first, the rest in the order used by the scheduler. Predicates the
+	// scheduler doesn't order, like custom ones from the policy, go last in the order of the policy.
 	predicateList := make([]predicateInfo, 0)
-	for _, predicateName := range priorityPredicates {
-		if predicate, found := predicateMap[predicateName]; found {
-			predicateList = append(predicateList, predicateInfo{name: predicateName, predicate: predicate})
-			delete(predicateMap, predicateName)
+	for _, predicateNames := range [][]string{priorityPredicates, predicates.Ordering(), predicateKeys, sortedKeys(predicateMap)} {
+		for _, predicateName := range predicateNames {
+			if predicate, found := predicateMap[predicateName]; found {
+				predicateList = append(predicateList, predicateInfo{name: predicateName, predicate: predicate})
+				delete(predicateMap, predicateName)
+			}
 		}
 	}
 
-	for predicateName, predicate := range predicateMap {
-		predicateList = append(predicateList, predicateInfo{name: predicateName, predicate: predicate})
-	}
-
 	for _, predInfo := range predicateList {
 		glog.V(1).Infof("Using predicate %s", predInfo.name)
 	}
@@ -120,9 +140,78 @@ func NewPredicateChecker(kubeClient kube_client.Interface, stop <-chan struct{})
 		predicates:                predicateList,
 		predicateMetadataProducer: metadataProducer,
 		enableAffinityPredicate:   true,
+		volumeLister:              volumeLister,
 	}, nil
 }
 
+// VolumeLister returns the lister of the persistent volume claims, persistent volumes and storage classes
+// watched for the volume predicates. It's nil for the test predicate checker.
+func (p *PredicateChecker) VolumeLister() kube_util.VolumeLister {
+	return p.volumeLister
+}
+
+// getPredicateKeys returns the names of predicates listed in the policy, registering the custom ones,
+// or the predicates of the algorithm provider if the policy doesn't list them.
+func getPredicateKeys(providerName string, policy *schedulerapi.Policy) ([]string, error) {
+	if policy == nil || policy.Predicates == nil {
+		provider, err := factory.GetAlgorithmProvider(providerName)
+		if err != nil {
+			return nil, err
+		}
+		glog.V(1).Infof("Using predicates of scheduler algorithm provider %s", providerName)
+		return provider.FitPredicateKeys.List(), nil
+	}
+
+	if len(policy.ExtenderConfigs) > 0 {
+		glog.Warningf("Scheduler policy configures %d extenders, they are not taken into account in simulations", len(policy.ExtenderConfigs))
+	}
+	keys := make([]string, 0, len(policy.Predicates))
+	for _, predicatePolicy := range policy.Predicates {
+		if err := validatePredicatePolicy(predicatePolicy); err != nil {
+			return nil, err
+		}
+		if predicatePolicy.Argument != nil {
+			keys = append(keys, factory.RegisterCustomFitPredicate(predicatePolicy))
+		} else if factory.IsFitPredicateRegistered(predicatePolicy.Name) {
+			keys = append(keys, predicatePolicy.Name)
+		} else {
+			glog.Warningf("Unknown predicate %s in scheduler policy, it is not taken into account in simulations", predicatePolicy.Name)
+		}
+	}
+	return keys, nil
+}
+
+var validPredicateName = regexp.MustCompile("^[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])$")
+
+// validatePredicatePolicy checks what the scheduler factory would otherwise reject by exiting the process.
+func validatePredicatePolicy(predicatePolicy schedulerapi.PredicatePolicy) error {
+	if !validPredicateName.MatchString(predicatePolicy.Name) {
+		return fmt.Errorf("invalid predicate name %q in scheduler policy", predicatePolicy.Name)
+	}
+	if predicatePolicy.Argument != nil {
+		arguments := 0
+		if predicatePolicy.Argument.ServiceAffinity != nil {
+			arguments++
+		}
+		if predicatePolicy.Argument.LabelsPresence != nil {
+			arguments++
+		}
+		if arguments != 1 {
+			return fmt.Errorf("predicate %s in scheduler policy must have exactly one argument, has %d", predicatePolicy.Name, arguments)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(predicateMap map[string]algorithm.FitPredicate) []string {
+	keys := make([]string, 0, len(predicateMap))
+	for key := range predicateMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func isNodeReadyAndSchedulablePredicate(pod *apiv1.Pod, meta algorithm.PredicateMetadata, nodeInfo *schedulercache.NodeInfo) (bool,
 	[]algorithm.PredicateFailureReason, error) {
 	ready := kube_util.IsNodeReadyAndSchedulable(nodeInfo.Node())
@@ -22,6 +22,10 @@ import (
 	"time"
 
 	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 
 	"github.com/stretchr/testify/assert"
@@ -67,3 +71,19 @@ func TestPredicates(t *testing.T) {
 	assert.Nil(t, predicateChecker.CheckPredicates(p4, nil, ni2))
 	assert.NotNil(t, predicateChecker.CheckPredicates(p3, nil, ni2))
 }
+
+func TestPredicateCheckerVolumeLister(t *testing.T) {
+	pvc := &apiv1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"}}
+	stop := make(chan struct{})
+	defer close(stop)
+	predicateChecker, err := NewPredicateChecker(fake.NewSimpleClientset(pvc), stop)
+	assert.NoError(t, err)
+
+	// The volume lister uses the informers started for the predicates.
+	err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, err := predicateChecker.VolumeLister().GetPersistentVolumeClaim("default", "data")
+		return err == nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, NewTestPredicateChecker().VolumeLister())
+}
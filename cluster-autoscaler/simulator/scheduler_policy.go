@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	// SchedulerPolicyConfigMapKey is the key holding the policy in the scheduler policy ConfigMap,
+	// the same as used by kube-scheduler.
+	SchedulerPolicyConfigMapKey = "policy.cfg"
+)
+
+// schedulerPolicy is the format of the kube-scheduler Policy (v1). Only the fields that matter for
+// predicates are read.
+type schedulerPolicy struct {
+	Predicates                     []schedulerapi.PredicatePolicy `json:"predicates"`
+	Extenders                      []schedulerExtender            `json:"extenders"`
+	HardPodAffinitySymmetricWeight int32                          `json:"hardPodAffinitySymmetricWeight"`
+}
+
+type schedulerExtender struct {
+	URLPrefix string `json:"urlPrefix"`
+}
+
+// ParseSchedulerPolicy parses a kube-scheduler Policy given in JSON or YAML, e.g.:
+//
+//	{
+//	  "kind": "Policy",
+//	  "apiVersion": "v1",
+//	  "predicates": [
+//	    {"name": "GeneralPredicates"},
+//	    {"name": "PodToleratesNodeTaints"},
+//	    {"name": "zone", "argument": {"serviceAffinity": {"labels": ["failure-domain.beta.kubernetes.io/zone"]}}}
+//	  ]
+//	}
+func ParseSchedulerPolicy(data []byte) (*schedulerapi.Policy, error) {
+	parsed := schedulerPolicy{}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse scheduler policy: %v", err)
+	}
+	policy := &schedulerapi.Policy{
+		Predicates:                     parsed.Predicates,
+		HardPodAffinitySymmetricWeight: parsed.HardPodAffinitySymmetricWeight,
+	}
+	for _, extender := range parsed.Extenders {
+		policy.ExtenderConfigs = append(policy.ExtenderConfigs, schedulerapi.ExtenderConfig{URLPrefix: extender.URLPrefix})
+	}
+	for _, predicate := range policy.Predicates {
+		if err := validatePredicatePolicy(predicate); err != nil {
+			return nil, err
+		}
+	}
+	if policy.HardPodAffinitySymmetricWeight < 0 || policy.HardPodAffinitySymmetricWeight > 100 {
+		return nil, fmt.Errorf("hardPodAffinitySymmetricWeight in scheduler policy must be in range 0-100, is %d", policy.HardPodAffinitySymmetricWeight)
+	}
+	return policy, nil
+}
+
+// LoadSchedulerPolicyFile reads a kube-scheduler Policy from the given file.
+func LoadSchedulerPolicyFile(path string) (*schedulerapi.Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchedulerPolicy(data)
+}
+
+// LoadSchedulerPolicyConfigMap reads a kube-scheduler Policy from the SchedulerPolicyConfigMapKey key
+// of the given ConfigMap.
+func LoadSchedulerPolicyConfigMap(kubeClient kube_client.Interface, namespace, name string) (*schedulerapi.Policy, error) {
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduler policy ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	data, found := configMap.Data[SchedulerPolicyConfigMapKey]
+	if !found {
+		return nil, fmt.Errorf("scheduler policy ConfigMap %s/%s has no %s key", namespace, name, SchedulerPolicyConfigMapKey)
+	}
+	return ParseSchedulerPolicy([]byte(data))
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+	"k8s.io/kubernetes/pkg/scheduler/factory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicyJSON = `{
+  "kind": "Policy",
+  "apiVersion": "v1",
+  "predicates": [
+    {"name": "GeneralPredicates"},
+    {"name": "PodToleratesNodeTaints"},
+    {"name": "NoSuchPredicate"},
+    {"name": "HasZoneLabel", "argument": {"labelsPresence": {"labels": ["zone"], "presence": true}}}
+  ],
+  "extenders": [{"urlPrefix": "http://127.0.0.1:8888/"}],
+  "hardPodAffinitySymmetricWeight": 10
+}`
+
+const testPolicyYAML = `
+kind: Policy
+apiVersion: v1
+predicates:
+- name: GeneralPredicates
+- name: HasZoneLabel
+  argument:
+    labelsPresence:
+      labels: [zone]
+      presence: true
+`
+
+func TestParseSchedulerPolicy(t *testing.T) {
+	policy, err := ParseSchedulerPolicy([]byte(testPolicyJSON))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(policy.Predicates))
+	assert.Equal(t, "GeneralPredicates", policy.Predicates[0].Name)
+	assert.Nil(t, policy.Predicates[0].Argument)
+	assert.Equal(t, "HasZoneLabel", policy.Predicates[3].Name)
+	assert.Equal(t, []string{"zone"}, policy.Predicates[3].Argument.LabelsPresence.Labels)
+	assert.True(t, policy.Predicates[3].Argument.LabelsPresence.Presence)
+	assert.Equal(t, []schedulerapi.ExtenderConfig{{URLPrefix: "http://127.0.0.1:8888/"}}, policy.ExtenderConfigs)
+	assert.Equal(t, int32(10), policy.HardPodAffinitySymmetricWeight)
+
+	policy, err = ParseSchedulerPolicy([]byte(testPolicyYAML))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(policy.Predicates))
+	assert.Equal(t, []string{"zone"}, policy.Predicates[1].Argument.LabelsPresence.Labels)
+
+	// Not listing predicates is different from listing none.
+	policy, err = ParseSchedulerPolicy([]byte(`{"kind": "Policy"}`))
+	assert.NoError(t, err)
+	assert.Nil(t, policy.Predicates)
+	policy, err = ParseSchedulerPolicy([]byte(`{"kind": "Policy", "predicates": []}`))
+	assert.NoError(t, err)
+	assert.NotNil(t, policy.Predicates)
+	assert.Equal(t, 0, len(policy.Predicates))
+
+	for _, invalid := range []string{
+		`{"predicates": [`,
+		`{"predicates": [{"name": "bad name"}]}`,
+		`{"predicates": [{"name": "NoArgument", "argument": {}}]}`,
+		`{"predicates": [{"name": "TwoArguments", "argument": {"labelsPresence": {"labels": ["a"]}, "serviceAffinity": {"labels": ["b"]}}}]}`,
+		`{"hardPodAffinitySymmetricWeight": 101}`,
+	} {
+		_, err = ParseSchedulerPolicy([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLoadSchedulerPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler-policy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(testPolicyJSON), 0644))
+
+	policy, err := LoadSchedulerPolicyFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(policy.Predicates))
+	_, err = LoadSchedulerPolicyFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+
+	kubeClient := fake.NewSimpleClientset(
+		&apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "scheduler-policy"},
+			Data:       map[string]string{SchedulerPolicyConfigMapKey: testPolicyYAML},
+		},
+		&apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "other"},
+			Data:       map[string]string{"other.cfg": testPolicyYAML},
+		})
+	policy, err = LoadSchedulerPolicyConfigMap(kubeClient, "kube-system", "scheduler-policy")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(policy.Predicates))
+	_, err = LoadSchedulerPolicyConfigMap(kubeClient, "kube-system", "other")
+	assert.Error(t, err)
+	_, err = LoadSchedulerPolicyConfigMap(kubeClient, "default", "scheduler-policy")
+	assert.Error(t, err)
+}
+
+func TestGetPredicateKeys(t *testing.T) {
+	provider, err := factory.GetAlgorithmProvider(factory.DefaultProvider)
+	assert.NoError(t, err)
+
+	keys, err := getPredicateKeys(factory.DefaultProvider, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, provider.FitPredicateKeys.List(), keys)
+
+	keys, err = getPredicateKeys(factory.DefaultProvider, &schedulerapi.Policy{})
+	assert.NoError(t, err)
+	assert.Equal(t, provider.FitPredicateKeys.List(), keys)
+
+	_, err = getPredicateKeys("NoSuchProvider", nil)
+	assert.Error(t, err)
+
+	policy, err := ParseSchedulerPolicy([]byte(testPolicyJSON))
+	assert.NoError(t, err)
+	keys, err = getPredicateKeys("NoSuchProvider", policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GeneralPredicates", "PodToleratesNodeTaints", "HasZoneLabel"}, keys)
+	assert.True(t, factory.IsFitPredicateRegistered("HasZoneLabel"))
+}
+
+func TestNewPredicateCheckerWithPolicy(t *testing.T) {
+	policy, err := ParseSchedulerPolicy([]byte(testPolicyJSON))
+	assert.NoError(t, err)
+	stop := make(chan struct{})
+	defer close(stop)
+	predicateChecker, err := NewPredicateCheckerWithPolicy(fake.NewSimpleClientset(), factory.DefaultProvider, policy, stop)
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, predicate := range predicateChecker.predicates {
+		names = append(names, predicate.name)
+	}
+	// CheckNodeCondition is mandatory in the scheduler, so it is used even if the policy doesn't list it.
+	assert.Equal(t, []string{"PodFitsResources", "GeneralPredicates", "PodToleratesNodeTaints", "CheckNodeCondition", "HasZoneLabel", "ready"}, names)
+
+	pod := BuildTestPod("p1", 100, 1000)
+	node := BuildTestNode("n1", 1000, 2000000)
+	SetNodeReadyState(node, true, time.Time{})
+	nodeInfo := schedulercache.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	predicateErr := predicateChecker.CheckPredicates(pod, nil, nodeInfo)
+	assert.NotNil(t, predicateErr)
+	assert.Equal(t, "HasZoneLabel", predicateErr.PredicateName())
+
+	node.Labels["zone"] = "a"
+	nodeInfo.SetNode(node)
+	assert.Nil(t, predicateChecker.CheckPredicates(pod, nil, nodeInfo))
+}
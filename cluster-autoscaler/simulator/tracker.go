@@ -83,6 +83,55 @@ func (tracker *UsageTracker) RegisterUsage(nodeA string, nodeB string, timestamp
 	}
 }
 
+// UsageInfo is a copy of UsageRecord that can be serialized.
+type UsageInfo struct {
+	Using         map[string]time.Time `json:"using,omitempty"`
+	UsingTooMany  bool                 `json:"usingTooMany,omitempty"`
+	UsedBy        map[string]time.Time `json:"usedBy,omitempty"`
+	UsedByTooMany bool                 `json:"usedByTooMany,omitempty"`
+}
+
+// GetUsageInfos returns a copy of all usage records, indexed by node name.
+func (tracker *UsageTracker) GetUsageInfos() map[string]UsageInfo {
+	result := make(map[string]UsageInfo, len(tracker.usage))
+	for node, record := range tracker.usage {
+		info := UsageInfo{
+			Using:         make(map[string]time.Time, len(record.using)),
+			UsingTooMany:  record.usingTooMany,
+			UsedBy:        make(map[string]time.Time, len(record.usedBy)),
+			UsedByTooMany: record.usedByTooMany,
+		}
+		for key, value := range record.using {
+			info.Using[key] = value
+		}
+		for key, value := range record.usedBy {
+			info.UsedBy[key] = value
+		}
+		result[node] = info
+	}
+	return result
+}
+
+// RestoreUsageInfos replaces all usage records with the given ones.
+func (tracker *UsageTracker) RestoreUsageInfos(infos map[string]UsageInfo) {
+	tracker.usage = make(map[string]*UsageRecord, len(infos))
+	for node, info := range infos {
+		record := &UsageRecord{
+			using:         make(map[string]time.Time, len(info.Using)),
+			usingTooMany:  info.UsingTooMany,
+			usedBy:        make(map[string]time.Time, len(info.UsedBy)),
+			usedByTooMany: info.UsedByTooMany,
+		}
+		for key, value := range info.Using {
+			record.using[key] = value
+		}
+		for key, value := range info.UsedBy {
+			record.usedBy[key] = value
+		}
+		tracker.usage[node] = record
+	}
+}
+
 // Unregister removes the given node from all usage records
 func (tracker *UsageTracker) Unregister(node string) {
 	if record, found := tracker.usage[node]; found {
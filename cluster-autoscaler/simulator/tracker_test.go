@@ -110,3 +110,31 @@ func TestRemove(t *testing.T) {
 	assert.True(t, foundC)
 	assert.False(t, foundX)
 }
+
+func TestGetUsageInfos(t *testing.T) {
+	tracker := NewUsageTracker()
+	now := time.Now()
+	tracker.RegisterUsage("A", "B", now)
+
+	infos := tracker.GetUsageInfos()
+	assert.Equal(t, 2, len(infos))
+	assert.Equal(t, map[string]time.Time{"B": now}, infos["A"].Using)
+	assert.Equal(t, map[string]time.Time{"A": now}, infos["B"].UsedBy)
+
+	// The result is a copy.
+	tracker.RegisterUsage("A", "C", now)
+	assert.Equal(t, 1, len(infos["A"].Using))
+}
+
+func TestRestoreUsageInfos(t *testing.T) {
+	tracker := NewUsageTracker()
+	now := time.Now()
+	tracker.RegisterUsage("A", "B", now)
+
+	restored := NewUsageTracker()
+	restored.RegisterUsage("C", "D", now)
+	restored.RestoreUsageInfos(tracker.GetUsageInfos())
+	assert.Equal(t, tracker.GetUsageInfos(), restored.GetUsageInfos())
+	_, found := restored.Get("C")
+	assert.False(t, found)
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	apiv1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	v1storagelister "k8s.io/client-go/listers/storage/v1"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+	"k8s.io/kubernetes/pkg/features"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+	volumeutil "k8s.io/kubernetes/pkg/volume/util"
+)
+
+const (
+	// maxCinderVolumeCountPredicateName is the name of the predicate limiting OpenStack Cinder volumes,
+	// which the scheduler doesn't limit. It's only checked on nodes reporting the limit.
+	maxCinderVolumeCountPredicateName = "MaxCinderVolumeCount"
+	// cinderVolumeLimitKey is the node allocatable resource holding the Cinder volume limit.
+	cinderVolumeLimitKey = "attachable-volumes-cinder"
+	// notSupportedProvisioner is the provisioner of storage classes for statically provisioned volumes.
+	notSupportedProvisioner = "kubernetes.io/no-provisioner"
+)
+
+// volumeBindingChecker checks the persistent volume claims of a pod like the CheckVolumeBinding
+// predicate. Unlike it, it doesn't remember the bindings it found, so that it can be used on template
+// nodes, and it doesn't depend on the nodes being known to the scheduler.
+type volumeBindingChecker struct {
+	pvcLister   v1lister.PersistentVolumeClaimLister
+	pvLister    v1lister.PersistentVolumeLister
+	classLister v1storagelister.StorageClassLister
+}
+
+// predicate checks that the bound volumes of the pod can be used on the node and that its unbound
+// claims waiting for the first consumer can be bound to an available volume or provisioned there.
+func (c *volumeBindingChecker) predicate(pod *apiv1.Pod, meta algorithm.PredicateMetadata, nodeInfo *schedulercache.NodeInfo) (bool,
+	[]algorithm.PredicateFailureReason, error) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.VolumeScheduling) {
+		return true, nil, nil
+	}
+	node := nodeInfo.Node()
+	if node == nil {
+		return false, nil, fmt.Errorf("node not found")
+	}
+
+	reasons := []algorithm.PredicateFailureReason{}
+	chosenVolumes := sets.NewString()
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc, err := c.pvcLister.PersistentVolumeClaims(pod.Namespace).Get(volume.PersistentVolumeClaim.ClaimName)
+		if err != nil {
+			return false, nil, err
+		}
+		if pvc.Spec.VolumeName != "" {
+			pv, err := c.pvLister.Get(pvc.Spec.VolumeName)
+			if err != nil {
+				return false, nil, err
+			}
+			if volumeutil.CheckNodeAffinity(pv, node.Labels) != nil {
+				reasons = append(reasons, predicates.ErrVolumeNodeConflict)
+			}
+			continue
+		}
+		if reason := c.checkUnboundClaim(pvc, node, chosenVolumes); reason != nil {
+			reasons = append(reasons, reason)
+		}
+	}
+	return len(reasons) == 0, reasons, nil
+}
+
+// checkUnboundClaim checks whether the claim can be bound to an available volume that wasn't chosen
+// for another claim of the pod, or provisioned for the node.
+func (c *volumeBindingChecker) checkUnboundClaim(pvc *apiv1.PersistentVolumeClaim, node *apiv1.Node,
+	chosenVolumes sets.String) algorithm.PredicateFailureReason {
+	className := v1helper.GetPersistentVolumeClaimClass(pvc)
+	class, err := c.getClass(className)
+	if err != nil || class.VolumeBindingMode == nil || *class.VolumeBindingMode != storagev1.VolumeBindingWaitForFirstConsumer {
+		// The persistent volume controller binds such claims regardless of pods, a new node doesn't help.
+		return predicates.NewFailureReason("pod has unbound immediate persistent volume claims")
+	}
+
+	pvs, err := c.pvLister.List(labels.Everything())
+	if err != nil {
+		return predicates.ErrVolumeBindConflict
+	}
+	for _, pv := range pvs {
+		if !chosenVolumes.Has(pv.Name) && isVolumeAvailableForClaim(pv, pvc, className, node) {
+			chosenVolumes.Insert(pv.Name)
+			return nil
+		}
+	}
+
+	if class.Provisioner == "" || class.Provisioner == notSupportedProvisioner {
+		return predicates.ErrVolumeBindConflict
+	}
+	if !v1helper.MatchTopologySelectorTerms(class.AllowedTopologies, labels.Set(node.Labels)) {
+		return predicates.NewFailureReason(fmt.Sprintf("node(s) not in allowed topologies of storage class %s", class.Name))
+	}
+	return nil
+}
+
+func (c *volumeBindingChecker) getClass(className string) (*storagev1.StorageClass, error) {
+	if className == "" {
+		return nil, fmt.Errorf("claim has no storage class")
+	}
+	return c.classLister.Get(className)
+}
+
+// isVolumeAvailableForClaim checks whether the unbound volume could be bound to the claim and used on the node.
+func isVolumeAvailableForClaim(pv *apiv1.PersistentVolume, pvc *apiv1.PersistentVolumeClaim, className string, node *apiv1.Node) bool {
+	if pv.Spec.ClaimRef != nil || pv.Status.Phase != apiv1.VolumeAvailable || v1helper.GetPersistentVolumeClass(pv) != className {
+		return false
+	}
+	requested := pvc.Spec.Resources.Requests[apiv1.ResourceStorage]
+	capacity := pv.Spec.Capacity[apiv1.ResourceStorage]
+	if capacity.Cmp(requested) < 0 {
+		return false
+	}
+	accessModes := make(map[apiv1.PersistentVolumeAccessMode]bool, len(pv.Spec.AccessModes))
+	for _, mode := range pv.Spec.AccessModes {
+		accessModes[mode] = true
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if !accessModes[mode] {
+			return false
+		}
+	}
+	if pvc.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(pvc.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(pv.Labels)) {
+			return false
+		}
+	}
+	return volumeutil.CheckNodeAffinity(pv, node.Labels) == nil
+}
+
+// attachableVolumeLimit describes how many volumes of one type can be attached to a node.
+type attachableVolumeLimit struct {
+	// predicateName is the name of the scheduler predicate checking the limit.
+	predicateName string
+	// limitKey is the node allocatable resource holding the limit, if the node reports it.
+	limitKey apiv1.ResourceName
+	// defaultLimit returns the limit of nodes that don't report it. If nil, such nodes aren't limited.
+	defaultLimit func(node *apiv1.Node) int
+	// filter returns the ids of volumes of the type.
+	filter predicates.VolumeFilter
+	// provisioners are the in-tree provisioners creating volumes of the type.
+	provisioners sets.String
+}
+
+var ebsNitroInstanceType = regexp.MustCompile(volumeutil.EBSNitroLimitRegex)
+
+// attachableVolumeLimits are the attachable volume limits of the cloud providers.
+var attachableVolumeLimits = []attachableVolumeLimit{
+	{
+		predicateName: predicates.MaxEBSVolumeCountPred,
+		limitKey:      volumeutil.EBSVolumeLimitKey,
+		defaultLimit: func(node *apiv1.Node) int {
+			if ebsNitroInstanceType.MatchString(node.Labels[kubeletapis.LabelInstanceType]) {
+				return volumeutil.DefaultMaxEBSNitroVolumeLimit
+			}
+			return volumeutil.DefaultMaxEBSVolumes
+		},
+		filter:       predicates.EBSVolumeFilter,
+		provisioners: sets.NewString("kubernetes.io/aws-ebs"),
+	},
+	{
+		predicateName: predicates.MaxGCEPDVolumeCountPred,
+		limitKey:      volumeutil.GCEVolumeLimitKey,
+		defaultLimit:  func(*apiv1.Node) int { return predicates.DefaultMaxGCEPDVolumes },
+		filter:        predicates.GCEPDVolumeFilter,
+		provisioners:  sets.NewString("kubernetes.io/gce-pd"),
+	},
+	{
+		predicateName: predicates.MaxAzureDiskVolumeCountPred,
+		limitKey:      volumeutil.AzureVolumeLimitKey,
+		defaultLimit:  func(*apiv1.Node) int { return predicates.DefaultMaxAzureDiskVolumes },
+		filter:        predicates.AzureDiskVolumeFilter,
+		provisioners:  sets.NewString("kubernetes.io/azure-disk"),
+	},
+	{
+		predicateName: maxCinderVolumeCountPredicateName,
+		limitKey:      cinderVolumeLimitKey,
+		filter: predicates.VolumeFilter{
+			FilterVolume: func(volume *apiv1.Volume) (string, bool) {
+				if volume.Cinder != nil {
+					return volume.Cinder.VolumeID, true
+				}
+				return "", false
+			},
+			FilterPersistentVolume: func(pv *apiv1.PersistentVolume) (string, bool) {
+				if pv.Spec.Cinder != nil {
+					return pv.Spec.Cinder.VolumeID, true
+				}
+				return "", false
+			},
+		},
+		provisioners: sets.NewString("kubernetes.io/cinder"),
+	},
+}
+
+// volumeLimitChecker checks an attachable volume limit like the MaxEBSVolumeCount, MaxGCEPDVolumeCount
+// and MaxAzureDiskVolumeCount predicates. Unlike them, it counts an unbound claim only towards the
+// limit of the volume type its storage class provisions, instead of towards all of them.
+type volumeLimitChecker struct {
+	limit       attachableVolumeLimit
+	pvcLister   v1lister.PersistentVolumeClaimLister
+	pvLister    v1lister.PersistentVolumeLister
+	classLister v1storagelister.StorageClassLister
+}
+
+func (c *volumeLimitChecker) predicate(pod *apiv1.Pod, meta algorithm.PredicateMetadata, nodeInfo *schedulercache.NodeInfo) (bool,
+	[]algorithm.PredicateFailureReason, error) {
+	if len(pod.Spec.Volumes) == 0 {
+		return true, nil, nil
+	}
+	node := nodeInfo.Node()
+	if node == nil {
+		return false, nil, fmt.Errorf("node not found")
+	}
+	maxVolumes, limited := c.maxVolumes(nodeInfo)
+	if !limited {
+		return true, nil, nil
+	}
+	newVolumes := sets.NewString()
+	c.addVolumes(pod, newVolumes)
+	if newVolumes.Len() == 0 {
+		return true, nil, nil
+	}
+	existingVolumes := sets.NewString()
+	for _, existingPod := range nodeInfo.Pods() {
+		c.addVolumes(existingPod, existingVolumes)
+	}
+	if existingVolumes.Union(newVolumes).Len() > maxVolumes {
+		return false, []algorithm.PredicateFailureReason{predicates.ErrMaxVolumeCountExceeded}, nil
+	}
+	return true, nil, nil
+}
+
+// maxVolumes returns the limit reported by the node or, if it doesn't report one, the default. Returns
+// false if the node isn't limited.
+func (c *volumeLimitChecker) maxVolumes(nodeInfo *schedulercache.NodeInfo) (int, bool) {
+	if limit, found := nodeInfo.VolumeLimits()[c.limit.limitKey]; found {
+		return int(limit), true
+	}
+	if c.limit.defaultLimit == nil {
+		return 0, false
+	}
+	if rawLimit := os.Getenv(predicates.KubeMaxPDVols); rawLimit != "" {
+		if limit, err := strconv.Atoi(rawLimit); err == nil && limit > 0 {
+			return limit, true
+		}
+	}
+	return c.limit.defaultLimit(nodeInfo.Node()), true
+}
+
+// addVolumes adds the ids of the pod's volumes of the limited type. Unbound claims are identified
+// by their name.
+func (c *volumeLimitChecker) addVolumes(pod *apiv1.Pod, volumes sets.String) {
+	for i := range pod.Spec.Volumes {
+		volume := &pod.Spec.Volumes[i]
+		if id, found := c.limit.filter.FilterVolume(volume); found {
+			volumes.Insert(id)
+			continue
+		}
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc, err := c.pvcLister.PersistentVolumeClaims(pod.Namespace).Get(volume.PersistentVolumeClaim.ClaimName)
+		if err != nil {
+			continue
+		}
+		if pvc.Spec.VolumeName == "" {
+			class, err := c.classLister.Get(v1helper.GetPersistentVolumeClaimClass(pvc))
+			if err == nil && c.limit.provisioners.Has(class.Provisioner) {
+				volumes.Insert(fmt.Sprintf("claim:%s/%s", pvc.Namespace, pvc.Name))
+			}
+			continue
+		}
+		pv, err := c.pvLister.Get(pvc.Spec.VolumeName)
+		if err != nil {
+			continue
+		}
+		if id, found := c.limit.filter.FilterPersistentVolume(pv); found {
+			volumes.Insert(id)
+		}
+	}
+}
+
+// replaceVolumePredicates replaces the scheduler's volume binding and volume limit predicates with
+// ones modelling delayed binding, and adds the Cinder volume limit if the volume limits are checked.
+// The Cinder volume limit is only checked on nodes reporting it.
+func replaceVolumePredicates(predicateMap map[string]algorithm.FitPredicate, pvcLister v1lister.PersistentVolumeClaimLister,
+	pvLister v1lister.PersistentVolumeLister, classLister v1storagelister.StorageClassLister) {
+	if _, found := predicateMap[predicates.CheckVolumeBindingPred]; found {
+		checker := &volumeBindingChecker{pvcLister: pvcLister, pvLister: pvLister, classLister: classLister}
+		predicateMap[predicates.CheckVolumeBindingPred] = checker.predicate
+	}
+	limitsChecked := false
+	for _, limit := range attachableVolumeLimits {
+		if _, found := predicateMap[limit.predicateName]; found {
+			limitsChecked = true
+		}
+	}
+	for _, limit := range attachableVolumeLimits {
+		if _, found := predicateMap[limit.predicateName]; found || (limitsChecked && limit.predicateName == maxCinderVolumeCountPredicateName) {
+			checker := &volumeLimitChecker{limit: limit, pvcLister: pvcLister, pvLister: pvLister, classLister: classLister}
+			predicateMap[limit.predicateName] = checker.predicate
+		}
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+	apiv1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	v1storagelister "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTestClass(name, provisioner string, mode storagev1.VolumeBindingMode, zones ...string) *storagev1.StorageClass {
+	class := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: name},
+		Provisioner:       provisioner,
+		VolumeBindingMode: &mode,
+	}
+	if len(zones) > 0 {
+		class.AllowedTopologies = []apiv1.TopologySelectorTerm{{
+			MatchLabelExpressions: []apiv1.TopologySelectorLabelRequirement{{
+				Key:    kubeletapis.LabelZoneFailureDomain,
+				Values: zones,
+			}},
+		}}
+	}
+	return class
+}
+
+func buildUnboundTestPVC(name, className string) *apiv1.PersistentVolumeClaim {
+	pvc := buildTestPVC(name, "")
+	pvc.Spec.StorageClassName = &className
+	pvc.Spec.AccessModes = []apiv1.PersistentVolumeAccessMode{apiv1.ReadWriteOnce}
+	pvc.Spec.Resources.Requests = apiv1.ResourceList{apiv1.ResourceStorage: resource.MustParse("10Gi")}
+	return pvc
+}
+
+func buildAvailableLocalPV(name, className, hostname string) *apiv1.PersistentVolume {
+	pv := buildTestPV(name, nil)
+	pv.Spec.StorageClassName = className
+	pv.Spec.AccessModes = []apiv1.PersistentVolumeAccessMode{apiv1.ReadWriteOnce}
+	pv.Spec.Capacity = apiv1.ResourceList{apiv1.ResourceStorage: resource.MustParse("100Gi")}
+	pv.Spec.NodeAffinity = &apiv1.VolumeNodeAffinity{
+		Required: &apiv1.NodeSelector{
+			NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+				MatchExpressions: []apiv1.NodeSelectorRequirement{{
+					Key:      kubeletapis.LabelHostname,
+					Operator: apiv1.NodeSelectorOpIn,
+					Values:   []string{hostname},
+				}},
+			}},
+		},
+	}
+	pv.Status.Phase = apiv1.VolumeAvailable
+	return pv
+}
+
+func buildTestVolumeListers(pvcs []*apiv1.PersistentVolumeClaim, pvs []*apiv1.PersistentVolume, classes []*storagev1.StorageClass) (
+	v1lister.PersistentVolumeClaimLister, v1lister.PersistentVolumeLister, v1storagelister.StorageClassLister) {
+	pvcStore := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pvc := range pvcs {
+		pvcStore.Add(pvc)
+	}
+	pvStore := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pv := range pvs {
+		pvStore.Add(pv)
+	}
+	classStore := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, class := range classes {
+		classStore.Add(class)
+	}
+	return v1lister.NewPersistentVolumeClaimLister(pvcStore), v1lister.NewPersistentVolumeLister(pvStore),
+		v1storagelister.NewStorageClassLister(classStore)
+}
+
+func buildTestNodeInfo(node *apiv1.Node, pods ...*apiv1.Pod) *schedulercache.NodeInfo {
+	nodeInfo := schedulercache.NewNodeInfo(pods...)
+	nodeInfo.SetNode(node)
+	return nodeInfo
+}
+
+func reasonStrings(reasons []algorithm.PredicateFailureReason) []string {
+	result := []string{}
+	for _, reason := range reasons {
+		result = append(result, reason.GetReason())
+	}
+	return result
+}
+
+func TestVolumeBindingPredicate(t *testing.T) {
+	pvcLister, pvLister, classLister := buildTestVolumeListers(
+		[]*apiv1.PersistentVolumeClaim{
+			buildTestPVC("bound", "bound"),
+			buildUnboundTestPVC("immediate", "standard"),
+			buildUnboundTestPVC("delayed", "zonal-delayed"),
+			buildUnboundTestPVC("local-1", "local"),
+			buildUnboundTestPVC("local-2", "local"),
+			buildUnboundTestPVC("no-class", ""),
+		},
+		[]*apiv1.PersistentVolume{
+			buildAvailableLocalPV("bound", "", "n1"),
+			buildAvailableLocalPV("local-n1", "local", "n1"),
+		},
+		[]*storagev1.StorageClass{
+			buildTestClass("standard", "kubernetes.io/aws-ebs", storagev1.VolumeBindingImmediate),
+			buildTestClass("zonal-delayed", "kubernetes.io/aws-ebs", storagev1.VolumeBindingWaitForFirstConsumer, "a"),
+			buildTestClass("local", notSupportedProvisioner, storagev1.VolumeBindingWaitForFirstConsumer),
+		})
+	checker := &volumeBindingChecker{pvcLister: pvcLister, pvLister: pvLister, classLister: classLister}
+
+	n1 := buildZoneNode("n1", "a")
+	n1.Labels[kubeletapis.LabelHostname] = "n1"
+	n2 := buildZoneNode("n2", "b")
+	n2.Labels[kubeletapis.LabelHostname] = "n2"
+
+	testCases := []struct {
+		desc    string
+		claims  []string
+		node    *apiv1.Node
+		reasons []string
+	}{
+		{desc: "no claims", node: n2, reasons: []string{}},
+		{desc: "bound volume", claims: []string{"bound"}, node: n1, reasons: []string{}},
+		{desc: "bound volume on other node", claims: []string{"bound"}, node: n2, reasons: []string{predicates.ErrVolumeNodeConflict.GetReason()}},
+		{desc: "unbound immediate claim", claims: []string{"immediate"}, node: n1, reasons: []string{"pod has unbound immediate persistent volume claims"}},
+		{desc: "claim without class", claims: []string{"no-class"}, node: n1, reasons: []string{"pod has unbound immediate persistent volume claims"}},
+		{desc: "delayed claim in allowed zone", claims: []string{"delayed"}, node: n1, reasons: []string{}},
+		{desc: "delayed claim in other zone", claims: []string{"delayed"}, node: n2, reasons: []string{"node(s) not in allowed topologies of storage class zonal-delayed"}},
+		{desc: "available local volume", claims: []string{"local-1"}, node: n1, reasons: []string{}},
+		{desc: "no local volume", claims: []string{"local-1"}, node: n2, reasons: []string{predicates.ErrVolumeBindConflict.GetReason()}},
+		{desc: "local volume chosen once", claims: []string{"local-1", "local-2"}, node: n1, reasons: []string{predicates.ErrVolumeBindConflict.GetReason()}},
+	}
+	for _, tc := range testCases {
+		pod := BuildTestPod("p", 100, 0)
+		for _, claim := range tc.claims {
+			addClaim(pod, claim)
+		}
+		fits, reasons, err := checker.predicate(pod, nil, buildTestNodeInfo(tc.node))
+		assert.NoError(t, err, tc.desc)
+		assert.Equal(t, len(tc.reasons) == 0, fits, tc.desc)
+		assert.Equal(t, tc.reasons, reasonStrings(reasons), tc.desc)
+	}
+
+	pod := BuildTestPod("p", 100, 0)
+	addClaim(pod, "missing")
+	_, _, err := checker.predicate(pod, nil, buildTestNodeInfo(n1))
+	assert.Error(t, err)
+}
+
+func TestVolumeLimitPredicate(t *testing.T) {
+	ebsPV := buildTestPV("ebs", nil)
+	ebsPV.Spec.AWSElasticBlockStore = &apiv1.AWSElasticBlockStoreVolumeSource{VolumeID: "vol-1"}
+	pvcLister, pvLister, classLister := buildTestVolumeListers(
+		[]*apiv1.PersistentVolumeClaim{
+			buildTestPVC("ebs", "ebs"),
+			buildUnboundTestPVC("ebs-1", "ebs-delayed"),
+			buildUnboundTestPVC("ebs-2", "ebs-delayed"),
+			buildUnboundTestPVC("cinder-1", "cinder-delayed"),
+			buildUnboundTestPVC("cinder-2", "cinder-delayed"),
+		},
+		[]*apiv1.PersistentVolume{ebsPV},
+		[]*storagev1.StorageClass{
+			buildTestClass("ebs-delayed", "kubernetes.io/aws-ebs", storagev1.VolumeBindingWaitForFirstConsumer),
+			buildTestClass("cinder-delayed", "kubernetes.io/cinder", storagev1.VolumeBindingWaitForFirstConsumer),
+		})
+	predicateMap := map[string]algorithm.FitPredicate{
+		predicates.MaxEBSVolumeCountPred: predicates.PodFitsResources,
+	}
+	replaceVolumePredicates(predicateMap, pvcLister, pvLister, classLister)
+	assert.Equal(t, 2, len(predicateMap))
+	ebsPredicate := predicateMap[predicates.MaxEBSVolumeCountPred]
+	cinderPredicate := predicateMap[maxCinderVolumeCountPredicateName]
+	assert.NotNil(t, cinderPredicate)
+
+	existingPod := BuildTestPod("existing", 100, 0)
+	addClaim(existingPod, "ebs")
+	node := BuildTestNode("n1", 1000, 1000)
+	node.Status.Allocatable[apiv1.ResourceName("attachable-volumes-aws-ebs")] = *resource.NewQuantity(2, resource.DecimalSI)
+	nodeInfo := buildTestNodeInfo(node, existingPod)
+
+	// The already attached volume counts only once.
+	pod := BuildTestPod("p", 100, 0)
+	addClaim(pod, "ebs")
+	addClaim(pod, "ebs-1")
+	fits, _, err := ebsPredicate(pod, nil, nodeInfo)
+	assert.NoError(t, err)
+	assert.True(t, fits)
+
+	addClaim(pod, "ebs-2")
+	fits, reasons, err := ebsPredicate(pod, nil, nodeInfo)
+	assert.NoError(t, err)
+	assert.False(t, fits)
+	assert.Equal(t, []string{predicates.ErrMaxVolumeCountExceeded.GetReason()}, reasonStrings(reasons))
+
+	// Claims of other provisioners don't count towards the limit.
+	pod = BuildTestPod("p", 100, 0)
+	addClaim(pod, "ebs-1")
+	addClaim(pod, "cinder-1")
+	fits, _, err = ebsPredicate(pod, nil, nodeInfo)
+	assert.NoError(t, err)
+	assert.True(t, fits)
+	fits, _, err = cinderPredicate(pod, nil, nodeInfo)
+	assert.NoError(t, err)
+	assert.True(t, fits)
+
+	// The Cinder volume limit is only checked on nodes reporting it.
+	addClaim(pod, "cinder-2")
+	fits, _, err = cinderPredicate(pod, nil, nodeInfo)
+	assert.NoError(t, err)
+	assert.True(t, fits)
+	cinderNode := BuildTestNode("n3", 1000, 1000)
+	cinderNode.Status.Allocatable[apiv1.ResourceName("attachable-volumes-cinder")] = *resource.NewQuantity(1, resource.DecimalSI)
+	fits, reasons, err = cinderPredicate(pod, nil, buildTestNodeInfo(cinderNode))
+	assert.NoError(t, err)
+	assert.False(t, fits)
+	assert.Equal(t, []string{predicates.ErrMaxVolumeCountExceeded.GetReason()}, reasonStrings(reasons))
+
+	// Nodes that don't report the limit get the default of their instance type.
+	checker := &volumeLimitChecker{limit: attachableVolumeLimits[0]}
+	nitroNode := BuildTestNode("n2", 1000, 1000)
+	nitroNode.Labels[kubeletapis.LabelInstanceType] = "m5.large"
+	maxVolumes, limited := checker.maxVolumes(buildTestNodeInfo(nitroNode))
+	assert.True(t, limited)
+	assert.Equal(t, 25, maxVolumes)
+	nitroNode.Labels[kubeletapis.LabelInstanceType] = "m4.large"
+	maxVolumes, _ = checker.maxVolumes(buildTestNodeInfo(nitroNode))
+	assert.Equal(t, 39, maxVolumes)
+}
+
+func TestReplaceVolumePredicates(t *testing.T) {
+	pvcLister, pvLister, classLister := buildTestVolumeListers(nil, nil, nil)
+	predicateMap := map[string]algorithm.FitPredicate{
+		"GeneralPredicates": predicates.GeneralPredicates,
+	}
+	replaceVolumePredicates(predicateMap, pvcLister, pvLister, classLister)
+	assert.Equal(t, 1, len(predicateMap))
+
+	predicateMap[predicates.CheckVolumeBindingPred] = predicates.GeneralPredicates
+	predicateMap[predicates.MaxGCEPDVolumeCountPred] = predicates.GeneralPredicates
+	replaceVolumePredicates(predicateMap, pvcLister, pvLister, classLister)
+	assert.Equal(t, 4, len(predicateMap))
+	assert.Contains(t, predicateMap, maxCinderVolumeCountPredicateName)
+	assert.NotContains(t, predicateMap, predicates.MaxEBSVolumeCountPred)
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+	apiv1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+	volumeutil "k8s.io/kubernetes/pkg/volume/util"
+
+	"github.com/golang/glog"
+)
+
+// VolumeTopology describes where the persistent volumes used by a pod can be accessed. For bound
+// volumes it is based on their zone and region labels and their node affinity, for claims waiting
+// for the first consumer on the allowed topologies of their storage class.
+type VolumeTopology struct {
+	volumes []*apiv1.PersistentVolume
+	classes []*storagev1.StorageClass
+}
+
+// GetVolumeTopology returns the topology of the persistent volumes used by the pod, or nil if none
+// of them is restricted to a part of the cluster. Claims, volumes and storage classes that can't be
+// found are ignored, the pod can't be scheduled until they exist anyway.
+func GetVolumeTopology(pod *apiv1.Pod, volumeLister kube_util.VolumeLister) *VolumeTopology {
+	if volumeLister == nil {
+		return nil
+	}
+	var volumes []*apiv1.PersistentVolume
+	var classes []*storagev1.StorageClass
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc, err := volumeLister.GetPersistentVolumeClaim(pod.Namespace, volume.PersistentVolumeClaim.ClaimName)
+		if err != nil {
+			glog.V(4).Infof("Failed to get persistent volume claim %s/%s of pod %s: %v", pod.Namespace, volume.PersistentVolumeClaim.ClaimName, pod.Name, err)
+			continue
+		}
+		if pvc.Spec.VolumeName == "" {
+			if class := getDelayedBindingClass(pvc, volumeLister); class != nil && len(class.AllowedTopologies) > 0 {
+				classes = append(classes, class)
+			}
+			continue
+		}
+		pv, err := volumeLister.GetPersistentVolume(pvc.Spec.VolumeName)
+		if err != nil {
+			glog.V(4).Infof("Failed to get persistent volume %s of pod %s/%s: %v", pvc.Spec.VolumeName, pod.Namespace, pod.Name, err)
+			continue
+		}
+		if hasTopology(pv) {
+			volumes = append(volumes, pv)
+		}
+	}
+	if len(volumes) == 0 && len(classes) == 0 {
+		return nil
+	}
+	return &VolumeTopology{volumes: volumes, classes: classes}
+}
+
+// getDelayedBindingClass returns the storage class of an unbound claim if the claim waits for the
+// first consumer to be bound or provisioned, nil otherwise.
+func getDelayedBindingClass(pvc *apiv1.PersistentVolumeClaim, volumeLister kube_util.VolumeLister) *storagev1.StorageClass {
+	className := v1helper.GetPersistentVolumeClaimClass(pvc)
+	if className == "" {
+		return nil
+	}
+	class, err := volumeLister.GetStorageClass(className)
+	if err != nil {
+		glog.V(4).Infof("Failed to get storage class %s of persistent volume claim %s/%s: %v", className, pvc.Namespace, pvc.Name, err)
+		return nil
+	}
+	if class.VolumeBindingMode == nil || *class.VolumeBindingMode != storagev1.VolumeBindingWaitForFirstConsumer {
+		return nil
+	}
+	return class
+}
+
+func hasTopology(pv *apiv1.PersistentVolume) bool {
+	_, hasZone := pv.Labels[kubeletapis.LabelZoneFailureDomain]
+	_, hasRegion := pv.Labels[kubeletapis.LabelZoneRegion]
+	hasNodeAffinity := pv.Spec.NodeAffinity != nil && pv.Spec.NodeAffinity.Required != nil
+	return hasZone || hasRegion || hasNodeAffinity
+}
+
+// Zones returns the zones all the volumes are available in according to their zone labels,
+// or nil if none of them has a zone label.
+func (t *VolumeTopology) Zones() sets.String {
+	var zones sets.String
+	for _, pv := range t.volumes {
+		value, found := pv.Labels[kubeletapis.LabelZoneFailureDomain]
+		if !found {
+			continue
+		}
+		pvZones, err := volumeutil.LabelZonesToSet(value)
+		if err != nil {
+			glog.Warningf("Failed to parse zones of persistent volume %s: %v", pv.Name, err)
+			continue
+		}
+		if zones == nil {
+			zones = pvZones
+		} else {
+			zones = zones.Intersection(pvZones)
+		}
+	}
+	return zones
+}
+
+// CheckNodeGroup checks whether new nodes of a node group can access all the volumes. The new nodes
+// are described by the template node of the group and the zones its existing nodes are in. Since it
+// isn't known in which of several zones a new node will be created, each of them has to fit.
+func (t *VolumeTopology) CheckNodeGroup(template *apiv1.Node, zones sets.String) error {
+	if err := t.checkNodeLabels(template.Labels); err != nil {
+		return err
+	}
+	templateZone := template.Labels[kubeletapis.LabelZoneFailureDomain]
+	for _, zone := range zones.List() {
+		if zone == templateZone {
+			continue
+		}
+		nodeLabels := make(map[string]string, len(template.Labels))
+		for key, value := range template.Labels {
+			nodeLabels[key] = value
+		}
+		nodeLabels[kubeletapis.LabelZoneFailureDomain] = zone
+		if err := t.checkNodeLabels(nodeLabels); err != nil {
+			return fmt.Errorf("node group spans several zones (%s)", t.describe())
+		}
+	}
+	return nil
+}
+
+// checkNodeLabels does the same checks as the NoVolumeZoneConflict and CheckVolumeBinding predicates,
+// except that a node without zone or region label is assumed not to match bound zonal volumes.
+func (t *VolumeTopology) checkNodeLabels(nodeLabels map[string]string) error {
+	for _, pv := range t.volumes {
+		for _, key := range []string{kubeletapis.LabelZoneFailureDomain, kubeletapis.LabelZoneRegion} {
+			value, found := pv.Labels[key]
+			if !found {
+				continue
+			}
+			allowed, err := volumeutil.LabelZonesToSet(value)
+			if err != nil {
+				glog.Warningf("Failed to parse %s label of persistent volume %s: %v", key, pv.Name, err)
+				continue
+			}
+			if !allowed.Has(nodeLabels[key]) {
+				return fmt.Errorf("volume zone conflict (%s)", t.describe())
+			}
+		}
+		if err := volumeutil.CheckNodeAffinity(pv, nodeLabels); err != nil {
+			return fmt.Errorf("volume node affinity conflict (volume %s)", pv.Name)
+		}
+	}
+	for _, class := range t.classes {
+		if !v1helper.MatchTopologySelectorTerms(class.AllowedTopologies, labels.Set(nodeLabels)) {
+			return fmt.Errorf("storage class topology conflict (storage class %s)", class.Name)
+		}
+	}
+	return nil
+}
+
+// describe returns where the volumes are, for messages.
+func (t *VolumeTopology) describe() string {
+	parts := []string{}
+	if zones := t.Zones(); zones != nil && zones.Len() == 0 {
+		parts = append(parts, "bound volumes have no zone in common")
+	} else if zones != nil {
+		parts = append(parts, fmt.Sprintf("bound volumes are in zone %s", strings.Join(zones.List(), ", ")))
+	} else if len(t.volumes) > 0 {
+		names := make([]string, 0, len(t.volumes))
+		for _, pv := range t.volumes {
+			names = append(names, pv.Name)
+		}
+		parts = append(parts, fmt.Sprintf("bound volumes %s", strings.Join(names, ", ")))
+	}
+	for _, class := range t.classes {
+		parts = append(parts, fmt.Sprintf("storage class %s", class.Name))
+	}
+	return strings.Join(parts, ", ")
+}
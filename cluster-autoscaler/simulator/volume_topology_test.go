@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+
+	kube_util "github.com/gardener/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+	apiv1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTestPVC(name, volumeName string) *apiv1.PersistentVolumeClaim {
+	return &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       apiv1.PersistentVolumeClaimSpec{VolumeName: volumeName},
+	}
+}
+
+func buildTestPV(name string, labels map[string]string) *apiv1.PersistentVolume {
+	return &apiv1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+	}
+}
+
+func addClaim(pod *apiv1.Pod, claimName string) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, apiv1.Volume{
+		Name: claimName,
+		VolumeSource: apiv1.VolumeSource{
+			PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+		},
+	})
+}
+
+func buildZoneNode(name, zone string) *apiv1.Node {
+	node := BuildTestNode(name, 1000, 1000)
+	node.Labels[kubeletapis.LabelZoneFailureDomain] = zone
+	node.Labels[kubeletapis.LabelZoneRegion] = "r"
+	return node
+}
+
+func TestGetVolumeTopology(t *testing.T) {
+	localPV := buildTestPV("local", nil)
+	localPV.Spec.NodeAffinity = &apiv1.VolumeNodeAffinity{
+		Required: &apiv1.NodeSelector{
+			NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+				MatchExpressions: []apiv1.NodeSelectorRequirement{{
+					Key:      kubeletapis.LabelHostname,
+					Operator: apiv1.NodeSelectorOpIn,
+					Values:   []string{"n1"},
+				}},
+			}},
+		},
+	}
+	volumeLister := kube_util.NewTestVolumeLister(
+		[]*apiv1.PersistentVolumeClaim{
+			buildTestPVC("zonal", "zonal"),
+			buildTestPVC("regional", "regional"),
+			buildTestPVC("nfs", "nfs"),
+			buildTestPVC("local", "local"),
+			buildTestPVC("unbound", ""),
+			buildTestPVC("lost", "missing"),
+			buildUnboundTestPVC("delayed", "zonal-delayed"),
+			buildUnboundTestPVC("delayed-anywhere", "delayed"),
+			buildUnboundTestPVC("immediate", "standard"),
+		},
+		[]*apiv1.PersistentVolume{
+			buildTestPV("zonal", map[string]string{kubeletapis.LabelZoneFailureDomain: "a", kubeletapis.LabelZoneRegion: "r"}),
+			buildTestPV("regional", map[string]string{kubeletapis.LabelZoneFailureDomain: "a__b"}),
+			buildTestPV("nfs", nil),
+			localPV,
+		},
+		[]*storagev1.StorageClass{
+			buildTestClass("zonal-delayed", "kubernetes.io/aws-ebs", storagev1.VolumeBindingWaitForFirstConsumer, "a"),
+			buildTestClass("delayed", "kubernetes.io/aws-ebs", storagev1.VolumeBindingWaitForFirstConsumer),
+			buildTestClass("standard", "kubernetes.io/aws-ebs", storagev1.VolumeBindingImmediate, "a"),
+		})
+
+	pod := BuildTestPod("p", 100, 0)
+	assert.Nil(t, GetVolumeTopology(pod, volumeLister))
+	for _, claim := range []string{"nfs", "unbound", "lost", "not-existing", "delayed-anywhere", "immediate"} {
+		addClaim(pod, claim)
+	}
+	assert.Nil(t, GetVolumeTopology(pod, volumeLister))
+	assert.Nil(t, GetVolumeTopology(pod, nil))
+
+	addClaim(pod, "regional")
+	topology := GetVolumeTopology(pod, volumeLister)
+	assert.NotNil(t, topology)
+	assert.Equal(t, sets.NewString("a", "b"), topology.Zones())
+	assert.NoError(t, topology.CheckNodeGroup(buildZoneNode("n", "b"), sets.NewString("a", "b")))
+	assert.Error(t, topology.CheckNodeGroup(buildZoneNode("n", "c"), nil))
+
+	addClaim(pod, "zonal")
+	topology = GetVolumeTopology(pod, volumeLister)
+	assert.Equal(t, sets.NewString("a"), topology.Zones())
+	assert.NoError(t, topology.CheckNodeGroup(buildZoneNode("n", "a"), nil))
+	assert.NoError(t, topology.CheckNodeGroup(buildZoneNode("n", "a"), sets.NewString("a")))
+
+	err := topology.CheckNodeGroup(buildZoneNode("n", "b"), sets.NewString("b"))
+	assert.Error(t, err)
+	assert.Equal(t, "volume zone conflict (bound volumes are in zone a)", err.Error())
+
+	// The template is in the right zone, but new nodes may be created in another one.
+	err = topology.CheckNodeGroup(buildZoneNode("n", "a"), sets.NewString("a", "b"))
+	assert.Error(t, err)
+	assert.Equal(t, "node group spans several zones (bound volumes are in zone a)", err.Error())
+
+	// Templates without zone labels can't be trusted.
+	assert.Error(t, topology.CheckNodeGroup(BuildTestNode("n", 1000, 1000), nil))
+
+	localPod := BuildTestPod("local", 100, 0)
+	addClaim(localPod, "local")
+	topology = GetVolumeTopology(localPod, volumeLister)
+	assert.NotNil(t, topology)
+	assert.Nil(t, topology.Zones())
+	err = topology.CheckNodeGroup(buildZoneNode("n2", "a"), nil)
+	assert.Error(t, err)
+	assert.Equal(t, "volume node affinity conflict (volume local)", err.Error())
+	node := buildZoneNode("n1", "a")
+	node.Labels[kubeletapis.LabelHostname] = "n1"
+	assert.NoError(t, topology.CheckNodeGroup(node, nil))
+
+	// Claims waiting for the first consumer can only be provisioned in the allowed zones of their class.
+	delayedPod := BuildTestPod("delayed", 100, 0)
+	addClaim(delayedPod, "delayed")
+	topology = GetVolumeTopology(delayedPod, volumeLister)
+	assert.NotNil(t, topology)
+	assert.NoError(t, topology.CheckNodeGroup(buildZoneNode("n", "a"), sets.NewString("a")))
+	err = topology.CheckNodeGroup(buildZoneNode("n", "b"), nil)
+	assert.Error(t, err)
+	assert.Equal(t, "storage class topology conflict (storage class zonal-delayed)", err.Error())
+	err = topology.CheckNodeGroup(buildZoneNode("n", "a"), sets.NewString("a", "b"))
+	assert.Error(t, err)
+	assert.Equal(t, "node group spans several zones (storage class zonal-delayed)", err.Error())
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/golang/glog"
+)
+
+// spanRecord is a finished span as written by jsonExporter.
+type spanRecord struct {
+	TraceID       string                 `json:"traceId"`
+	SpanID        string                 `json:"spanId"`
+	ParentSpanID  string                 `json:"parentSpanId,omitempty"`
+	Name          string                 `json:"name"`
+	StartTime     time.Time              `json:"startTime"`
+	EndTime       time.Time              `json:"endTime"`
+	DurationMs    float64                `json:"durationMs"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	StatusCode    int32                  `json:"statusCode,omitempty"`
+	StatusMessage string                 `json:"statusMessage,omitempty"`
+}
+
+// jsonExporter writes finished spans to a writer, one JSON object per line. It needs no
+// external services, so traces can be inspected with standard tools.
+type jsonExporter struct {
+	sync.Mutex
+	writer io.Writer
+}
+
+func newJSONExporter(writer io.Writer) *jsonExporter {
+	return &jsonExporter{writer: writer}
+}
+
+// ExportSpan writes the span.
+func (e *jsonExporter) ExportSpan(data *trace.SpanData) {
+	record := spanRecord{
+		TraceID:       data.TraceID.String(),
+		SpanID:        data.SpanID.String(),
+		Name:          data.Name,
+		StartTime:     data.StartTime,
+		EndTime:       data.EndTime,
+		DurationMs:    float64(data.EndTime.Sub(data.StartTime)) / float64(time.Millisecond),
+		Attributes:    data.Attributes,
+		StatusCode:    data.Code,
+		StatusMessage: data.Message,
+	}
+	if data.ParentSpanID != (trace.SpanID{}) {
+		record.ParentSpanID = data.ParentSpanID.String()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		glog.Errorf("Failed to marshal span %s: %v", data.Name, err)
+		return
+	}
+	e.Lock()
+	defer e.Unlock()
+	if _, err := e.writer.Write(append(line, '\n')); err != nil {
+		glog.Errorf("Failed to write span %s: %v", data.Name, err)
+	}
+}
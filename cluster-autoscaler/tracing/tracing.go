@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"contrib.go.opencensus.io/exporter/ocagent"
+	"go.opencensus.io/trace"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// NoExporter - traces are not exported.
+	NoExporter = "none"
+	// StdoutExporter - finished spans are written to stdout, one JSON object per line.
+	StdoutExporter = "stdout"
+	// FileExporter - finished spans are appended to a file, one JSON object per line.
+	FileExporter = "file"
+	// AgentExporter - finished spans are sent to an OpenCensus agent.
+	AgentExporter = "ocagent"
+
+	serviceName = "cluster-autoscaler"
+)
+
+// AvailableExporters is a list of available trace exporters.
+var AvailableExporters = []string{NoExporter, StdoutExporter, FileExporter, AgentExporter}
+
+// Options configure the export of traces.
+type Options struct {
+	// Exporter is one of AvailableExporters.
+	Exporter string
+	// File is the path spans are appended to by FileExporter.
+	File string
+	// AgentAddress is the host:port of the agent AgentExporter sends spans to.
+	AgentAddress string
+	// SampleFraction is the fraction of autoscaler loops that are traced.
+	SampleFraction float64
+}
+
+// Setup registers the exporter configured in options. The returned function flushes spans not
+// exported yet and releases the exporter; it should be called before the process exits.
+func Setup(options Options) (func(), error) {
+	if options.SampleFraction < 0 || options.SampleFraction > 1 {
+		return nil, fmt.Errorf("trace sample fraction must be between 0 and 1, got %v", options.SampleFraction)
+	}
+	var exporter trace.Exporter
+	stop := func() {}
+	switch options.Exporter {
+	case NoExporter, "":
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+		return stop, nil
+	case StdoutExporter:
+		exporter = newJSONExporter(os.Stdout)
+	case FileExporter:
+		if options.File == "" {
+			return nil, fmt.Errorf("trace file must be set for the %s exporter", FileExporter)
+		}
+		file, err := os.OpenFile(options.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file %s: %v", options.File, err)
+		}
+		exporter = newJSONExporter(file)
+		stop = func() {
+			if err := file.Close(); err != nil {
+				glog.Errorf("Failed to close trace file %s: %v", options.File, err)
+			}
+		}
+	case AgentExporter:
+		agentExporter, err := ocagent.NewExporter(ocagent.WithInsecure(), ocagent.WithAddress(options.AgentAddress),
+			ocagent.WithServiceName(serviceName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create trace agent exporter: %v", err)
+		}
+		exporter = agentExporter
+		stop = func() {
+			agentExporter.Flush()
+			if err := agentExporter.Stop(); err != nil {
+				glog.Errorf("Failed to stop trace agent exporter: %v", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", options.Exporter)
+	}
+	trace.RegisterExporter(exporter)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(options.SampleFraction)})
+	return func() {
+		trace.UnregisterExporter(exporter)
+		stop()
+	}, nil
+}
+
+// StartSpan starts a span with the given attributes. The span is a child of the span in parent,
+// or the root of a new trace if parent holds no span.
+func StartSpan(parent context.Context, name string, attributes ...trace.Attribute) (context.Context, *trace.Span) {
+	spanCtx, span := trace.StartSpan(parent, name)
+	if len(attributes) > 0 {
+		span.AddAttributes(attributes...)
+	}
+	return spanCtx, span
+}
+
+// EndSpan ends the span, marking it as failed with err if err is not nil.
+func EndSpan(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	span.End()
+}
+
+// Detach returns a context holding the span of parent, but not its deadline or cancellation. Used for
+// work that belongs to the trace of parent but may outlive it.
+func Detach(parent context.Context) context.Context {
+	return trace.NewContext(context.Background(), trace.FromContext(parent))
+}
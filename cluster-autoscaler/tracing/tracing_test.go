@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opencensus.io/trace"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readSpans(t *testing.T, path string) []spanRecord {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	spans := []spanRecord{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span spanRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	stop, err := Setup(Options{Exporter: FileExporter, File: path, SampleFraction: 1})
+	assert.NoError(t, err)
+	rootCtx, root := StartSpan(context.Background(), "RunOnce")
+	_, child := StartSpan(rootCtx, "ScaleUp", trace.StringAttribute("node_group", "ng1"))
+	EndSpan(child, fmt.Errorf("no capacity"))
+	EndSpan(root, nil)
+	stop()
+
+	spans := readSpans(t, path)
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "ScaleUp", spans[0].Name)
+	assert.Equal(t, "RunOnce", spans[1].Name)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Empty(t, spans[1].ParentSpanID)
+	assert.Equal(t, "ng1", spans[0].Attributes["node_group"])
+	assert.Equal(t, "no capacity", spans[0].StatusMessage)
+	assert.Empty(t, spans[1].StatusMessage)
+
+	// Spans ended after stop are not exported.
+	_, span := StartSpan(context.Background(), "RunOnce")
+	EndSpan(span, nil)
+	assert.Len(t, readSpans(t, path), 2)
+}
+
+func TestSetupErrors(t *testing.T) {
+	_, err := Setup(Options{Exporter: "unknown", SampleFraction: 1})
+	assert.Error(t, err)
+	_, err = Setup(Options{Exporter: FileExporter, SampleFraction: 1})
+	assert.Error(t, err)
+	_, err = Setup(Options{Exporter: StdoutExporter, SampleFraction: 2})
+	assert.Error(t, err)
+	stop, err := Setup(Options{Exporter: NoExporter})
+	assert.NoError(t, err)
+	stop()
+}
+
+func TestDetach(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	parent, span := trace.StartSpan(parent, "RunOnce")
+	defer span.End()
+	cancel()
+	detached := Detach(parent)
+	assert.NoError(t, detached.Err())
+	assert.Equal(t, span, trace.FromContext(detached))
+}
@@ -81,3 +81,44 @@ func (b *Backoff) IsBackedOff(key string, currentTime time.Time) bool {
 	backoffInfo, found := b.backoffInfo[key]
 	return found && backoffInfo.backoffUntil.After(currentTime)
 }
+
+// GetBackoffUntil returns the time until which the key is backed off. The second return value
+// is false if the key isn't backed off at the given time.
+func (b *Backoff) GetBackoffUntil(key string, currentTime time.Time) (time.Time, bool) {
+	backoffInfo, found := b.backoffInfo[key]
+	if !found || !backoffInfo.backoffUntil.After(currentTime) {
+		return time.Time{}, false
+	}
+	return backoffInfo.backoffUntil, true
+}
+
+// Entry is a serializable copy of the backoff data kept for a single key.
+type Entry struct {
+	Duration            time.Duration `json:"duration"`
+	BackoffUntil        time.Time     `json:"backoffUntil"`
+	LastFailedExecution time.Time     `json:"lastFailedExecution"`
+}
+
+// GetEntries returns a copy of the backoff data of all keys.
+func (b *Backoff) GetEntries() map[string]Entry {
+	result := make(map[string]Entry, len(b.backoffInfo))
+	for key, backoffInfo := range b.backoffInfo {
+		result[key] = Entry{
+			Duration:            backoffInfo.duration,
+			BackoffUntil:        backoffInfo.backoffUntil,
+			LastFailedExecution: backoffInfo.lastFailedExecution,
+		}
+	}
+	return result
+}
+
+// RestoreEntries sets backoff data for the given keys, overwriting existing data.
+func (b *Backoff) RestoreEntries(entries map[string]Entry) {
+	for key, entry := range entries {
+		b.backoffInfo[key] = backoffInfo{
+			duration:            entry.Duration,
+			backoffUntil:        entry.BackoffUntil,
+			lastFailedExecution: entry.LastFailedExecution,
+		}
+	}
+}
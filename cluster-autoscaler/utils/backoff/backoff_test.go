@@ -69,3 +69,29 @@ func TestResetStaleBackoffData(t *testing.T) {
 	backoff.RemoveStaleBackoffData(startTime.Add(5 * time.Hour))
 	assert.Equal(t, 0, len(backoff.backoffInfo))
 }
+
+func TestGetBackoffUntil(t *testing.T) {
+	backoff := NewBackoff(1*time.Minute, 3*time.Minute, 3*time.Hour)
+	startTime := time.Now()
+	_, found := backoff.GetBackoffUntil("key1", startTime)
+	assert.False(t, found)
+	backoff.Backoff("key1", startTime)
+	until, found := backoff.GetBackoffUntil("key1", startTime)
+	assert.True(t, found)
+	assert.Equal(t, startTime.Add(time.Minute), until)
+	_, found = backoff.GetBackoffUntil("key1", startTime.Add(2*time.Minute))
+	assert.False(t, found)
+}
+
+func TestRestoreEntries(t *testing.T) {
+	backoff := NewBackoff(1*time.Minute, 3*time.Minute, 3*time.Hour)
+	startTime := time.Now()
+	backoff.Backoff("key1", startTime)
+
+	restored := NewBackoff(1*time.Minute, 3*time.Minute, 3*time.Hour)
+	restored.RestoreEntries(backoff.GetEntries())
+	assert.True(t, restored.IsBackedOff("key1", startTime))
+	assert.False(t, restored.IsBackedOff("key2", startTime))
+	// Duration is restored too, so the next backoff is doubled.
+	assert.Equal(t, startTime.Add(4*time.Minute), restored.Backoff("key1", startTime.Add(2*time.Minute)))
+}
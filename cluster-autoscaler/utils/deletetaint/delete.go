@@ -22,6 +22,7 @@ import (
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kube_client "k8s.io/client-go/kubernetes"
 
@@ -31,48 +32,87 @@ import (
 const (
 	// ToBeDeletedTaint is a taint used to make the node unschedulable.
 	ToBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+	// DeletionCandidateTaint is a taint used to mark unneeded node as preferably unschedulable.
+	DeletionCandidateTaint = "DeletionCandidateOfClusterAutoscaler"
+)
+
+// Mutable only in unit tests
+var (
+	maxRetryDeadline      = 5 * time.Second
+	conflictRetryInterval = 750 * time.Millisecond
 )
 
 // MarkToBeDeleted sets a taint that makes the node unschedulable.
 func MarkToBeDeleted(node *apiv1.Node, client kube_client.Interface) error {
-	// Get the newest version of the node.
-	freshNode, err := client.CoreV1().Nodes().Get(node.Name, metav1.GetOptions{})
-	if err != nil || freshNode == nil {
-		return fmt.Errorf("failed to get node %v: %v", node.Name, err)
-	}
+	return addTaint(node, client, ToBeDeletedTaint, apiv1.TaintEffectNoSchedule)
+}
 
-	added, err := addToBeDeletedTaint(freshNode)
-	if added == false {
-		return err
-	}
-	_, err = client.CoreV1().Nodes().Update(freshNode)
-	if err != nil {
-		glog.Warningf("Error while adding taints on node %v: %v", node.Name, err)
-		return err
+// MarkDeletionCandidate sets a soft taint that makes the node preferably unschedulable.
+func MarkDeletionCandidate(node *apiv1.Node, client kube_client.Interface) error {
+	return addTaint(node, client, DeletionCandidateTaint, apiv1.TaintEffectPreferNoSchedule)
+}
+
+// addTaint adds the taint to the newest version of the node. Updates that fail because of
+// a conflicting write are retried with a freshly fetched node for up to maxRetryDeadline.
+func addTaint(node *apiv1.Node, client kube_client.Interface, taintKey string, effect apiv1.TaintEffect) error {
+	retryDeadline := time.Now().Add(maxRetryDeadline)
+	for {
+		// Get the newest version of the node.
+		freshNode, err := client.CoreV1().Nodes().Get(node.Name, metav1.GetOptions{})
+		if err != nil || freshNode == nil {
+			return fmt.Errorf("failed to get node %v: %v", node.Name, err)
+		}
+
+		if !addTaintToSpec(freshNode, taintKey, effect) {
+			return nil
+		}
+		_, err = client.CoreV1().Nodes().Update(freshNode)
+		if err != nil && kube_errors.IsConflict(err) && time.Now().Before(retryDeadline) {
+			glog.V(2).Infof("Conflict while adding %v taint on node %v, retrying", taintKey, node.Name)
+			time.Sleep(conflictRetryInterval)
+			continue
+		}
+		if err != nil {
+			glog.Warningf("Error while adding %v taint on node %v: %v", taintKey, node.Name, err)
+			return err
+		}
+		glog.V(1).Infof("Successfully added %v taint on node %v", taintKey, node.Name)
+		return nil
 	}
-	glog.V(1).Infof("Successfully added toBeDeletedTaint on node %v", node.Name)
-	return nil
 }
 
 func addToBeDeletedTaint(node *apiv1.Node) (bool, error) {
+	return addTaintToSpec(node, ToBeDeletedTaint, apiv1.TaintEffectNoSchedule), nil
+}
+
+func addTaintToSpec(node *apiv1.Node, taintKey string, effect apiv1.TaintEffect) bool {
 	for _, taint := range node.Spec.Taints {
-		if taint.Key == ToBeDeletedTaint {
-			glog.V(2).Infof("ToBeDeletedTaint already present on node %v, taint: %v", node.Name, taint)
-			return false, nil
+		if taint.Key == taintKey {
+			glog.V(2).Infof("%v already present on node %v, taint: %v", taintKey, node.Name, taint)
+			return false
 		}
 	}
 	node.Spec.Taints = append(node.Spec.Taints, apiv1.Taint{
-		Key:    ToBeDeletedTaint,
+		Key:    taintKey,
 		Value:  fmt.Sprint(time.Now().Unix()),
-		Effect: apiv1.TaintEffectNoSchedule,
+		Effect: effect,
 	})
-	return true, nil
+	return true
 }
 
 // HasToBeDeletedTaint returns true if ToBeDeleted taint is applied on the node.
 func HasToBeDeletedTaint(node *apiv1.Node) bool {
+	return hasTaint(node, ToBeDeletedTaint)
+}
+
+// HasDeletionCandidateTaint returns true if DeletionCandidate taint is applied on the node.
+func HasDeletionCandidateTaint(node *apiv1.Node) bool {
+	return hasTaint(node, DeletionCandidateTaint)
+}
+
+func hasTaint(node *apiv1.Node, taintKey string) bool {
 	for _, taint := range node.Spec.Taints {
-		if taint.Key == ToBeDeletedTaint {
+		if taint.Key == taintKey {
 			return true
 		}
 	}
@@ -81,8 +121,17 @@ func HasToBeDeletedTaint(node *apiv1.Node) bool {
 
 // GetToBeDeletedTime returns the date when the node was marked by CA as for delete.
 func GetToBeDeletedTime(node *apiv1.Node) (*time.Time, error) {
+	return getTaintTime(node, ToBeDeletedTaint)
+}
+
+// GetDeletionCandidateTime returns the date when the node was marked by CA as a deletion candidate.
+func GetDeletionCandidateTime(node *apiv1.Node) (*time.Time, error) {
+	return getTaintTime(node, DeletionCandidateTaint)
+}
+
+func getTaintTime(node *apiv1.Node, taintKey string) (*time.Time, error) {
 	for _, taint := range node.Spec.Taints {
-		if taint.Key == ToBeDeletedTaint {
+		if taint.Key == taintKey {
 			resultTimestamp, err := strconv.ParseInt(taint.Value, 10, 64)
 			if err != nil {
 				return nil, err
@@ -96,28 +145,47 @@ func GetToBeDeletedTime(node *apiv1.Node) (*time.Time, error) {
 
 // CleanToBeDeleted cleans ToBeDeleted taint.
 func CleanToBeDeleted(node *apiv1.Node, client kube_client.Interface) (bool, error) {
-	freshNode, err := client.CoreV1().Nodes().Get(node.Name, metav1.GetOptions{})
-	if err != nil || freshNode == nil {
-		return false, fmt.Errorf("failed to get node %v: %v", node.Name, err)
-	}
-	newTaints := make([]apiv1.Taint, 0)
-	for _, taint := range freshNode.Spec.Taints {
-		if taint.Key == ToBeDeletedTaint {
-			glog.V(1).Infof("Releasing taint %+v on node %v", taint, node.Name)
-		} else {
-			newTaints = append(newTaints, taint)
+	return cleanTaint(node, client, ToBeDeletedTaint)
+}
+
+// CleanDeletionCandidate cleans DeletionCandidate taint.
+func CleanDeletionCandidate(node *apiv1.Node, client kube_client.Interface) (bool, error) {
+	return cleanTaint(node, client, DeletionCandidateTaint)
+}
+
+// cleanTaint removes the taint from the newest version of the node, retrying on conflicts
+// like addTaint. Returns true if the node was changed.
+func cleanTaint(node *apiv1.Node, client kube_client.Interface, taintKey string) (bool, error) {
+	retryDeadline := time.Now().Add(maxRetryDeadline)
+	for {
+		freshNode, err := client.CoreV1().Nodes().Get(node.Name, metav1.GetOptions{})
+		if err != nil || freshNode == nil {
+			return false, fmt.Errorf("failed to get node %v: %v", node.Name, err)
+		}
+		newTaints := make([]apiv1.Taint, 0)
+		for _, taint := range freshNode.Spec.Taints {
+			if taint.Key == taintKey {
+				glog.V(1).Infof("Releasing taint %+v on node %v", taint, node.Name)
+			} else {
+				newTaints = append(newTaints, taint)
+			}
+		}
+		if len(newTaints) == len(freshNode.Spec.Taints) {
+			return false, nil
 		}
-	}
 
-	if len(newTaints) != len(freshNode.Spec.Taints) {
 		freshNode.Spec.Taints = newTaints
-		_, err := client.CoreV1().Nodes().Update(freshNode)
+		_, err = client.CoreV1().Nodes().Update(freshNode)
+		if err != nil && kube_errors.IsConflict(err) && time.Now().Before(retryDeadline) {
+			glog.V(2).Infof("Conflict while releasing %v taint on node %v, retrying", taintKey, node.Name)
+			time.Sleep(conflictRetryInterval)
+			continue
+		}
 		if err != nil {
-			glog.Warningf("Error while releasing taints on node %v: %v", node.Name, err)
+			glog.Warningf("Error while releasing %v taint on node %v: %v", taintKey, node.Name, err)
 			return false, err
 		}
-		glog.V(1).Infof("Successfully released toBeDeletedTaint on node %v", node.Name)
+		glog.V(1).Infof("Successfully released %v taint on node %v", taintKey, node.Name)
 		return true, nil
 	}
-	return false, nil
 }
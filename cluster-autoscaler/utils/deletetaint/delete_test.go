@@ -65,6 +65,66 @@ func TestCleanNodes(t *testing.T) {
 	assert.False(t, HasToBeDeletedTaint(node))
 }
 
+func TestSoftMarkNodes(t *testing.T) {
+	node := BuildTestNode("node", 1000, 1000)
+	fakeClient, updatedNodes := buildFakeClientAndUpdateChannel(node)
+	err := MarkDeletionCandidate(node, fakeClient)
+	assert.NoError(t, err)
+	assert.Equal(t, node.Name, getStringFromChan(updatedNodes))
+	assert.True(t, HasDeletionCandidateTaint(node))
+	assert.False(t, HasToBeDeletedTaint(node))
+	assert.Equal(t, apiv1.TaintEffectPreferNoSchedule, node.Spec.Taints[0].Effect)
+
+	val, err := GetDeletionCandidateTime(node)
+	assert.NoError(t, err)
+	assert.True(t, time.Now().Sub(*val) < 10*time.Second)
+
+	// Marking the node again doesn't update it.
+	err = MarkDeletionCandidate(node, fakeClient)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(updatedNodes))
+}
+
+func TestSoftCleanNodes(t *testing.T) {
+	node := BuildTestNode("node", 1000, 1000)
+	addTaintToSpec(node, DeletionCandidateTaint, apiv1.TaintEffectPreferNoSchedule)
+	addToBeDeletedTaint(node)
+	fakeClient, updatedNodes := buildFakeClientAndUpdateChannel(node)
+
+	cleaned, err := CleanDeletionCandidate(node, fakeClient)
+	assert.True(t, cleaned)
+	assert.NoError(t, err)
+	assert.Equal(t, node.Name, getStringFromChan(updatedNodes))
+	assert.False(t, HasDeletionCandidateTaint(node))
+	assert.True(t, HasToBeDeletedTaint(node))
+
+	cleaned, err = CleanDeletionCandidate(node, fakeClient)
+	assert.False(t, cleaned)
+	assert.NoError(t, err)
+}
+
+func TestMarkNodesRetriesOnConflict(t *testing.T) {
+	conflictRetryInterval = time.Millisecond
+	node := BuildTestNode("node", 1000, 1000)
+	fakeClient, updatedNodes := buildFakeClientAndUpdateChannel(node)
+	conflicts := 2
+	fakeClient.Fake.PrependReactor("update", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			conflicts--
+			// Simulate a concurrent write that came first.
+			node.Spec.Taints = nil
+			return true, nil, errors.NewConflict(apiv1.Resource("node"), node.Name, nil)
+		}
+		return false, nil, nil
+	})
+
+	err := MarkDeletionCandidate(node, fakeClient)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, conflicts)
+	assert.Equal(t, node.Name, getStringFromChan(updatedNodes))
+	assert.True(t, HasDeletionCandidateTaint(node))
+}
+
 func buildFakeClientAndUpdateChannel(node *apiv1.Node) (*fake.Clientset, chan string) {
 	fakeClient := &fake.Clientset{}
 	updatedNodes := make(chan string, 10)
@@ -0,0 +1,199 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	client "k8s.io/client-go/kubernetes"
+)
+
+// Ownership describes what happens to a pod once it is evicted, as decided by its controller.
+type Ownership string
+
+const (
+	// UnknownOwnership - the resolver doesn't know the controller of the pod.
+	UnknownOwnership Ownership = ""
+	// ReplicatedOwnership - the controller recreates the pod elsewhere once it is evicted.
+	ReplicatedOwnership Ownership = "Replicated"
+	// DaemonSetOwnership - the pod runs on every node and doesn't need to be moved.
+	DaemonSetOwnership Ownership = "DaemonSet"
+)
+
+// ControllerResolver decides whether the controller of a pod takes care of the pod once it is evicted.
+type ControllerResolver interface {
+	// Resolve returns the ownership of a pod controlled by controllerRef, or UnknownOwnership if the
+	// kind of the controller is not handled by this resolver. If checkReferences is true, the resolver
+	// may use client to verify that the controller still exists and has at least minReplica replicas.
+	// If the controller makes the pod unmovable, the blocking pod is returned along with the error.
+	Resolve(pod *apiv1.Pod, controllerRef *metav1.OwnerReference, checkReferences bool, client client.Interface,
+		minReplica int32) (Ownership, *BlockingPod, error)
+}
+
+// NewBuiltinControllerResolver returns a resolver handling the built-in ReplicationController, DaemonSet,
+// Job, ReplicaSet and StatefulSet controllers.
+func NewBuiltinControllerResolver() ControllerResolver {
+	return builtinControllerResolver{}
+}
+
+type builtinControllerResolver struct{}
+
+// Resolve implements ControllerResolver.
+func (builtinControllerResolver) Resolve(pod *apiv1.Pod, controllerRef *metav1.OwnerReference, checkReferences bool,
+	client client.Interface, minReplica int32) (Ownership, *BlockingPod, error) {
+
+	// For now, owner controller must be in the same namespace as the pod
+	// so OwnerReference doesn't have its own Namespace field
+	controllerNamespace := pod.Namespace
+
+	switch controllerRef.Kind {
+	case "ReplicationController":
+		if checkReferences {
+			rc, err := client.CoreV1().ReplicationControllers(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
+			// Assume a reason for an error is because the RC is either
+			// gone/missing or that the rc has too few replicas configured.
+			// TODO: replace the minReplica check with pod disruption budget.
+			if err == nil && rc != nil {
+				if rc.Spec.Replicas != nil && *rc.Spec.Replicas < minReplica {
+					return UnknownOwnership, &BlockingPod{Pod: pod, Reason: MinReplicasReached}, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d",
+						pod.Namespace, pod.Name, rc.Spec.Replicas, minReplica)
+				}
+			} else {
+				return UnknownOwnership, &BlockingPod{Pod: pod, Reason: ControllerNotFound}, fmt.Errorf("replication controller for %s/%s is not available, err: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+		return ReplicatedOwnership, nil, nil
+	case "DaemonSet":
+		if checkReferences {
+			ds, err := client.ExtensionsV1beta1().DaemonSets(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
+
+			// Assume the only reason for an error is because the DaemonSet is
+			// gone/missing, not for any other cause.  TODO(mml): something more
+			// sophisticated than this
+			if err != nil || ds == nil {
+				return UnknownOwnership, &BlockingPod{Pod: pod, Reason: ControllerNotFound}, fmt.Errorf("daemonset for %s/%s is not present, err: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+		// Otherwise, treat daemonset-managed pods as unmanaged since
+		// DaemonSet Controller currently ignores the unschedulable bit.
+		// FIXME(mml): Add link to the issue concerning a proper way to drain
+		// daemonset pods, probably using taints.
+		return DaemonSetOwnership, nil, nil
+	case "Job":
+		if checkReferences {
+			job, err := client.BatchV1().Jobs(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
+
+			// Assume the only reason for an error is because the Job is
+			// gone/missing, not for any other cause.  TODO(mml): something more
+			// sophisticated than this
+			if err != nil || job == nil {
+				return UnknownOwnership, &BlockingPod{Pod: pod, Reason: ControllerNotFound}, fmt.Errorf("job for %s/%s is not available: err: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+		return ReplicatedOwnership, nil, nil
+	case "ReplicaSet":
+		if checkReferences {
+			rs, err := client.ExtensionsV1beta1().ReplicaSets(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
+
+			// Assume the only reason for an error is because the RS is
+			// gone/missing, not for any other cause.  TODO(mml): something more
+			// sophisticated than this
+			if err == nil && rs != nil {
+				if rs.Spec.Replicas != nil && *rs.Spec.Replicas < minReplica {
+					return UnknownOwnership, &BlockingPod{Pod: pod, Reason: MinReplicasReached}, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d",
+						pod.Namespace, pod.Name, rs.Spec.Replicas, minReplica)
+				}
+			} else {
+				return UnknownOwnership, &BlockingPod{Pod: pod, Reason: ControllerNotFound}, fmt.Errorf("replication controller for %s/%s is not available, err: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+		return ReplicatedOwnership, nil, nil
+	case "StatefulSet":
+		if checkReferences {
+			ss, err := client.AppsV1beta1().StatefulSets(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
+
+			// Assume the only reason for an error is because the StatefulSet is
+			// gone/missing, not for any other cause.  TODO(mml): something more
+			// sophisticated than this
+			if err != nil || ss == nil {
+				return UnknownOwnership, &BlockingPod{Pod: pod, Reason: ControllerNotFound}, fmt.Errorf("statefulset for %s/%s is not available: err: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+		return ReplicatedOwnership, nil, nil
+	}
+	return UnknownOwnership, nil, nil
+}
+
+// NewReplicatedKindsResolver returns a resolver treating pods controlled by any of the given kinds as
+// replicated. Kinds are given as "Kind.group" (e.g. "Workflow.argoproj.io"), or as "Kind" to match
+// the kind in any API group. The controllers are not looked up, even if references are checked.
+func NewReplicatedKindsResolver(kinds []string) (ControllerResolver, error) {
+	resolver := replicatedKindsResolver{kinds: make(map[schema.GroupKind]bool)}
+	for _, kind := range kinds {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		groupKind := schema.ParseGroupKind(kind)
+		if groupKind.Kind == "" {
+			return nil, fmt.Errorf("invalid controller kind %q", kind)
+		}
+		resolver.kinds[groupKind] = true
+	}
+	return resolver, nil
+}
+
+type replicatedKindsResolver struct {
+	kinds map[schema.GroupKind]bool
+}
+
+// Resolve implements ControllerResolver.
+func (r replicatedKindsResolver) Resolve(pod *apiv1.Pod, controllerRef *metav1.OwnerReference, checkReferences bool,
+	client client.Interface, minReplica int32) (Ownership, *BlockingPod, error) {
+	groupKind := schema.GroupKind{Kind: controllerRef.Kind}
+	if gv, err := schema.ParseGroupVersion(controllerRef.APIVersion); err == nil {
+		groupKind.Group = gv.Group
+	}
+	if r.kinds[groupKind] || r.kinds[schema.GroupKind{Kind: controllerRef.Kind}] {
+		return ReplicatedOwnership, nil, nil
+	}
+	return UnknownOwnership, nil, nil
+}
+
+// NewChainControllerResolver returns a resolver asking the given resolvers in order and returning the
+// first known ownership.
+func NewChainControllerResolver(resolvers ...ControllerResolver) ControllerResolver {
+	return chainControllerResolver(resolvers)
+}
+
+type chainControllerResolver []ControllerResolver
+
+// Resolve implements ControllerResolver.
+func (c chainControllerResolver) Resolve(pod *apiv1.Pod, controllerRef *metav1.OwnerReference, checkReferences bool,
+	client client.Interface, minReplica int32) (Ownership, *BlockingPod, error) {
+	for _, resolver := range c {
+		ownership, blockingPod, err := resolver.Resolve(pod, controllerRef, checkReferences, client, minReplica)
+		if err != nil || ownership != UnknownOwnership {
+			return ownership, blockingPod, err
+		}
+	}
+	return UnknownOwnership, nil, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"testing"
+
+	. "github.com/gardener/autoscaler/cluster-autoscaler/utils/test"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildControlledPod(kind, apiVersion string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pod",
+			Namespace:       "default",
+			OwnerReferences: GenerateOwnerReferences("owner", kind, apiVersion, ""),
+		},
+	}
+}
+
+func TestBuiltinControllerResolver(t *testing.T) {
+	resolver := NewBuiltinControllerResolver()
+	for kind, expected := range map[string]Ownership{
+		"ReplicationController": ReplicatedOwnership,
+		"DaemonSet":             DaemonSetOwnership,
+		"Job":                   ReplicatedOwnership,
+		"ReplicaSet":            ReplicatedOwnership,
+		"StatefulSet":           ReplicatedOwnership,
+		"Workflow":              UnknownOwnership,
+	} {
+		pod := buildControlledPod(kind, "v1")
+		ownership, blockingPod, err := resolver.Resolve(pod, ControllerRef(pod), false, nil, 0)
+		assert.NoError(t, err, kind)
+		assert.Nil(t, blockingPod, kind)
+		assert.Equal(t, expected, ownership, kind)
+	}
+}
+
+func TestReplicatedKindsResolver(t *testing.T) {
+	resolver, err := NewReplicatedKindsResolver([]string{"Workflow.argoproj.io", " Etcd ", ""})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		kind       string
+		apiVersion string
+		expected   Ownership
+	}{
+		{"Workflow", "argoproj.io/v1alpha1", ReplicatedOwnership},
+		{"Workflow", "example.com/v1", UnknownOwnership},
+		{"Etcd", "druid.gardener.cloud/v1alpha1", ReplicatedOwnership},
+		{"Etcd", "v1", ReplicatedOwnership},
+		{"ReplicaSet", "extensions/v1beta1", UnknownOwnership},
+	}
+	for _, tc := range testCases {
+		pod := buildControlledPod(tc.kind, tc.apiVersion)
+		ownership, blockingPod, err := resolver.Resolve(pod, ControllerRef(pod), true, nil, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, blockingPod)
+		assert.Equal(t, tc.expected, ownership, "%s %s", tc.apiVersion, tc.kind)
+	}
+
+	_, err = NewReplicatedKindsResolver([]string{".argoproj.io"})
+	assert.Error(t, err)
+}
+
+func TestChainControllerResolver(t *testing.T) {
+	custom, err := NewReplicatedKindsResolver([]string{"Workflow.argoproj.io", "DaemonSet"})
+	assert.NoError(t, err)
+	resolver := NewChainControllerResolver(NewBuiltinControllerResolver(), custom)
+
+	pod := buildControlledPod("Workflow", "argoproj.io/v1alpha1")
+	ownership, _, err := resolver.Resolve(pod, ControllerRef(pod), false, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ReplicatedOwnership, ownership)
+
+	// The first resolver knowing the kind wins.
+	pod = buildControlledPod("DaemonSet", "extensions/v1beta1")
+	ownership, _, err = resolver.Resolve(pod, ControllerRef(pod), false, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, DaemonSetOwnership, ownership)
+
+	pod = buildControlledPod("Unknown", "example.com/v1")
+	ownership, _, err = resolver.Resolve(pod, ControllerRef(pod), false, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, UnknownOwnership, ownership)
+}
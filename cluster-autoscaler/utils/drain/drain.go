@@ -33,14 +33,53 @@ const (
 	PodDeletionTimeout = 12 * time.Minute
 )
 
+var defaultControllerResolver = NewBuiltinControllerResolver()
+
 const (
 	// PodSafeToEvictKey - annotation that ignores constraints to evict a pod like not being replicated, being on
 	// kube-system namespace or having a local storage.
 	PodSafeToEvictKey = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+	// PodEvictionGracePeriodKey - annotation overriding the grace period, in seconds, given to a pod evicted
+	// during scale down.
+	PodEvictionGracePeriodKey = "cluster-autoscaler.kubernetes.io/eviction-grace-period-seconds"
 )
 
+// BlockingPodReason describes why a pod prevents its node from being drained.
+type BlockingPodReason string
+
+const (
+	// ControllerNotFound - pod is blocking scale down because its controller can't be found.
+	ControllerNotFound BlockingPodReason = "ControllerNotFound"
+	// MinReplicasReached - pod is blocking scale down because its controller already has the minimum number of replicas.
+	MinReplicasReached BlockingPodReason = "MinReplicasReached"
+	// NotReplicated - pod is blocking scale down because it's not replicated.
+	NotReplicated BlockingPodReason = "NotReplicated"
+	// LocalStorageRequested - pod is blocking scale down because it requests local storage.
+	LocalStorageRequested BlockingPodReason = "LocalStorageRequested"
+	// NotSafeToEvictAnnotation - pod is blocking scale down because it has "not safe to evict" annotation.
+	NotSafeToEvictAnnotation BlockingPodReason = "NotSafeToEvictAnnotation"
+	// UnmovableKubeSystemPod - pod is blocking scale down because it's a non-daemonset, non-mirrored, non-pdb-assigned kube-system pod.
+	UnmovableKubeSystemPod BlockingPodReason = "UnmovableKubeSystemPod"
+	// NotEnoughPdb - pod is blocking scale down because it doesn't have enough PDB left.
+	NotEnoughPdb BlockingPodReason = "NotEnoughPdb"
+	// UnexpectedError - pod is blocking scale down because of an unexpected error.
+	UnexpectedError BlockingPodReason = "UnexpectedError"
+)
+
+// BlockingPod represents a pod which is blocking the scale down of a node.
+type BlockingPod struct {
+	Pod    *apiv1.Pod
+	Reason BlockingPodReason
+}
+
+// String returns a human readable description of the blocking pod.
+func (b *BlockingPod) String() string {
+	return fmt.Sprintf("%s/%s (%s)", b.Pod.Namespace, b.Pod.Name, b.Reason)
+}
+
 // GetPodsForDeletionOnNodeDrain returns pods that should be deleted on node drain as well as some extra information
-// about possibly problematic pods (unreplicated and daemonsets).
+// about possibly problematic pods (unreplicated and daemonsets). If the node can't be drained, the pod that blocks
+// the drain is returned along with the error. The policy may relax the default rules; it can be nil.
 func GetPodsForDeletionOnNodeDrain(
 	podList []*apiv1.Pod,
 	pdbs []*policyv1.PodDisruptionBudget,
@@ -50,7 +89,8 @@ func GetPodsForDeletionOnNodeDrain(
 	checkReferences bool, // Setting this to true requires client to be not-null.
 	client client.Interface,
 	minReplica int32,
-	currentTime time.Time) ([]*apiv1.Pod, error) {
+	currentTime time.Time,
+	policy *Policy) ([]*apiv1.Pod, *BlockingPod, error) {
 
 	pods := []*apiv1.Pod{}
 	// filter kube-system PDBs to avoid doing it for every kube-system pod
@@ -60,6 +100,7 @@ func GetPodsForDeletionOnNodeDrain(
 			kubeSystemPDBs = append(kubeSystemPDBs, pdb)
 		}
 	}
+	resolver := policy.controllerResolver()
 
 	for _, pod := range podList {
 		if IsMirrorPod(pod) {
@@ -74,136 +115,45 @@ func GetPodsForDeletionOnNodeDrain(
 			continue
 		}
 
-		daemonsetPod := false
-		replicated := false
-		safeToEvict := hasSafeToEvictAnnotation(pod)
+		safeToEvict := policy.safeToEvict(pod)
 		terminal := isPodTerminal(pod)
 
-		controllerRef := ControllerRef(pod)
-		refKind := ""
-		if controllerRef != nil {
-			refKind = controllerRef.Kind
-		}
-
-		// For now, owner controller must be in the same namespace as the pod
-		// so OwnerReference doesn't have its own Namespace field
-		controllerNamespace := pod.Namespace
-
-		if refKind == "ReplicationController" {
-			if checkReferences {
-				rc, err := client.CoreV1().ReplicationControllers(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
-				// Assume a reason for an error is because the RC is either
-				// gone/missing or that the rc has too few replicas configured.
-				// TODO: replace the minReplica check with pod disruption budget.
-				if err == nil && rc != nil {
-					if rc.Spec.Replicas != nil && *rc.Spec.Replicas < minReplica {
-						return []*apiv1.Pod{}, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d",
-							pod.Namespace, pod.Name, rc.Spec.Replicas, minReplica)
-					}
-					replicated = true
-
-				} else {
-					return []*apiv1.Pod{}, fmt.Errorf("replication controller for %s/%s is not available, err: %v", pod.Namespace, pod.Name, err)
-				}
-			} else {
-				replicated = true
-			}
-		} else if refKind == "DaemonSet" {
-			if checkReferences {
-				ds, err := client.ExtensionsV1beta1().DaemonSets(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
-
-				// Assume the only reason for an error is because the DaemonSet is
-				// gone/missing, not for any other cause.  TODO(mml): something more
-				// sophisticated than this
-				if err == nil && ds != nil {
-					// Otherwise, treat daemonset-managed pods as unmanaged since
-					// DaemonSet Controller currently ignores the unschedulable bit.
-					// FIXME(mml): Add link to the issue concerning a proper way to drain
-					// daemonset pods, probably using taints.
-					daemonsetPod = true
-				} else {
-					return []*apiv1.Pod{}, fmt.Errorf("daemonset for %s/%s is not present, err: %v", pod.Namespace, pod.Name, err)
-				}
-			} else {
-				daemonsetPod = true
-			}
-		} else if refKind == "Job" {
-			if checkReferences {
-				job, err := client.BatchV1().Jobs(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
-
-				// Assume the only reason for an error is because the Job is
-				// gone/missing, not for any other cause.  TODO(mml): something more
-				// sophisticated than this
-				if err == nil && job != nil {
-					replicated = true
-				} else {
-					return []*apiv1.Pod{}, fmt.Errorf("job for %s/%s is not available: err: %v", pod.Namespace, pod.Name, err)
-				}
-			} else {
-				replicated = true
-			}
-		} else if refKind == "ReplicaSet" {
-			if checkReferences {
-				rs, err := client.ExtensionsV1beta1().ReplicaSets(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
-
-				// Assume the only reason for an error is because the RS is
-				// gone/missing, not for any other cause.  TODO(mml): something more
-				// sophisticated than this
-				if err == nil && rs != nil {
-					if rs.Spec.Replicas != nil && *rs.Spec.Replicas < minReplica {
-						return []*apiv1.Pod{}, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d",
-							pod.Namespace, pod.Name, rs.Spec.Replicas, minReplica)
-					}
-					replicated = true
-				} else {
-					return []*apiv1.Pod{}, fmt.Errorf("replication controller for %s/%s is not available, err: %v", pod.Namespace, pod.Name, err)
-				}
-			} else {
-				replicated = true
-			}
-		} else if refKind == "StatefulSet" {
-			if checkReferences {
-				ss, err := client.AppsV1beta1().StatefulSets(controllerNamespace).Get(controllerRef.Name, metav1.GetOptions{})
-
-				// Assume the only reason for an error is because the StatefulSet is
-				// gone/missing, not for any other cause.  TODO(mml): something more
-				// sophisticated than this
-				if err == nil && ss != nil {
-					replicated = true
-				} else {
-					return []*apiv1.Pod{}, fmt.Errorf("statefulset for %s/%s is not available: err: %v", pod.Namespace, pod.Name, err)
-				}
-			} else {
-				replicated = true
+		ownership := UnknownOwnership
+		if controllerRef := ControllerRef(pod); controllerRef != nil {
+			var blockingPod *BlockingPod
+			var err error
+			ownership, blockingPod, err = resolver.Resolve(pod, controllerRef, checkReferences, client, minReplica)
+			if err != nil {
+				return []*apiv1.Pod{}, blockingPod, err
 			}
 		}
-		if daemonsetPod {
+		if ownership == DaemonSetOwnership {
 			continue
 		}
 
 		if !deleteAll && !safeToEvict && !terminal {
-			if !replicated {
-				return []*apiv1.Pod{}, fmt.Errorf("%s/%s is not replicated", pod.Namespace, pod.Name)
+			if ownership != ReplicatedOwnership {
+				return []*apiv1.Pod{}, &BlockingPod{Pod: pod, Reason: NotReplicated}, fmt.Errorf("%s/%s is not replicated", pod.Namespace, pod.Name)
 			}
 			if pod.Namespace == "kube-system" && skipNodesWithSystemPods {
 				hasPDB, err := checkKubeSystemPDBs(pod, kubeSystemPDBs)
 				if err != nil {
-					return []*apiv1.Pod{}, fmt.Errorf("error matching pods to pdbs: %v", err)
+					return []*apiv1.Pod{}, &BlockingPod{Pod: pod, Reason: UnexpectedError}, fmt.Errorf("error matching pods to pdbs: %v", err)
 				}
 				if !hasPDB {
-					return []*apiv1.Pod{}, fmt.Errorf("non-daemonset, non-mirrored, non-pdb-assigned kube-system pod present: %s", pod.Name)
+					return []*apiv1.Pod{}, &BlockingPod{Pod: pod, Reason: UnmovableKubeSystemPod}, fmt.Errorf("non-daemonset, non-mirrored, non-pdb-assigned kube-system pod present: %s", pod.Name)
 				}
 			}
-			if HasLocalStorage(pod) && skipNodesWithLocalStorage {
-				return []*apiv1.Pod{}, fmt.Errorf("pod with local storage present: %s", pod.Name)
+			if skipNodesWithLocalStorage && policy.hasUnsafeLocalStorage(pod) {
+				return []*apiv1.Pod{}, &BlockingPod{Pod: pod, Reason: LocalStorageRequested}, fmt.Errorf("pod with local storage present: %s", pod.Name)
 			}
 			if hasNotSafeToEvictAnnotation(pod) {
-				return []*apiv1.Pod{}, fmt.Errorf("pod annotated as not safe to evict present: %s", pod.Name)
+				return []*apiv1.Pod{}, &BlockingPod{Pod: pod, Reason: NotSafeToEvictAnnotation}, fmt.Errorf("pod annotated as not safe to evict present: %s", pod.Name)
 			}
 		}
 		pods = append(pods, pod)
 	}
-	return pods, nil
+	return pods, nil, nil
 }
 
 // ControllerRef returns the OwnerReference to pod's controller.
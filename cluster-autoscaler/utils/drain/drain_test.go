@@ -32,6 +32,8 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 	"k8s.io/kubernetes/pkg/api/testapi"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDrain(t *testing.T) {
@@ -351,13 +353,14 @@ func TestDrain(t *testing.T) {
 	}
 
 	tests := []struct {
-		description string
-		pods        []*apiv1.Pod
-		pdbs        []*policyv1.PodDisruptionBudget
-		rcs         []apiv1.ReplicationController
-		replicaSets []extensions.ReplicaSet
-		expectFatal bool
-		expectPods  []*apiv1.Pod
+		description       string
+		pods              []*apiv1.Pod
+		pdbs              []*policyv1.PodDisruptionBudget
+		rcs               []apiv1.ReplicationController
+		replicaSets       []extensions.ReplicaSet
+		expectFatal       bool
+		expectPods        []*apiv1.Pod
+		expectBlockingPod *BlockingPod
 	}{
 		{
 			description: "RC-managed pod",
@@ -409,18 +412,20 @@ func TestDrain(t *testing.T) {
 			expectPods:  []*apiv1.Pod{},
 		},
 		{
-			description: "naked pod",
-			pods:        []*apiv1.Pod{nakedPod},
-			pdbs:        []*policyv1.PodDisruptionBudget{},
-			expectFatal: true,
-			expectPods:  []*apiv1.Pod{},
+			description:       "naked pod",
+			pods:              []*apiv1.Pod{nakedPod},
+			pdbs:              []*policyv1.PodDisruptionBudget{},
+			expectFatal:       true,
+			expectPods:        []*apiv1.Pod{},
+			expectBlockingPod: &BlockingPod{Pod: nakedPod, Reason: NotReplicated},
 		},
 		{
-			description: "pod with EmptyDir",
-			pods:        []*apiv1.Pod{emptydirPod},
-			pdbs:        []*policyv1.PodDisruptionBudget{},
-			expectFatal: true,
-			expectPods:  []*apiv1.Pod{},
+			description:       "pod with EmptyDir",
+			pods:              []*apiv1.Pod{emptydirPod},
+			pdbs:              []*policyv1.PodDisruptionBudget{},
+			expectFatal:       true,
+			expectPods:        []*apiv1.Pod{},
+			expectBlockingPod: &BlockingPod{Pod: emptydirPod, Reason: NotReplicated},
 		},
 		{
 			description: "failed pod",
@@ -465,20 +470,22 @@ func TestDrain(t *testing.T) {
 			expectPods:  []*apiv1.Pod{emptydirSafePod},
 		},
 		{
-			description: "RC-managed pod with PodSafeToEvict=false annotation",
-			pods:        []*apiv1.Pod{unsafeRcPod},
-			rcs:         []apiv1.ReplicationController{rc},
-			pdbs:        []*policyv1.PodDisruptionBudget{},
-			expectFatal: true,
-			expectPods:  []*apiv1.Pod{},
+			description:       "RC-managed pod with PodSafeToEvict=false annotation",
+			pods:              []*apiv1.Pod{unsafeRcPod},
+			rcs:               []apiv1.ReplicationController{rc},
+			pdbs:              []*policyv1.PodDisruptionBudget{},
+			expectFatal:       true,
+			expectPods:        []*apiv1.Pod{},
+			expectBlockingPod: &BlockingPod{Pod: unsafeRcPod, Reason: NotSafeToEvictAnnotation},
 		},
 		{
-			description: "Job-managed pod with PodSafeToEvict=false annotation",
-			pods:        []*apiv1.Pod{unsafeJobPod},
-			pdbs:        []*policyv1.PodDisruptionBudget{},
-			rcs:         []apiv1.ReplicationController{rc},
-			expectFatal: true,
-			expectPods:  []*apiv1.Pod{},
+			description:       "Job-managed pod with PodSafeToEvict=false annotation",
+			pods:              []*apiv1.Pod{unsafeJobPod},
+			pdbs:              []*policyv1.PodDisruptionBudget{},
+			rcs:               []apiv1.ReplicationController{rc},
+			expectFatal:       true,
+			expectPods:        []*apiv1.Pod{},
+			expectBlockingPod: &BlockingPod{Pod: unsafeJobPod, Reason: NotSafeToEvictAnnotation},
 		},
 		{
 			description: "empty PDB with RC-managed pod",
@@ -497,12 +504,13 @@ func TestDrain(t *testing.T) {
 			expectPods:  []*apiv1.Pod{kubeSystemRcPod},
 		},
 		{
-			description: "kube-system PDB with non-matching kube-system pod",
-			pods:        []*apiv1.Pod{kubeSystemRcPod},
-			pdbs:        []*policyv1.PodDisruptionBudget{kubeSystemFakePDB},
-			rcs:         []apiv1.ReplicationController{rc},
-			expectFatal: true,
-			expectPods:  []*apiv1.Pod{},
+			description:       "kube-system PDB with non-matching kube-system pod",
+			pods:              []*apiv1.Pod{kubeSystemRcPod},
+			pdbs:              []*policyv1.PodDisruptionBudget{kubeSystemFakePDB},
+			rcs:               []apiv1.ReplicationController{rc},
+			expectFatal:       true,
+			expectPods:        []*apiv1.Pod{},
+			expectBlockingPod: &BlockingPod{Pod: kubeSystemRcPod, Reason: UnmovableKubeSystemPod},
 		},
 		{
 			description: "kube-system PDB with default namespace pod",
@@ -513,12 +521,13 @@ func TestDrain(t *testing.T) {
 			expectPods:  []*apiv1.Pod{rcPod},
 		},
 		{
-			description: "default namespace PDB with matching labels kube-system pod",
-			pods:        []*apiv1.Pod{kubeSystemRcPod},
-			pdbs:        []*policyv1.PodDisruptionBudget{defaultNamespacePDB},
-			rcs:         []apiv1.ReplicationController{rc},
-			expectFatal: true,
-			expectPods:  []*apiv1.Pod{},
+			description:       "default namespace PDB with matching labels kube-system pod",
+			pods:              []*apiv1.Pod{kubeSystemRcPod},
+			pdbs:              []*policyv1.PodDisruptionBudget{defaultNamespacePDB},
+			rcs:               []apiv1.ReplicationController{rc},
+			expectFatal:       true,
+			expectPods:        []*apiv1.Pod{},
+			expectBlockingPod: &BlockingPod{Pod: kubeSystemRcPod, Reason: UnmovableKubeSystemPod},
 		},
 	}
 
@@ -543,8 +552,8 @@ func TestDrain(t *testing.T) {
 		if len(test.replicaSets) > 0 {
 			register("replicasets", &test.replicaSets[0], test.replicaSets[0].ObjectMeta)
 		}
-		pods, err := GetPodsForDeletionOnNodeDrain(test.pods, test.pdbs,
-			false, true, true, true, fakeClient, 0, time.Now())
+		pods, blockingPod, err := GetPodsForDeletionOnNodeDrain(test.pods, test.pdbs,
+			false, true, true, true, fakeClient, 0, time.Now(), nil)
 
 		if test.expectFatal {
 			if err == nil {
@@ -561,5 +570,7 @@ func TestDrain(t *testing.T) {
 		if len(pods) != len(test.expectPods) {
 			t.Fatalf("Wrong pod list content: %v", test.description)
 		}
+
+		assert.Equal(t, test.expectBlockingPod, blockingPod, test.description)
 	}
 }
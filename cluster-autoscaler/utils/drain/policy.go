@@ -0,0 +1,171 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PodSafeToEvictLocalVolumesKey - annotation listing, comma separated, the names of emptyDir volumes whose
+	// data may be lost when the pod is evicted. It can be set on pods and on namespaces.
+	PodSafeToEvictLocalVolumesKey = "cluster-autoscaler.kubernetes.io/safe-to-evict-local-volumes"
+	// DrainPolicyConfigMapKey is the key holding the namespace policies in the drain policy ConfigMap.
+	DrainPolicyConfigMapKey = "policy"
+)
+
+// Policy relaxes the rules deciding which pods block the drain of a node.
+// A nil Policy keeps the default rules.
+type Policy struct {
+	// ControllerResolver decides which pods are replicated. If nil, only the built-in controllers are known.
+	ControllerResolver ControllerResolver
+	// SafeToEvictLocalVolumes are names of emptyDir volumes whose data may be lost in all pods.
+	SafeToEvictLocalVolumes []string
+	// Namespaces holds namespace-level defaults, keyed by namespace name.
+	Namespaces map[string]NamespacePolicy
+}
+
+// NamespacePolicy holds the defaults for pods in a namespace.
+type NamespacePolicy struct {
+	// SafeToEvict treats pods of the namespace as if they had the safe-to-evict annotation set to "true",
+	// unless it is explicitly set to "false" on the pod.
+	SafeToEvict bool `json:"safeToEvict,omitempty"`
+	// SafeToEvictLocalVolumes are names of emptyDir volumes whose data may be lost in pods of the namespace.
+	SafeToEvictLocalVolumes []string `json:"safeToEvictLocalVolumes,omitempty"`
+}
+
+// policyConfig is the format of the drain policy ConfigMap.
+type policyConfig struct {
+	Namespaces map[string]NamespacePolicy `json:"namespaces"`
+}
+
+// ParseNamespacePolicies parses namespace policies given in YAML or JSON, e.g.:
+//
+//	namespaces:
+//	  ci:
+//	    safeToEvict: true
+//	  monitoring:
+//	    safeToEvictLocalVolumes: [cache, tmp]
+func ParseNamespacePolicies(data []byte) (map[string]NamespacePolicy, error) {
+	config := policyConfig{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse drain policy: %v", err)
+	}
+	for namespace := range config.Namespaces {
+		if namespace == "" {
+			return nil, fmt.Errorf("drain policy for empty namespace name")
+		}
+	}
+	return config.Namespaces, nil
+}
+
+// NamespacePolicyFromAnnotations returns the policy set by the annotations of the namespace. The second
+// result is false if the namespace has no policy annotations.
+func NamespacePolicyFromAnnotations(namespace *apiv1.Namespace) (NamespacePolicy, bool) {
+	policy := NamespacePolicy{}
+	annotations := namespace.GetAnnotations()
+	safeToEvict, hasSafeToEvict := annotations[PodSafeToEvictKey]
+	volumes, hasVolumes := annotations[PodSafeToEvictLocalVolumesKey]
+	policy.SafeToEvict = safeToEvict == "true"
+	policy.SafeToEvictLocalVolumes = splitVolumeNames(volumes)
+	return policy, hasSafeToEvict || hasVolumes
+}
+
+// Merge returns a policy combining both policies. Pods are safe to evict if either policy says so and
+// volumes listed by either policy are safe to lose.
+func (p NamespacePolicy) Merge(other NamespacePolicy) NamespacePolicy {
+	volumes := make([]string, 0, len(p.SafeToEvictLocalVolumes)+len(other.SafeToEvictLocalVolumes))
+	volumes = append(volumes, p.SafeToEvictLocalVolumes...)
+	volumes = append(volumes, other.SafeToEvictLocalVolumes...)
+	return NamespacePolicy{
+		SafeToEvict:             p.SafeToEvict || other.SafeToEvict,
+		SafeToEvictLocalVolumes: volumes,
+	}
+}
+
+func (p *Policy) controllerResolver() ControllerResolver {
+	if p == nil || p.ControllerResolver == nil {
+		return defaultControllerResolver
+	}
+	return p.ControllerResolver
+}
+
+func (p *Policy) namespacePolicy(namespace string) NamespacePolicy {
+	if p == nil {
+		return NamespacePolicy{}
+	}
+	return p.Namespaces[namespace]
+}
+
+// safeToEvict checks if the pod can be evicted regardless of other constraints.
+func (p *Policy) safeToEvict(pod *apiv1.Pod) bool {
+	if hasSafeToEvictAnnotation(pod) {
+		return true
+	}
+	return !hasNotSafeToEvictAnnotation(pod) && p.namespacePolicy(pod.Namespace).SafeToEvict
+}
+
+// hasUnsafeLocalStorage checks if the pod has a hostPath volume, or an emptyDir volume that is not
+// allowlisted by the pod annotation, the namespace policy or the global policy.
+func (p *Policy) hasUnsafeLocalStorage(pod *apiv1.Pod) bool {
+	var safeVolumes map[string]bool
+	for _, volume := range pod.Spec.Volumes {
+		if !isLocalVolume(&volume) {
+			continue
+		}
+		if volume.HostPath != nil {
+			return true
+		}
+		if safeVolumes == nil {
+			safeVolumes = p.safeLocalVolumes(pod)
+		}
+		if !safeVolumes[volume.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) safeLocalVolumes(pod *apiv1.Pod) map[string]bool {
+	result := make(map[string]bool)
+	for _, name := range splitVolumeNames(pod.GetAnnotations()[PodSafeToEvictLocalVolumesKey]) {
+		result[name] = true
+	}
+	if p != nil {
+		for _, name := range p.SafeToEvictLocalVolumes {
+			result[name] = true
+		}
+	}
+	for _, name := range p.namespacePolicy(pod.Namespace).SafeToEvictLocalVolumes {
+		result[name] = true
+	}
+	return result
+}
+
+func splitVolumeNames(value string) []string {
+	result := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
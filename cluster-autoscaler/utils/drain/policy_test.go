@@ -0,0 +1,193 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildPodWithVolumes(namespace string, annotations map[string]string, volumes ...apiv1.Volume) *apiv1.Pod {
+	pod := buildControlledPod("ReplicaSet", "extensions/v1beta1")
+	pod.Namespace = namespace
+	pod.Annotations = annotations
+	pod.Spec.Volumes = volumes
+	return pod
+}
+
+func emptyDirVolume(name string) apiv1.Volume {
+	return apiv1.Volume{Name: name, VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}}
+}
+
+func hostPathVolume(name string) apiv1.Volume {
+	return apiv1.Volume{Name: name, VolumeSource: apiv1.VolumeSource{HostPath: &apiv1.HostPathVolumeSource{Path: "/tmp"}}}
+}
+
+func TestParseNamespacePolicies(t *testing.T) {
+	namespaces, err := ParseNamespacePolicies([]byte(`
+namespaces:
+  ci:
+    safeToEvict: true
+  monitoring:
+    safeToEvictLocalVolumes: [cache, tmp]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]NamespacePolicy{
+		"ci":         {SafeToEvict: true},
+		"monitoring": {SafeToEvictLocalVolumes: []string{"cache", "tmp"}},
+	}, namespaces)
+
+	namespaces, err = ParseNamespacePolicies([]byte(`{"namespaces": {"ci": {"safeToEvict": true}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]NamespacePolicy{"ci": {SafeToEvict: true}}, namespaces)
+
+	namespaces, err = ParseNamespacePolicies([]byte(""))
+	assert.NoError(t, err)
+	assert.Empty(t, namespaces)
+
+	_, err = ParseNamespacePolicies([]byte("namespaces: [ci]"))
+	assert.Error(t, err)
+}
+
+func TestNamespacePolicyFromAnnotations(t *testing.T) {
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}
+	_, found := NamespacePolicyFromAnnotations(namespace)
+	assert.False(t, found)
+
+	namespace.Annotations = map[string]string{
+		PodSafeToEvictKey:             "true",
+		PodSafeToEvictLocalVolumesKey: "cache, tmp,",
+	}
+	policy, found := NamespacePolicyFromAnnotations(namespace)
+	assert.True(t, found)
+	assert.Equal(t, NamespacePolicy{SafeToEvict: true, SafeToEvictLocalVolumes: []string{"cache", "tmp"}}, policy)
+
+	merged := NamespacePolicy{SafeToEvictLocalVolumes: []string{"scratch"}}.Merge(policy)
+	assert.Equal(t, NamespacePolicy{SafeToEvict: true, SafeToEvictLocalVolumes: []string{"scratch", "cache", "tmp"}}, merged)
+}
+
+func TestGetPodsForDeletionOnNodeDrainWithPolicy(t *testing.T) {
+	argoResolver, err := NewReplicatedKindsResolver([]string{"Workflow.argoproj.io"})
+	assert.NoError(t, err)
+	policy := &Policy{
+		ControllerResolver:      NewChainControllerResolver(NewBuiltinControllerResolver(), argoResolver),
+		SafeToEvictLocalVolumes: []string{"global-cache"},
+		Namespaces: map[string]NamespacePolicy{
+			"ci":         {SafeToEvict: true},
+			"monitoring": {SafeToEvictLocalVolumes: []string{"metrics-cache"}},
+		},
+	}
+
+	notSafeInCi := buildControlledPod("ReplicaSet", "extensions/v1beta1")
+	notSafeInCi.Namespace = "ci"
+	notSafeInCi.Annotations = map[string]string{PodSafeToEvictKey: "false"}
+
+	unreplicatedInCi := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bare", Namespace: "ci"}}
+	unreplicated := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bare", Namespace: "default"}}
+
+	testCases := []struct {
+		description string
+		pod         *apiv1.Pod
+		policy      *Policy
+		reason      BlockingPodReason
+	}{
+		{
+			description: "emptyDir blocks without policy",
+			pod:         buildPodWithVolumes("default", nil, emptyDirVolume("global-cache")),
+			reason:      LocalStorageRequested,
+		},
+		{
+			description: "globally allowlisted emptyDir",
+			pod:         buildPodWithVolumes("default", nil, emptyDirVolume("global-cache")),
+			policy:      policy,
+		},
+		{
+			description: "emptyDir allowlisted by pod annotation",
+			pod: buildPodWithVolumes("default", map[string]string{PodSafeToEvictLocalVolumesKey: "scratch,other"},
+				emptyDirVolume("scratch")),
+		},
+		{
+			description: "emptyDir allowlisted in another namespace",
+			pod:         buildPodWithVolumes("default", nil, emptyDirVolume("metrics-cache")),
+			policy:      policy,
+			reason:      LocalStorageRequested,
+		},
+		{
+			description: "emptyDir allowlisted by namespace policy",
+			pod:         buildPodWithVolumes("monitoring", nil, emptyDirVolume("metrics-cache"), emptyDirVolume("global-cache")),
+			policy:      policy,
+		},
+		{
+			description: "one emptyDir not allowlisted",
+			pod:         buildPodWithVolumes("monitoring", nil, emptyDirVolume("metrics-cache"), emptyDirVolume("data")),
+			policy:      policy,
+			reason:      LocalStorageRequested,
+		},
+		{
+			description: "hostPath can't be allowlisted",
+			pod: buildPodWithVolumes("default", map[string]string{PodSafeToEvictLocalVolumesKey: "host"},
+				hostPathVolume("host")),
+			policy: policy,
+			reason: LocalStorageRequested,
+		},
+		{
+			description: "unreplicated pod in safe to evict namespace",
+			pod:         unreplicatedInCi,
+			policy:      policy,
+		},
+		{
+			description: "unreplicated pod",
+			pod:         unreplicated,
+			policy:      policy,
+			reason:      NotReplicated,
+		},
+		{
+			description: "pod annotation overrides namespace policy",
+			pod:         notSafeInCi,
+			policy:      policy,
+			reason:      NotSafeToEvictAnnotation,
+		},
+		{
+			description: "custom controller without policy",
+			pod:         buildControlledPod("Workflow", "argoproj.io/v1alpha1"),
+			reason:      NotReplicated,
+		},
+		{
+			description: "custom controller treated as replicated",
+			pod:         buildControlledPod("Workflow", "argoproj.io/v1alpha1"),
+			policy:      policy,
+		},
+	}
+
+	for _, tc := range testCases {
+		pods, blockingPod, err := GetPodsForDeletionOnNodeDrain([]*apiv1.Pod{tc.pod}, nil,
+			false, true, true, false, nil, 0, time.Now(), tc.policy)
+		if tc.reason == "" {
+			assert.NoError(t, err, tc.description)
+			assert.Nil(t, blockingPod, tc.description)
+			assert.Equal(t, []*apiv1.Pod{tc.pod}, pods, tc.description)
+		} else {
+			assert.Error(t, err, tc.description)
+			assert.Equal(t, &BlockingPod{Pod: tc.pod, Reason: tc.reason}, blockingPod, tc.description)
+		}
+	}
+}
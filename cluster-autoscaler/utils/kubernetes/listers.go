@@ -22,12 +22,15 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
 	policyv1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
 	client "k8s.io/client-go/kubernetes"
 	v1lister "k8s.io/client-go/listers/core/v1"
 	v1extensionslister "k8s.io/client-go/listers/extensions/v1beta1"
 	v1policylister "k8s.io/client-go/listers/policy/v1beta1"
+	v1storagelister "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
 	podv1 "k8s.io/kubernetes/pkg/api/v1/pod"
 )
@@ -270,6 +273,62 @@ func NewPodDisruptionBudgetLister(kubeClient client.Interface, stopchannel <-cha
 	}
 }
 
+// NamespaceLister lists namespaces.
+type NamespaceLister interface {
+	List() ([]*apiv1.Namespace, error)
+}
+
+// NamespaceListerImpl lists all namespaces.
+type NamespaceListerImpl struct {
+	namespaceLister v1lister.NamespaceLister
+}
+
+// List returns all namespaces
+func (lister *NamespaceListerImpl) List() ([]*apiv1.Namespace, error) {
+	return lister.namespaceLister.List(labels.Everything())
+}
+
+// NewNamespaceLister builds a namespace lister.
+func NewNamespaceLister(kubeClient client.Interface, stopchannel <-chan struct{}) NamespaceLister {
+	listWatcher := cache.NewListWatchFromClient(kubeClient.CoreV1().RESTClient(), "namespaces", apiv1.NamespaceAll, fields.Everything())
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	namespaceLister := v1lister.NewNamespaceLister(store)
+	reflector := cache.NewReflector(listWatcher, &apiv1.Namespace{}, store, time.Hour)
+	go reflector.Run(stopchannel)
+	return &NamespaceListerImpl{
+		namespaceLister: namespaceLister,
+	}
+}
+
+// ConfigMapLister gets config maps.
+type ConfigMapLister interface {
+	Get(name string) (*apiv1.ConfigMap, error)
+}
+
+// ConfigMapListerImpl gets config maps of a single namespace.
+type ConfigMapListerImpl struct {
+	configMapLister v1lister.ConfigMapNamespaceLister
+}
+
+// Get returns the config map with the given name.
+func (lister *ConfigMapListerImpl) Get(name string) (*apiv1.ConfigMap, error) {
+	return lister.configMapLister.Get(name)
+}
+
+// NewConfigMapLister builds a lister of a single config map with the given namespace and name. Other config
+// maps in the namespace are not watched.
+func NewConfigMapLister(kubeClient client.Interface, namespace, name string, stopchannel <-chan struct{}) ConfigMapLister {
+	selector := fields.OneTermEqualSelector("metadata.name", name)
+	listWatcher := cache.NewListWatchFromClient(kubeClient.CoreV1().RESTClient(), "configmaps", namespace, selector)
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	configMapLister := v1lister.NewConfigMapLister(store).ConfigMaps(namespace)
+	reflector := cache.NewReflector(listWatcher, &apiv1.ConfigMap{}, store, time.Hour)
+	go reflector.Run(stopchannel)
+	return &ConfigMapListerImpl{
+		configMapLister: configMapLister,
+	}
+}
+
 // DaemonSetLister lists daemonsets.
 type DaemonSetLister interface {
 	List() ([]*extensionsv1.DaemonSet, error)
@@ -296,3 +355,42 @@ func NewDaemonSetLister(kubeClient client.Interface, stopchannel <-chan struct{}
 		daemonSetLister: lister,
 	}
 }
+
+// VolumeLister gets persistent volume claims, persistent volumes and storage classes.
+type VolumeLister interface {
+	GetPersistentVolumeClaim(namespace, name string) (*apiv1.PersistentVolumeClaim, error)
+	GetPersistentVolume(name string) (*apiv1.PersistentVolume, error)
+	GetStorageClass(name string) (*storagev1.StorageClass, error)
+}
+
+// VolumeListerImpl gets persistent volume claims, persistent volumes and storage classes from informer caches.
+type VolumeListerImpl struct {
+	pvcLister   v1lister.PersistentVolumeClaimLister
+	pvLister    v1lister.PersistentVolumeLister
+	classLister v1storagelister.StorageClassLister
+}
+
+// GetPersistentVolumeClaim returns the persistent volume claim with the given namespace and name.
+func (lister *VolumeListerImpl) GetPersistentVolumeClaim(namespace, name string) (*apiv1.PersistentVolumeClaim, error) {
+	return lister.pvcLister.PersistentVolumeClaims(namespace).Get(name)
+}
+
+// GetPersistentVolume returns the persistent volume with the given name.
+func (lister *VolumeListerImpl) GetPersistentVolume(name string) (*apiv1.PersistentVolume, error) {
+	return lister.pvLister.Get(name)
+}
+
+// GetStorageClass returns the storage class with the given name.
+func (lister *VolumeListerImpl) GetStorageClass(name string) (*storagev1.StorageClass, error) {
+	return lister.classLister.Get(name)
+}
+
+// NewVolumeLister builds a persistent volume claim, persistent volume and storage class lister using the
+// informers of the given factory, so that other users of the factory share the same watches.
+func NewVolumeLister(informerFactory informers.SharedInformerFactory) VolumeLister {
+	return &VolumeListerImpl{
+		pvcLister:   informerFactory.Core().V1().PersistentVolumeClaims().Lister(),
+		pvLister:    informerFactory.Core().V1().PersistentVolumes().Lister(),
+		classLister: informerFactory.Storage().V1().StorageClasses().Lister(),
+	}
+}
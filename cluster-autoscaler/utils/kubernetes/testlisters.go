@@ -0,0 +1,179 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// TestPodLister is a PodLister returning a replaceable list of pods. Intended for tests.
+type TestPodLister struct {
+	mutex sync.Mutex
+	pods  []*apiv1.Pod
+}
+
+// NewTestPodLister builds TestPodLister returning the given pods.
+func NewTestPodLister(pods []*apiv1.Pod) *TestPodLister {
+	return &TestPodLister{pods: pods}
+}
+
+// List returns the current pods.
+func (lister *TestPodLister) List() ([]*apiv1.Pod, error) {
+	lister.mutex.Lock()
+	defer lister.mutex.Unlock()
+	return lister.pods, nil
+}
+
+// SetPods replaces the returned pods.
+func (lister *TestPodLister) SetPods(pods []*apiv1.Pod) {
+	lister.mutex.Lock()
+	defer lister.mutex.Unlock()
+	lister.pods = pods
+}
+
+// TestPodDisruptionBudgetLister is a PodDisruptionBudgetLister returning a replaceable list of pdbs.
+// Intended for tests.
+type TestPodDisruptionBudgetLister struct {
+	mutex sync.Mutex
+	pdbs  []*policyv1.PodDisruptionBudget
+}
+
+// NewTestPodDisruptionBudgetLister builds TestPodDisruptionBudgetLister returning the given pdbs.
+func NewTestPodDisruptionBudgetLister(pdbs []*policyv1.PodDisruptionBudget) *TestPodDisruptionBudgetLister {
+	return &TestPodDisruptionBudgetLister{pdbs: pdbs}
+}
+
+// List returns the current pdbs.
+func (lister *TestPodDisruptionBudgetLister) List() ([]*policyv1.PodDisruptionBudget, error) {
+	lister.mutex.Lock()
+	defer lister.mutex.Unlock()
+	return lister.pdbs, nil
+}
+
+// SetPdbs replaces the returned pdbs.
+func (lister *TestPodDisruptionBudgetLister) SetPdbs(pdbs []*policyv1.PodDisruptionBudget) {
+	lister.mutex.Lock()
+	defer lister.mutex.Unlock()
+	lister.pdbs = pdbs
+}
+
+// TestNamespaceLister is a NamespaceLister returning a fixed list of namespaces. Intended for tests.
+type TestNamespaceLister struct {
+	namespaces []*apiv1.Namespace
+}
+
+// NewTestNamespaceLister builds TestNamespaceLister returning the given namespaces.
+func NewTestNamespaceLister(namespaces []*apiv1.Namespace) *TestNamespaceLister {
+	return &TestNamespaceLister{namespaces: namespaces}
+}
+
+// List returns the namespaces.
+func (lister *TestNamespaceLister) List() ([]*apiv1.Namespace, error) {
+	return lister.namespaces, nil
+}
+
+// TestConfigMapLister is a ConfigMapLister returning config maps that can be replaced during the test.
+// Intended for tests.
+type TestConfigMapLister struct {
+	mutex      sync.Mutex
+	configMaps map[string]*apiv1.ConfigMap
+}
+
+// NewTestConfigMapLister builds TestConfigMapLister returning the given config maps.
+func NewTestConfigMapLister(configMaps []*apiv1.ConfigMap) *TestConfigMapLister {
+	lister := &TestConfigMapLister{}
+	lister.SetConfigMaps(configMaps)
+	return lister
+}
+
+// Get returns the config map with the given name.
+func (lister *TestConfigMapLister) Get(name string) (*apiv1.ConfigMap, error) {
+	lister.mutex.Lock()
+	defer lister.mutex.Unlock()
+	if configMap, found := lister.configMaps[name]; found {
+		return configMap, nil
+	}
+	return nil, errors.NewNotFound(apiv1.Resource("configmaps"), name)
+}
+
+// SetConfigMaps replaces the config maps returned by the lister.
+func (lister *TestConfigMapLister) SetConfigMaps(configMaps []*apiv1.ConfigMap) {
+	lister.mutex.Lock()
+	defer lister.mutex.Unlock()
+	lister.configMaps = make(map[string]*apiv1.ConfigMap, len(configMaps))
+	for _, configMap := range configMaps {
+		lister.configMaps[configMap.Name] = configMap
+	}
+}
+
+// TestVolumeLister is a VolumeLister returning fixed persistent volume claims, persistent volumes and
+// storage classes. Intended for tests.
+type TestVolumeLister struct {
+	pvcs    map[string]*apiv1.PersistentVolumeClaim
+	pvs     map[string]*apiv1.PersistentVolume
+	classes map[string]*storagev1.StorageClass
+}
+
+// NewTestVolumeLister builds TestVolumeLister returning the given persistent volume claims, persistent volumes
+// and storage classes.
+func NewTestVolumeLister(pvcs []*apiv1.PersistentVolumeClaim, pvs []*apiv1.PersistentVolume,
+	classes []*storagev1.StorageClass) *TestVolumeLister {
+	lister := &TestVolumeLister{
+		pvcs:    make(map[string]*apiv1.PersistentVolumeClaim, len(pvcs)),
+		pvs:     make(map[string]*apiv1.PersistentVolume, len(pvs)),
+		classes: make(map[string]*storagev1.StorageClass, len(classes)),
+	}
+	for _, pvc := range pvcs {
+		lister.pvcs[pvc.Namespace+"/"+pvc.Name] = pvc
+	}
+	for _, pv := range pvs {
+		lister.pvs[pv.Name] = pv
+	}
+	for _, class := range classes {
+		lister.classes[class.Name] = class
+	}
+	return lister
+}
+
+// GetPersistentVolumeClaim returns the persistent volume claim with the given namespace and name.
+func (lister *TestVolumeLister) GetPersistentVolumeClaim(namespace, name string) (*apiv1.PersistentVolumeClaim, error) {
+	if pvc, found := lister.pvcs[namespace+"/"+name]; found {
+		return pvc, nil
+	}
+	return nil, errors.NewNotFound(apiv1.Resource("persistentvolumeclaims"), name)
+}
+
+// GetPersistentVolume returns the persistent volume with the given name.
+func (lister *TestVolumeLister) GetPersistentVolume(name string) (*apiv1.PersistentVolume, error) {
+	if pv, found := lister.pvs[name]; found {
+		return pv, nil
+	}
+	return nil, errors.NewNotFound(apiv1.Resource("persistentvolumes"), name)
+}
+
+// GetStorageClass returns the storage class with the given name.
+func (lister *TestVolumeLister) GetStorageClass(name string) (*storagev1.StorageClass, error) {
+	if class, found := lister.classes[name]; found {
+		return class, nil
+	}
+	return nil, errors.NewNotFound(storagev1.Resource("storageclasses"), name)
+}
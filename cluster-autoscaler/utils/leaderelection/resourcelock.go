@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"encoding/json"
+	"fmt"
+
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// LeasesResourceLock is the lock type storing the leader election record in a Lease.
+	LeasesResourceLock = "leases"
+)
+
+// AvailableResourceLocks lists the supported lock types.
+var AvailableResourceLocks = []string{resourcelock.EndpointsResourceLock, resourcelock.ConfigMapsResourceLock, LeasesResourceLock}
+
+// ReleasableLock is a resource lock that can be given up before it expires.
+type ReleasableLock interface {
+	resourcelock.Interface
+	// Release deletes the lock object if it's held by this instance, so that other candidates
+	// acquire it on their next retry instead of waiting for the lease to expire.
+	Release() error
+}
+
+// NewResourceLock creates a lock of the given type: endpoints, configmaps or leases.
+func NewResourceLock(lockType string, namespace string, name string, client kube_client.Interface,
+	rlc resourcelock.ResourceLockConfig) (ReleasableLock, error) {
+	switch lockType {
+	case LeasesResourceLock:
+		return &LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Client:     client.CoordinationV1beta1(),
+			LockConfig: rlc,
+		}, nil
+	case resourcelock.EndpointsResourceLock:
+		lock, err := resourcelock.New(lockType, namespace, name, client.CoreV1(), rlc)
+		if err != nil {
+			return nil, err
+		}
+		endpoints := client.CoreV1().Endpoints(namespace)
+		return &annotationLock{
+			Interface: lock,
+			get:       func() (metav1.Object, error) { return endpoints.Get(name, metav1.GetOptions{}) },
+			delete:    func(options *metav1.DeleteOptions) error { return endpoints.Delete(name, options) },
+		}, nil
+	case resourcelock.ConfigMapsResourceLock:
+		lock, err := resourcelock.New(lockType, namespace, name, client.CoreV1(), rlc)
+		if err != nil {
+			return nil, err
+		}
+		configMaps := client.CoreV1().ConfigMaps(namespace)
+		return &annotationLock{
+			Interface: lock,
+			get:       func() (metav1.Object, error) { return configMaps.Get(name, metav1.GetOptions{}) },
+			delete:    func(options *metav1.DeleteOptions) error { return configMaps.Delete(name, options) },
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid lock type %s", lockType)
+}
+
+// annotationLock adds Release to the locks storing the leader election record in an annotation.
+type annotationLock struct {
+	resourcelock.Interface
+	get    func() (metav1.Object, error)
+	delete func(*metav1.DeleteOptions) error
+}
+
+// Release deletes the lock object if it's held by this instance.
+func (l *annotationLock) Release() error {
+	object, err := l.get()
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+	recordBytes, found := object.GetAnnotations()[resourcelock.LeaderElectionRecordAnnotationKey]
+	if !found {
+		return nil
+	}
+	var record resourcelock.LeaderElectionRecord
+	if err := json.Unmarshal([]byte(recordBytes), &record); err != nil {
+		return err
+	}
+	if record.HolderIdentity != l.Identity() {
+		return nil
+	}
+	return ignoreNotFound(l.delete(deleteOptionsFor(object)))
+}
+
+// deleteOptionsFor makes sure that only the given object is deleted, not one recreated in the meantime.
+func deleteOptionsFor(object metav1.Object) *metav1.DeleteOptions {
+	uid := object.GetUID()
+	return &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}}
+}
+
+func ignoreNotFound(err error) error {
+	if kube_errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	kube_record "k8s.io/client-go/tools/record"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLock(t *testing.T, lockType string, client *fake.Clientset, identity string) ReleasableLock {
+	lock, err := NewResourceLock(lockType, "kube-system", "cluster-autoscaler", client, resourcelock.ResourceLockConfig{
+		Identity:      identity,
+		EventRecorder: kube_record.NewFakeRecorder(10),
+	})
+	assert.NoError(t, err)
+	return lock
+}
+
+func TestLeaseLock(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	lock := newTestLock(t, LeasesResourceLock, client, "a")
+
+	_, err := lock.Get()
+	assert.Error(t, err)
+	assert.Error(t, lock.Update(resourcelock.LeaderElectionRecord{}))
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	record := resourcelock.LeaderElectionRecord{
+		HolderIdentity:       "a",
+		LeaseDurationSeconds: 15,
+		AcquireTime:          now,
+		RenewTime:            now,
+		LeaderTransitions:    2,
+	}
+	assert.NoError(t, lock.Create(record))
+	got, err := lock.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, record, *got)
+
+	record.RenewTime = metav1.NewTime(now.Add(time.Second))
+	assert.NoError(t, lock.Update(record))
+	lease, err := client.CoordinationV1beta1().Leases("kube-system").Get("cluster-autoscaler", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", *lease.Spec.HolderIdentity)
+	assert.Equal(t, record.RenewTime.Time, lease.Spec.RenewTime.Time)
+	assert.Equal(t, "kube-system/cluster-autoscaler", lock.Describe())
+}
+
+func TestReleaseLock(t *testing.T) {
+	for _, lockType := range AvailableResourceLocks {
+		client := fake.NewSimpleClientset()
+		holder := newTestLock(t, lockType, client, "a")
+		other := newTestLock(t, lockType, client, "b")
+
+		// Nothing to release.
+		assert.NoError(t, holder.Release(), lockType)
+
+		assert.NoError(t, holder.Create(resourcelock.LeaderElectionRecord{HolderIdentity: "a"}), lockType)
+		assert.NoError(t, other.Release(), lockType)
+		_, err := holder.Get()
+		assert.NoError(t, err, "lock released by non-holder: %s", lockType)
+
+		assert.NoError(t, holder.Release(), lockType)
+		_, err = holder.Get()
+		assert.Error(t, err, "lock not released: %s", lockType)
+	}
+}
+
+func TestNewResourceLockInvalidType(t *testing.T) {
+	_, err := NewResourceLock("secrets", "kube-system", "cluster-autoscaler", fake.NewSimpleClientset(),
+		resourcelock.ResourceLockConfig{Identity: "a"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	ctx "context"
+	"fmt"
+	"reflect"
+	"time"
+
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/golang/glog"
+)
+
+// Run runs the leader election with the given lock until stopCtx is done. onStartedLeading is called
+// once the lock is acquired, with a context that is done when the leadership is lost, i.e. when the
+// lease couldn't be renewed within config.RenewDeadline. Unlike leaderelection.LeaderElector.Run, the
+// lease keeps being renewed after stopCtx is done, until onStartedLeading returns, so that no standby
+// candidate takes over while the leader is finishing its work. The lock is then released, so that a
+// standby candidate takes over without waiting for the lease to expire.
+// Only LeaseDuration, RenewDeadline, RetryPeriod and Callbacks.OnNewLeader of config are used.
+func Run(stopCtx ctx.Context, config leaderelection.LeaderElectionConfig, lock ReleasableLock,
+	onStartedLeading func(leaderCtx ctx.Context)) error {
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	e := &elector{config: config, lock: lock}
+	if !e.acquire(stopCtx) {
+		return nil
+	}
+
+	leaderCtx, loseLeadership := ctx.WithCancel(ctx.Background())
+	defer loseLeadership()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		onStartedLeading(leaderCtx)
+	}()
+	e.renew(leaderCtx, loseLeadership, done)
+	<-done
+	// Release is a no-op if another candidate took over in the meantime.
+	return lock.Release()
+}
+
+func validateConfig(config leaderelection.LeaderElectionConfig) error {
+	if config.LeaseDuration <= config.RenewDeadline {
+		return fmt.Errorf("leaseDuration must be greater than renewDeadline")
+	}
+	if config.RenewDeadline <= time.Duration(leaderelection.JitterFactor*float64(config.RetryPeriod)) {
+		return fmt.Errorf("renewDeadline must be greater than retryPeriod*JitterFactor")
+	}
+	if config.RetryPeriod < 1 {
+		return fmt.Errorf("retryPeriod must be greater than zero")
+	}
+	return nil
+}
+
+// elector acquires and renews the lease. Its state is only accessed by the goroutine running Run.
+type elector struct {
+	config leaderelection.LeaderElectionConfig
+	lock   ReleasableLock
+	// observedRecord is the last record read or written, observedTime is when it last changed.
+	observedRecord resourcelock.LeaderElectionRecord
+	observedTime   time.Time
+	reportedLeader string
+}
+
+// acquire tries to acquire the lease every RetryPeriod. Returns false if stopCtx is done first.
+func (e *elector) acquire(stopCtx ctx.Context) bool {
+	glog.Infof("attempting to acquire leader lease %v...", e.lock.Describe())
+	for {
+		acquired := e.tryAcquireOrRenew()
+		e.maybeReportTransition()
+		if acquired {
+			e.lock.RecordEvent("became leader")
+			glog.Infof("successfully acquired lease %v", e.lock.Describe())
+			return true
+		}
+		select {
+		case <-stopCtx.Done():
+			return false
+		case <-time.After(wait.Jitter(e.config.RetryPeriod, leaderelection.JitterFactor)):
+		}
+	}
+}
+
+// renew renews the lease every RetryPeriod until done is closed. If the lease isn't renewed within
+// RenewDeadline, also when a renewal hangs, loseLeadership is called and renew returns.
+func (e *elector) renew(leaderCtx ctx.Context, loseLeadership ctx.CancelFunc, done <-chan struct{}) {
+	deadline := time.AfterFunc(e.config.RenewDeadline, loseLeadership)
+	defer deadline.Stop()
+	ticker := time.NewTicker(e.config.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-leaderCtx.Done():
+			e.lock.RecordEvent("stopped leading")
+			glog.Infof("failed to renew lease %v within %v", e.lock.Describe(), e.config.RenewDeadline)
+			return
+		case <-ticker.C:
+		}
+		renewed := e.tryAcquireOrRenew()
+		e.maybeReportTransition()
+		if renewed && leaderCtx.Err() == nil {
+			deadline.Reset(e.config.RenewDeadline)
+			glog.V(4).Infof("successfully renewed lease %v", e.lock.Describe())
+		}
+	}
+}
+
+// tryAcquireOrRenew acquires the lease if it's free or expired, or renews it if it's held by this
+// candidate. Returns true on success.
+func (e *elector) tryAcquireOrRenew() bool {
+	now := metav1.Now()
+	record := resourcelock.LeaderElectionRecord{
+		HolderIdentity:       e.lock.Identity(),
+		LeaseDurationSeconds: int(e.config.LeaseDuration / time.Second),
+		RenewTime:            now,
+		AcquireTime:          now,
+	}
+
+	oldRecord, err := e.lock.Get()
+	if err != nil {
+		if !kube_errors.IsNotFound(err) {
+			glog.Errorf("error retrieving resource lock %v: %v", e.lock.Describe(), err)
+			return false
+		}
+		if err = e.lock.Create(record); err != nil {
+			glog.Errorf("error initially creating leader election record: %v", err)
+			return false
+		}
+		e.observe(record)
+		return true
+	}
+
+	if !reflect.DeepEqual(e.observedRecord, *oldRecord) {
+		e.observe(*oldRecord)
+	}
+	if !e.isLeader() && e.observedTime.Add(e.config.LeaseDuration).After(now.Time) {
+		glog.V(4).Infof("lock is held by %v and has not yet expired", oldRecord.HolderIdentity)
+		return false
+	}
+
+	if e.isLeader() {
+		record.AcquireTime = oldRecord.AcquireTime
+		record.LeaderTransitions = oldRecord.LeaderTransitions
+	} else {
+		record.LeaderTransitions = oldRecord.LeaderTransitions + 1
+	}
+	if err = e.lock.Update(record); err != nil {
+		glog.Errorf("Failed to update lock: %v", err)
+		return false
+	}
+	e.observe(record)
+	return true
+}
+
+func (e *elector) observe(record resourcelock.LeaderElectionRecord) {
+	e.observedRecord = record
+	e.observedTime = time.Now()
+}
+
+func (e *elector) isLeader() bool {
+	return e.observedRecord.HolderIdentity == e.lock.Identity()
+}
+
+func (e *elector) maybeReportTransition() {
+	if e.observedRecord.HolderIdentity == e.reportedLeader {
+		return
+	}
+	e.reportedLeader = e.observedRecord.HolderIdentity
+	if e.config.Callbacks.OnNewLeader != nil {
+		go e.config.Callbacks.OnNewLeader(e.reportedLeader)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	ctx "context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testElectionConfig() leaderelection.LeaderElectionConfig {
+	return leaderelection.LeaderElectionConfig{
+		LeaseDuration: time.Minute,
+		RenewDeadline: 30 * time.Second,
+		RetryPeriod:   10 * time.Millisecond,
+	}
+}
+
+// failingLock fails all updates once failing is set.
+type failingLock struct {
+	ReleasableLock
+	failing int32
+}
+
+func (l *failingLock) Update(ler resourcelock.LeaderElectionRecord) error {
+	if atomic.LoadInt32(&l.failing) != 0 {
+		return fmt.Errorf("update failed")
+	}
+	return l.ReleasableLock.Update(ler)
+}
+
+func TestRunKeepsLeadingUntilStopped(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	config := leaderelection.LeaderElectionConfig{
+		LeaseDuration: 300 * time.Millisecond,
+		RenewDeadline: 200 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	}
+
+	startedA := make(chan struct{})
+	stoppedA := make(chan struct{})
+	ctxA, cancelA := ctx.WithCancel(ctx.Background())
+	runA := make(chan error)
+	go func() {
+		runA <- Run(ctxA, config, newTestLock(t, LeasesResourceLock, client, "a"), func(leaderCtx ctx.Context) {
+			close(startedA)
+			<-ctxA.Done()
+			// Simulate waiting for in-flight actions for longer than the lease duration.
+			time.Sleep(3 * config.LeaseDuration)
+			assert.NoError(t, leaderCtx.Err(), "a lost leadership while stopping")
+			close(stoppedA)
+		})
+	}()
+	select {
+	case <-startedA:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a didn't become leader")
+	}
+
+	startedB := make(chan struct{})
+	ctxB, cancelB := ctx.WithCancel(ctx.Background())
+	defer cancelB()
+	go Run(ctxB, config, newTestLock(t, LeasesResourceLock, client, "b"), func(leaderCtx ctx.Context) {
+		select {
+		case <-stoppedA:
+		default:
+			t.Error("b took over before a stopped leading")
+		}
+		close(startedB)
+		<-leaderCtx.Done()
+	})
+
+	cancelA()
+	select {
+	case err := <-runA:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after a stopped")
+	}
+	select {
+	case <-stoppedA:
+	default:
+		t.Fatal("Run returned before a stopped leading")
+	}
+
+	select {
+	case <-startedB:
+	case <-time.After(5 * time.Second):
+		t.Fatal("b didn't take over after a released the lock")
+	}
+}
+
+func TestRunLosesLeadershipWhileStopping(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	config := leaderelection.LeaderElectionConfig{
+		LeaseDuration: time.Second,
+		RenewDeadline: 200 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	}
+	lock := &failingLock{ReleasableLock: newTestLock(t, LeasesResourceLock, client, "a")}
+
+	started := make(chan struct{})
+	stopCtx, cancel := ctx.WithCancel(ctx.Background())
+	var failingSince time.Time
+	runDone := make(chan error)
+	go func() {
+		runDone <- Run(stopCtx, config, lock, func(leaderCtx ctx.Context) {
+			close(started)
+			<-stopCtx.Done()
+			// Waiting for in-flight actions, the lease can't be renewed anymore.
+			select {
+			case <-leaderCtx.Done():
+				assert.True(t, time.Since(failingSince) < config.LeaseDuration, "leadership loss noticed after the lease expired")
+			case <-time.After(5 * time.Second):
+				t.Error("leadership loss wasn't noticed")
+			}
+		})
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a didn't become leader")
+	}
+
+	failingSince = time.Now()
+	atomic.StoreInt32(&lock.failing, 1)
+	cancel()
+	select {
+	case err := <-runDone:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run didn't return")
+	}
+}
+
+func TestRunWithoutLeadership(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	holder := newTestLock(t, LeasesResourceLock, client, "b")
+	assert.NoError(t, holder.Create(resourcelock.LeaderElectionRecord{HolderIdentity: "b", LeaseDurationSeconds: 60}))
+
+	runCtx, cancel := ctx.WithTimeout(ctx.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := Run(runCtx, testElectionConfig(), newTestLock(t, LeasesResourceLock, client, "a"), func(leaderCtx ctx.Context) {
+		t.Error("a shouldn't become leader")
+	})
+	assert.NoError(t, err)
+
+	record, err := holder.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "b", record.HolderIdentity)
+}
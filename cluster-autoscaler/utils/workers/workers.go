@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workers
+
+import (
+	"sync"
+)
+
+// Run calls work for every piece in [0, pieces) using at most workers goroutines and waits
+// until all pieces are done. Each goroutine passes its own index in [0, workers) to work, so
+// that it can use state that is not shared with other goroutines. The order in which pieces
+// are processed is not specified; callers wanting deterministic results should store the
+// result of every piece under its index.
+func Run(workers, pieces int, work func(worker, piece int)) {
+	if workers > pieces {
+		workers = pieces
+	}
+	if workers <= 1 {
+		for piece := 0; piece < pieces; piece++ {
+			work(0, piece)
+		}
+		return
+	}
+
+	toProcess := make(chan int, pieces)
+	for piece := 0; piece < pieces; piece++ {
+		toProcess <- piece
+	}
+	close(toProcess)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for worker := 0; worker < workers; worker++ {
+		go func(worker int) {
+			defer wg.Done()
+			for piece := range toProcess {
+				work(worker, piece)
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+// Count returns the number of workers to use for the given parallelism, which is at least one.
+func Count(parallelism int) int {
+	if parallelism < 1 {
+		return 1
+	}
+	return parallelism
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workers
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 100} {
+		results := make([]int, 10)
+		var lock sync.Mutex
+		used := map[int]bool{}
+		Run(workers, len(results), func(worker, piece int) {
+			results[piece] = piece * piece
+			lock.Lock()
+			used[worker] = true
+			lock.Unlock()
+		})
+		for i, result := range results {
+			assert.Equal(t, i*i, result)
+		}
+		for worker := range used {
+			assert.True(t, worker >= 0 && worker < Count(workers) && worker < len(results))
+		}
+	}
+}
+
+func TestRunNoPieces(t *testing.T) {
+	Run(4, 0, func(worker, piece int) {
+		t.Errorf("unexpected piece %d", piece)
+	})
+}
+
+func TestCount(t *testing.T) {
+	assert.Equal(t, 1, Count(-1))
+	assert.Equal(t, 1, Count(0))
+	assert.Equal(t, 8, Count(8))
+}